@@ -0,0 +1,262 @@
+// fakeipmi 模拟ipmitool，用于在没有BMC的环境中测试fanap的ipmi传感器和风扇
+//
+// 使用方法（配置文件中ipmi.command指向本程序）:
+//
+//	go build -o /tmp/fakeipmi ./cmd/fakeipmi
+//	"ipmi": {"command": "/tmp/fakeipmi -vendor supermicro -state /tmp/fakeipmi.json", "preset": "supermicro"}
+//
+// 支持 sdr list/elist，以及所选厂商的风扇控制raw命令。状态（风扇模式、各区域占空比、温度）保存在state文件中，
+// 可以直接编辑其中的温度；风扇转速按占空比计算。加上 -log 后把每次调用追加到日志文件
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// state 模拟BMC的状态
+type state struct {
+	// Mode Supermicro风扇模式（0x00 标准、0x01 全速、0x02 最佳、0x04 重IO）
+	Mode int `json:"mode"`
+	// Manual Dell是否处于手动模式
+	Manual bool `json:"manual"`
+	// Duty 各区域的占空比，自动模式下BMC使用AutoDuty
+	Duty map[string]int `json:"duty"`
+	// AutoDuty 自动模式下的占空比
+	AutoDuty int `json:"auto_duty"`
+	// Temps 温度传感器的读数
+	Temps map[string]float64 `json:"temps"`
+}
+
+func defaultState() *state {
+	return &state{
+		Mode:     0x02,
+		Duty:     map[string]int{},
+		AutoDuty: 50,
+		Temps:    map[string]float64{"CPU Temp": 55, "System Temp": 32, "Peripheral Temp": 38},
+	}
+}
+
+// fans 风扇名称和Supermicro的区域（0为CPU风扇，1为外设风扇）；Dell按风扇在列表中的编号设置
+var fans = []struct {
+	name string
+	zone int
+}{
+	{"FAN1", 0},
+	{"FAN2", 0},
+	{"FANA", 1},
+}
+
+// errInvalid 不支持的raw命令
+var errInvalid = errors.New("Invalid command")
+
+func main() {
+	statePath := flag.String("state", "/tmp/fakeipmi.json", "状态文件")
+	vendor := flag.String("vendor", "supermicro", "模拟的BMC厂商: supermicro、dell")
+	logPath := flag.String("log", "", "把每次调用追加到该文件，为空时不记录")
+	flag.Parse()
+	args := flag.Args()
+
+	if *logPath != "" {
+		if f, err := os.OpenFile(*logPath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644); err == nil {
+			fmt.Fprintf(f, "%s %s\n", time.Now().Format("15:04:05.000"), strings.Join(args, " "))
+			f.Close()
+		}
+	}
+
+	st, err := load(*statePath)
+	if err != nil {
+		fatal("读取状态文件失败: %v", err)
+	}
+
+	if len(args) == 0 {
+		fatal("usage: fakeipmi [options] sdr|raw ...")
+	}
+	switch args[0] {
+	case "sdr":
+		elist := len(args) > 1 && args[1] == "elist"
+		printSDR(st, *vendor, elist)
+		return
+	case "raw":
+		req, err := parseBytes(args[1:])
+		if err != nil {
+			fatal("%v", err)
+		}
+		var resp []byte
+		switch *vendor {
+		case "supermicro":
+			resp, err = supermicro(st, req)
+		case "dell":
+			resp, err = dell(st, req)
+		default:
+			fatal("未知的厂商 %q", *vendor)
+		}
+		if err != nil {
+			fatal("Unable to send RAW command (channel=0x0 netfn=0x%x lun=0x0 cmd=0x%x rsp=0xc1): %v", req[0], req[1], err)
+		}
+		if err := save(*statePath, st); err != nil {
+			fatal("保存状态文件失败: %v", err)
+		}
+		if len(resp) > 0 {
+			for _, b := range resp {
+				fmt.Printf(" %02x", b)
+			}
+			fmt.Println()
+		}
+	default:
+		fatal("不支持的命令 %q", args[0])
+	}
+}
+
+// supermicro 模拟Supermicro X10/X11的风扇控制命令
+func supermicro(st *state, req []byte) ([]byte, error) {
+	switch {
+	case match(req, 0x30, 0x45, 0x00):
+		return []byte{byte(st.Mode)}, nil
+	case match(req, 0x30, 0x45, 0x01) && len(req) == 4:
+		st.Mode = int(req[3])
+		return nil, nil
+	case match(req, 0x30, 0x70, 0x66, 0x00) && len(req) == 5:
+		return []byte{byte(duty(st, "supermicro", int(req[4])))}, nil
+	case match(req, 0x30, 0x70, 0x66, 0x01) && len(req) == 6:
+		if req[5] > 100 {
+			return nil, errInvalid
+		}
+		st.Duty[strconv.Itoa(int(req[4]))] = int(req[5])
+		return nil, nil
+	}
+	return nil, errInvalid
+}
+
+// dell 模拟Dell iDRAC的风扇控制命令
+func dell(st *state, req []byte) ([]byte, error) {
+	switch {
+	case match(req, 0x30, 0x30, 0x01) && len(req) == 4:
+		st.Manual = req[3] == 0x00
+		return nil, nil
+	case match(req, 0x30, 0x30, 0x02) && len(req) == 5:
+		if req[4] > 100 {
+			return nil, errInvalid
+		}
+		if req[3] == 0xff {
+			for i := range fans {
+				st.Duty[strconv.Itoa(i)] = int(req[4])
+			}
+		} else {
+			st.Duty[strconv.Itoa(int(req[3]))] = int(req[4])
+		}
+		return nil, nil
+	}
+	return nil, errInvalid
+}
+
+// duty 返回区域当前的占空比，自动模式下为AutoDuty
+func duty(st *state, vendor string, zone int) int {
+	manual := st.Manual
+	if vendor == "supermicro" {
+		manual = st.Mode == 0x01
+	}
+	d, ok := st.Duty[strconv.Itoa(zone)]
+	if !manual || !ok {
+		return st.AutoDuty
+	}
+	return d
+}
+
+// printSDR 按 sdr list 或 sdr elist 的格式输出传感器读数
+func printSDR(st *state, vendor string, elist bool) {
+	id := 1
+	line := func(name, reading string) {
+		if elist {
+			fmt.Printf("%-16s | %02Xh | ok  |  7.1 | %s\n", name, id, reading)
+		} else {
+			fmt.Printf("%-16s | %-17s | ok\n", name, reading)
+		}
+		id++
+	}
+
+	for _, name := range []string{"CPU Temp", "System Temp", "Peripheral Temp"} {
+		if t, ok := st.Temps[name]; ok {
+			line(name, fmt.Sprintf("%g degrees C", t))
+		}
+	}
+	for i, f := range fans {
+		zone := f.zone
+		if vendor == "dell" {
+			// Dell按风扇编号设置，FAN1、FAN2、FANA依次为0、1、2
+			zone = i
+		}
+		line(f.name, fmt.Sprintf("%d RPM", duty(st, vendor, zone)*30))
+	}
+	if elist {
+		fmt.Printf("%-16s | %02Xh | ns  |  7.1 | No Reading\n", "FAN3", id)
+	} else {
+		fmt.Printf("%-16s | %-17s | ns\n", "FAN3", "no reading")
+	}
+}
+
+// match 检查请求是否以prefix开头
+func match(req []byte, prefix ...byte) bool {
+	if len(req) < len(prefix) {
+		return false
+	}
+	for i, b := range prefix {
+		if req[i] != b {
+			return false
+		}
+	}
+	return true
+}
+
+// parseBytes 解析raw命令的字节参数
+func parseBytes(args []string) ([]byte, error) {
+	if len(args) < 2 {
+		return nil, fmt.Errorf("Not enough parameters given.")
+	}
+	req := make([]byte, len(args))
+	for i, a := range args {
+		v, err := strconv.ParseUint(a, 0, 8)
+		if err != nil {
+			return nil, fmt.Errorf("Given data \"%s\" is invalid.", a)
+		}
+		req[i] = byte(v)
+	}
+	return req, nil
+}
+
+func load(path string) (*state, error) {
+	st := defaultState()
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return st, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, st); err != nil {
+		return nil, err
+	}
+	if st.Duty == nil {
+		st.Duty = map[string]int{}
+	}
+	return st, nil
+}
+
+func save(path string, st *state) error {
+	data, err := json.MarshalIndent(st, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+func fatal(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, format+"\n", args...)
+	os.Exit(1)
+}
@@ -0,0 +1,61 @@
+// fakesys 创建模拟的sysfs目录树，用于在没有真实硬件的环境中测试fanap
+//
+// 使用方法:
+//
+//	go run ./cmd/fakesys -root ./hwmon-test
+//	sudo fanap -sysfs-root ./hwmon-test -list
+//
+// 加上 -simulate 后持续运行，根据PWM值更新模拟风扇的转速，并模拟pwmchip通道的导出，
+// 可用于测试 fanap probe、pwmchip风扇等需要硬件响应的功能；-layout laptop 生成dell_smm和ThinkPad风扇的笔记本布局
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/fanap/pkg/sysfs/fixture"
+)
+
+func main() {
+	root := flag.String("root", "./hwmon-test", "模拟sysfs目录树的根目录")
+	clean := flag.Bool("clean", true, "创建前删除已存在的目录")
+	simulate := flag.Duration("simulate", 0, "按该间隔根据PWM值更新模拟风扇的转速，直到Ctrl+C (如: 200ms)，0表示不模拟")
+	layout := flag.String("layout", "demo", "目录树布局: demo（台式机）、laptop（dell_smm和ThinkPad风扇）")
+	flag.Parse()
+
+	build := map[string]func(string) (*fixture.Tree, error){"demo": fixture.Demo, "laptop": fixture.Laptop}[*layout]
+	if build == nil {
+		log.Fatalf("未知的布局 %q（可选: demo、laptop）", *layout)
+	}
+
+	if *clean {
+		if err := os.RemoveAll(*root); err != nil {
+			log.Fatalf("清理旧目录失败: %v", err)
+		}
+	}
+
+	tree, err := build(*root)
+	if err != nil {
+		log.Fatalf("创建模拟目录树失败: %v", err)
+	}
+
+	fmt.Println("=== 模拟sysfs目录树创建完成 ===")
+	fmt.Printf("根目录: %s\n\n", tree.Root)
+	fmt.Println("使用方法:")
+	fmt.Printf("  fanap -sysfs-root %s -list\n", tree.Root)
+	fmt.Printf("  FANAP_SYSFS_ROOT=%s fanap -verbose\n", tree.Root)
+
+	if *simulate > 0 {
+		fmt.Printf("\n模拟风扇运行中（每%v更新一次转速），按Ctrl+C停止...\n", *simulate)
+		ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+		defer stop()
+		if err := tree.Simulate(ctx, *simulate); err != nil {
+			log.Fatalf("模拟风扇失败: %v", err)
+		}
+	}
+}
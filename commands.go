@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/fanap/pkg/calibrate"
+	"github.com/fanap/pkg/config"
+	"github.com/fanap/pkg/probe"
+	"github.com/fanap/pkg/sysfs"
+	"github.com/fanap/pkg/tools"
+)
+
+// runCommand 执行子命令，args[0]为子命令名称
+func runCommand(args []string) error {
+	switch args[0] {
+	case "probe":
+		return runProbe(args[1:])
+	case "calibrate":
+		return runCalibrate(args[1:])
+	default:
+		return fmt.Errorf("未知的子命令 %q（可选: probe、calibrate），使用 -help 查看帮助", args[0])
+	}
+}
+
+// stringList 可以重复指定的字符串参数
+type stringList []string
+
+func (l *stringList) String() string { return strings.Join(*l, ",") }
+
+func (l *stringList) Set(s string) error {
+	*l = append(*l, s)
+	return nil
+}
+
+// runProbe 探测PWM通道与风扇转速输入的对应关系，-write 时写入配置文件
+func runProbe(args []string) error {
+	fs := flag.NewFlagSet("probe", flag.ContinueOnError)
+	var pwms stringList
+	fs.Var(&pwms, "pwm", "只探测该风扇选择器对应的PWM通道，可以重复指定 (默认: 所有通道)")
+	settle := fs.Duration("settle", probe.DefaultSettle, "改变PWM后等待转速稳定的时间")
+	lowPWM := fs.Int("low-pwm", probe.DefaultLowPWM, "测试时把PWM通道降到的值 (0-254)")
+	drop := fs.Float64("drop", probe.DefaultDrop, "转速下降超过该比例时认为风扇受该通道控制 (0-1)")
+	write := fs.Bool("write", false, "把对应关系写入 -config 指定的配置文件")
+	cfgPath := fs.String("config", *configFile, "配置文件路径，不存在时生成新的配置文件")
+	format := fs.String("format", *outFormat, "输出格式 (table, json, yaml)")
+	root := fs.String("sysfs-root", *sysfsRoot, "sysfs根目录 (默认: 真实系统)")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf("probe 不接受参数: %s", strings.Join(fs.Args(), " "))
+	}
+
+	outputFormat, err := tools.ParseFormat(*format)
+	if err != nil {
+		return err
+	}
+	if *write && *cfgPath == "" {
+		return fmt.Errorf("-write 需要用 -config 指定配置文件")
+	}
+	sysfs.SetRoot(*root)
+
+	opts := probe.Options{PWMs: pwms, Settle: *settle, LowPWM: *lowPWM, Drop: *drop}
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+
+	// 中断时停止探测并恢复风扇
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	result, err := probe.Run(ctx, opts)
+	if err != nil {
+		return fmt.Errorf("探测失败: %w", err)
+	}
+	if err := tools.Write(os.Stdout, result, outputFormat); err != nil {
+		return err
+	}
+
+	if *write {
+		notes, err := probe.WriteConfig(*cfgPath, result)
+		for _, n := range notes {
+			log.Print(n)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runCalibrate 测量风扇的停转、启动和最低稳定PWM，结果保存到校准结果文件
+func runCalibrate(args []string) error {
+	fs := flag.NewFlagSet("calibrate", flag.ContinueOnError)
+	var pwms stringList
+	fs.Var(&pwms, "pwm", "只校准该风扇选择器对应的风扇，可以重复指定 (默认: 配置文件中的pwm风扇，或所有有转速输入的PWM通道)")
+	tachSel := fs.String("tach", *tach, "转速输入选择器，只能与单个 -pwm 一起使用 (默认: 与pwmN同编号的fanN_input)")
+	settle := fs.Duration("settle", calibrate.DefaultSettle, "改变PWM后等待转速稳定的时间")
+	step := fs.Int("step", calibrate.DefaultStep, "每一步改变的PWM")
+	stateFile := fs.String("calibration", *calibFile, "保存校准结果的文件，为空时不保存")
+	cfgPath := fs.String("config", *configFile, "配置文件路径，校准其中所有pwm类型的风扇")
+	format := fs.String("format", *outFormat, "输出格式 (table, json, yaml)")
+	root := fs.String("sysfs-root", *sysfsRoot, "sysfs根目录 (默认: 真实系统)")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf("calibrate 不接受参数: %s", strings.Join(fs.Args(), " "))
+	}
+
+	outputFormat, err := tools.ParseFormat(*format)
+	if err != nil {
+		return err
+	}
+	opts := calibrate.Options{Settle: *settle, Step: *step}
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+	sysfs.SetRoot(*root)
+
+	var targets []calibrate.Target
+	switch {
+	case len(pwms) > 0:
+		if *tachSel != "" && len(pwms) > 1 {
+			return fmt.Errorf("-tach 只能与单个 -pwm 一起使用")
+		}
+		for _, p := range pwms {
+			targets = append(targets, calibrate.Target{PWM: p, Tach: *tachSel})
+		}
+	case *cfgPath != "":
+		cfg, err := config.Load(*cfgPath)
+		if err != nil {
+			return err
+		}
+		targets = calibrate.ConfigTargets(cfg)
+		if len(targets) == 0 {
+			return fmt.Errorf("配置文件中没有pwm类型的风扇")
+		}
+	default:
+		targets, err = calibrate.AllTargets()
+		if err != nil {
+			return err
+		}
+	}
+
+	// 中断时停止校准并恢复风扇
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	result, err := calibrate.Run(ctx, targets, opts)
+	if err != nil {
+		return fmt.Errorf("校准失败: %w", err)
+	}
+
+	if *stateFile != "" && len(result.Fans) > 0 {
+		state, err := calibrate.Load(*stateFile)
+		if errors.Is(err, os.ErrNotExist) {
+			state, err = &calibrate.State{}, nil
+		}
+		if err != nil {
+			return err
+		}
+		state.Merge(result.Fans)
+		if err := state.Save(*stateFile); err != nil {
+			return err
+		}
+		result.File = *stateFile
+	}
+	if err := tools.Write(os.Stdout, result, outputFormat); err != nil {
+		return err
+	}
+	if len(result.Failed) > 0 {
+		return fmt.Errorf("%d 个风扇校准失败", len(result.Failed))
+	}
+	return nil
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
 	"log"
@@ -10,7 +11,17 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/fanap/pkg/api"
+	"github.com/fanap/pkg/calibrate"
+	"github.com/fanap/pkg/config"
 	"github.com/fanap/pkg/controller"
+	"github.com/fanap/pkg/curve"
+	"github.com/fanap/pkg/filter"
+	"github.com/fanap/pkg/hook"
+	"github.com/fanap/pkg/metrics"
+	"github.com/fanap/pkg/pid"
+	"github.com/fanap/pkg/settings"
+	"github.com/fanap/pkg/sysfs"
 	"github.com/fanap/pkg/tools"
 )
 
@@ -20,13 +31,14 @@ const (
 
 const (
 	// 默认配置
-	DefaultInterval   = 5 * time.Second
-	DefaultLowTemp    = 40.0
-	DefaultHighTemp   = 75.0
-	DefaultMinPWM     = 50
-	DefaultMaxPWM     = 255
+	DefaultInterval   = settings.DefaultInterval
+	DefaultLowTemp    = settings.DefaultLowTemp
+	DefaultHighTemp   = settings.DefaultHighTemp
+	DefaultMinPWM     = settings.DefaultMinPWM
+	DefaultMaxPWM     = settings.DefaultMaxPWM
 	DefaultTempSensor = "auto"
 	DefaultPWMDevice  = "auto"
+	DefaultCurveType  = curve.TypeLinear
 )
 
 var (
@@ -34,16 +46,54 @@ var (
 	showVersion = flag.Bool("version", false, "显示版本信息")
 	listSensors = flag.Bool("list", false, "列出所有可用的温度传感器和PWM风扇设备")
 	checkHWMon  = flag.Bool("check", false, "检查hwmon设备（诊断模式）")
+	outFormat   = flag.String("format", tools.FormatTable, "-list和-check的输出格式 (table, json, yaml)")
 
 	// 风扇控制参数
-	interval   = flag.Duration("interval", DefaultInterval, "温度检查间隔 (如: 5s, 10s)")
-	lowTemp    = flag.Float64("low-temp", DefaultLowTemp, "低温阈值（摄氏度）")
-	highTemp   = flag.Float64("high-temp", DefaultHighTemp, "高温阈值（摄氏度）")
-	minPWM     = flag.Int("min-pwm", DefaultMinPWM, "最小PWM值 (0-255)")
-	maxPWM     = flag.Int("max-pwm", DefaultMaxPWM, "最大PWM值 (0-255)")
-	tempSensor = flag.String("sensor", DefaultTempSensor, "温度传感器路径 (auto=自动检测)")
-	pwmDevice  = flag.String("pwm", DefaultPWMDevice, "PWM风扇设备路径 (auto=自动检测)")
-	verbose    = flag.Bool("verbose", false, "详细输出模式")
+	interval    = flag.Duration("interval", DefaultInterval, "温度检查间隔 (如: 5s, 10s)")
+	lowTemp     = flag.Float64("low-temp", DefaultLowTemp, "低温阈值（摄氏度）")
+	highTemp    = flag.Float64("high-temp", DefaultHighTemp, "高温阈值（摄氏度）")
+	minPWM      = flag.Int("min-pwm", DefaultMinPWM, "最小PWM值 (0-255)")
+	maxPWM      = flag.Int("max-pwm", DefaultMaxPWM, "最大PWM值 (0-255)")
+	tempSensor  = flag.String("sensor", DefaultTempSensor, "温度传感器选择器 (auto=自动检测，绝对路径、hwmon:芯片/标签、thermal:类型)")
+	pwmDevice   = flag.String("pwm", DefaultPWMDevice, "PWM风扇选择器 (auto=自动检测，绝对路径、hwmon:芯片/通道、device:设备路径/通道、pwmchip:控制器/通道、thinkpad)")
+	curvePts    = flag.String("curve", "", "多点风扇曲线，格式 温度:PWM,... (如: 40:50,55:50,70:150,80:255)")
+	curveType   = flag.String("curve-type", DefaultCurveType, "风扇曲线类型 (linear=分段线性, step=阶梯)")
+	targetTemp  = flag.Float64("target-temp", 0, "PID模式的目标温度（摄氏度），0表示不使用PID模式")
+	pidKp       = flag.Float64("kp", pid.DefaultKp, "PID比例增益")
+	pidKi       = flag.Float64("ki", pid.DefaultKi, "PID积分增益")
+	pidKd       = flag.Float64("kd", pid.DefaultKd, "PID微分增益")
+	hystRise    = flag.Float64("hysteresis-rise", 0, "升温回差（摄氏度），温度升高超过该值才提速")
+	hystFall    = flag.Float64("hysteresis-fall", 0, "降温回差（摄氏度），温度降低超过该值才降速")
+	rampUp      = flag.Float64("ramp-up", 0, "提速时每秒最多增加的PWM，0表示不限制")
+	rampDown    = flag.Float64("ramp-down", 0, "降速时每秒最多减少的PWM，0表示不限制")
+	filterSpec  = flag.String("filter", "", "温度滤波链，格式 类型:参数,... (如: spike:10,median:5,ema:0.3)")
+	sampleIntv  = flag.Duration("sample-interval", 0, "温度采样间隔，可以比检查间隔更短 (默认: 每次检查时采样)")
+	onPWM       = flag.Int("on-pwm", controller.DefaultOnPWM, "2级冷却设备的打开阈值 (0-255)")
+	offPWM      = flag.Int("off-pwm", controller.DefaultOffPWM, "2级冷却设备的关闭阈值 (0-255)")
+	verbose     = flag.Bool("verbose", false, "详细输出模式")
+	sysfsRoot   = flag.String("sysfs-root", "", "sysfs根目录，用于在模拟的目录树上运行 (默认: 真实系统)")
+	configFile  = flag.String("config", "", "配置文件路径 (JSON格式)，设置后忽略单风扇控制参数")
+	metricsAddr = flag.String("metrics-addr", "", "Prometheus指标监听地址 (如: 127.0.0.1:9101)，为空时不启用")
+	apiAddr     = flag.String("api-addr", "", "HTTP管理接口监听地址 (如: 127.0.0.1:9102 或 unix:/run/fanap.sock)，为空时不启用")
+	apiToken    = flag.String("api-token", "", "HTTP管理接口的访问令牌 (Authorization: Bearer <token>)")
+	fsReads     = flag.Int("failsafe-reads", controller.DefaultFailsafeReads, "传感器连续失败多少次后进入失效保护，0表示不启用")
+	fsDuty      = flag.Float64("failsafe-duty", controller.DefaultFailsafeDuty, "失效保护时的风扇占空比 (%)")
+	fsMinTemp   = flag.Float64("failsafe-min-temp", controller.DefaultFailsafeMinTemp, "合理温度的下限，超出范围的读数视为失败")
+	fsMaxTemp   = flag.Float64("failsafe-max-temp", controller.DefaultFailsafeMaxTemp, "合理温度的上限，超出范围的读数视为失败")
+	onExit      = flag.String("on-exit", "", "退出时对风扇的处理方式 (restore=恢复原始模式, full=全速, leave=保持不变)，默认restore")
+	tach        = flag.String("tach", "", "风扇的转速输入选择器 (如: hwmon:nct6775/fan2)，默认使用与pwmN同编号的fanN_input")
+	stallDetect = flag.Bool("stall-detect", false, "根据fanN_input检测风扇停转")
+	stallMinPWM = flag.Int("stall-min-pwm", controller.DefaultStallMinPWM, "只在PWM不低于该值时检查风扇转速")
+	stallMinRPM = flag.Int("stall-min-rpm", 0, "转速低于该值视为停转，0表示使用fanN_min")
+	stallChecks = flag.Int("stall-checks", controller.DefaultStallChecks, "连续多少次检查异常后判定为停转")
+	stallKick   = flag.Duration("stall-kick", controller.DefaultStallKick, "停转后全速启动的时长，0表示不尝试重新启动")
+	zeroRPM     = flag.Bool("zero-rpm", false, "启用零转速模式，低温时风扇停转")
+	zeroStop    = flag.Float64("zero-rpm-stop-temp", controller.DefaultZeroRPMStopTemp, "零转速模式的停转温度")
+	zeroStart   = flag.Float64("zero-rpm-start-temp", controller.DefaultZeroRPMStartTemp, "零转速模式下停转后重新启动的温度")
+	zeroKickPWM = flag.Int("zero-rpm-kick-pwm", controller.DefaultZeroRPMKickPWM, "风扇从停转状态启动时的PWM")
+	zeroKick    = flag.Duration("zero-rpm-kick", controller.DefaultZeroRPMKick, "风扇从停转状态启动时以启动PWM运行的时长，0表示直接以计算出的PWM启动")
+	alarmCmd    = flag.String("alarm-command", "", "风扇停转、传感器失效及其恢复时执行的命令")
+	calibFile   = flag.String("calibration", calibrate.DefaultStateFile, "风扇校准结果文件，存在时自动加载，为空时不使用")
 )
 
 // getEnvDuration 从环境变量获取时间间隔
@@ -125,18 +175,169 @@ func main() {
 	if *pwmDevice == DefaultPWMDevice {
 		*pwmDevice = getEnvString("FANAP_PWM", DefaultPWMDevice)
 	}
+	if *curvePts == "" {
+		*curvePts = getEnvString("FANAP_CURVE", "")
+	}
+	if *curveType == DefaultCurveType {
+		*curveType = getEnvString("FANAP_CURVE_TYPE", DefaultCurveType)
+	}
+	if *targetTemp == 0 {
+		*targetTemp = getEnvFloat("FANAP_TARGET_TEMP", 0)
+	}
+	if *pidKp == pid.DefaultKp {
+		*pidKp = getEnvFloat("FANAP_KP", pid.DefaultKp)
+	}
+	if *pidKi == pid.DefaultKi {
+		*pidKi = getEnvFloat("FANAP_KI", pid.DefaultKi)
+	}
+	if *pidKd == pid.DefaultKd {
+		*pidKd = getEnvFloat("FANAP_KD", pid.DefaultKd)
+	}
+	if *hystRise == 0 {
+		*hystRise = getEnvFloat("FANAP_HYSTERESIS_RISE", 0)
+	}
+	if *hystFall == 0 {
+		*hystFall = getEnvFloat("FANAP_HYSTERESIS_FALL", 0)
+	}
+	if *rampUp == 0 {
+		*rampUp = getEnvFloat("FANAP_RAMP_UP", 0)
+	}
+	if *rampDown == 0 {
+		*rampDown = getEnvFloat("FANAP_RAMP_DOWN", 0)
+	}
+	if *filterSpec == "" {
+		*filterSpec = getEnvString("FANAP_FILTER", "")
+	}
+	if *sampleIntv == 0 {
+		*sampleIntv = getEnvDuration("FANAP_SAMPLE_INTERVAL", 0)
+	}
+	if *onPWM == controller.DefaultOnPWM {
+		*onPWM = getEnvInt("FANAP_ON_PWM", controller.DefaultOnPWM)
+	}
+	if *offPWM == controller.DefaultOffPWM {
+		*offPWM = getEnvInt("FANAP_OFF_PWM", controller.DefaultOffPWM)
+	}
 	if !*verbose {
 		*verbose = getEnvBool("FANAP_VERBOSE", false)
 	}
+	if *sysfsRoot == "" {
+		*sysfsRoot = getEnvString(sysfs.EnvRoot, "")
+	}
+	sysfs.SetRoot(*sysfsRoot)
+	if *configFile == "" {
+		*configFile = getEnvString("FANAP_CONFIG", "")
+	}
+	if *metricsAddr == "" {
+		*metricsAddr = getEnvString("FANAP_METRICS_ADDR", "")
+	}
+	if *apiAddr == "" {
+		*apiAddr = getEnvString("FANAP_API_ADDR", "")
+	}
+	if *apiToken == "" {
+		*apiToken = getEnvString("FANAP_API_TOKEN", "")
+	}
+	if *onExit == "" {
+		*onExit = getEnvString("FANAP_ON_EXIT", "")
+	}
+	if *fsReads == controller.DefaultFailsafeReads {
+		*fsReads = getEnvInt("FANAP_FAILSAFE_READS", controller.DefaultFailsafeReads)
+	}
+	if *fsDuty == controller.DefaultFailsafeDuty {
+		*fsDuty = getEnvFloat("FANAP_FAILSAFE_DUTY", controller.DefaultFailsafeDuty)
+	}
+	if *fsMinTemp == controller.DefaultFailsafeMinTemp {
+		*fsMinTemp = getEnvFloat("FANAP_FAILSAFE_MIN_TEMP", controller.DefaultFailsafeMinTemp)
+	}
+	if *fsMaxTemp == controller.DefaultFailsafeMaxTemp {
+		*fsMaxTemp = getEnvFloat("FANAP_FAILSAFE_MAX_TEMP", controller.DefaultFailsafeMaxTemp)
+	}
+	if *tach == "" {
+		*tach = getEnvString("FANAP_TACH", "")
+	}
+	if !*stallDetect {
+		*stallDetect = getEnvBool("FANAP_STALL_DETECT", false)
+	}
+	if *stallMinPWM == controller.DefaultStallMinPWM {
+		*stallMinPWM = getEnvInt("FANAP_STALL_MIN_PWM", controller.DefaultStallMinPWM)
+	}
+	if *stallMinRPM == 0 {
+		*stallMinRPM = getEnvInt("FANAP_STALL_MIN_RPM", 0)
+	}
+	if *stallChecks == controller.DefaultStallChecks {
+		*stallChecks = getEnvInt("FANAP_STALL_CHECKS", controller.DefaultStallChecks)
+	}
+	if *stallKick == controller.DefaultStallKick {
+		*stallKick = getEnvDuration("FANAP_STALL_KICK", controller.DefaultStallKick)
+	}
+	if !*zeroRPM {
+		*zeroRPM = getEnvBool("FANAP_ZERO_RPM", false)
+	}
+	if *zeroStop == controller.DefaultZeroRPMStopTemp {
+		*zeroStop = getEnvFloat("FANAP_ZERO_RPM_STOP_TEMP", controller.DefaultZeroRPMStopTemp)
+	}
+	if *zeroStart == controller.DefaultZeroRPMStartTemp {
+		*zeroStart = getEnvFloat("FANAP_ZERO_RPM_START_TEMP", controller.DefaultZeroRPMStartTemp)
+	}
+	if *zeroKickPWM == controller.DefaultZeroRPMKickPWM {
+		*zeroKickPWM = getEnvInt("FANAP_ZERO_RPM_KICK_PWM", controller.DefaultZeroRPMKickPWM)
+	}
+	if *zeroKick == controller.DefaultZeroRPMKick {
+		*zeroKick = getEnvDuration("FANAP_ZERO_RPM_KICK", controller.DefaultZeroRPMKick)
+	}
+	if *alarmCmd == "" {
+		*alarmCmd = getEnvString("FANAP_ALARM_COMMAND", "")
+	}
+	if *calibFile == calibrate.DefaultStateFile {
+		*calibFile = getEnvString("FANAP_CALIBRATION", calibrate.DefaultStateFile)
+	}
+	if *outFormat == tools.FormatTable {
+		*outFormat = getEnvString("FANAP_FORMAT", tools.FormatTable)
+	}
+
+	// 子命令（如 fanap probe）
+	if flag.NArg() > 0 {
+		if err := runCommand(flag.Args()); err != nil {
+			log.Printf("错误: %v", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
 
 	// 显示配置信息
 	log.Println("=== Fanap 配置 ===")
+	if *configFile != "" {
+		log.Printf("配置文件: %s", *configFile)
+	}
 	log.Printf("温度检查间隔: %v", *interval)
-	log.Printf("温度阈值: %.1f°C - %.1f°C", *lowTemp, *highTemp)
+	if *targetTemp != 0 {
+		log.Printf("PID目标温度: %.1f°C (kp=%g, ki=%g, kd=%g)", *targetTemp, *pidKp, *pidKi, *pidKd)
+	} else if *curvePts != "" {
+		log.Printf("风扇曲线: %s (%s)", *curvePts, *curveType)
+	} else {
+		log.Printf("温度阈值: %.1f°C - %.1f°C", *lowTemp, *highTemp)
+	}
 	log.Printf("PWM范围: %d - %d", *minPWM, *maxPWM)
+	if *hystRise != 0 || *hystFall != 0 {
+		log.Printf("温度回差: 升温 %.1f°C, 降温 %.1f°C", *hystRise, *hystFall)
+	}
+	if *filterSpec != "" {
+		log.Printf("温度滤波: %s (采样间隔: %v)", *filterSpec, *sampleIntv)
+	}
+	if *rampUp != 0 || *rampDown != 0 {
+		log.Printf("PWM变化速率限制: 提速 %g/s, 降速 %g/s", *rampUp, *rampDown)
+	}
 	log.Printf("温度传感器: %s", *tempSensor)
 	log.Printf("PWM设备: %s", *pwmDevice)
 	log.Printf("详细日志: %v", *verbose)
+	if root := sysfs.Root(); root != "" {
+		log.Printf("sysfs根目录: %s", root)
+	}
+	if *metricsAddr != "" {
+		log.Printf("指标监听地址: %s", *metricsAddr)
+	}
+	if *apiAddr != "" {
+		log.Printf("管理接口地址: %s (访问令牌: %v)", *apiAddr, *apiToken != "")
+	}
 
 	// 处理特殊命令
 	if *showHelp {
@@ -149,18 +350,27 @@ func main() {
 		os.Exit(0)
 	}
 
-	if *listSensors {
-		tools.ListHWMon()
-		os.Exit(0)
-	}
-
-	if *checkHWMon {
-		tools.CheckHWMon()
+	if *listSensors || *checkHWMon {
+		if err := runTools(); err != nil {
+			log.Printf("错误: %v", err)
+			os.Exit(1)
+		}
 		os.Exit(0)
 	}
 
 	// 运行风扇控制程序
-	runFanController()
+	// 运行函数返回前会按退出策略处理风扇，这里才可以安全地以非0状态退出
+	signal.Notify(stopSignals, syscall.SIGINT, syscall.SIGTERM)
+	var err error
+	if *configFile != "" {
+		err = runConfigFanController(*configFile)
+	} else {
+		err = runFanController()
+	}
+	if err != nil {
+		log.Printf("错误: %v", err)
+		os.Exit(1)
+	}
 }
 
 func printVersion() {
@@ -168,6 +378,23 @@ func printVersion() {
 	fmt.Println("用Go语言编写，支持Linux系统的hwmon硬件监控接口")
 }
 
+// runTools 执行 -list 或 -check，按 -format 输出结果
+func runTools() error {
+	format, err := tools.ParseFormat(*outFormat)
+	if err != nil {
+		return err
+	}
+
+	if *listSensors {
+		inv, err := tools.ListHWMon()
+		if err != nil {
+			return fmt.Errorf("%w\n提示: 确保使用root权限运行，并且内核支持hwmon硬件监控", err)
+		}
+		return tools.Write(os.Stdout, inv, format)
+	}
+	return tools.Write(os.Stdout, tools.CheckHWMon(), format)
+}
+
 func printHelp() {
 	fmt.Printf(`Fanap v%s - CPU温度控制风扇程序
 =====================================
@@ -176,18 +403,85 @@ func printHelp() {
   fanap [选项]
   fanap -list              列出所有可用的温度传感器和PWM风扇设备
   fanap -check             检查hwmon设备（诊断模式）
+  fanap -list -format json 以JSON（或yaml）格式输出检测结果，便于脚本解析
+  fanap probe [选项]       测出每个PWM通道实际控制的风扇
+  fanap calibrate [选项]   测量风扇的停转、启动和最低稳定PWM
   fanap -help              显示帮助信息
   fanap -version           显示版本信息
 
+检测选项:
+  -format string            -list和-check的输出格式: table、json、yaml (默认: table)
+
+probe 选项:
+  -pwm string               只探测该风扇选择器对应的PWM通道，可以重复指定 (默认: 所有通道)
+  -settle duration          改变PWM后等待转速稳定的时间 (默认: 5s)
+  -low-pwm int              测试时把PWM通道降到的值 (默认: 0)
+  -drop float               转速下降超过该比例时认为风扇受该通道控制 (默认: 0.25)
+  -write                    把对应关系写入 -config 指定的配置文件，不存在时生成新的配置文件
+  -config / -format / -sysfs-root  同下
+
+calibrate 选项:
+  -pwm string               只校准该风扇选择器对应的风扇，可以重复指定 (默认: -config中的pwm风扇，或所有PWM通道)
+  -tach string              转速输入选择器，只能与单个 -pwm 一起使用
+  -settle duration          改变PWM后等待转速稳定的时间 (默认: 3s)
+  -step int                 每一步改变的PWM (默认: 8)
+  -calibration / -config / -format / -sysfs-root  同下
+
 风扇控制选项:
   -interval duration        温度检查间隔 (默认: 5s)
   -low-temp float           低温阈值，低于此温度使用最小PWM (默认: 40.0)
   -high-temp float          高温阈值，高于此温度使用最大PWM (默认: 75.0)
   -min-pwm int              最小PWM值，0-255 (默认: 50)
   -max-pwm int              最大PWM值，0-255 (默认: 255)
-  -sensor string            温度传感器路径 (默认: auto，自动检测)
-  -pwm string               PWM风扇设备路径 (默认: auto，自动检测)
+  -sensor string            温度传感器选择器 (默认: auto，自动检测)
+                            绝对路径 (可以包含通配符)、hwmon:芯片/标签 (如 hwmon:coretemp/Package id 0)、
+                            thermal:类型 (如 thermal:x86_pkg_temp)
+  -pwm string               PWM风扇选择器 (默认: auto，自动检测)
+                            绝对路径 (可以包含通配符)、hwmon:芯片/通道或标签 (如 hwmon:nct6775/pwm2)、
+                            device:设备路径/通道 (如 device:platform/nct6775.656/pwm2)、
+                            pwmchip:控制器/通道 (/sys/class/pwm，如 pwmchip:pwmchip0/0)、
+                            thinkpad (/proc/acpi/ibm/fan，0-7档，看门狗30s)
+  -curve string             多点风扇曲线 温度:PWM,... 设置后忽略温度阈值
+                            (如: 40:50,55:50,70:150,80:255，PWM也可写成百分比 70:60%%)
+  -curve-type string        风扇曲线类型: linear (分段线性) 或 step (阶梯) (默认: linear)
+  -target-temp float        PID模式的目标温度，设置后自动调节PWM保持该温度 (默认: 0，不启用)
+  -kp float                 PID比例增益 (默认: 10)
+  -ki float                 PID积分增益 (默认: 0.5)
+  -kd float                 PID微分增益 (默认: 0)
+  -hysteresis-rise float    升温回差，温度升高超过该值才提速 (默认: 0)
+  -hysteresis-fall float    降温回差，温度降低超过该值才降速 (默认: 0)
+  -ramp-up float            提速时每秒最多增加的PWM (默认: 0，不限制)
+  -ramp-down float          降速时每秒最多减少的PWM (默认: 0，不限制)
+  -filter string            温度滤波链 类型:参数,... (如: spike:10,median:5,ema:0.3)
+                            average:N 移动平均, ema:α 指数平滑, median:N 中值, spike:Δ 尖峰抑制
+  -sample-interval duration 温度采样间隔，可以比检查间隔更短 (默认: 每次检查时采样)
+  -on-pwm int               2级冷却设备在关闭状态下PWM达到该值时打开 (默认: 128)
+  -off-pwm int              2级冷却设备在打开状态下PWM降到该值时关闭 (默认: 127)
   -verbose                  详细输出模式
+  -sysfs-root string        sysfs根目录，用于在模拟的目录树上测试 (默认: 真实系统)
+  -config string            配置文件路径 (JSON格式)，可声明多个传感器和风扇
+  -metrics-addr string      Prometheus指标监听地址，如 127.0.0.1:9101 (默认: 不启用)
+  -api-addr string          HTTP管理接口监听地址，如 127.0.0.1:9102 或 unix:/run/fanap.sock (默认: 不启用)
+  -api-token string         HTTP管理接口的访问令牌 (默认: 不检查)
+  -failsafe-reads int        传感器连续失败（或读数超出合理范围）多少次后进入失效保护，0表示不启用 (默认: 3)
+  -failsafe-duty float      失效保护时的风扇占空比，百分比 (默认: 100)
+  -failsafe-min-temp float  合理温度的下限 (默认: -40)
+  -failsafe-max-temp float  合理温度的上限 (默认: 150)
+  -on-exit string           退出时对风扇的处理方式: restore (恢复原始模式)、full (全速)、leave (保持不变) (默认: restore)
+  -tach string              风扇的转速输入选择器，如 hwmon:nct6775/fan2 (默认: 与pwmN同编号的fanN_input)
+                            对应关系可以用 fanap probe 测出
+  -stall-detect             根据fanN_input检测风扇停转，停转时告警并尝试全速启动 (默认: 不启用)
+  -stall-min-pwm int        只在PWM不低于该值时检查风扇转速 (默认: 80)
+  -stall-min-rpm int        转速低于该值视为停转 (默认: 0，使用fanN_min，不存在时只有0转视为停转)
+  -stall-checks int         连续多少次检查异常后判定为停转 (默认: 3)
+  -stall-kick duration      停转后全速启动的时长，0表示不尝试重新启动 (默认: 2s)
+  -zero-rpm                 启用零转速模式，低温时风扇停转，升温后先以启动PWM启动 (默认: 不启用)
+  -zero-rpm-stop-temp float 温度降到该值及以下时风扇停转 (默认: 40)
+  -zero-rpm-start-temp float 停转后温度升到该值及以上时重新启动 (默认: 45)
+  -zero-rpm-kick-pwm int    风扇从停转状态启动时的PWM (默认: 255)
+  -zero-rpm-kick duration   以启动PWM运行的时长，0表示直接以计算出的PWM启动 (默认: 2s)
+  -alarm-command string     风扇停转、传感器失效及其恢复时执行的命令 (默认: 不执行)
+  -calibration string       风扇校准结果文件，存在时自动加载，为空时不使用 (默认: /var/lib/fanap/calibration.json)
 
 环境变量 (Docker):
   FANAP_INTERVAL           温度检查间隔 (如: 5s, 10s)
@@ -195,9 +489,37 @@ func printHelp() {
   FANAP_HIGH_TEMP          高温阈值 (默认: 75.0)
   FANAP_MIN_PWM            最小PWM值，0-255 (默认: 50)
   FANAP_MAX_PWM            最大PWM值，0-255 (默认: 255)
-  FANAP_SENSOR             温度传感器路径 (默认: auto)
-  FANAP_PWM                PWM风扇设备路径 (默认: auto)
+  FANAP_SENSOR             温度传感器选择器 (默认: auto)
+  FANAP_PWM                PWM风扇选择器，pwmchip:开头时为PWM子系统的通道，thinkpad为ThinkPad风扇 (默认: auto)
+  FANAP_CURVE              多点风扇曲线 (默认: 空)
+  FANAP_CURVE_TYPE         风扇曲线类型 (默认: linear)
+  FANAP_TARGET_TEMP        PID模式的目标温度 (默认: 0，不启用)
+  FANAP_KP / FANAP_KI / FANAP_KD  PID增益
+  FANAP_HYSTERESIS_RISE    升温回差 (默认: 0)
+  FANAP_HYSTERESIS_FALL    降温回差 (默认: 0)
+  FANAP_RAMP_UP / FANAP_RAMP_DOWN  PWM变化速率限制 (默认: 0，不限制)
+  FANAP_FILTER             温度滤波链 (默认: 空)
+  FANAP_SAMPLE_INTERVAL    温度采样间隔 (默认: 每次检查时采样)
+  FANAP_ON_PWM / FANAP_OFF_PWM  2级冷却设备的开/关阈值 (默认: 128 / 127)
   FANAP_VERBOSE            详细输出模式 (默认: false)
+  FANAP_SYSFS_ROOT         sysfs根目录 (默认: 真实系统)
+  FANAP_CONFIG             配置文件路径
+  FANAP_METRICS_ADDR       Prometheus指标监听地址 (默认: 不启用)
+  FANAP_API_ADDR           HTTP管理接口监听地址 (默认: 不启用)
+  FANAP_API_TOKEN          HTTP管理接口的访问令牌
+  FANAP_FAILSAFE_READS / FANAP_FAILSAFE_DUTY  失效保护的连续失败次数和占空比 (默认: 3 / 100)
+  FANAP_FAILSAFE_MIN_TEMP / FANAP_FAILSAFE_MAX_TEMP  合理温度范围 (默认: -40 / 150)
+  FANAP_ON_EXIT            退出时对风扇的处理方式 (默认: restore)
+  FANAP_TACH               风扇的转速输入选择器 (默认: 与pwmN同编号的fanN_input)
+  FANAP_STALL_DETECT       根据fanN_input检测风扇停转 (默认: false)
+  FANAP_STALL_MIN_PWM / FANAP_STALL_MIN_RPM  停转检测的PWM阈值和最低转速 (默认: 80 / 0)
+  FANAP_STALL_CHECKS / FANAP_STALL_KICK  停转判定次数和全速启动时长 (默认: 3 / 2s)
+  FANAP_ZERO_RPM           启用零转速模式 (默认: false)
+  FANAP_ZERO_RPM_STOP_TEMP / FANAP_ZERO_RPM_START_TEMP  零转速模式的停转和启动温度 (默认: 40 / 45)
+  FANAP_ZERO_RPM_KICK_PWM / FANAP_ZERO_RPM_KICK  从停转状态启动时的PWM和时长 (默认: 255 / 2s)
+  FANAP_ALARM_COMMAND      告警时执行的命令 (默认: 不执行)
+  FANAP_CALIBRATION        风扇校准结果文件 (默认: /var/lib/fanap/calibration.json)
+  FANAP_FORMAT             -list和-check的输出格式 (默认: table)
 
 配置优先级:
   1. 命令行参数
@@ -207,6 +529,8 @@ func printHelp() {
 支持的控制模式:
   - PWM控制 (标准Linux系统)
   - Cooling Device (QNAP等NAS设备)
+  - Linux PWM子系统 (树莓派等单板计算机，-pwm pwmchip:pwmchip0/0)
+  - ThinkPad风扇 (thinkpad_acpi fan_control=1，-pwm thinkpad)
 
 示例:
   # 直接运行
@@ -218,9 +542,40 @@ func printHelp() {
   # 列出可用的传感器和风扇
   sudo fanap -list
 
+  # 以JSON格式输出，获取每个风扇的选择器
+  sudo fanap -list -format json | jq -r '.chips[].pwms[].selector'
+
+  # 测出PWM通道与风扇的对应关系并写入配置文件
+  sudo fanap probe -config /etc/fanap/fanap.json -write
+
+  # 校准风扇，之后启动时自动使用校准结果
+  sudo fanap calibrate -config /etc/fanap/fanap.json
+
   # 自定义温度阈值
   sudo fanap -low-temp=35 -high-temp=65 -verbose
 
+  # 55°C以下保持静音，70°C以上快速提速
+  sudo fanap -curve=40:60,55:60,70:120,80:255
+
+  # 以最低噪音保持65°C
+  sudo fanap -target-temp=65
+
+  # 使用配置文件控制多个风扇
+  sudo fanap -config /etc/fanap/fanap.json
+
+  # 导出Prometheus指标 (http://127.0.0.1:9101/metrics)
+  sudo fanap -metrics-addr 127.0.0.1:9101
+
+  # 启用管理接口，运行时查看状态和调整风扇
+  sudo fanap -api-addr unix:/run/fanap.sock
+  curl --unix-socket /run/fanap.sock http://localhost/api/v1/status
+
+  # 风扇停转时发送邮件
+  sudo fanap -stall-detect -alarm-command 'echo "$FANAP_ALARM_MESSAGE" | mail -s "fanap: $FANAP_ALARM_EVENT" root'
+
+  # 低于40°C时风扇停转，升到45°C后重新启动
+  sudo fanap -zero-rpm -zero-rpm-stop-temp 40 -zero-rpm-start-temp 45 -stall-detect
+
   # Docker运行
   docker run -d --device=/sys/class/hwmon --device=/sys/class/thermal \
              -e FANAP_VERBOSE=true fanap
@@ -229,62 +584,312 @@ func printHelp() {
   - 温度 <= 低温阈值: 使用最小PWM
   - 温度 >= 高温阈值: 使用最大PWM
   - 温度介于两者: 线性插值计算PWM值
+  - 设置 -curve 后按多点曲线计算PWM值
+  - 设置 -target-temp 后使用PID闭环控制保持目标温度
 
 注意:
   - 需要root权限或设备访问权限运行
-  - 程序退出时会自动恢复原始风扇控制模式
+  - 程序退出（包括初始化失败和控制循环异常）时按 -on-exit 处理风扇，默认恢复原始风扇控制模式
   - 使用 -verbose 模式测试，确认程序工作正常
 
 `, Version)
 }
 
-func runFanController() {
+func runFanController() error {
 	// 验证参数
-	if *lowTemp >= *highTemp {
-		log.Fatal("错误: 低温阈值必须小于高温阈值")
+	if err := settings.Thresholds(*lowTemp, *highTemp); err != nil {
+		return err
+	}
+	if err := settings.PWMRange(*minPWM, *maxPWM); err != nil {
+		return err
+	}
+	hysteresis := controller.Hysteresis{Rise: *hystRise, Fall: *hystFall}
+	if err := hysteresis.Validate(); err != nil {
+		return err
+	}
+	ramp := controller.Ramp{Up: *rampUp, Down: *rampDown}
+	if err := ramp.Validate(); err != nil {
+		return err
+	}
+	exitPolicy, err := controller.ParseExitPolicy(*onExit)
+	if err != nil {
+		return err
 	}
-	if *minPWM < 0 || *minPWM > 255 {
-		log.Fatal("错误: 最小PWM值必须在0-255之间")
+	failsafe := controller.Failsafe{MaxFailures: *fsReads, Duty: *fsDuty, MinTemp: *fsMinTemp, MaxTemp: *fsMaxTemp}
+	if err := failsafe.Validate(); err != nil {
+		return err
 	}
-	if *maxPWM < 0 || *maxPWM > 255 {
-		log.Fatal("错误: 最大PWM值必须在0-255之间")
+	stall := controller.StallDetect{MinPWM: *stallMinPWM, MinRPM: *stallMinRPM, Checks: *stallChecks, Kick: *stallKick}
+	if err := stall.Validate(); err != nil {
+		return err
 	}
-	if *minPWM >= *maxPWM {
-		log.Fatal("错误: 最小PWM值必须小于最大PWM值")
+	zero := controller.ZeroRPM{StopTemp: *zeroStop, StartTemp: *zeroStart, KickPWM: *zeroKickPWM, Kick: *zeroKick}
+	if err := zero.Validate(); err != nil {
+		return err
+	}
+	var filters []filter.Spec
+	if *filterSpec != "" {
+		var err error
+		filters, err = filter.Parse(*filterSpec)
+		if err != nil {
+			return fmt.Errorf("温度滤波格式无效: %w", err)
+		}
+	} else if *sampleIntv != 0 {
+		return fmt.Errorf("-sample-interval 需要与 -filter 一起使用")
+	}
+
+	var fanCurve curve.Curve
+	if *curvePts != "" {
+		points, err := curve.Parse(*curvePts)
+		if err != nil {
+			return fmt.Errorf("风扇曲线格式无效: %w", err)
+		}
+		fanCurve, err = curve.New(*curveType, points)
+		if err != nil {
+			return fmt.Errorf("风扇曲线无效: %w", err)
+		}
+	}
+
+	var fanPID *pid.Controller
+	if *targetTemp != 0 {
+		var err error
+		fanPID, err = pid.New(*targetTemp, pid.Gains{Kp: *pidKp, Ki: *pidKi, Kd: *pidKd})
+		if err != nil {
+			return fmt.Errorf("PID参数无效: %w", err)
+		}
 	}
 
 	log.Printf("风扇控制程序启动 v%s", Version)
 
 	// 自动检测并创建控制器
 	var ctrl *controller.TempController
-	var err error
 
 	// 如果手动指定了传感器和风扇，使用指定的配置
 	if *tempSensor != "auto" || *pwmDevice != "auto" {
 		log.Printf("使用手动配置: sensor=%s, pwm=%s", *tempSensor, *pwmDevice)
-		ctrl, err = controller.NewControllerWithPWM(*pwmDevice, *minPWM, *maxPWM, *lowTemp, *highTemp, *interval, *verbose)
+		ctrl, err = controller.NewControllerWithPWM(*tempSensor, *pwmDevice, *minPWM, *maxPWM, *lowTemp, *highTemp, *interval, *verbose)
 	} else {
 		// 自动检测
 		log.Println("自动检测温度传感器和风扇控制器")
-		ctrl, err = controller.NewController(*lowTemp, *highTemp, *interval, *verbose)
+		ctrl, err = controller.NewController(*minPWM, *maxPWM, *lowTemp, *highTemp, *interval, *verbose)
 	}
 
 	if err != nil {
-		log.Fatalf("初始化控制器失败: %v\n提示: 运行 'sudo fanap -check' 诊断问题", err)
+		return fmt.Errorf("初始化控制器失败: %w\n提示: 运行 'sudo fanap -check' 诊断问题", err)
 	}
 	defer ctrl.Stop()
 
+	if fanCurve != nil {
+		if err := ctrl.SetCurve("", fanCurve); err != nil {
+			return fmt.Errorf("设置风扇曲线失败: %w", err)
+		}
+	}
+	if fanPID != nil {
+		if err := ctrl.SetPID("", fanPID); err != nil {
+			return fmt.Errorf("设置PID控制失败: %w", err)
+		}
+	}
+	if err := ctrl.SetHysteresis("", hysteresis); err != nil {
+		return fmt.Errorf("设置温度回差失败: %w", err)
+	}
+	if len(filters) > 0 {
+		if err := ctrl.SetFilter("", filters, *sampleIntv); err != nil {
+			return fmt.Errorf("设置温度滤波失败: %w", err)
+		}
+	}
+	if err := ctrl.SetRamp("", ramp); err != nil {
+		return fmt.Errorf("设置PWM变化速率限制失败: %w", err)
+	}
+	if *onPWM != controller.DefaultOnPWM || *offPWM != controller.DefaultOffPWM {
+		if err := ctrl.SetOnOffThresholds("", *onPWM, *offPWM); err != nil {
+			log.Printf("警告: 设置开/关阈值失败: %v", err)
+		}
+	}
+
+	if err := ctrl.SetExitPolicy(exitPolicy); err != nil {
+		return err
+	}
+	if err := ctrl.SetFailsafe(failsafe); err != nil {
+		return err
+	}
+	if *tach != "" {
+		if err := ctrl.SetTach("", *tach); err != nil {
+			return fmt.Errorf("设置转速输入失败: %w", err)
+		}
+	}
+	if err := applyCalibration(ctrl, *calibFile); err != nil {
+		return err
+	}
+	if *stallDetect {
+		if err := ctrl.SetStallDetect("", stall); err != nil {
+			return fmt.Errorf("启用停转检测失败: %w", err)
+		}
+		log.Printf("停转检测: PWM≥%d, 连续%d次, 全速启动%v", stall.MinPWM, stall.Checks, stall.Kick)
+	}
+	if *zeroRPM {
+		if err := ctrl.SetZeroRPM("", zero); err != nil {
+			return fmt.Errorf("启用零转速模式失败: %w", err)
+		}
+		log.Printf("零转速模式: ≤%.1f°C停转, ≥%.1f°C启动 (PWM %d, %v)", zero.StopTemp, zero.StartTemp, zero.KickPWM, zero.Kick)
+	}
+	if *alarmCmd != "" {
+		ctrl.OnAlarm(alarmHook(*alarmCmd))
+	}
+
+	if *metricsAddr != "" {
+		srv, err := startMetrics(ctrl, *metricsAddr)
+		if err != nil {
+			return err
+		}
+		defer srv.Close()
+	}
+	if *apiAddr != "" {
+		srv, err := startAPI(ctrl, *apiAddr, *apiToken)
+		if err != nil {
+			return err
+		}
+		defer srv.Close()
+	}
+
 	// 启动控制器
 	if err := ctrl.Start(); err != nil {
-		log.Fatalf("启动控制器失败: %v", err)
+		return fmt.Errorf("启动控制器失败: %w", err)
 	}
 
 	log.Println("风扇控制器运行中，按Ctrl+C停止...")
+	return waitForStop(ctrl)
+}
 
-	// 等待中断信号
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
-	<-sigChan
+// runConfigFanController 根据配置文件运行风扇控制程序
+func runConfigFanController(path string) error {
+	cfg, err := config.Load(path)
+	if err != nil {
+		return fmt.Errorf("加载配置失败: %w", err)
+	}
+
+	log.Printf("风扇控制程序启动 v%s", Version)
+	log.Printf("配置文件声明了 %d 个传感器、%d 个风扇", len(cfg.Sensors), len(cfg.Fans))
 
-	log.Println("接收到停止信号，正在关闭...")
+	// 命令行参数优先于配置文件
+	if *onExit != "" {
+		cfg.OnExit = *onExit
+	}
+	ctrl, err := controller.NewFromConfig(cfg, *verbose)
+	if err != nil {
+		return fmt.Errorf("初始化控制器失败: %w\n提示: 运行 'sudo fanap -check' 诊断问题", err)
+	}
+	defer ctrl.Stop()
+
+	if err := applyCalibration(ctrl, *calibFile); err != nil {
+		return err
+	}
+
+	command := *alarmCmd
+	if command == "" {
+		command = cfg.AlarmCommand
+	}
+	if command != "" {
+		ctrl.OnAlarm(alarmHook(command))
+	}
+
+	addr := *metricsAddr
+	if addr == "" && cfg.Metrics != nil {
+		addr = cfg.Metrics.Listen
+	}
+	if addr != "" {
+		srv, err := startMetrics(ctrl, addr)
+		if err != nil {
+			return err
+		}
+		defer srv.Close()
+	}
+
+	addr, token := *apiAddr, *apiToken
+	if cfg.API != nil {
+		if addr == "" {
+			addr = cfg.API.Listen
+		}
+		if token == "" {
+			token = cfg.API.Token
+		}
+	}
+	if addr != "" {
+		srv, err := startAPI(ctrl, addr, token)
+		if err != nil {
+			return err
+		}
+		defer srv.Close()
+	}
+
+	if err := ctrl.Start(); err != nil {
+		return fmt.Errorf("启动控制器失败: %w", err)
+	}
+
+	log.Println("风扇控制器运行中，按Ctrl+C停止...")
+	return waitForStop(ctrl)
+}
+
+// applyCalibration 加载风扇校准结果，文件不存在时不使用
+func applyCalibration(ctrl *controller.TempController, path string) error {
+	if path == "" {
+		return nil
+	}
+	state, err := calibrate.Load(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("%w\n提示: 重新运行 'sudo fanap calibrate'，或删除该文件", err)
+	}
+	log.Printf("风扇校准结果: %s (%d 个风扇)", path, len(state.Fans))
+	return ctrl.ApplyCalibration(state)
+}
+
+// startMetrics 注册控制器指标并启动Prometheus指标服务
+func startMetrics(ctrl *controller.TempController, addr string) (*metrics.Server, error) {
+	reg := metrics.NewRegistry()
+	reg.NewGauge("fanap_build_info", "fanap版本信息", "version").Set(1, Version)
+	ctrl.EnableMetrics(reg)
+
+	srv, err := metrics.Serve(addr, reg)
+	if err != nil {
+		return nil, fmt.Errorf("启动指标服务失败: %w", err)
+	}
+	return srv, nil
+}
+
+// startAPI 启动HTTP管理接口
+func startAPI(ctrl *controller.TempController, addr, token string) (*api.Server, error) {
+	srv, err := api.Serve(addr, token, ctrl)
+	if err != nil {
+		return nil, fmt.Errorf("启动管理接口失败: %w", err)
+	}
+	return srv, nil
+}
+
+// alarmHook 返回执行告警命令的告警回调，告警内容通过环境变量传给命令
+func alarmHook(command string) func(controller.Alarm) {
+	cmd := hook.NewCommand(command, 0)
+	return func(a controller.Alarm) {
+		cmd.Run(map[string]string{
+			"FANAP_ALARM_EVENT":   a.Event,
+			"FANAP_ALARM_FAN":     a.Fan,
+			"FANAP_ALARM_SENSOR":  a.Sensor,
+			"FANAP_ALARM_MESSAGE": a.Message,
+		})
+	}
+}
+
+// stopSignals 接收停止信号，在初始化之前注册，保证初始化期间收到的信号也会走正常的退出流程
+var stopSignals = make(chan os.Signal, 1)
+
+// waitForStop 等待停止信号或控制循环异常退出
+func waitForStop(ctrl *controller.TempController) error {
+	select {
+	case <-stopSignals:
+		log.Println("接收到停止信号，正在关闭...")
+		return nil
+	case <-ctrl.Done():
+		return ctrl.Err()
+	}
 }
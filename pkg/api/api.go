@@ -0,0 +1,308 @@
+// Package api 提供管理运行中的温度控制器的HTTP JSON接口
+//
+// 接口列表（所有路径以 /api/v1 开头）：
+//
+//	GET    /status                 控制器状态：传感器温度、风扇PWM、控制模式
+//	GET    /fans/{name}            单个风扇的状态
+//	PUT    /fans/{name}/control    替换风扇的控制规则（格式同配置文件的 control）
+//	PUT    /fans/{name}/override   手动指定PWM {"pwm": 200}
+//	DELETE /fans/{name}/override   解除手动PWM，恢复自动控制
+//	GET    /profiles               控制方案列表和当前方案
+//	PUT    /profile                切换控制方案 {"name": "quiet"}
+package api
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/fanap/pkg/config"
+	"github.com/fanap/pkg/controller"
+)
+
+// 请求体的最大长度
+const maxBodySize = 64 << 10
+
+// Server HTTP管理接口服务
+type Server struct {
+	srv        *http.Server
+	socketPath string
+}
+
+// Serve 在addr上启动管理接口
+// addr 可以是 "主机:端口" 或 "unix:/path/to/socket"；token非空时请求需要携带 "Authorization: Bearer <token>"
+func Serve(addr, token string, ctrl *controller.TempController) (*Server, error) {
+	s := &Server{}
+
+	var ln net.Listener
+	var err error
+	if path, ok := strings.CutPrefix(addr, "unix:"); ok {
+		// 删除上次异常退出残留的套接字文件
+		if fi, err := os.Lstat(path); err == nil && fi.Mode()&os.ModeSocket != 0 {
+			os.Remove(path)
+		}
+		ln, err = net.Listen("unix", path)
+		if err != nil {
+			return nil, fmt.Errorf("监听unix套接字 %s 失败: %w", path, err)
+		}
+		if err := os.Chmod(path, 0600); err != nil {
+			ln.Close()
+			return nil, fmt.Errorf("设置套接字权限失败: %w", err)
+		}
+		s.socketPath = path
+	} else {
+		ln, err = net.Listen("tcp", addr)
+		if err != nil {
+			return nil, fmt.Errorf("监听管理接口地址 %s 失败: %w", addr, err)
+		}
+		if token == "" && !isLoopback(ln.Addr()) {
+			log.Printf("警告: 管理接口监听在非本地地址 %s 且未设置访问令牌", ln.Addr())
+		}
+	}
+
+	s.srv = &http.Server{
+		Handler:           NewHandler(ctrl, token),
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+
+	go func() {
+		if err := s.srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("管理接口异常退出: %v", err)
+		}
+	}()
+
+	log.Printf("管理接口监听: %s", addr)
+	return s, nil
+}
+
+// Close 关闭管理接口
+func (s *Server) Close() error {
+	err := s.srv.Close()
+	if s.socketPath != "" {
+		os.Remove(s.socketPath)
+	}
+	return err
+}
+
+// handler 管理接口的HTTP处理器
+type handler struct {
+	ctrl  *controller.TempController
+	token string
+}
+
+// NewHandler 创建管理接口的HTTP处理器，token非空时检查访问令牌
+func NewHandler(ctrl *controller.TempController, token string) http.Handler {
+	return &handler{ctrl: ctrl, token: token}
+}
+
+// ServeHTTP 按路径分发请求
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !h.authorized(r) {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		writeError(w, http.StatusUnauthorized, fmt.Errorf("未授权"))
+		return
+	}
+
+	path, ok := strings.CutPrefix(r.URL.Path, "/api/v1/")
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("未知的路径: %s", r.URL.Path))
+		return
+	}
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+
+	switch {
+	case len(parts) == 1 && parts[0] == "status":
+		h.route(w, r, map[string]http.HandlerFunc{http.MethodGet: h.getStatus})
+	case len(parts) == 1 && parts[0] == "profiles":
+		h.route(w, r, map[string]http.HandlerFunc{http.MethodGet: h.getProfiles})
+	case len(parts) == 1 && parts[0] == "profile":
+		h.route(w, r, map[string]http.HandlerFunc{http.MethodPut: h.putProfile})
+	case len(parts) == 2 && parts[0] == "fans":
+		h.route(w, r, map[string]http.HandlerFunc{
+			http.MethodGet: func(w http.ResponseWriter, r *http.Request) { h.getFan(w, parts[1]) },
+		})
+	case len(parts) == 3 && parts[0] == "fans" && parts[2] == "control":
+		h.route(w, r, map[string]http.HandlerFunc{
+			http.MethodPut: func(w http.ResponseWriter, r *http.Request) { h.putControl(w, r, parts[1]) },
+		})
+	case len(parts) == 3 && parts[0] == "fans" && parts[2] == "override":
+		h.route(w, r, map[string]http.HandlerFunc{
+			http.MethodPut:    func(w http.ResponseWriter, r *http.Request) { h.putOverride(w, r, parts[1]) },
+			http.MethodDelete: func(w http.ResponseWriter, r *http.Request) { h.deleteOverride(w, parts[1]) },
+		})
+	default:
+		writeError(w, http.StatusNotFound, fmt.Errorf("未知的路径: %s", r.URL.Path))
+	}
+}
+
+// route 按请求方法分发
+func (h *handler) route(w http.ResponseWriter, r *http.Request, methods map[string]http.HandlerFunc) {
+	fn, ok := methods[r.Method]
+	if !ok {
+		allowed := make([]string, 0, len(methods))
+		for m := range methods {
+			allowed = append(allowed, m)
+		}
+		w.Header().Set("Allow", strings.Join(allowed, ", "))
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("不支持的请求方法: %s", r.Method))
+		return
+	}
+	fn(w, r)
+}
+
+// authorized 检查访问令牌
+func (h *handler) authorized(r *http.Request) bool {
+	if h.token == "" {
+		return true
+	}
+	got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(got), []byte(h.token)) == 1
+}
+
+func (h *handler) getStatus(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, h.ctrl.Status())
+}
+
+func (h *handler) getFan(w http.ResponseWriter, name string) {
+	for _, fs := range h.ctrl.Status().Fans {
+		if fs.Name == name {
+			writeJSON(w, http.StatusOK, fs)
+			return
+		}
+	}
+	writeError(w, http.StatusNotFound, fmt.Errorf("%w: %s", controller.ErrFanNotFound, name))
+}
+
+func (h *handler) putControl(w http.ResponseWriter, r *http.Request, name string) {
+	var cc config.ControlConfig
+	if err := readJSON(r, &cc); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	cc.ApplyDefaults()
+	if err := cc.Validate(); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	ctl, err := controller.NewControl(cc)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := h.ctrl.SetControl(name, ctl); err != nil {
+		writeError(w, statusOf(err), err)
+		return
+	}
+	h.getFan(w, name)
+}
+
+func (h *handler) putOverride(w http.ResponseWriter, r *http.Request, name string) {
+	var req struct {
+		PWM *int `json:"pwm"`
+	}
+	if err := readJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if req.PWM == nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("pwm: 必须指定PWM值"))
+		return
+	}
+
+	if err := h.ctrl.SetOverride(name, *req.PWM); err != nil {
+		writeError(w, statusOf(err), err)
+		return
+	}
+	h.getFan(w, name)
+}
+
+func (h *handler) deleteOverride(w http.ResponseWriter, name string) {
+	if err := h.ctrl.ClearOverride(name); err != nil {
+		writeError(w, statusOf(err), err)
+		return
+	}
+	h.getFan(w, name)
+}
+
+// profilesResponse 控制方案列表
+type profilesResponse struct {
+	Active   string   `json:"active"`
+	Profiles []string `json:"profiles"`
+}
+
+func (h *handler) getProfiles(w http.ResponseWriter, _ *http.Request) {
+	names, active := h.ctrl.Profiles()
+	writeJSON(w, http.StatusOK, profilesResponse{Active: active, Profiles: names})
+}
+
+func (h *handler) putProfile(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Name string `json:"name"`
+	}
+	if err := readJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := h.ctrl.SetProfile(req.Name); err != nil {
+		writeError(w, statusOf(err), err)
+		return
+	}
+	h.getProfiles(w, r)
+}
+
+// readJSON 解析请求体，拒绝未知的字段
+func readJSON(r *http.Request, v interface{}) error {
+	data, err := io.ReadAll(io.LimitReader(r.Body, maxBodySize))
+	if err != nil {
+		return fmt.Errorf("读取请求失败: %w", err)
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return fmt.Errorf("请求格式无效: %w", err)
+	}
+	return nil
+}
+
+// statusOf 根据控制器返回的错误选择HTTP状态码
+func statusOf(err error) int {
+	if errors.Is(err, controller.ErrFanNotFound) || errors.Is(err, controller.ErrProfileNotFound) {
+		return http.StatusNotFound
+	}
+	return http.StatusBadRequest
+}
+
+// errorResponse 错误响应
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+func writeError(w http.ResponseWriter, code int, err error) {
+	writeJSON(w, code, errorResponse{Error: err.Error()})
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(code)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(v)
+}
+
+// isLoopback 判断监听地址是否只接受本机连接
+func isLoopback(addr net.Addr) bool {
+	tcp, ok := addr.(*net.TCPAddr)
+	return ok && tcp.IP.IsLoopback()
+}
@@ -0,0 +1,314 @@
+// Package calibrate 测量风扇的停转PWM、启动PWM、最低稳定PWM和PWM→转速曲线
+//
+// 校准时从全速开始逐步降低PWM，记录每一步稳定后的转速，直到风扇停转；再从停转状态逐步提高PWM，
+// 找到风扇重新启动的PWM。校准结果保存在状态文件中，风扇控制程序启动时自动加载，
+// 保证PWM不低于风扇能够稳定转动的值，并在风扇从停转状态启动时先以启动PWM运行。
+package calibrate
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/fanap/pkg/config"
+	"github.com/fanap/pkg/fan"
+	"github.com/fanap/pkg/hwmon"
+)
+
+// 默认参数
+const (
+	// DefaultSettle 改变PWM后等待风扇转速稳定的时间
+	DefaultSettle = 3 * time.Second
+	// DefaultStep 每一步改变的PWM
+	DefaultStep = 8
+)
+
+// Options 校准参数
+type Options struct {
+	// Settle 改变PWM后等待转速稳定的时间
+	Settle time.Duration
+	// Step 每一步改变的PWM
+	Step int
+}
+
+// DefaultOptions 返回默认的校准参数
+func DefaultOptions() Options {
+	return Options{Settle: DefaultSettle, Step: DefaultStep}
+}
+
+// Validate 检查校准参数
+func (o Options) Validate() error {
+	if o.Settle <= 0 {
+		return fmt.Errorf("等待时间必须大于0")
+	}
+	if o.Step < 1 || o.Step > 128 {
+		return fmt.Errorf("PWM步长必须在1-128之间")
+	}
+	return nil
+}
+
+// Target 需要校准的风扇
+type Target struct {
+	// Name 配置文件中的风扇名称，可以为空
+	Name string
+	// PWM 风扇选择器
+	PWM string
+	// Tach 转速输入选择器，为空时使用与pwmN同编号的fanN_input
+	Tach string
+}
+
+func (t Target) String() string {
+	if t.Name != "" {
+		return t.Name + " (" + t.PWM + ")"
+	}
+	return t.PWM
+}
+
+// ConfigTargets 返回配置文件中所有pwm类型的风扇
+func ConfigTargets(cfg *config.Config) []Target {
+	var targets []Target
+	for _, fc := range cfg.Fans {
+		if fc.Type == config.FanPWM {
+			targets = append(targets, Target{Name: fc.Name, PWM: fc.Device, Tach: fc.Tach})
+		}
+	}
+	return targets
+}
+
+// AllTargets 返回所有有同编号转速输入的PWM通道
+func AllTargets() ([]Target, error) {
+	chips, err := hwmon.Scan()
+	if err != nil {
+		return nil, err
+	}
+
+	var targets []Target
+	for _, c := range chips {
+		for _, p := range c.PWMs {
+			if p.FanInput != "" {
+				targets = append(targets, Target{PWM: p.Path})
+			}
+		}
+	}
+	return targets, nil
+}
+
+// Result 校准结果
+type Result struct {
+	Fans   []Fan     `json:"fans"`
+	Failed []Failure `json:"failed,omitempty"`
+	// File 保存校准结果的文件
+	File string `json:"file,omitempty"`
+}
+
+// Failure 校准失败的风扇
+type Failure struct {
+	Target string `json:"target"`
+	Error  string `json:"error"`
+}
+
+// Run 依次校准所有风扇，ctx结束时中止校准
+// 每个风扇校准结束后恢复其原始模式和PWM值；校准期间风扇会停转，应在系统空闲时执行
+func Run(ctx context.Context, targets []Target, opts Options) (*Result, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("没有需要校准的风扇")
+	}
+
+	result := &Result{Fans: []Fan{}}
+	for _, t := range targets {
+		log.Printf("校准风扇 %s", t)
+		f, err := calibrateFan(ctx, t, opts)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			log.Printf("  ✗ 校准失败: %v", err)
+			result.Failed = append(result.Failed, Failure{Target: t.String(), Error: err.Error()})
+			continue
+		}
+		result.Fans = append(result.Fans, *f)
+	}
+	return result, nil
+}
+
+// calibrateFan 校准一个风扇
+func calibrateFan(ctx context.Context, t Target, opts Options) (*Fan, error) {
+	pwmFan, err := fan.NewPWMFan(t.PWM, false)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := pwmFan.Close(); err != nil {
+			log.Printf("警告: 恢复风扇 %s 失败: %v", t, err)
+		}
+	}()
+	if t.Tach != "" {
+		if err := pwmFan.SetTach(t.Tach); err != nil {
+			return nil, err
+		}
+	}
+
+	result := &Fan{
+		Name:     t.Name,
+		Selector: stableSelector(pwmFan.PWMPath()),
+		PWM:      pwmFan.PWMPath(),
+		Tach:     pwmFan.TachPath(),
+		Time:     time.Now().Truncate(time.Second),
+	}
+	if result.Tach == "" {
+		return nil, fmt.Errorf("风扇没有转速输入，请指定tach")
+	}
+
+	// measure 设置PWM，等待转速稳定后读取转速
+	measure := func(pwm int) (int, error) {
+		if err := pwmFan.SetSpeed(pwm); err != nil {
+			return 0, err
+		}
+		if err := sleep(ctx, opts.Settle); err != nil {
+			return 0, err
+		}
+		rpm, err := pwmFan.GetRPM()
+		if err != nil {
+			return 0, err
+		}
+		log.Printf("  PWM %3d: %d RPM", pwm, rpm)
+		return rpm, nil
+	}
+
+	full, err := measure(255)
+	if err != nil {
+		return nil, err
+	}
+	if full == 0 {
+		return nil, fmt.Errorf("全速运行时转速为0，风扇未连接或转速输入不对应（可以用 fanap probe 测出）")
+	}
+	result.MaxRPM = full
+	result.Curve = append(result.Curve, Point{PWM: 255, RPM: full})
+
+	// 逐步降速，直到风扇停转
+	stop, lastRunning := -1, 255
+	for pwm := 255 - opts.Step; stop < 0; pwm -= opts.Step {
+		if pwm < 0 {
+			pwm = 0
+		}
+		rpm, err := measure(pwm)
+		if err != nil {
+			return nil, err
+		}
+		result.Curve = append(result.Curve, Point{PWM: pwm, RPM: rpm})
+		if rpm == 0 {
+			stop = pwm
+			break
+		}
+		lastRunning = pwm
+		if pwm == 0 {
+			break
+		}
+	}
+
+	if stop < 0 {
+		result.NoStop = true
+	} else {
+		result.StopPWM = stop
+		result.MinPWM = lastRunning
+
+		// 从停转状态逐步提速，直到风扇重新启动
+		result.StartPWM = -1
+		for pwm := stop + opts.Step; pwm <= 255; pwm += opts.Step {
+			rpm, err := measure(pwm)
+			if err != nil {
+				return nil, err
+			}
+			if rpm > 0 {
+				result.StartPWM = pwm
+				break
+			}
+		}
+		if result.StartPWM < 0 {
+			return nil, fmt.Errorf("风扇停转后无法重新启动")
+		}
+		if result.MinPWM > result.StartPWM {
+			result.MinPWM = result.StartPWM
+		}
+	}
+
+	sort.Slice(result.Curve, func(i, j int) bool { return result.Curve[i].PWM < result.Curve[j].PWM })
+	if result.NoStop {
+		log.Printf("  ✓ PWM降到0也不停转，全速 %d RPM", result.MaxRPM)
+	} else {
+		log.Printf("  ✓ 停转PWM %d, 启动PWM %d, 最低稳定PWM %d, 全速 %d RPM",
+			result.StopPWM, result.StartPWM, result.MinPWM, result.MaxRPM)
+	}
+	return result, nil
+}
+
+// stableSelector 返回不依赖hwmonN编号的风扇选择器，优先使用设备路径
+func stableSelector(pwmPath string) string {
+	chips, err := hwmon.Scan()
+	if err != nil {
+		return pwmPath
+	}
+	for _, c := range chips {
+		for _, p := range c.PWMs {
+			if p.Path != pwmPath {
+				continue
+			}
+			switch {
+			case c.Device != "":
+				return "device:" + c.Device + "/" + p.Name()
+			case c.Name != "":
+				return "hwmon:" + c.Name + "/" + p.Name()
+			}
+		}
+	}
+	return pwmPath
+}
+
+// sleep 等待d，ctx结束时提前返回错误
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// WriteTable 以文本形式输出校准结果
+func (r *Result) WriteTable(w io.Writer) error {
+	fmt.Fprint(w, "=== 风扇校准结果 ===\n\n")
+	for _, f := range r.Fans {
+		name := f.Selector
+		if f.Name != "" {
+			name = f.Name + " (" + f.Selector + ")"
+		}
+		fmt.Fprintf(w, "%s\n", name)
+		fmt.Fprintf(w, "  PWM: %s\n  转速输入: %s\n", f.PWM, f.Tach)
+		if f.NoStop {
+			fmt.Fprintln(w, "  PWM降到0也不停转")
+		} else {
+			fmt.Fprintf(w, "  停转PWM: %d, 启动PWM: %d, 最低稳定PWM: %d\n", f.StopPWM, f.StartPWM, f.MinPWM)
+		}
+		fmt.Fprintf(w, "  全速转速: %d RPM\n", f.MaxRPM)
+		fmt.Fprint(w, "  PWM→转速:")
+		for _, p := range f.Curve {
+			fmt.Fprintf(w, " %d:%d", p.PWM, p.RPM)
+		}
+		fmt.Fprint(w, "\n\n")
+	}
+	for _, f := range r.Failed {
+		fmt.Fprintf(w, "%s\n  ✗ 校准失败: %s\n\n", f.Target, f.Error)
+	}
+	if r.File != "" {
+		fmt.Fprintf(w, "校准结果已保存到 %s\n", r.File)
+	}
+	return nil
+}
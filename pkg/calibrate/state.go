@@ -0,0 +1,139 @@
+package calibrate
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fanap/pkg/fan"
+)
+
+// DefaultStateFile 默认的校准结果文件，风扇控制程序启动时自动加载
+const DefaultStateFile = "/var/lib/fanap/calibration.json"
+
+// State 校准结果文件
+type State struct {
+	Fans []Fan `json:"fans"`
+}
+
+// Fan 一个风扇的校准结果
+type Fan struct {
+	// Name 配置文件中的风扇名称，按选择器校准时为空
+	Name string `json:"name,omitempty"`
+	// Selector 不依赖hwmonN编号的风扇选择器，加载时按它查找风扇
+	Selector string `json:"selector"`
+	// PWM、Tach 校准时的pwmN和转速输入文件
+	PWM  string `json:"pwm"`
+	Tach string `json:"tach"`
+	// StopPWM 降速时风扇停转的PWM
+	StopPWM int `json:"stop_pwm"`
+	// StartPWM 风扇停转后能够重新启动的最低PWM
+	StartPWM int `json:"start_pwm"`
+	// MinPWM 风扇能够稳定转动的最低PWM
+	MinPWM int `json:"min_pwm"`
+	// NoStop PWM降到0风扇也不停转，此时StopPWM、StartPWM和MinPWM均为0
+	NoStop bool `json:"no_stop,omitempty"`
+	// MaxRPM PWM为255时的转速
+	MaxRPM int `json:"max_rpm"`
+	// Curve PWM与转速的对应关系，按PWM升序排列
+	Curve []Point `json:"curve"`
+	// Time 校准时间
+	Time time.Time `json:"time"`
+}
+
+// Point PWM→转速曲线上的一个点
+type Point struct {
+	PWM int `json:"pwm"`
+	RPM int `json:"rpm"`
+}
+
+// Load 读取校准结果文件，文件不存在时返回os.ErrNotExist
+func Load(path string) (*State, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	state := &State{}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("解析校准结果文件 %s 失败: %w", path, err)
+	}
+	for i, f := range state.Fans {
+		if err := f.Validate(); err != nil {
+			return nil, fmt.Errorf("校准结果文件 %s 无效: fans[%d]: %w", path, i, err)
+		}
+	}
+	return state, nil
+}
+
+// Save 写入校准结果文件，目录不存在时自动创建
+func (s *State) Save(path string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("创建目录失败: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("写入校准结果文件失败: %w", err)
+	}
+	return nil
+}
+
+// Merge 加入新的校准结果，替换选择器相同的旧结果（新结果没有风扇名称时沿用旧的名称）
+func (s *State) Merge(fans []Fan) {
+	for _, f := range fans {
+		replaced := false
+		for i := range s.Fans {
+			if s.Fans[i].Selector == f.Selector {
+				if f.Name == "" {
+					f.Name = s.Fans[i].Name
+				}
+				s.Fans[i] = f
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			s.Fans = append(s.Fans, f)
+		}
+	}
+}
+
+// Lookup 查找pwmN文件为pwmPath的风扇的校准结果
+// 按选择器重新定位风扇，重启后hwmonN编号变化也能找到
+func (s *State) Lookup(pwmPath string) (Fan, bool) {
+	for _, f := range s.Fans {
+		sel, err := fan.ParseSelector(f.Selector)
+		if err != nil {
+			continue
+		}
+		target, err := sel.Resolve()
+		if err != nil {
+			continue
+		}
+		if target.Path == pwmPath {
+			return f, true
+		}
+	}
+	return Fan{}, false
+}
+
+// Validate 检查校准结果
+func (f Fan) Validate() error {
+	if f.Selector == "" {
+		return fmt.Errorf("缺少风扇选择器")
+	}
+	for _, v := range []int{f.StopPWM, f.StartPWM, f.MinPWM} {
+		if v < 0 || v > 255 {
+			return fmt.Errorf("PWM值必须在0-255之间")
+		}
+	}
+	if f.MinPWM > f.StartPWM && !f.NoStop {
+		return fmt.Errorf("最低稳定PWM %d 高于启动PWM %d", f.MinPWM, f.StartPWM)
+	}
+	return nil
+}
@@ -0,0 +1,1282 @@
+// Package config 解析fanap的声明式配置文件（JSON格式）
+// 配置文件中可以声明多个命名的温度传感器、风扇以及每个风扇的控制规则
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fanap/pkg/curve"
+	"github.com/fanap/pkg/fan"
+	"github.com/fanap/pkg/filter"
+	"github.com/fanap/pkg/ipmi"
+	"github.com/fanap/pkg/pid"
+	"github.com/fanap/pkg/pwmchip"
+	"github.com/fanap/pkg/sensor"
+	"github.com/fanap/pkg/settings"
+	"github.com/fanap/pkg/thinkpad"
+	"github.com/fanap/pkg/virtual"
+)
+
+// DefaultIPMICache IPMI传感器读数的默认缓存时间
+const DefaultIPMICache = 2 * time.Second
+
+// 传感器类型
+const (
+	SensorAuto    = "auto"
+	SensorHWMon   = "hwmon"
+	SensorThermal = "thermal"
+	SensorVirtual = "virtual"
+	SensorExec    = "exec"
+	SensorIPMI    = "ipmi"
+)
+
+// 多传感器聚合方式
+const (
+	AggregateMax      = "max"
+	AggregateWeighted = "weighted"
+)
+
+// 退出时对风扇的处理方式
+const (
+	OnExitRestore = "restore"
+	OnExitFull    = "full"
+	OnExitLeave   = "leave"
+)
+
+// 风扇类型
+const (
+	FanAuto     = "auto"
+	FanPWM      = "pwm"
+	FanCooling  = "cooling"
+	FanExec     = "exec"
+	FanIPMI     = "ipmi"
+	FanPWMChip  = "pwmchip"
+	FanThinkPad = "thinkpad"
+)
+
+// Config 配置文件根节点
+type Config struct {
+	// Interval 温度检查间隔
+	Interval Duration `json:"interval"`
+	// OnExit 退出时对风扇的处理方式: restore（恢复原始模式，默认）、full（全速）、leave（保持不变）
+	OnExit string `json:"on_exit"`
+	// Sensors 命名的温度传感器
+	Sensors []SensorConfig `json:"sensors"`
+	// Fans 命名的风扇及其控制规则
+	Fans []FanConfig `json:"fans"`
+	// Failsafe 传感器失效保护
+	Failsafe *FailsafeConfig `json:"failsafe"`
+	// Profiles 可以在运行时切换的控制方案
+	Profiles []ProfileConfig `json:"profiles"`
+	// Metrics Prometheus指标服务
+	Metrics *MetricsConfig `json:"metrics"`
+	// API HTTP管理接口
+	API *APIConfig `json:"api"`
+	// AlarmCommand 风扇停转、传感器失效及其恢复时执行的命令（通过 /bin/sh -c 执行）
+	AlarmCommand string `json:"alarm_command"`
+}
+
+// FailsafeConfig 传感器失效保护配置
+type FailsafeConfig struct {
+	// MaxFailures 连续失败（或读数不合理）多少次后进入保护，0表示不启用
+	MaxFailures *int `json:"max_failures"`
+	// Duty 保护时的占空比（%）
+	Duty *float64 `json:"duty"`
+	// MinTemp 合理温度的下限
+	MinTemp *float64 `json:"min_temp"`
+	// MaxTemp 合理温度的上限
+	MaxTemp *float64 `json:"max_temp"`
+}
+
+// Settings 返回失效保护设置（需要先填充默认值）
+func (f *FailsafeConfig) Settings() settings.Failsafe {
+	return settings.Failsafe{MaxFailures: *f.MaxFailures, Duty: *f.Duty, MinTemp: *f.MinTemp, MaxTemp: *f.MaxTemp}
+}
+
+// DefaultProfile 启动时的控制方案（即fans中声明的控制规则）的名称
+const DefaultProfile = "default"
+
+// ProfileConfig 控制方案，为部分风扇替换控制规则
+type ProfileConfig struct {
+	// Name 方案名称
+	Name string `json:"name"`
+	// Fans 风扇名称到控制规则的映射，未列出的风扇使用fans中声明的控制规则
+	Fans map[string]ControlConfig `json:"fans"`
+}
+
+// APIConfig HTTP管理接口配置
+type APIConfig struct {
+	// Listen 监听地址，如 "127.0.0.1:9102" 或 "unix:/run/fanap.sock"
+	Listen string `json:"listen"`
+	// Token 访问令牌，设置后请求需要携带 "Authorization: Bearer <token>"
+	Token string `json:"token"`
+}
+
+// MetricsConfig Prometheus指标服务配置
+type MetricsConfig struct {
+	// Listen 监听地址，如 "127.0.0.1:9101"
+	Listen string `json:"listen"`
+}
+
+// SensorConfig 温度传感器配置
+type SensorConfig struct {
+	// Name 传感器名称，供风扇引用
+	Name string `json:"name"`
+	// Type 传感器类型: auto、hwmon、thermal、virtual、exec、ipmi
+	Type string `json:"type"`
+	// Path 传感器选择器: auto（自动检测）、绝对路径（可以包含通配符）、
+	// hwmon:芯片/标签、thermal:类型；type为hwmon或thermal时可以省略前缀，virtual、exec和ipmi类型不使用
+	Path string `json:"path"`
+	// Exec exec类型的命令
+	Exec *ExecConfig `json:"exec"`
+	// IPMI ipmi类型的BMC传感器
+	IPMI *IPMIConfig `json:"ipmi"`
+	// Op virtual类型的组合方式: max、avg、weighted、delta
+	Op string `json:"op"`
+	// Inputs virtual类型的输入，每项为其他传感器的名称或传感器选择器（匹配的所有传感器都作为输入）；
+	// delta为第一个输入减去第二个输入
+	Inputs []string `json:"inputs"`
+	// Weights weighted组合时各输入的权重，未列出的输入权重为1
+	Weights map[string]float64 `json:"weights"`
+	// Filters 滤波链，按顺序应用
+	Filters []filter.Spec `json:"filters"`
+	// SampleInterval 采样间隔，可以比控制间隔更短；为空时每个控制周期采样一次
+	SampleInterval Duration `json:"sample_interval"`
+}
+
+// FanConfig 风扇配置
+type FanConfig struct {
+	// Name 风扇名称
+	Name string `json:"name"`
+	// Type 风扇类型: auto、pwm、cooling、exec、ipmi、pwmchip、thinkpad
+	Type string `json:"type"`
+	// Device 设备路径，auto表示自动检测；pwm风扇也可以使用风扇选择器（如 "hwmon:nct6775/pwm2"）；
+	// pwmchip风扇为PWM通道（如 "pwmchip0/0" 或 "fe6e0000.pwm/0"）；thinkpad风扇为风扇控制文件，auto表示 /proc/acpi/ibm/fan；
+	// exec和ipmi类型不使用
+	Device string `json:"device"`
+	// Exec exec类型的命令
+	Exec *ExecConfig `json:"exec"`
+	// IPMI ipmi类型的BMC风扇区域
+	IPMI *IPMIConfig `json:"ipmi"`
+	// PWMChip pwmchip类型的周期和极性
+	PWMChip *PWMChipConfig `json:"pwmchip"`
+	// ThinkPad thinkpad类型的看门狗和全速档位
+	ThinkPad *ThinkPadConfig `json:"thinkpad"`
+	// Levels pwm风扇支持的转速档位数，PWM向上取整到各档位；为空时按驱动识别（dell_smm为3档），0表示连续调速
+	Levels *int `json:"levels"`
+	// Tach pwm风扇的转速输入（如 "hwmon:nct6775/fan2"），为空时使用与pwmN同编号的fanN_input；
+	// 可以用 fanap probe 测出并写入
+	Tach string `json:"tach"`
+	// MinPWM 最小PWM值 (0-255)
+	MinPWM *int `json:"min_pwm"`
+	// MaxPWM 最大PWM值 (0-255)
+	MaxPWM *int `json:"max_pwm"`
+	// Sensor 风扇跟随的传感器名称（单个传感器）
+	Sensor string `json:"sensor"`
+	// Sensors 风扇跟随的多个传感器名称，与Sensor二选一
+	Sensors []string `json:"sensors"`
+	// Aggregate 多个传感器的聚合方式: max（默认）、weighted
+	Aggregate string `json:"aggregate"`
+	// Weights 加权平均时各传感器的权重，未列出的传感器权重为1
+	Weights map[string]float64 `json:"weights"`
+	// Control 控制规则
+	Control ControlConfig `json:"control"`
+	// Hysteresis 回差设置
+	Hysteresis *HysteresisConfig `json:"hysteresis"`
+	// Ramp PWM变化速率限制
+	Ramp *RampConfig `json:"ramp"`
+	// Stall 停转检测，设置后根据fanN_input检测风扇停转
+	Stall *StallConfig `json:"stall"`
+	// ZeroRPM 零转速模式，设置后低温时风扇停转
+	ZeroRPM *ZeroRPMConfig `json:"zero_rpm"`
+}
+
+// ExecConfig 外部命令插件配置
+type ExecConfig struct {
+	// Command 通过 /bin/sh -c 执行的命令
+	Command string `json:"command"`
+	// Timeout 命令或请求的超时时间，默认5s
+	Timeout Duration `json:"timeout"`
+	// Persistent 以常驻进程运行命令，通过标准输入输出逐行交换JSON消息
+	Persistent bool `json:"persistent"`
+	// Cache 传感器读数的缓存时间，缓存期间不再执行命令（仅传感器）
+	Cache Duration `json:"cache"`
+	// Restore 单次命令方式下退出时执行的恢复命令（仅风扇）
+	Restore string `json:"restore"`
+}
+
+// IPMIConfig 通过ipmitool访问BMC的传感器和风扇配置
+type IPMIConfig struct {
+	// Command ipmitool命令，可以包含连接参数（如 "ipmitool -I lanplus -H 10.0.0.5 -U admin -f /etc/fanap/ipmi.pass"），默认 "ipmitool"
+	Command string `json:"command"`
+	// Timeout 每次执行ipmitool的超时时间，默认10s
+	Timeout Duration `json:"timeout"`
+	// Cache sdr读数的缓存时间，命令相同的传感器和转速在缓存时间内共用一次读取，默认2s
+	Cache *Duration `json:"cache"`
+	// Sensor sdr中的温度传感器名称，如 "CPU Temp"（仅传感器）
+	Sensor string `json:"sensor"`
+	// Preset 风扇控制预设: supermicro、dell、custom（仅风扇）
+	Preset string `json:"preset"`
+	// Zone 风扇区域，默认使用预设的区域: supermicro为0（CPU风扇），dell为255（所有风扇）（仅风扇）
+	Zone *int `json:"zone"`
+	// Tach sdr中的风扇转速传感器名称，如 "FAN1"，用于停转检测和零转速模式（仅风扇）
+	Tach string `json:"tach"`
+	// Raw 替换预设中的raw命令（仅风扇），custom预设必须指定raw.set
+	Raw *IPMIRawConfig `json:"raw"`
+}
+
+// PWMChipConfig Linux PWM子系统通道的设置
+type PWMChipConfig struct {
+	// Period 周期，默认40µs（25kHz）
+	Period Duration `json:"period"`
+	// Polarity 极性: normal、inversed，为空时保持驱动的设置
+	Polarity string `json:"polarity"`
+}
+
+// ThinkPadConfig thinkpad_acpi风扇的设置
+type ThinkPadConfig struct {
+	// Watchdog 看门狗超时时间（1-120秒），fanap异常退出后固件在超时后切回自动控制；默认30s，0表示不启用
+	Watchdog *Duration `json:"watchdog"`
+	// FullSpeed 全速运行时使用的档位: full-speed（默认）、disengaged
+	FullSpeed string `json:"full_speed"`
+}
+
+// IPMIRawConfig BMC风扇控制的raw命令，如 "0x30 0x70 0x66 0x01 {zone} {duty}"
+type IPMIRawConfig struct {
+	// Mode 读取当前风扇模式，响应的第一个字节在退出时作为auto中的{mode}
+	Mode string `json:"mode"`
+	// Manual 切换到手动模式
+	Manual string `json:"manual"`
+	// Set 设置占空比，可以使用{zone}、{duty}（0-100）和{pwm}（0-255）
+	Set string `json:"set"`
+	// Get 读取占空比，响应的第一个字节为占空比
+	Get string `json:"get"`
+	// Auto 恢复BMC自动模式，可以使用{zone}和{mode}
+	Auto string `json:"auto"`
+}
+
+// ZeroRPMConfig 零转速模式配置
+type ZeroRPMConfig struct {
+	// StopTemp 温度降到该值及以下时风扇停转
+	StopTemp *float64 `json:"stop_temp"`
+	// StartTemp 停转后温度升到该值及以上时重新启动
+	StartTemp *float64 `json:"start_temp"`
+	// KickPWM 从停转状态启动时的PWM
+	KickPWM *int `json:"kick_pwm"`
+	// Kick 以KickPWM运行的时长，"0s"表示直接以计算出的PWM启动
+	Kick *Duration `json:"kick"`
+}
+
+// Settings 返回零转速模式设置（需要先填充默认值）
+func (z *ZeroRPMConfig) Settings() settings.ZeroRPM {
+	return settings.ZeroRPM{StopTemp: *z.StopTemp, StartTemp: *z.StartTemp, KickPWM: *z.KickPWM, Kick: z.Kick.Duration}
+}
+
+// StallConfig 风扇停转检测配置
+type StallConfig struct {
+	// MinPWM 只在PWM不低于该值时检查转速
+	MinPWM *int `json:"min_pwm"`
+	// MinRPM 转速低于该值视为停转，0表示使用fanN_min
+	MinRPM *int `json:"min_rpm"`
+	// Checks 连续多少次检查异常后判定为停转
+	Checks *int `json:"checks"`
+	// Kick 停转后全速启动的时长，"0s"表示不尝试重新启动
+	Kick *Duration `json:"kick"`
+}
+
+// Settings 返回停转检测设置（需要先填充默认值）
+func (st *StallConfig) Settings() settings.StallDetect {
+	return settings.StallDetect{MinPWM: *st.MinPWM, MinRPM: *st.MinRPM, Checks: *st.Checks, Kick: st.Kick.Duration}
+}
+
+// RampConfig PWM变化速率限制（PWM/秒），0表示不限制
+type RampConfig struct {
+	// Up 提速时每秒最多增加的PWM
+	Up float64 `json:"up"`
+	// Down 降速时每秒最多减少的PWM
+	Down float64 `json:"down"`
+}
+
+// Settings 返回速率限制设置
+func (r *RampConfig) Settings() settings.Ramp {
+	return settings.Ramp{Up: r.Up, Down: r.Down}
+}
+
+// HysteresisConfig 回差配置，避免风扇在阈值附近反复调速
+type HysteresisConfig struct {
+	// Rise 温度升高超过该值（°C）才重新计算PWM
+	Rise float64 `json:"rise"`
+	// Fall 温度降低超过该值（°C）才重新计算PWM
+	Fall float64 `json:"fall"`
+	// OnPWM 2级（开/关）冷却设备关闭状态下，PWM达到该值时打开
+	OnPWM *int `json:"on_pwm"`
+	// OffPWM 2级（开/关）冷却设备打开状态下，PWM降到该值时关闭
+	OffPWM *int `json:"off_pwm"`
+}
+
+// Settings 返回温度回差设置
+func (h *HysteresisConfig) Settings() settings.Hysteresis {
+	return settings.Hysteresis{Rise: h.Rise, Fall: h.Fall}
+}
+
+// HasOnOff 是否设置了开/关阈值
+func (h *HysteresisConfig) HasOnOff() bool {
+	return h != nil && (h.OnPWM != nil || h.OffPWM != nil)
+}
+
+// ControlConfig 风扇控制规则
+type ControlConfig struct {
+	// LowTemp 低温阈值，低于此温度使用最小PWM
+	LowTemp *float64 `json:"low_temp"`
+	// HighTemp 高温阈值，高于此温度使用最大PWM
+	HighTemp *float64 `json:"high_temp"`
+	// Curve 多点风扇曲线，与LowTemp/HighTemp二选一
+	Curve *CurveConfig `json:"curve"`
+	// PID 目标温度闭环控制，与Curve、LowTemp/HighTemp互斥
+	PID *PIDConfig `json:"pid"`
+}
+
+// PIDConfig PID控制配置
+type PIDConfig struct {
+	// Target 目标温度（摄氏度）
+	Target *float64 `json:"target"`
+	// Kp 比例增益（PWM/°C）
+	Kp *float64 `json:"kp"`
+	// Ki 积分增益（PWM/(°C·s)）
+	Ki *float64 `json:"ki"`
+	// Kd 微分增益（PWM·s/°C）
+	Kd *float64 `json:"kd"`
+}
+
+// Gains 返回PID增益
+func (p *PIDConfig) Gains() pid.Gains {
+	return pid.Gains{Kp: *p.Kp, Ki: *p.Ki, Kd: *p.Kd}
+}
+
+// CurveConfig 风扇曲线配置
+type CurveConfig struct {
+	// Type 曲线类型: linear（分段线性，默认）、step（阶梯）
+	Type string `json:"type"`
+	// Points 控制点，温度严格递增，PWM单调不减
+	Points []curve.Point `json:"points"`
+}
+
+// Duration 支持 "5s" 格式的时间间隔
+type Duration struct {
+	time.Duration
+}
+
+// UnmarshalJSON 解析时间间隔字符串
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("时间间隔必须是字符串（如 \"5s\"）")
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return fmt.Errorf("无效的时间间隔 %q", s)
+	}
+	d.Duration = v
+	return nil
+}
+
+// MarshalJSON 输出时间间隔字符串
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+// FieldError 指向配置文件中具体键的错误
+type FieldError struct {
+	// Key 出错的键路径，如 "fans[1].control.low_temp"
+	Key string
+	Msg string
+}
+
+func (e *FieldError) Error() string {
+	if e.Key == "" {
+		return e.Msg
+	}
+	return fmt.Sprintf("%s: %s", e.Key, e.Msg)
+}
+
+// Load 读取并解析配置文件
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取配置文件失败: %w", err)
+	}
+
+	cfg, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("配置文件 %s 无效:\n%w", path, err)
+	}
+	return cfg, nil
+}
+
+// Parse 解析配置内容，填充默认值并验证
+func Parse(data []byte) (*Config, error) {
+	// 先解析为通用结构，检查未知的键和值的格式，以便报告准确的键路径
+	var raw interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, syntaxError(data, err)
+	}
+	if errs := checkKeys(raw, configType, ""); len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	cfg := &Config{}
+	if err := json.Unmarshal(data, cfg); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) {
+			return nil, &FieldError{Key: jsonPath(typeErr.Field), Msg: fmt.Sprintf("类型错误，期望 %s", typeErr.Type)}
+		}
+		return nil, err
+	}
+
+	cfg.applyDefaults()
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// applyDefaults 填充未设置的配置项
+func (c *Config) applyDefaults() {
+	if c.Interval.Duration == 0 {
+		c.Interval.Duration = settings.DefaultInterval
+	}
+	if c.OnExit == "" {
+		c.OnExit = OnExitRestore
+	}
+	if c.Failsafe == nil {
+		c.Failsafe = &FailsafeConfig{}
+	}
+	f := c.Failsafe
+	if f.MaxFailures == nil {
+		f.MaxFailures = intPtr(settings.DefaultFailsafeReads)
+	}
+	if f.Duty == nil {
+		f.Duty = floatPtr(settings.DefaultFailsafeDuty)
+	}
+	if f.MinTemp == nil {
+		f.MinTemp = floatPtr(settings.DefaultFailsafeMinTemp)
+	}
+	if f.MaxTemp == nil {
+		f.MaxTemp = floatPtr(settings.DefaultFailsafeMaxTemp)
+	}
+
+	for i := range c.Sensors {
+		s := &c.Sensors[i]
+		if s.Type == "" {
+			s.Type = SensorAuto
+		}
+		if s.Path == "" && s.Type != SensorVirtual && s.Type != SensorExec && s.Type != SensorIPMI {
+			s.Path = "auto"
+		}
+		s.IPMI.applyDefaults()
+	}
+
+	for i := range c.Fans {
+		f := &c.Fans[i]
+		if f.Type == "" {
+			f.Type = FanAuto
+		}
+		if f.Device == "" && f.Type != FanExec && f.Type != FanIPMI && f.Type != FanPWMChip {
+			f.Device = "auto"
+		}
+		f.IPMI.applyDefaults()
+		if f.Type == FanThinkPad {
+			if f.ThinkPad == nil {
+				f.ThinkPad = &ThinkPadConfig{}
+			}
+			if f.ThinkPad.Watchdog == nil {
+				f.ThinkPad.Watchdog = &Duration{thinkpad.DefaultWatchdog}
+			}
+		}
+		if f.Aggregate == "" {
+			f.Aggregate = AggregateMax
+		}
+		if h := f.Hysteresis; h.HasOnOff() {
+			if h.OnPWM == nil {
+				h.OnPWM = intPtr(settings.DefaultOnPWM)
+			}
+			if h.OffPWM == nil {
+				h.OffPWM = intPtr(settings.DefaultOffPWM)
+			}
+		}
+		if st := f.Stall; st != nil {
+			if st.MinPWM == nil {
+				st.MinPWM = intPtr(settings.DefaultStallMinPWM)
+			}
+			if st.MinRPM == nil {
+				st.MinRPM = intPtr(0)
+			}
+			if st.Checks == nil {
+				st.Checks = intPtr(settings.DefaultStallChecks)
+			}
+			if st.Kick == nil {
+				st.Kick = &Duration{settings.DefaultStallKick}
+			}
+		}
+		if z := f.ZeroRPM; z != nil {
+			if z.StopTemp == nil {
+				z.StopTemp = floatPtr(settings.DefaultZeroRPMStopTemp)
+			}
+			if z.StartTemp == nil {
+				z.StartTemp = floatPtr(settings.DefaultZeroRPMStartTemp)
+			}
+			if z.KickPWM == nil {
+				z.KickPWM = intPtr(settings.DefaultZeroRPMKickPWM)
+			}
+			if z.Kick == nil {
+				z.Kick = &Duration{settings.DefaultZeroRPMKick}
+			}
+		}
+		if f.MinPWM == nil {
+			f.MinPWM = intPtr(settings.DefaultMinPWM)
+		}
+		if f.MaxPWM == nil {
+			f.MaxPWM = intPtr(settings.DefaultMaxPWM)
+		}
+		f.Control.ApplyDefaults()
+	}
+
+	for i := range c.Profiles {
+		for name, cc := range c.Profiles[i].Fans {
+			cc.ApplyDefaults()
+			c.Profiles[i].Fans[name] = cc
+		}
+	}
+}
+
+// ApplyDefaults 填充控制规则中未设置的项
+func (cc *ControlConfig) ApplyDefaults() {
+	if p := cc.PID; p != nil {
+		if p.Kp == nil {
+			p.Kp = floatPtr(pid.DefaultKp)
+		}
+		if p.Ki == nil {
+			p.Ki = floatPtr(pid.DefaultKi)
+		}
+		if p.Kd == nil {
+			p.Kd = floatPtr(pid.DefaultKd)
+		}
+		return
+	}
+	if cc.Curve != nil {
+		if cc.Curve.Type == "" {
+			cc.Curve.Type = curve.TypeLinear
+		}
+		return
+	}
+	if cc.LowTemp == nil {
+		cc.LowTemp = floatPtr(settings.DefaultLowTemp)
+	}
+	if cc.HighTemp == nil {
+		cc.HighTemp = floatPtr(settings.DefaultHighTemp)
+	}
+}
+
+// Validate 验证配置，返回所有指向具体键的错误
+func (c *Config) Validate() error {
+	var errs []error
+	fail := func(key, format string, args ...interface{}) {
+		errs = append(errs, &FieldError{Key: key, Msg: fmt.Sprintf(format, args...)})
+	}
+
+	if c.Interval.Duration <= 0 {
+		fail("interval", "时间间隔必须大于0")
+	}
+
+	if f := c.Failsafe; f != nil {
+		errs = append(errs, settingErrors("failsafe", f.Settings().Validate())...)
+	}
+
+	switch c.OnExit {
+	case OnExitRestore, OnExitFull, OnExitLeave:
+	default:
+		fail("on_exit", "未知的退出策略 %q（可选: restore、full、leave）", c.OnExit)
+	}
+
+	if c.Metrics != nil {
+		if _, _, err := net.SplitHostPort(c.Metrics.Listen); err != nil {
+			fail("metrics.listen", "无效的监听地址 %q（格式: 主机:端口）", c.Metrics.Listen)
+		}
+	}
+	if c.API != nil {
+		if err := validateListen(c.API.Listen); err != nil {
+			fail("api.listen", "%v", err)
+		}
+	}
+
+	defined := make(map[string]bool)
+	for _, s := range c.Sensors {
+		defined[s.Name] = true
+	}
+
+	sensorNames := make(map[string]bool)
+	for i, s := range c.Sensors {
+		key := fmt.Sprintf("sensors[%d]", i)
+		switch {
+		case s.Name == "":
+			fail(key+".name", "传感器名称不能为空")
+		case sensorNames[s.Name]:
+			fail(key+".name", "传感器名称重复: %q", s.Name)
+		}
+		sensorNames[s.Name] = true
+
+		switch s.Type {
+		case SensorAuto, SensorHWMon, SensorThermal, SensorVirtual, SensorExec, SensorIPMI:
+		default:
+			fail(key+".type", "未知的传感器类型 %q（可选: auto、hwmon、thermal、virtual、exec、ipmi）", s.Type)
+		}
+
+		if s.Type == SensorExec {
+			if s.Path != "" {
+				fail(key+".path", "exec类型的传感器不使用path")
+			}
+			if s.Exec == nil {
+				fail(key+".exec", "exec类型的传感器必须指定exec.command")
+			} else {
+				errs = append(errs, s.Exec.validate(key+".exec", false)...)
+			}
+		} else if s.Exec != nil {
+			fail(key+".exec", "exec只能用于exec类型的传感器")
+		}
+
+		if s.Type == SensorIPMI {
+			if s.Path != "" {
+				fail(key+".path", "ipmi类型的传感器不使用path，请使用ipmi.sensor")
+			}
+			if s.IPMI == nil {
+				fail(key+".ipmi", "ipmi类型的传感器必须指定ipmi.sensor")
+			} else {
+				errs = append(errs, s.IPMI.validate(key+".ipmi", false)...)
+			}
+		} else if s.IPMI != nil {
+			fail(key+".ipmi", "ipmi只能用于ipmi类型的传感器")
+		}
+
+		if s.Type == SensorVirtual {
+			errs = append(errs, c.validateVirtual(key, s, defined)...)
+		} else if s.Op != "" || len(s.Inputs) > 0 || len(s.Weights) > 0 {
+			fail(key+".type", "op、inputs和weights只能用于virtual类型的传感器")
+		} else if s.Type == SensorExec || s.Type == SensorIPMI {
+			// exec和ipmi类型不使用选择器
+		} else if sel, err := sensor.Parse(s.Selector()); err != nil {
+			fail(key+".path", "%v", err)
+		} else if s.Type != SensorAuto && sel.Kind != sensor.KindAuto && sel.Kind != sensor.KindPath && sel.Kind != s.Type {
+			fail(key+".path", "选择器 %q 与传感器类型 %q 不一致", s.Path, s.Type)
+		}
+
+		if s.SampleInterval.Duration < 0 {
+			fail(key+".sample_interval", "采样间隔不能为负数")
+		}
+		if s.SampleInterval.Duration > 0 && len(s.Filters) == 0 {
+			fail(key+".sample_interval", "sample_interval需要与filters一起使用")
+		}
+		for j, spec := range s.Filters {
+			if err := spec.Validate(); err != nil {
+				fkey := fmt.Sprintf("%s.filters[%d]", key, j)
+				var se *filter.SpecError
+				if errors.As(err, &se) {
+					fkey += "." + se.Field
+				}
+				fail(fkey, "%v", err)
+			}
+		}
+	}
+
+	if len(c.Fans) == 0 {
+		fail("fans", "至少需要配置一个风扇")
+	}
+
+	fanNames := make(map[string]bool)
+	for i, f := range c.Fans {
+		key := fmt.Sprintf("fans[%d]", i)
+		switch {
+		case f.Name == "":
+			fail(key+".name", "风扇名称不能为空")
+		case fanNames[f.Name]:
+			fail(key+".name", "风扇名称重复: %q", f.Name)
+		}
+		fanNames[f.Name] = true
+
+		switch f.Type {
+		case FanAuto, FanPWM, FanCooling, FanExec, FanIPMI, FanPWMChip, FanThinkPad:
+		default:
+			fail(key+".type", "未知的风扇类型 %q（可选: auto、pwm、cooling、exec、ipmi、pwmchip、thinkpad）", f.Type)
+		}
+		if f.Type == FanExec {
+			if f.Device != "" {
+				fail(key+".device", "exec类型的风扇不使用device")
+			}
+			if f.Exec == nil {
+				fail(key+".exec", "exec类型的风扇必须指定exec.command")
+			} else {
+				errs = append(errs, f.Exec.validate(key+".exec", true)...)
+			}
+		} else if f.Exec != nil {
+			fail(key+".exec", "exec只能用于exec类型的风扇")
+		}
+		if f.Type == FanIPMI {
+			if f.Device != "" {
+				fail(key+".device", "ipmi类型的风扇不使用device")
+			}
+			if f.IPMI == nil {
+				fail(key+".ipmi", "ipmi类型的风扇必须指定ipmi.preset")
+			} else {
+				errs = append(errs, f.IPMI.validate(key+".ipmi", true)...)
+			}
+		} else if f.IPMI != nil {
+			fail(key+".ipmi", "ipmi只能用于ipmi类型的风扇")
+		}
+		if f.Type == FanPWM {
+			if _, err := fan.ParseSelector(f.Device); err != nil {
+				fail(key+".device", "%v", err)
+			}
+		}
+		if f.Type == FanPWMChip {
+			if f.Device == "" {
+				fail(key+".device", "pwmchip类型的风扇必须指定PWM通道（如 \"pwmchip0/0\"）")
+			} else if _, err := pwmchip.ParseSpec(f.Device); err != nil {
+				fail(key+".device", "%v", err)
+			}
+			if pc := f.PWMChip; pc != nil {
+				if pc.Period.Duration < 0 {
+					fail(key+".pwmchip.period", "周期不能为负数")
+				} else if pc.Period.Duration > 0 && pc.Period.Duration < time.Microsecond {
+					fail(key+".pwmchip.period", "周期过短: %v", pc.Period.Duration)
+				}
+				switch pc.Polarity {
+				case "", pwmchip.PolarityNormal, pwmchip.PolarityInversed:
+				default:
+					fail(key+".pwmchip.polarity", "未知的极性 %q（可选: normal、inversed）", pc.Polarity)
+				}
+			}
+		} else if f.PWMChip != nil {
+			fail(key+".pwmchip", "pwmchip只能用于pwmchip类型的风扇")
+		}
+		if f.Type == FanThinkPad {
+			if f.Device != "auto" && !filepath.IsAbs(f.Device) {
+				fail(key+".device", "thinkpad类型的风扇的device必须是auto或风扇控制文件的绝对路径")
+			}
+			tp := f.ThinkPad
+			if w := tp.Watchdog.Duration; w < 0 || w > thinkpad.MaxWatchdog {
+				fail(key+".thinkpad.watchdog", "看门狗超时时间必须在0-%d秒之间", int(thinkpad.MaxWatchdog.Seconds()))
+			}
+			switch tp.FullSpeed {
+			case "", thinkpad.LevelFullSpeed, thinkpad.LevelDisengaged:
+			default:
+				fail(key+".thinkpad.full_speed", "未知的全速档位 %q（可选: full-speed、disengaged）", tp.FullSpeed)
+			}
+		} else if f.ThinkPad != nil {
+			fail(key+".thinkpad", "thinkpad只能用于thinkpad类型的风扇")
+		}
+		if f.Levels != nil {
+			if f.Type != FanPWM {
+				fail(key+".levels", "只有pwm类型的风扇可以指定档位数")
+			} else if *f.Levels != 0 && (*f.Levels < 2 || *f.Levels > 256) {
+				fail(key+".levels", "档位数必须在2-256之间，0表示连续调速")
+			}
+		}
+		if f.Tach != "" {
+			if f.Type != FanPWM {
+				fail(key+".tach", "只有pwm类型的风扇可以指定转速输入")
+			} else if _, err := fan.ParseSelector(f.Tach); err != nil {
+				fail(key+".tach", "%v", err)
+			}
+		}
+
+		errs = append(errs, settingErrors(key, settings.PWMRange(*f.MinPWM, *f.MaxPWM))...)
+
+		switch {
+		case f.Sensor != "" && len(f.Sensors) > 0:
+			fail(key+".sensors", "sensor和sensors不能同时设置")
+		case f.Sensor != "":
+			if !sensorNames[f.Sensor] {
+				fail(key+".sensor", "未定义的传感器 %q", f.Sensor)
+			}
+		case len(f.Sensors) > 0:
+			seen := make(map[string]bool)
+			for j, name := range f.Sensors {
+				switch {
+				case !sensorNames[name]:
+					fail(fmt.Sprintf("%s.sensors[%d]", key, j), "未定义的传感器 %q", name)
+				case seen[name]:
+					fail(fmt.Sprintf("%s.sensors[%d]", key, j), "传感器重复: %q", name)
+				}
+				seen[name] = true
+			}
+		default:
+			fail(key+".sensor", "必须指定风扇跟随的传感器（sensor或sensors）")
+		}
+
+		switch f.Aggregate {
+		case AggregateMax, AggregateWeighted:
+		default:
+			fail(key+".aggregate", "未知的聚合方式 %q（可选: max、weighted）", f.Aggregate)
+		}
+
+		inputs := make(map[string]bool)
+		for _, name := range f.Inputs() {
+			inputs[name] = true
+		}
+		for _, name := range sortedWeightKeys(f.Weights) {
+			switch {
+			case !inputs[name]:
+				fail(key+".weights."+name, "传感器 %q 不在该风扇的传感器列表中", name)
+			case f.Weights[name] < 0:
+				fail(key+".weights."+name, "权重不能为负数")
+			}
+		}
+
+		if h := f.Hysteresis; h != nil {
+			errs = append(errs, settingErrors(key+".hysteresis", h.Settings().Validate())...)
+			if h.HasOnOff() {
+				errs = append(errs, settingErrors(key+".hysteresis", settings.OnOff(*h.OnPWM, *h.OffPWM))...)
+			}
+		}
+
+		if r := f.Ramp; r != nil {
+			errs = append(errs, settingErrors(key+".ramp", r.Settings().Validate())...)
+		}
+
+		if st := f.Stall; st != nil {
+			if f.Type == FanCooling {
+				fail(key+".stall", "cooling类型的风扇不支持停转检测")
+			}
+			errs = append(errs, settingErrors(key+".stall", st.Settings().Validate())...)
+		}
+
+		if z := f.ZeroRPM; z != nil {
+			errs = append(errs, settingErrors(key+".zero_rpm", z.Settings().Validate())...)
+		}
+
+		errs = append(errs, f.Control.validate(key+".control")...)
+	}
+
+	profileNames := make(map[string]bool)
+	for i, p := range c.Profiles {
+		key := fmt.Sprintf("profiles[%d]", i)
+		switch {
+		case p.Name == "":
+			fail(key+".name", "配置方案名称不能为空")
+		case p.Name == DefaultProfile:
+			fail(key+".name", "%q 是保留的配置方案名称", DefaultProfile)
+		case profileNames[p.Name]:
+			fail(key+".name", "配置方案名称重复: %q", p.Name)
+		}
+		profileNames[p.Name] = true
+
+		if len(p.Fans) == 0 {
+			fail(key+".fans", "至少需要为一个风扇设置控制规则")
+		}
+		for _, name := range sortedControlKeys(p.Fans) {
+			if !fanNames[name] {
+				fail(key+".fans."+name, "未定义的风扇 %q", name)
+				continue
+			}
+			cc := p.Fans[name]
+			errs = append(errs, cc.validate(key+".fans."+name)...)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Validate 验证控制规则（需要先调用ApplyDefaults），返回的错误键相对于控制规则本身
+func (cc *ControlConfig) Validate() error {
+	return errors.Join(cc.validate("")...)
+}
+
+// validate 验证控制规则，key为控制规则在配置文件中的路径
+func (cc *ControlConfig) validate(key string) []error {
+	var errs []error
+	fail := func(k, format string, args ...interface{}) {
+		errs = append(errs, &FieldError{Key: joinKey(key, k), Msg: fmt.Sprintf(format, args...)})
+	}
+
+	if p := cc.PID; p != nil {
+		switch {
+		case cc.Curve != nil:
+			fail("pid", "pid和curve不能同时设置")
+		case cc.LowTemp != nil || cc.HighTemp != nil:
+			fail("pid", "pid和low_temp/high_temp不能同时设置")
+		}
+		if p.Target == nil {
+			fail("pid.target", "必须指定目标温度")
+		}
+		if err := p.Gains().Validate(); err != nil {
+			fail("pid", "%v", err)
+		}
+	} else if c := cc.Curve; c != nil {
+		if cc.LowTemp != nil || cc.HighTemp != nil {
+			fail("curve", "curve和low_temp/high_temp不能同时设置")
+		}
+		if err := curve.Validate(c.Type, c.Points); err != nil {
+			errs = append(errs, curveError(joinKey(key, "curve"), err))
+		}
+	} else {
+		errs = append(errs, settingErrors(key, settings.Thresholds(*cc.LowTemp, *cc.HighTemp))...)
+	}
+
+	return errs
+}
+
+// validate 验证外部命令插件配置，fan表示用于风扇
+func (e *ExecConfig) validate(key string, fan bool) []error {
+	var errs []error
+	fail := func(k, format string, args ...interface{}) {
+		errs = append(errs, &FieldError{Key: k, Msg: fmt.Sprintf(format, args...)})
+	}
+
+	if strings.TrimSpace(e.Command) == "" {
+		fail(key+".command", "命令不能为空")
+	}
+	if e.Timeout.Duration < 0 {
+		fail(key+".timeout", "超时时间不能为负数")
+	}
+	if e.Cache.Duration < 0 {
+		fail(key+".cache", "缓存时间不能为负数")
+	}
+	if fan && e.Cache.Duration != 0 {
+		fail(key+".cache", "cache只能用于传感器")
+	}
+	if !fan && e.Restore != "" {
+		fail(key+".restore", "restore只能用于风扇")
+	}
+	if e.Persistent && e.Restore != "" {
+		fail(key+".restore", "常驻插件通过restore请求恢复风扇，不使用恢复命令")
+	}
+	return errs
+}
+
+// applyDefaults 填充未设置的项，i为nil时不做任何事
+func (i *IPMIConfig) applyDefaults() {
+	if i == nil {
+		return
+	}
+	if i.Command == "" {
+		i.Command = ipmi.DefaultCommand
+	}
+	if i.Cache == nil {
+		i.Cache = &Duration{DefaultIPMICache}
+	}
+}
+
+// validate 验证IPMI配置，fan表示用于风扇
+func (i *IPMIConfig) validate(key string, fan bool) []error {
+	var errs []error
+	fail := func(k, format string, args ...interface{}) {
+		errs = append(errs, &FieldError{Key: k, Msg: fmt.Sprintf(format, args...)})
+	}
+
+	if strings.TrimSpace(i.Command) == "" {
+		fail(key+".command", "命令不能为空")
+	}
+	if i.Timeout.Duration < 0 {
+		fail(key+".timeout", "超时时间不能为负数")
+	}
+	if i.Cache.Duration < 0 {
+		fail(key+".cache", "缓存时间不能为负数")
+	}
+
+	if !fan {
+		if i.Sensor == "" {
+			fail(key+".sensor", "必须指定sdr中的传感器名称")
+		}
+		if i.Preset != "" || i.Zone != nil || i.Tach != "" || i.Raw != nil {
+			fail(key, "preset、zone、tach和raw只能用于风扇")
+		}
+		return errs
+	}
+
+	if i.Sensor != "" {
+		fail(key+".sensor", "sensor只能用于传感器，风扇转速请使用tach")
+	}
+	preset, err := ipmi.LookupPreset(i.Preset)
+	if err != nil {
+		fail(key+".preset", "%v", err)
+	}
+	if i.Zone != nil && (*i.Zone < 0 || *i.Zone > 255) {
+		fail(key+".zone", "风扇区域必须在0-255之间")
+	}
+	if r := i.Raw; r != nil {
+		for _, c := range []struct{ kind, cmd string }{
+			{"mode", r.Mode}, {"manual", r.Manual}, {"set", r.Set}, {"get", r.Get}, {"auto", r.Auto},
+		} {
+			if c.cmd == "" {
+				continue
+			}
+			if err := ipmi.ValidateRaw(c.kind, c.cmd); err != nil {
+				fail(key+".raw."+c.kind, "%v", err)
+			}
+		}
+	}
+	if err == nil && preset.Set == "" && (i.Raw == nil || i.Raw.Set == "") {
+		fail(key+".raw.set", "custom预设必须指定设置占空比的raw命令")
+	}
+	return errs
+}
+
+// FanPreset 返回替换了raw命令的预设和风扇区域，需要先通过验证
+func (i *IPMIConfig) FanPreset() (ipmi.Preset, int) {
+	p, _ := ipmi.LookupPreset(i.Preset)
+	if r := i.Raw; r != nil {
+		for _, c := range []struct {
+			dst *string
+			src string
+		}{
+			{&p.Mode, r.Mode}, {&p.Manual, r.Manual}, {&p.Set, r.Set}, {&p.Get, r.Get}, {&p.Auto, r.Auto},
+		} {
+			if c.src != "" {
+				*c.dst = c.src
+			}
+		}
+	}
+	zone := p.Zone
+	if i.Zone != nil {
+		zone = *i.Zone
+	}
+	return p, zone
+}
+
+// validateVirtual 验证虚拟传感器，defined为所有已声明的传感器名称
+func (c *Config) validateVirtual(key string, s SensorConfig, defined map[string]bool) []error {
+	var errs []error
+	fail := func(k, format string, args ...interface{}) {
+		errs = append(errs, &FieldError{Key: k, Msg: fmt.Sprintf(format, args...)})
+	}
+
+	if s.Path != "" {
+		fail(key+".path", "virtual类型的传感器不使用path，请在inputs中指定输入")
+	}
+	switch s.Op {
+	case virtual.OpMax, virtual.OpAvg, virtual.OpWeighted, virtual.OpDelta:
+		if err := virtual.ValidateOp(s.Op, len(s.Inputs)); err != nil {
+			fail(key+".inputs", "%v", err)
+		}
+	case "":
+		fail(key+".op", "必须指定组合方式（max、avg、weighted、delta）")
+	default:
+		fail(key+".op", "未知的组合方式 %q（可选: max、avg、weighted、delta）", s.Op)
+	}
+
+	seen := make(map[string]bool)
+	for j, in := range s.Inputs {
+		ikey := fmt.Sprintf("%s.inputs[%d]", key, j)
+		switch {
+		case seen[in]:
+			fail(ikey, "输入重复: %q", in)
+		case defined[in]:
+			if cycle := c.sensorCycle(in, []string{s.Name}); cycle != nil {
+				fail(ikey, "虚拟传感器循环引用: %s", strings.Join(cycle, " -> "))
+			}
+		default:
+			if sel, err := sensor.Parse(in); err != nil {
+				fail(ikey, "%q 既不是已定义的传感器，也不是有效的选择器: %v", in, err)
+			} else if sel.Kind == sensor.KindAuto {
+				fail(ikey, "虚拟传感器的输入不能为auto")
+			}
+		}
+		seen[in] = true
+	}
+
+	if len(s.Weights) > 0 && s.Op != virtual.OpWeighted {
+		fail(key+".weights", "weights只能用于weighted组合")
+		return errs
+	}
+	for _, name := range sortedWeightKeys(s.Weights) {
+		switch {
+		case !seen[name]:
+			fail(key+".weights."+name, "%q 不在该传感器的输入列表中", name)
+		case s.Weights[name] < 0:
+			fail(key+".weights."+name, "权重不能为负数")
+		}
+	}
+	return errs
+}
+
+// sensorCycle 沿虚拟传感器的输入查找回到path中传感器的引用，返回循环经过的传感器，没有循环时返回nil
+func (c *Config) sensorCycle(name string, path []string) []string {
+	for i, p := range path {
+		if p == name {
+			return append(append([]string{}, path[i:]...), name)
+		}
+	}
+	s, ok := c.Sensor(name)
+	if !ok || s.Type != SensorVirtual {
+		return nil
+	}
+
+	path = append(path[:len(path):len(path)], name)
+	for _, in := range s.Inputs {
+		if cycle := c.sensorCycle(in, path); cycle != nil {
+			return cycle
+		}
+	}
+	return nil
+}
+
+// Weight 返回虚拟传感器输入的权重，未设置时为1
+func (s SensorConfig) Weight(input string) float64 {
+	if w, ok := s.Weights[input]; ok {
+		return w
+	}
+	return 1
+}
+
+// Inputs 返回风扇跟随的所有传感器名称
+func (f FanConfig) Inputs() []string {
+	if f.Sensor != "" {
+		return []string{f.Sensor}
+	}
+	return f.Sensors
+}
+
+// Weight 返回传感器的权重，未设置时为1
+func (f FanConfig) Weight(sensor string) float64 {
+	if w, ok := f.Weights[sensor]; ok {
+		return w
+	}
+	return 1
+}
+
+// Selector 返回传感器的选择器，type为hwmon或thermal时为省略了前缀的path补上前缀
+func (s SensorConfig) Selector() string {
+	if s.Type == SensorAuto || s.Path == "auto" || filepath.IsAbs(s.Path) ||
+		strings.HasPrefix(s.Path, SensorHWMon+":") || strings.HasPrefix(s.Path, SensorThermal+":") {
+		return s.Path
+	}
+	return s.Type + ":" + s.Path
+}
+
+// Sensor 按名称查找传感器配置
+func (c *Config) Sensor(name string) (SensorConfig, bool) {
+	for _, s := range c.Sensors {
+		if s.Name == name {
+			return s, true
+		}
+	}
+	return SensorConfig{}, false
+}
+
+// settingErrors 把 settings 包的验证错误转换为指向配置键的错误，key为设置项所在配置段的路径
+func settingErrors(key string, err error) []error {
+	if err == nil {
+		return nil
+	}
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		var errs []error
+		for _, e := range joined.Unwrap() {
+			errs = append(errs, settingErrors(key, e)...)
+		}
+		return errs
+	}
+	var se *settings.Error
+	if errors.As(err, &se) {
+		return []error{&FieldError{Key: joinKey(key, se.Field), Msg: se.Msg}}
+	}
+	return []error{&FieldError{Key: key, Msg: err.Error()}}
+}
+
+// curveError 将曲线验证错误转换为指向具体键的错误
+func curveError(key string, err error) error {
+	var pe *curve.PointError
+	if !errors.As(err, &pe) {
+		return &FieldError{Key: key, Msg: err.Error()}
+	}
+	if pe.Index < 0 {
+		return &FieldError{Key: key + "." + pe.Field, Msg: pe.Msg}
+	}
+	return &FieldError{Key: fmt.Sprintf("%s.points[%d].%s", key, pe.Index, pe.Field), Msg: pe.Msg}
+}
+
+// syntaxError 将JSON语法错误转换为带行列号的错误
+func syntaxError(data []byte, err error) error {
+	var synErr *json.SyntaxError
+	if !errors.As(err, &synErr) {
+		return err
+	}
+
+	line := 1 + bytes.Count(data[:synErr.Offset], []byte("\n"))
+	col := int(synErr.Offset) - bytes.LastIndex(data[:synErr.Offset], []byte("\n")) - 1
+	return fmt.Errorf("第%d行第%d列: JSON语法错误: %v", line, col, err)
+}
+
+// jsonPath 将encoding/json的字段路径（如 "fans.1.min_pwm"）转换为 "fans[1].min_pwm"
+func jsonPath(field string) string {
+	var b strings.Builder
+	for i, part := range strings.Split(field, ".") {
+		if _, err := fmt.Sscanf(part, "%d", new(int)); err == nil {
+			b.WriteString("[" + part + "]")
+			continue
+		}
+		if i > 0 {
+			b.WriteString(".")
+		}
+		b.WriteString(part)
+	}
+	return b.String()
+}
+
+// validateListen 验证HTTP管理接口的监听地址（主机:端口 或 unix:路径）
+func validateListen(addr string) error {
+	if path, ok := strings.CutPrefix(addr, "unix:"); ok {
+		if path == "" {
+			return fmt.Errorf("unix套接字路径不能为空")
+		}
+		return nil
+	}
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		return fmt.Errorf("无效的监听地址 %q（格式: 主机:端口 或 unix:路径）", addr)
+	}
+	return nil
+}
+
+// joinKey 拼接键路径
+func joinKey(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	if key == "" {
+		return prefix
+	}
+	return prefix + "." + key
+}
+
+func sortedControlKeys(m map[string]ControlConfig) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func sortedWeightKeys(m map[string]float64) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func intPtr(v int) *int {
+	return &v
+}
+
+func floatPtr(v float64) *float64 {
+	return &v
+}
@@ -0,0 +1,92 @@
+package config
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// fieldKeys 返回验证错误中所有的键路径
+func fieldKeys(err error) []string {
+	var keys []string
+	joined, ok := err.(interface{ Unwrap() []error })
+	if !ok {
+		return nil
+	}
+	for _, e := range joined.Unwrap() {
+		var fe *FieldError
+		if errors.As(e, &fe) {
+			keys = append(keys, fe.Key)
+		}
+	}
+	return keys
+}
+
+func TestValidateKeys(t *testing.T) {
+	tests := []struct {
+		name string
+		fan  string // 风扇配置中额外的字段
+		top  string // 顶层额外的字段
+		want []string
+	}{
+		{"有效配置", ``, ``, nil},
+		{"PWM范围", `"min_pwm": 300, "max_pwm": 100`, ``, []string{"fans[0].min_pwm", "fans[0].min_pwm"}},
+		{"温度阈值", `"control": {"low_temp": 60, "high_temp": 50}`, ``, []string{"fans[0].control.low_temp"}},
+		{"失效保护", ``, `"failsafe": {"max_failures": -1, "duty": 120},`, []string{"failsafe.max_failures", "failsafe.duty"}},
+		{"回差和开关阈值", `"hysteresis": {"fall": -1, "on_pwm": 100, "off_pwm": 120}`, ``, []string{"fans[0].hysteresis.fall", "fans[0].hysteresis.off_pwm"}},
+		{"速率限制", `"ramp": {"up": -1}`, ``, []string{"fans[0].ramp.up"}},
+		{"停转检测", `"stall": {"checks": 0, "min_pwm": 256}`, ``, []string{"fans[0].stall.min_pwm", "fans[0].stall.checks"}},
+		{"零转速模式", `"zero_rpm": {"stop_temp": 50, "start_temp": 45, "kick_pwm": 0}`, ``, []string{"fans[0].zero_rpm", "fans[0].zero_rpm.kick_pwm"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fan := `"name": "cpu", "type": "pwm", "sensor": "cpu"`
+			if tt.fan != "" {
+				fan += ", " + tt.fan
+			}
+			data := `{` + tt.top + `
+				"sensors": [{"name": "cpu", "type": "hwmon"}],
+				"fans": [{` + fan + `}]
+			}`
+
+			_, err := Parse([]byte(data))
+			if tt.want == nil {
+				if err != nil {
+					t.Fatalf("Parse() 错误 = %v, 期望没有错误", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("Parse() 应返回错误")
+			}
+			if got := fieldKeys(err); strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("错误的键 = %v, 期望 %v\n%v", got, tt.want, err)
+			}
+		})
+	}
+}
+
+func TestDefaults(t *testing.T) {
+	cfg, err := Parse([]byte(`{
+		"sensors": [{"name": "cpu"}],
+		"fans": [{"name": "cpu", "sensor": "cpu", "stall": {}, "zero_rpm": {}}]
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f := cfg.Fans[0]
+	if fs := cfg.Failsafe.Settings(); fs.Validate() != nil || fs.MaxFailures != 3 {
+		t.Errorf("默认的失效保护 = %+v", fs)
+	}
+	if st := f.Stall.Settings(); st.Validate() != nil || st.MinPWM != 80 {
+		t.Errorf("默认的停转检测 = %+v", st)
+	}
+	if z := f.ZeroRPM.Settings(); z.Validate() != nil || z.StartTemp != 45 {
+		t.Errorf("默认的零转速模式 = %+v", z)
+	}
+	if *f.MinPWM != 50 || *f.MaxPWM != 255 || *f.Control.LowTemp != 40 || *f.Control.HighTemp != 75 {
+		t.Errorf("默认的PWM范围和温度阈值 = %d-%d, %.0f-%.0f", *f.MinPWM, *f.MaxPWM, *f.Control.LowTemp, *f.Control.HighTemp)
+	}
+}
@@ -0,0 +1,215 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// SetFanValues 在配置文件内容中为风扇设置同一个键（如 "tach"），values为风扇名称到值的映射
+// 其他内容、键的顺序和写在一行内的对象保持不变，其余部分使用两个空格缩进
+func SetFanValues(data []byte, key string, values map[string]interface{}) ([]byte, error) {
+	root, err := parseNode(data)
+	if err != nil {
+		return nil, syntaxError(data, err)
+	}
+	if !root.isObject {
+		return nil, fmt.Errorf("配置文件的根节点必须是对象")
+	}
+
+	fans := root.get("fans")
+	if fans == nil || !fans.isArray {
+		return nil, &FieldError{Key: "fans", Msg: "缺少风扇列表"}
+	}
+
+	found := make(map[string]bool)
+	for _, f := range fans.array {
+		if !f.isObject {
+			continue
+		}
+		var name string
+		if n := f.get("name"); n == nil || json.Unmarshal(n.raw, &name) != nil {
+			continue
+		}
+		v, ok := values[name]
+		if !ok {
+			continue
+		}
+		raw, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		f.set(key, &node{raw: raw})
+		found[name] = true
+	}
+	for name := range values {
+		if !found[name] {
+			return nil, fmt.Errorf("配置文件中没有名为 %q 的风扇", name)
+		}
+	}
+
+	var buf bytes.Buffer
+	root.write(&buf, 0)
+	buf.WriteByte('\n')
+	return buf.Bytes(), nil
+}
+
+// node 保持键顺序的JSON节点
+type node struct {
+	// object 对象的键值对，按原始顺序排列
+	object []member
+	// array 数组元素
+	array []*node
+	// raw 标量的原始JSON文本
+	raw json.RawMessage
+	// isObject、isArray 区分空对象和空数组
+	isObject, isArray bool
+	// inline 原文写在一行内
+	inline bool
+}
+
+type member struct {
+	key   string
+	value *node
+}
+
+func parseNode(data []byte) (*node, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	n, err := decodeNode(dec, data)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); err == nil {
+		return nil, fmt.Errorf("配置文件的根节点之后还有多余的内容")
+	}
+	return n, nil
+}
+
+func decodeNode(dec *json.Decoder, data []byte) (*node, error) {
+	start := dec.InputOffset()
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	// inline 判断从start到当前位置的原文是否在一行内，start之后可能还有上一个值后的分隔符
+	inline := func(n *node) *node {
+		span := bytes.TrimLeft(data[start:dec.InputOffset()], " \t\r\n,:")
+		n.inline = !bytes.Contains(span, []byte("\n"))
+		return n
+	}
+
+	switch tok {
+	case json.Delim('{'):
+		n := &node{isObject: true}
+		for dec.More() {
+			kt, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			v, err := decodeNode(dec, data)
+			if err != nil {
+				return nil, err
+			}
+			n.object = append(n.object, member{key: kt.(string), value: v})
+		}
+		if _, err := dec.Token(); err != nil {
+			return nil, err
+		}
+		return inline(n), nil
+	case json.Delim('['):
+		n := &node{isArray: true}
+		for dec.More() {
+			v, err := decodeNode(dec, data)
+			if err != nil {
+				return nil, err
+			}
+			n.array = append(n.array, v)
+		}
+		if _, err := dec.Token(); err != nil {
+			return nil, err
+		}
+		return inline(n), nil
+	}
+	raw, err := json.Marshal(tok)
+	if err != nil {
+		return nil, err
+	}
+	return &node{raw: raw}, nil
+}
+
+// get 返回对象中键对应的值
+func (n *node) get(key string) *node {
+	for _, m := range n.object {
+		if m.key == key {
+			return m.value
+		}
+	}
+	return nil
+}
+
+// set 设置对象中的键，不存在时追加到末尾
+func (n *node) set(key string, v *node) {
+	for i := range n.object {
+		if n.object[i].key == key {
+			n.object[i].value = v
+			return
+		}
+	}
+	n.object = append(n.object, member{key: key, value: v})
+}
+
+func (n *node) write(buf *bytes.Buffer, indent int) {
+	pad := func(level int) {
+		if n.inline {
+			return
+		}
+		buf.WriteByte('\n')
+		buf.Write(bytes.Repeat([]byte("  "), level))
+	}
+	sep := func() {
+		if n.inline {
+			buf.WriteString(", ")
+		} else {
+			buf.WriteByte(',')
+		}
+	}
+
+	switch {
+	case n.isObject:
+		if len(n.object) == 0 {
+			buf.WriteString("{}")
+			return
+		}
+		buf.WriteByte('{')
+		for i, m := range n.object {
+			if i > 0 {
+				sep()
+			}
+			pad(indent + 1)
+			key, _ := json.Marshal(m.key)
+			buf.Write(key)
+			buf.WriteString(": ")
+			m.value.write(buf, indent+1)
+		}
+		pad(indent)
+		buf.WriteByte('}')
+	case n.isArray:
+		if len(n.array) == 0 {
+			buf.WriteString("[]")
+			return
+		}
+		buf.WriteByte('[')
+		for i, v := range n.array {
+			if i > 0 {
+				sep()
+			}
+			pad(indent + 1)
+			v.write(buf, indent+1)
+		}
+		pad(indent)
+		buf.WriteByte(']')
+	default:
+		buf.Write(n.raw)
+	}
+}
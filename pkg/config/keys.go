@@ -0,0 +1,110 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+var (
+	configType   = reflect.TypeOf(Config{})
+	durationType = reflect.TypeOf(Duration{})
+)
+
+// checkKeys 对照结构体的json标签检查原始JSON中的未知键和时间间隔格式，
+// 返回的错误都带有出错键的完整路径
+func checkKeys(raw interface{}, t reflect.Type, path string) []error {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if t == durationType {
+		if raw == nil {
+			return nil
+		}
+		s, ok := raw.(string)
+		if !ok {
+			return []error{&FieldError{Key: path, Msg: "时间间隔必须是字符串（如 \"5s\"）"}}
+		}
+		var d Duration
+		if err := d.UnmarshalJSON([]byte(fmt.Sprintf("%q", s))); err != nil {
+			return []error{&FieldError{Key: path, Msg: err.Error()}}
+		}
+		return nil
+	}
+
+	var errs []error
+	switch t.Kind() {
+	case reflect.Struct:
+		obj, ok := raw.(map[string]interface{})
+		if !ok {
+			// 类型不匹配由encoding/json报告
+			return nil
+		}
+
+		fields := jsonFields(t)
+		for _, k := range sortedKeys(obj) {
+			key := k
+			if path != "" {
+				key = path + "." + k
+			}
+			field, ok := fields[k]
+			if !ok {
+				errs = append(errs, &FieldError{Key: key, Msg: "未知的配置项"})
+				continue
+			}
+			errs = append(errs, checkKeys(obj[k], field.Type, key)...)
+		}
+
+	case reflect.Slice:
+		arr, ok := raw.([]interface{})
+		if !ok {
+			return nil
+		}
+		for i, v := range arr {
+			errs = append(errs, checkKeys(v, t.Elem(), fmt.Sprintf("%s[%d]", path, i))...)
+		}
+
+	case reflect.Map:
+		obj, ok := raw.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		for _, k := range sortedKeys(obj) {
+			errs = append(errs, checkKeys(obj[k], t.Elem(), path+"."+k)...)
+		}
+	}
+
+	return errs
+}
+
+// jsonFields 返回结构体的json键到字段的映射
+func jsonFields(t reflect.Type) map[string]reflect.StructField {
+	fields := make(map[string]reflect.StructField)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name := strings.Split(f.Tag.Get("json"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields[name] = f
+	}
+	return fields
+}
+
+// sortedKeys 返回排序后的键，保证错误输出顺序稳定
+func sortedKeys(obj map[string]interface{}) []string {
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
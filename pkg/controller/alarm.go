@@ -0,0 +1,43 @@
+package controller
+
+import "log"
+
+// 告警事件
+const (
+	// AlarmFanStall 风扇停转或转速过低
+	AlarmFanStall = "fan_stall"
+	// AlarmFanRecovered 停转的风扇恢复转动
+	AlarmFanRecovered = "fan_recovered"
+	// AlarmFanStartFailed 零转速模式下风扇未能从停转状态启动
+	AlarmFanStartFailed = "fan_start_failed"
+	// AlarmSensorFailed 传感器连续读取失败，跟随它的风扇进入保护模式
+	AlarmSensorFailed = "sensor_failed"
+	// AlarmSensorRecovered 失效的传感器恢复正常
+	AlarmSensorRecovered = "sensor_recovered"
+)
+
+// Alarm 控制器发出的告警
+type Alarm struct {
+	// Event 告警事件，如 AlarmFanStall
+	Event string
+	// Fan 相关的风扇名称，与风扇无关时为空
+	Fan string
+	// Sensor 相关的传感器名称，与传感器无关时为空
+	Sensor string
+	// Message 告警说明
+	Message string
+}
+
+// OnAlarm 设置告警回调，控制器在风扇停转、启动失败、传感器失效及其恢复时调用
+// 回调在控制循环中每轮调整结束后同步调用（不持有控制器的锁），不能阻塞，耗时的处理需要放到单独的goroutine中
+func (c *TempController) OnAlarm(fn func(Alarm)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.onAlarm = fn
+}
+
+// alarm 记录告警日志，需要持有mu；告警回调由 adjustZones 在释放mu后调用
+func (c *TempController) alarm(a Alarm) {
+	log.Print(a.Message)
+	c.alarms = append(c.alarms, a)
+}
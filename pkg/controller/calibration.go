@@ -0,0 +1,100 @@
+package controller
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/fanap/pkg/calibrate"
+)
+
+// DefaultStartKick 风扇从停转状态启动时以启动PWM运行的最短时间
+// PWM只在控制周期中设置，启动PWM保持到该时间之后的第一个控制周期，
+// 实际时长按控制周期取整且至少为一个周期（如5秒间隔下为5秒，1秒间隔下为2秒）
+const DefaultStartKick = 2 * time.Second
+
+// Calibratable 可以使用校准结果的风扇控制器
+type Calibratable interface {
+	PWMPath() string
+	SetCalibration(cal calibrate.Fan) error
+}
+
+// ApplyCalibration 为校准结果中能找到的风扇设置停转、启动和最低稳定PWM，需要在 Start 之前调用
+func (c *TempController) ApplyCalibration(state *calibrate.State) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, zone := range c.zones {
+		f, ok := zone.Fan.(Calibratable)
+		if !ok {
+			continue
+		}
+		cal, ok := state.Lookup(f.PWMPath())
+		if !ok {
+			continue
+		}
+		if err := f.SetCalibration(cal); err != nil {
+			return fmt.Errorf("风扇 %s: %w", zone.displayName(), err)
+		}
+		if cal.NoStop {
+			log.Printf("%s使用校准结果: 风扇不会停转", zone.logPrefix())
+			continue
+		}
+		log.Printf("%s使用校准结果: 停转PWM %d, 启动PWM %d, 最低稳定PWM %d, PWM范围 %d-%d",
+			zone.logPrefix(), cal.StopPWM, cal.StartPWM, cal.MinPWM, zone.Fan.GetMinSpeed(), zone.Fan.GetMaxSpeed())
+	}
+	return nil
+}
+
+// PWMPath 返回风扇pwmN文件的路径
+func (fc *FanControllerImpl) PWMPath() string {
+	return fc.fan.PWMPath()
+}
+
+// SetCalibration 使用风扇的校准结果
+// 最小PWM大于0时提高到最低稳定PWM；最小PWM为0时允许风扇停转，但不会输出介于0和最低稳定PWM之间的值
+func (fc *FanControllerImpl) SetCalibration(cal calibrate.Fan) error {
+	if err := cal.Validate(); err != nil {
+		return err
+	}
+	if !cal.NoStop && cal.MinPWM >= fc.maxPWM {
+		return fmt.Errorf("最低稳定PWM %d 不低于最大PWM %d", cal.MinPWM, fc.maxPWM)
+	}
+
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+
+	if fc.minPWM > 0 && fc.minPWM < cal.MinPWM {
+		fc.minPWM = cal.MinPWM
+	}
+	fc.cal = &cal
+
+	// 从风扇当前的PWM开始判断是否需要启动
+	if pwm, err := fc.fan.GetSpeed(); err == nil {
+		fc.lastPWM = pwm
+	}
+	return nil
+}
+
+// calibrated 按校准结果调整PWM，需要持有mu
+func (fc *FanControllerImpl) calibrated(pwm int, now time.Time) int {
+	cal := fc.cal
+	if cal == nil || cal.NoStop || pwm == 0 {
+		return pwm
+	}
+	if pwm < cal.MinPWM {
+		pwm = cal.MinPWM
+	}
+
+	// 风扇从停转状态启动时先以启动PWM运行一段时间，到时间后的下一个控制周期再降到目标PWM
+	if fc.lastPWM <= cal.StopPWM && pwm < cal.StartPWM {
+		if fc.verbose {
+			fmt.Printf("风扇从停转状态启动: PWM=%d (至少%v，按控制周期取整)\n", cal.StartPWM, DefaultStartKick)
+		}
+		fc.kickUntil = now.Add(DefaultStartKick)
+	}
+	if now.Before(fc.kickUntil) && pwm < cal.StartPWM {
+		pwm = cal.StartPWM
+	}
+	return pwm
+}
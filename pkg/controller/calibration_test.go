@@ -0,0 +1,42 @@
+package controller
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/fanap/pkg/calibrate"
+)
+
+func TestCalibratedStartKick(t *testing.T) {
+	cal := &calibrate.Fan{StopPWM: 48, StartPWM: 96, MinPWM: 64}
+
+	tests := []struct {
+		name     string
+		interval time.Duration
+		want     []int // 从停转状态启动后每个控制周期的PWM
+	}{
+		// 启动PWM保持到 DefaultStartKick 之后的第一个控制周期
+		{"5秒间隔", 5 * time.Second, []int{96, 70, 70}},
+		{"1秒间隔", time.Second, []int{96, 96, 70, 70}},
+		{"500毫秒间隔", 500 * time.Millisecond, []int{96, 96, 96, 96, 70}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fc := &FanControllerImpl{cal: cal, lastPWM: 0}
+			now := time.Now()
+
+			var got []int
+			for range tt.want {
+				pwm := fc.calibrated(70, now)
+				fc.lastPWM = pwm
+				got = append(got, pwm)
+				now = now.Add(tt.interval)
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("每个周期的PWM = %v, 期望 %v", got, tt.want)
+			}
+		})
+	}
+}
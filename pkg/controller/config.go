@@ -0,0 +1,416 @@
+package controller
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/fanap/pkg/config"
+	"github.com/fanap/pkg/curve"
+	"github.com/fanap/pkg/filter"
+	"github.com/fanap/pkg/ipmi"
+	"github.com/fanap/pkg/pid"
+	"github.com/fanap/pkg/plugin"
+	"github.com/fanap/pkg/pwmchip"
+	"github.com/fanap/pkg/sensor"
+	"github.com/fanap/pkg/temp"
+	"github.com/fanap/pkg/thermal"
+	"github.com/fanap/pkg/thinkpad"
+	"github.com/fanap/pkg/virtual"
+)
+
+// NewFromConfig 根据配置文件创建温度控制器
+// 所有风扇在同一个控制循环中调度，多个风扇引用同一个传感器时共享同一个传感器实例
+func NewFromConfig(cfg *config.Config, verbose bool) (_ *TempController, err error) {
+	c := NewMulti(cfg.Interval.Duration, verbose)
+
+	// 出错时恢复已打开风扇的原始模式，并关闭已添加的传感器（插件进程、后台采样等）
+	var fans []FanController
+	defer func() {
+		if err == nil {
+			return
+		}
+		for _, f := range fans {
+			f.Close()
+		}
+		c.closeSensors()
+	}()
+
+	if err := c.SetExitPolicy(ExitPolicy(cfg.OnExit)); err != nil {
+		return nil, err
+	}
+	if f := cfg.Failsafe; f != nil {
+		err := c.SetFailsafe(f.Settings())
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, sc := range sensorOrder(cfg) {
+		sensor, err := newSensorFromConfig(c, sc)
+		if err != nil {
+			return nil, fmt.Errorf("初始化传感器 %q 失败: %w", sc.Name, err)
+		}
+		if err := c.AddSensor(sc.Name, sensor); err != nil {
+			sensor.Close()
+			return nil, err
+		}
+		if len(sc.Filters) > 0 {
+			if err := c.SetFilter(sc.Name, sc.Filters, sc.SampleInterval.Duration); err != nil {
+				return nil, err
+			}
+			log.Printf("传感器 %s: 滤波=%s, 采样间隔=%v", sc.Name, filter.Describe(sc.Filters), sc.SampleInterval.Duration)
+		}
+	}
+
+	for _, fc := range cfg.Fans {
+		fanCtrl, err := newFanFromConfig(fc, verbose)
+		if err != nil {
+			return nil, fmt.Errorf("初始化风扇 %q 失败: %w", fc.Name, err)
+		}
+		fans = append(fans, fanCtrl)
+
+		var inputs []SensorInput
+		for _, name := range fc.Inputs() {
+			inputs = append(inputs, SensorInput{Sensor: name, Weight: fc.Weight(name)})
+		}
+
+		zone := FanZone{
+			Name:      fc.Name,
+			Fan:       fanCtrl,
+			Inputs:    inputs,
+			Aggregate: fc.Aggregate,
+		}
+		ctl, err := NewControl(fc.Control)
+		if err != nil {
+			return nil, fmt.Errorf("风扇 %q: %w", fc.Name, err)
+		}
+		zone.setControl(ctl)
+		if h := fc.Hysteresis; h != nil {
+			zone.Hysteresis = h.Settings()
+			if h.HasOnOff() {
+				if err := setOnOffThresholds(fanCtrl, *h.OnPWM, *h.OffPWM); err != nil {
+					return nil, fmt.Errorf("风扇 %q: %w", fc.Name, err)
+				}
+			}
+		}
+		if r := fc.Ramp; r != nil {
+			zone.Ramp = r.Settings()
+		}
+		if err := c.AddFan(zone); err != nil {
+			return nil, err
+		}
+		if st := fc.Stall; st != nil {
+			err := c.SetStallDetect(fc.Name, st.Settings())
+			if err != nil {
+				return nil, err
+			}
+			log.Printf("风扇 %s: 停转检测 PWM≥%d, 连续%d次, 全速启动%v", fc.Name, *st.MinPWM, *st.Checks, st.Kick.Duration)
+		}
+		if z := fc.ZeroRPM; z != nil {
+			err := c.SetZeroRPM(fc.Name, z.Settings())
+			if err != nil {
+				return nil, err
+			}
+			log.Printf("风扇 %s: 零转速模式 ≤%.1f°C停转, ≥%.1f°C启动 (PWM %d, %v)", fc.Name, *z.StopTemp, *z.StartTemp, *z.KickPWM, z.Kick.Duration)
+		}
+
+		log.Printf("风扇 %s: 传感器=%s (%s), %s, PWM范围=%d-%d",
+			fc.Name, strings.Join(fc.Inputs(), ","), fc.Aggregate,
+			describeControl(zone), *fc.MinPWM, *fc.MaxPWM)
+	}
+
+	for _, pc := range cfg.Profiles {
+		controls := make(map[string]Control, len(pc.Fans))
+		for name, cc := range pc.Fans {
+			ctl, err := NewControl(cc)
+			if err != nil {
+				return nil, fmt.Errorf("控制方案 %q, 风扇 %q: %w", pc.Name, name, err)
+			}
+			controls[name] = ctl
+		}
+		if err := c.AddProfile(pc.Name, controls); err != nil {
+			return nil, err
+		}
+		log.Printf("控制方案 %s: %d 个风扇", pc.Name, len(controls))
+	}
+
+	return c, nil
+}
+
+// NewControl 根据配置创建控制规则，cc需要已经填充默认值并通过验证
+func NewControl(cc config.ControlConfig) (Control, error) {
+	if p := cc.PID; p != nil {
+		ctl, err := pid.New(*p.Target, p.Gains())
+		if err != nil {
+			return Control{}, fmt.Errorf("PID参数无效: %w", err)
+		}
+		return Control{PID: ctl}, nil
+	}
+	if c := cc.Curve; c != nil {
+		cv, err := curve.New(c.Type, c.Points)
+		if err != nil {
+			return Control{}, fmt.Errorf("曲线无效: %w", err)
+		}
+		return Control{Curve: cv}, nil
+	}
+	return Control{LowTemp: *cc.LowTemp, HighTemp: *cc.HighTemp}, nil
+}
+
+// setOnOffThresholds 设置2级风扇的开/关阈值，风扇不支持时只记录警告
+func setOnOffThresholds(fanCtrl FanController, onPWM, offPWM int) error {
+	f, ok := fanCtrl.(OnOffFan)
+	if !ok {
+		log.Printf("警告: 风扇不是2级冷却设备，忽略开/关阈值")
+		return nil
+	}
+	return f.SetThresholds(onPWM, offPWM)
+}
+
+// describeControl 描述风扇的控制规则，用于日志
+func describeControl(zone FanZone) string {
+	if zone.PID != nil {
+		g := zone.PID.Gains()
+		return fmt.Sprintf("PID目标温度=%.1f°C (kp=%g, ki=%g, kd=%g)", zone.PID.Target(), g.Kp, g.Ki, g.Kd)
+	}
+	if zone.Curve != nil {
+		return fmt.Sprintf("%s曲线=%s", zone.Curve.Type(), curve.Format(zone.Curve.Points()))
+	}
+	return fmt.Sprintf("温度阈值=%.1f°C-%.1f°C", zone.LowTemp, zone.HighTemp)
+}
+
+// sensorOrder 返回需要初始化的传感器：被风扇引用或经虚拟传感器间接引用的传感器
+// 按配置文件中的顺序排列，但虚拟传感器引用的传感器排在虚拟传感器之前
+func sensorOrder(cfg *config.Config) []config.SensorConfig {
+	used := make(map[string]bool)
+	var use func(name string)
+	use = func(name string) {
+		sc, ok := cfg.Sensor(name)
+		if !ok || used[name] {
+			return
+		}
+		used[name] = true
+		if sc.Type == config.SensorVirtual {
+			for _, in := range sc.Inputs {
+				use(in)
+			}
+		}
+	}
+	for _, fc := range cfg.Fans {
+		for _, name := range fc.Inputs() {
+			use(name)
+		}
+	}
+
+	var order []config.SensorConfig
+	added := make(map[string]bool)
+	var add func(sc config.SensorConfig)
+	add = func(sc config.SensorConfig) {
+		if added[sc.Name] {
+			return
+		}
+		added[sc.Name] = true
+		if sc.Type == config.SensorVirtual {
+			for _, in := range sc.Inputs {
+				if ref, ok := cfg.Sensor(in); ok {
+					add(ref)
+				}
+			}
+		}
+		order = append(order, sc)
+	}
+	for _, sc := range cfg.Sensors {
+		if !used[sc.Name] {
+			log.Printf("传感器 %s 未被任何风扇引用，跳过", sc.Name)
+			continue
+		}
+		add(sc)
+	}
+	return order
+}
+
+// newSensorFromConfig 根据传感器配置创建温度传感器，虚拟传感器引用的传感器必须已经添加到控制器
+func newSensorFromConfig(c *TempController, sc config.SensorConfig) (TempSensor, error) {
+	switch sc.Type {
+	case config.SensorVirtual:
+		return newVirtualSensor(c, sc)
+	case config.SensorExec:
+		s, err := plugin.NewSensor(sc.Name, plugin.SensorOptions{
+			Command:    sc.Exec.Command,
+			Timeout:    sc.Exec.Timeout.Duration,
+			Cache:      sc.Exec.Cache.Duration,
+			Persistent: sc.Exec.Persistent,
+		})
+		if err != nil {
+			return nil, err
+		}
+		log.Printf("温度传感器 %s: %s", sc.Name, s)
+		return s, nil
+	case config.SensorIPMI:
+		client, err := ipmi.Open(sc.IPMI.Command, sc.IPMI.Timeout.Duration)
+		if err != nil {
+			return nil, err
+		}
+		s, err := ipmi.NewSensor(client, sc.IPMI.Sensor, sc.IPMI.Cache.Duration)
+		if err != nil {
+			return nil, err
+		}
+		log.Printf("温度传感器 %s: %s", sc.Name, s)
+		return s, nil
+	}
+	return openSensor(sc.Selector(), func() (TempSensor, error) {
+		switch sc.Type {
+		case config.SensorHWMon:
+			return temp.NewSensor("auto")
+		case config.SensorThermal:
+			return thermal.NewZone("auto")
+		default:
+			return detectSensor()
+		}
+	})
+}
+
+// newVirtualSensor 根据虚拟传感器的配置组合其输入
+// 引用的其他传感器使用控制器中已添加的实例（包括滤波，每轮只读取一次），
+// 选择器匹配的所有传感器都作为输入（delta只使用第一个），由虚拟传感器打开和关闭
+func newVirtualSensor(c *TempController, sc config.SensorConfig) (TempSensor, error) {
+	var inputs []virtual.Input
+	cleanup := func() {
+		for _, in := range inputs {
+			in.Source.Close()
+		}
+	}
+
+	for _, name := range sc.Inputs {
+		if src, ok := c.shareSensor(name); ok {
+			inputs = append(inputs, virtual.Input{Name: name, Source: src, Weight: sc.Weight(name)})
+			continue
+		}
+
+		sel, err := sensor.Parse(name)
+		if err != nil {
+			cleanup()
+			return nil, fmt.Errorf("输入 %s: %w", name, err)
+		}
+		var sensors []*sensor.Sensor
+		if sc.Op == virtual.OpDelta {
+			var s *sensor.Sensor
+			s, err = sensor.Open(sel)
+			sensors = []*sensor.Sensor{s}
+		} else {
+			sensors, err = sensor.OpenAll(sel)
+		}
+		if err != nil {
+			cleanup()
+			return nil, fmt.Errorf("输入 %s: %w", name, err)
+		}
+		for _, s := range sensors {
+			inputs = append(inputs, virtual.Input{Name: targetName(s.Target()), Source: s, Weight: sc.Weight(name)})
+		}
+	}
+
+	v, err := virtual.New(sc.Op, inputs)
+	if err != nil {
+		cleanup()
+		return nil, err
+	}
+	log.Printf("虚拟传感器 %s: %s", sc.Name, v)
+	return v, nil
+}
+
+// targetName 传感器在虚拟传感器中的名称，用于日志和错误信息
+func targetName(t sensor.Target) string {
+	if t.Kind == sensor.KindThermal {
+		return sensor.KindThermal + ":" + t.Chip
+	}
+	return sensor.KindHWMon + ":" + t.Chip + "/" + t.Label
+}
+
+// newFanFromConfig 根据风扇配置创建风扇控制器
+func newFanFromConfig(fc config.FanConfig, verbose bool) (FanController, error) {
+	switch fc.Type {
+	case config.FanPWM:
+		fanCtrl, err := NewFanController(fc.Device, *fc.MinPWM, *fc.MaxPWM, verbose)
+		if err != nil {
+			return nil, err
+		}
+		if fc.Tach != "" {
+			if err := fanCtrl.SetTach(fc.Tach); err != nil {
+				fanCtrl.Close()
+				return nil, err
+			}
+		}
+		if fc.Levels != nil {
+			if err := fanCtrl.SetLevels(*fc.Levels); err != nil {
+				fanCtrl.Close()
+				return nil, err
+			}
+		}
+		return fanCtrl, nil
+	case config.FanCooling:
+		return NewCoolingDeviceControllerWithDevice(fc.Device, verbose)
+	case config.FanExec:
+		f, err := plugin.NewFan(fc.Name, plugin.FanOptions{
+			Command:        fc.Exec.Command,
+			Timeout:        fc.Exec.Timeout.Duration,
+			Persistent:     fc.Exec.Persistent,
+			RestoreCommand: fc.Exec.Restore,
+			MinPWM:         *fc.MinPWM,
+			MaxPWM:         *fc.MaxPWM,
+		})
+		if err != nil {
+			return nil, err
+		}
+		log.Printf("风扇 %s: %s", fc.Name, f)
+		return f, nil
+	case config.FanIPMI:
+		client, err := ipmi.Open(fc.IPMI.Command, fc.IPMI.Timeout.Duration)
+		if err != nil {
+			return nil, err
+		}
+		preset, zone := fc.IPMI.FanPreset()
+		f, err := ipmi.NewFan(client, ipmi.FanOptions{
+			Preset: preset,
+			Zone:   zone,
+			Tach:   fc.IPMI.Tach,
+			Cache:  fc.IPMI.Cache.Duration,
+			MinPWM: *fc.MinPWM,
+			MaxPWM: *fc.MaxPWM,
+		})
+		if err != nil {
+			return nil, err
+		}
+		log.Printf("风扇 %s: %s", fc.Name, f)
+		return f, nil
+	case config.FanPWMChip:
+		opts := pwmchip.Options{MinPWM: *fc.MinPWM, MaxPWM: *fc.MaxPWM}
+		if pc := fc.PWMChip; pc != nil {
+			opts.Period = pc.Period.Duration
+			opts.Polarity = pc.Polarity
+		}
+		f, err := pwmchip.NewFan(fc.Device, opts)
+		if err != nil {
+			return nil, err
+		}
+		log.Printf("风扇 %s: %s", fc.Name, f)
+		return f, nil
+	case config.FanThinkPad:
+		opts := thinkpad.Options{
+			Watchdog:  fc.ThinkPad.Watchdog.Duration,
+			FullSpeed: fc.ThinkPad.FullSpeed,
+			MinPWM:    *fc.MinPWM,
+			MaxPWM:    *fc.MaxPWM,
+		}
+		if fc.Device != "auto" {
+			opts.Path = fc.Device
+		}
+		f, err := thinkpad.NewFan(opts)
+		if err != nil {
+			return nil, err
+		}
+		log.Printf("风扇 %s: %s", fc.Name, f)
+		return f, nil
+	default:
+		return detectFanController(*fc.MinPWM, *fc.MaxPWM, verbose)
+	}
+}
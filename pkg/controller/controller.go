@@ -1,15 +1,26 @@
 package controller
 
 import (
+	"errors"
 	"fmt"
 	"log"
+	"runtime/debug"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/fanap/pkg/calibrate"
 	"github.com/fanap/pkg/cooling"
+	"github.com/fanap/pkg/curve"
 	"github.com/fanap/pkg/fan"
+	"github.com/fanap/pkg/filter"
+	"github.com/fanap/pkg/pid"
+	"github.com/fanap/pkg/pwmchip"
+	"github.com/fanap/pkg/sensor"
+	"github.com/fanap/pkg/settings"
 	"github.com/fanap/pkg/temp"
 	"github.com/fanap/pkg/thermal"
+	"github.com/fanap/pkg/thinkpad"
 )
 
 // Controller 控制器接口
@@ -35,12 +46,16 @@ type FanController interface {
 
 // FanControllerImpl PWM风扇控制器实现
 type FanControllerImpl struct {
-	fan      *fan.PWMFan
-	minPWM   int
-	maxPWM   int
-	verbose  bool
-	lastPWM  int
-	mu       sync.Mutex
+	fan     *fan.PWMFan
+	minPWM  int
+	maxPWM  int
+	verbose bool
+	lastPWM int
+	mu      sync.Mutex
+
+	// cal 校准结果，kickUntil 从停转状态启动时以启动PWM运行到该时间
+	cal       *calibrate.Fan
+	kickUntil time.Time
 }
 
 // NewFanController 创建新的PWM风扇控制器
@@ -50,37 +65,83 @@ func NewFanController(deviceName string, minPWM, maxPWM int, verbose bool) (*Fan
 		return nil, err
 	}
 
-	return &FanControllerImpl{
+	fc := &FanControllerImpl{
 		fan:     pwmFan,
 		minPWM:  minPWM,
 		maxPWM:  maxPWM,
 		verbose: verbose,
 		lastPWM: 0,
-	}, nil
+	}
+	if err := fc.checkLevels(pwmFan.Levels()); err != nil {
+		pwmFan.Close()
+		return nil, err
+	}
+	return fc, nil
+}
+
+// 2级（开/关）冷却设备的默认阈值：PWM ≥ 128 打开，≤ 127 关闭
+const (
+	DefaultOnPWM  = settings.DefaultOnPWM
+	DefaultOffPWM = settings.DefaultOffPWM
+)
+
+// OnOffFan 支持开/关阈值的风扇控制器（如只有2级的冷却设备）
+type OnOffFan interface {
+	SetThresholds(onPWM, offPWM int) error
 }
 
 // CoolingDeviceController 冷却设备控制器实现
 type CoolingDeviceController struct {
-	cooling  *cooling.CoolingDevice
-	verbose  bool
+	cooling   *cooling.CoolingDevice
+	verbose   bool
 	lastLevel int
-	mu       sync.Mutex
+	onPWM     int
+	offPWM    int
+	mu        sync.Mutex
 }
 
-// NewCoolingDeviceController 创建新的冷却设备控制器
+// NewCoolingDeviceController 创建新的冷却设备控制器（自动检测）
 func NewCoolingDeviceController(verbose bool) (*CoolingDeviceController, error) {
-	coolingDevice, err := cooling.NewDevice("auto", verbose)
+	return NewCoolingDeviceControllerWithDevice("auto", verbose)
+}
+
+// NewCoolingDeviceControllerWithDevice 创建新的冷却设备控制器（指定设备）
+func NewCoolingDeviceControllerWithDevice(deviceName string, verbose bool) (*CoolingDeviceController, error) {
+	coolingDevice, err := cooling.NewDevice(deviceName, verbose)
 	if err != nil {
 		return nil, err
 	}
 
+	// 从设备的当前级别开始，保证开/关回差判断与实际状态一致
+	lastLevel, err := coolingDevice.GetLevel()
+	if err != nil {
+		lastLevel = 0
+	}
+
 	return &CoolingDeviceController{
-		cooling:  coolingDevice,
-		verbose:  verbose,
-		lastLevel: 0,
+		cooling:   coolingDevice,
+		verbose:   verbose,
+		lastLevel: lastLevel,
+		onPWM:     DefaultOnPWM,
+		offPWM:    DefaultOffPWM,
 	}, nil
 }
 
+// SetThresholds 设置2级设备的开/关阈值
+// 关闭状态下PWM ≥ onPWM 时打开，打开状态下PWM ≤ offPWM 时关闭，两者之间保持当前状态
+func (cc *CoolingDeviceController) SetThresholds(onPWM, offPWM int) error {
+	if err := settings.OnOff(onPWM, offPWM); err != nil {
+		return err
+	}
+
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+
+	cc.onPWM = onPWM
+	cc.offPWM = offPWM
+	return nil
+}
+
 // SetSpeed 设置风扇速度（冷却级别）
 func (cc *CoolingDeviceController) SetSpeed(speed int) error {
 	cc.mu.Lock()
@@ -94,12 +155,12 @@ func (cc *CoolingDeviceController) SetSpeed(speed int) error {
 	// 将速度（0-255）映射到冷却级别（0-maxLevel）
 	level := (speed * maxLevel) / 255
 
-	// 对于只有2级（开/关）的设备，使用阈值
+	// 对于只有2级（开/关）的设备，使用带回差的阈值
 	if maxLevel == 1 {
-		// 如果速度高于最小PWM的50%，打开风扇，否则关闭
-		if speed > 127 { // 50% of 255
+		level = cc.lastLevel
+		if cc.lastLevel == 0 && speed >= cc.onPWM {
 			level = 1
-		} else {
+		} else if cc.lastLevel == 1 && speed <= cc.offPWM {
 			level = 0
 		}
 
@@ -144,6 +205,11 @@ func (cc *CoolingDeviceController) GetSpeed() (int, error) {
 	return (level * 255) / maxLevel, nil
 }
 
+// GetLevel 获取当前冷却级别
+func (cc *CoolingDeviceController) GetLevel() (int, error) {
+	return cc.cooling.GetLevel()
+}
+
 // GetMinSpeed 获取最小速度
 func (cc *CoolingDeviceController) GetMinSpeed() int {
 	return 0
@@ -172,6 +238,12 @@ func (fc *FanControllerImpl) SetSpeed(pwm int) error {
 	fc.mu.Lock()
 	defer fc.mu.Unlock()
 
+	pwm = fc.calibrated(pwm, time.Now())
+	if levels := fc.fan.Levels(); levels != nil {
+		// 在PWM范围内取整，避免超过最大PWM的部分被驱动向上取整到更高的档位
+		pwm, _ = fan.QuantizeRange(pwm, fc.minPWM, fc.maxPWM, levels)
+	}
+
 	// 避免重复设置相同的值
 	if pwm == fc.lastPWM {
 		return nil
@@ -190,6 +262,50 @@ func (fc *FanControllerImpl) GetSpeed() (int, error) {
 	return fc.fan.GetSpeed()
 }
 
+// GetRPM 获取风扇转速
+func (fc *FanControllerImpl) GetRPM() (int, error) {
+	return fc.fan.GetRPM()
+}
+
+// GetMinRPM 获取风扇的最低转速阈值（fanN_min）
+func (fc *FanControllerImpl) GetMinRPM() (int, error) {
+	return fc.fan.GetMinRPM()
+}
+
+// SetTach 指定风扇的转速输入（风扇选择器，如 "hwmon:nct6775/fan2"）
+func (fc *FanControllerImpl) SetTach(tach string) error {
+	return fc.fan.SetTach(tach)
+}
+
+// SetLevels 指定风扇支持的转速档位数，0表示连续调速
+// PWM范围内没有任何档位时返回错误
+func (fc *FanControllerImpl) SetLevels(n int) error {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+
+	if n > 0 {
+		levels, err := fan.LevelValues(n)
+		if err != nil {
+			return err
+		}
+		if err := fc.checkLevels(levels); err != nil {
+			return err
+		}
+	}
+	return fc.fan.SetLevels(n)
+}
+
+// checkLevels 检查PWM范围内是否有风扇支持的档位，连续调速时不检查
+func (fc *FanControllerImpl) checkLevels(levels []int) error {
+	if levels == nil {
+		return nil
+	}
+	if _, ok := fan.QuantizeRange(0, fc.minPWM, fc.maxPWM, levels); !ok {
+		return fmt.Errorf("PWM范围 %d-%d 内没有风扇支持的档位 %v", fc.minPWM, fc.maxPWM, levels)
+	}
+	return nil
+}
+
 // Close 关闭风扇控制器
 func (fc *FanControllerImpl) Close() error {
 	return fc.fan.Close()
@@ -205,20 +321,52 @@ func (fc *FanControllerImpl) GetMaxSpeed() int {
 	return fc.maxPWM
 }
 
+// 按名称查找风扇或控制方案失败时返回的错误
+var (
+	ErrFanNotFound     = errors.New("未找到风扇")
+	ErrProfileNotFound = errors.New("未找到控制方案")
+)
+
 // TempController 温度控制器
+// 一个控制器可以管理多个温度传感器和多个风扇，所有风扇在同一个控制循环中调度
+// 控制器运行时可以通过 Set* 方法和 Status 并发地修改和查询状态
 type TempController struct {
-	sensor   TempSensor
-	fan      FanController
-	lowTemp  float64
-	highTemp float64
-	interval time.Duration
-	verbose  bool
-	stopChan chan struct{}
-	running  bool
-}
-
-// NewController 创建新的温度控制器（自动检测）
-func NewController(lowTemp, highTemp float64, interval time.Duration, verbose bool) (*TempController, error) {
+	sensors     map[string]TempSensor
+	sensorNames []string
+	zones       []*FanZone
+	interval    time.Duration
+	verbose     bool
+	stopChan    chan struct{}
+	loopDone    chan struct{}
+	running     bool
+	metrics     *controllerMetrics
+	exitPolicy  ExitPolicy
+	failsafe    Failsafe
+	releaseOnce sync.Once
+	onAlarm     func(Alarm)
+
+	// 以下字段由mu保护
+	mu           sync.Mutex
+	lastTemps    map[string]float64
+	lastErrs     map[string]error
+	failures     map[string]int
+	profiles     map[string]map[string]Control
+	profileNames []string
+	profile      string
+	stopped      bool
+	err          error
+	pending      []fanWrite
+	alarms       []Alarm
+
+	// writeMu 串行化控制循环和手动覆盖对风扇的写入，需要同时持有时先获取writeMu
+	writeMu sync.Mutex
+}
+
+// defaultSensor 单传感器控制器中传感器的名称
+const defaultSensor = "default"
+
+// NewController 创建新的温度控制器（自动检测），minPWM和maxPWM只用于PWM风扇
+func NewController(minPWM, maxPWM int, lowTemp, highTemp float64, interval time.Duration, verbose bool) (*TempController, error) {
 	// 尝试检测温度传感器
 	sensor, err := detectSensor()
 	if err != nil {
@@ -226,47 +374,320 @@ func NewController(lowTemp, highTemp float64, interval time.Duration, verbose bo
 	}
 
 	// 尝试检测风扇控制器
-	fanCtrl, err := detectFanController(verbose)
+	fanCtrl, err := detectFanController(minPWM, maxPWM, verbose)
 	if err != nil {
 		return nil, fmt.Errorf("检测风扇控制器失败: %w", err)
 	}
 
-	return &TempController{
-		sensor:   sensor,
-		fan:      fanCtrl,
-		lowTemp:  lowTemp,
-		highTemp: highTemp,
-		interval: interval,
-		verbose:  verbose,
-		stopChan: make(chan struct{}),
-		running:  false,
-	}, nil
+	return New(sensor, fanCtrl, lowTemp, highTemp, interval, verbose), nil
 }
 
-// NewControllerWithPWM 创建新的温度控制器（指定PWM设备）
-func NewControllerWithPWM(pwmDevice string, minPWM, maxPWM int, lowTemp, highTemp float64, interval time.Duration, verbose bool) (*TempController, error) {
-	// 使用hwmon温度传感器
-	sensor, err := temp.NewSensor("auto")
+// PWMChipPrefix -pwm中表示Linux PWM子系统通道的前缀，如 "pwmchip:pwmchip0/0"
+const PWMChipPrefix = "pwmchip:"
+
+// ThinkPadDevice -pwm中表示thinkpad_acpi风扇（/proc/acpi/ibm/fan）的名称
+const ThinkPadDevice = "thinkpad"
+
+// NewControllerWithPWM 创建新的温度控制器（指定温度传感器和PWM设备）
+// sensorSel 为传感器选择器（见 sensor.Parse），"auto" 表示自动检测hwmon温度传感器
+func NewControllerWithPWM(sensorSel, pwmDevice string, minPWM, maxPWM int, lowTemp, highTemp float64, interval time.Duration, verbose bool) (*TempController, error) {
+	tempSensor, err := openSensor(sensorSel, func() (TempSensor, error) {
+		return temp.NewSensor("auto")
+	})
 	if err != nil {
 		return nil, fmt.Errorf("初始化温度传感器失败: %w", err)
 	}
 
-	// 使用PWM风扇控制器
-	fanCtrl, err := NewFanController(pwmDevice, minPWM, maxPWM, verbose)
+	// 使用PWM风扇控制器，pwmchip:前缀表示PWM子系统的通道，thinkpad表示thinkpad_acpi的风扇
+	var fanCtrl FanController
+	if spec, ok := strings.CutPrefix(pwmDevice, PWMChipPrefix); ok {
+		var f *pwmchip.Fan
+		if f, err = pwmchip.NewFan(spec, pwmchip.Options{MinPWM: minPWM, MaxPWM: maxPWM}); err == nil {
+			log.Printf("风扇: %s", f)
+			fanCtrl = f
+		}
+	} else if pwmDevice == ThinkPadDevice {
+		var f *thinkpad.Fan
+		if f, err = thinkpad.NewFan(thinkpad.Options{Watchdog: thinkpad.DefaultWatchdog, MinPWM: minPWM, MaxPWM: maxPWM}); err == nil {
+			log.Printf("风扇: %s", f)
+			fanCtrl = f
+		}
+	} else {
+		fanCtrl, err = NewFanController(pwmDevice, minPWM, maxPWM, verbose)
+	}
 	if err != nil {
+		tempSensor.Close()
 		return nil, fmt.Errorf("初始化风扇控制器失败: %w", err)
 	}
 
+	return New(tempSensor, fanCtrl, lowTemp, highTemp, interval, verbose), nil
+}
+
+// New 使用指定的温度传感器和风扇控制器创建单风扇温度控制器
+func New(sensor TempSensor, fanCtrl FanController, lowTemp, highTemp float64, interval time.Duration, verbose bool) *TempController {
+	c := NewMulti(interval, verbose)
+	c.sensors[defaultSensor] = sensor
+	c.sensorNames = append(c.sensorNames, defaultSensor)
+	c.zones = append(c.zones, &FanZone{
+		Fan:       fanCtrl,
+		Inputs:    []SensorInput{{Sensor: defaultSensor, Weight: 1}},
+		Aggregate: AggregateMax,
+		LowTemp:   lowTemp,
+		HighTemp:  highTemp,
+	})
+	return c
+}
+
+// NewMulti 创建空的多风扇温度控制器，使用 AddSensor 和 AddFan 添加传感器和风扇
+func NewMulti(interval time.Duration, verbose bool) *TempController {
 	return &TempController{
-		sensor:   sensor,
-		fan:      fanCtrl,
-		lowTemp:  lowTemp,
-		highTemp: highTemp,
-		interval: interval,
-		verbose:  verbose,
-		stopChan: make(chan struct{}),
-		running:  false,
-	}, nil
+		sensors:    make(map[string]TempSensor),
+		interval:   interval,
+		verbose:    verbose,
+		stopChan:   make(chan struct{}),
+		loopDone:   make(chan struct{}),
+		running:    false,
+		exitPolicy: ExitRestore,
+		lastTemps:  make(map[string]float64),
+		lastErrs:   make(map[string]error),
+		failures:   make(map[string]int),
+		failsafe:   DefaultFailsafe(),
+		profiles:   make(map[string]map[string]Control),
+		profile:    DefaultProfile,
+	}
+}
+
+// AddSensor 添加命名的温度传感器
+func (c *TempController) AddSensor(name string, sensor TempSensor) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.running {
+		return fmt.Errorf("控制器运行中，无法添加传感器")
+	}
+	if name == "" {
+		return fmt.Errorf("传感器名称不能为空")
+	}
+	if _, ok := c.sensors[name]; ok {
+		return fmt.Errorf("传感器名称重复: %s", name)
+	}
+
+	c.sensors[name] = sensor
+	c.sensorNames = append(c.sensorNames, name)
+	return nil
+}
+
+// AddFan 添加风扇及其控制规则，风扇引用的传感器必须已经添加
+func (c *TempController) AddFan(zone FanZone) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.running {
+		return fmt.Errorf("控制器运行中，无法添加风扇")
+	}
+	if zone.Fan == nil {
+		return fmt.Errorf("风扇 %s: 未指定风扇控制器", zone.Name)
+	}
+	for _, z := range c.zones {
+		if z.Name == zone.Name {
+			return fmt.Errorf("风扇名称重复: %s", zone.Name)
+		}
+	}
+	if len(zone.Inputs) == 0 {
+		return fmt.Errorf("风扇 %s: 至少需要一个传感器", zone.Name)
+	}
+
+	inputs := make([]SensorInput, len(zone.Inputs))
+	for i, in := range zone.Inputs {
+		if _, ok := c.sensors[in.Sensor]; !ok {
+			return fmt.Errorf("风扇 %s: 未定义的传感器 %s", zone.Name, in.Sensor)
+		}
+		if in.Weight == 0 {
+			in.Weight = 1
+		}
+		inputs[i] = in
+	}
+	zone.Inputs = inputs
+
+	switch zone.Aggregate {
+	case "":
+		zone.Aggregate = AggregateMax
+	case AggregateMax, AggregateWeighted:
+	default:
+		return fmt.Errorf("风扇 %s: 未知的聚合方式 %s", zone.Name, zone.Aggregate)
+	}
+
+	if err := zone.Hysteresis.Validate(); err != nil {
+		return fmt.Errorf("风扇 %s: %w", zone.Name, err)
+	}
+	if err := zone.Ramp.Validate(); err != nil {
+		return fmt.Errorf("风扇 %s: %w", zone.Name, err)
+	}
+
+	if zone.PID == nil && zone.Curve == nil {
+		if err := settings.Thresholds(zone.LowTemp, zone.HighTemp); err != nil {
+			return fmt.Errorf("风扇 %s: %w", zone.Name, err)
+		}
+	}
+
+	c.zones = append(c.zones, &zone)
+	return nil
+}
+
+// SetCurve 设置风扇曲线，name为空时表示单风扇控制器中的风扇
+// cv为nil时恢复为低温/高温阈值之间的线性控制
+func (c *TempController) SetCurve(name string, cv curve.Curve) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	zone, err := c.zone(name)
+	if err != nil {
+		return err
+	}
+	zone.Curve = cv
+	zone.hystInit = false
+	return nil
+}
+
+// SetPID 设置目标温度闭环控制，name为空时表示单风扇控制器中的风扇
+// p为nil时恢复为曲线或阈值控制
+func (c *TempController) SetPID(name string, p *pid.Controller) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	zone, err := c.zone(name)
+	if err != nil {
+		return err
+	}
+	if p != nil {
+		p.Reset()
+	}
+	zone.PID = p
+	return nil
+}
+
+// SetFilter 为传感器添加滤波链，name为空时表示单风扇控制器中的传感器
+// sampleInterval大于0时在后台以该间隔采样，控制循环使用最新的滤波结果
+func (c *TempController) SetFilter(name string, specs []filter.Spec, sampleInterval time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.running {
+		return fmt.Errorf("控制器运行中，无法修改传感器滤波")
+	}
+	if name == "" {
+		name = defaultSensor
+	}
+	sensor, ok := c.sensors[name]
+	if !ok {
+		return fmt.Errorf("未找到传感器: %s", name)
+	}
+
+	f, err := filter.NewChain(specs)
+	if err != nil {
+		return fmt.Errorf("传感器 %s: %w", name, err)
+	}
+
+	// 被虚拟传感器引用的传感器在共享实例内部滤波，虚拟传感器同样使用滤波后的读数
+	shared, _ := sensor.(*sharedSensor)
+	if shared != nil {
+		sensor = shared.TempSensor
+	}
+	// 重复设置时替换原有的滤波链，停止原来的后台采样
+	if fs, ok := sensor.(*filter.Sensor); ok {
+		fs.Stop()
+		sensor = fs.Raw()
+	}
+	if shared != nil {
+		shared.TempSensor = filter.NewSensor(sensor, f, sampleInterval)
+		return nil
+	}
+	c.sensors[name] = filter.NewSensor(sensor, f, sampleInterval)
+	return nil
+}
+
+// SetHysteresis 设置风扇的温度回差，name为空时表示单风扇控制器中的风扇
+func (c *TempController) SetHysteresis(name string, h Hysteresis) error {
+	if err := h.Validate(); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	zone, err := c.zone(name)
+	if err != nil {
+		return err
+	}
+	zone.Hysteresis = h
+	zone.hystInit = false
+	return nil
+}
+
+// SetRamp 设置风扇的PWM变化速率限制，name为空时表示单风扇控制器中的风扇
+func (c *TempController) SetRamp(name string, r Ramp) error {
+	if err := r.Validate(); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	zone, err := c.zone(name)
+	if err != nil {
+		return err
+	}
+	zone.Ramp = r
+	zone.rampInit = false
+	return nil
+}
+
+// SetOnOffThresholds 设置2级（开/关）风扇的开/关阈值，风扇不支持时返回错误
+func (c *TempController) SetOnOffThresholds(name string, onPWM, offPWM int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	zone, err := c.zone(name)
+	if err != nil {
+		return err
+	}
+	f, ok := zone.Fan.(OnOffFan)
+	if !ok {
+		return fmt.Errorf("风扇 %s 不支持开/关阈值", name)
+	}
+	return f.SetThresholds(onPWM, offPWM)
+}
+
+// TachSetter 可以指定转速输入的风扇控制器
+type TachSetter interface {
+	SetTach(tach string) error
+}
+
+// SetTach 指定风扇的转速输入，name为空时表示单风扇控制器中的风扇
+// 需要在 SetStallDetect 之前调用，风扇不支持时返回错误
+func (c *TempController) SetTach(name, tach string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	zone, err := c.zone(name)
+	if err != nil {
+		return err
+	}
+	f, ok := zone.Fan.(TachSetter)
+	if !ok {
+		return fmt.Errorf("风扇 %s 不支持指定转速输入", zone.displayName())
+	}
+	return f.SetTach(tach)
+}
+
+// zone 按名称查找风扇，单风扇控制器中的风扇也可以用 "default" 查找
+func (c *TempController) zone(name string) (*FanZone, error) {
+	for _, z := range c.zones {
+		if z.Name == name || z.displayName() == name {
+			return z, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrFanNotFound, name)
 }
 
 // detectSensor 自动检测温度传感器
@@ -289,8 +710,26 @@ func detectSensor() (TempSensor, error) {
 	return hwmonSensor, nil
 }
 
+// openSensor 按选择器打开温度传感器，选择器为 "auto" 时使用auto自动检测
+func openSensor(selector string, auto func() (TempSensor, error)) (TempSensor, error) {
+	sel, err := sensor.Parse(selector)
+	if err != nil {
+		return nil, err
+	}
+	if sel.Kind == sensor.KindAuto {
+		return auto()
+	}
+
+	s, err := sensor.Open(sel)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("温度传感器 %s: %s", sel, s.Target())
+	return s, nil
+}
+
 // detectFanController 自动检测风扇控制器
-func detectFanController(verbose bool) (FanController, error) {
+func detectFanController(minPWM, maxPWM int, verbose bool) (FanController, error) {
 	// 优先尝试cooling_device（如QNAP等设备）
 	fanCtrl, err := NewCoolingDeviceController(verbose)
 	if err == nil {
@@ -302,7 +741,7 @@ func detectFanController(verbose bool) (FanController, error) {
 	if verbose {
 		log.Println("cooling_device不可用，尝试PWM风扇控制器")
 	}
-	pwmFanCtrl, err := NewFanController("auto", 50, 255, verbose)
+	pwmFanCtrl, err := NewFanController("auto", minPWM, maxPWM, verbose)
 	if err != nil {
 		return nil, fmt.Errorf("无法找到任何风扇控制器")
 	}
@@ -313,9 +752,21 @@ func detectFanController(verbose bool) (FanController, error) {
 
 // Start 启动控制器
 func (c *TempController) Start() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	if c.running {
 		return fmt.Errorf("控制器已在运行")
 	}
+	if c.stopped {
+		return fmt.Errorf("控制器已停止")
+	}
+	if len(c.zones) == 0 {
+		return fmt.Errorf("未配置任何风扇")
+	}
+
+	// 启动时的控制规则作为默认控制方案，切换方案后可以切换回来
+	c.profiles[DefaultProfile] = c.snapshotControls()
 
 	c.running = true
 	go c.controlLoop()
@@ -323,18 +774,53 @@ func (c *TempController) Start() error {
 	return nil
 }
 
-// Stop 停止控制器
+// Stop 停止控制器，等待控制循环退出后按退出策略处理所有风扇并关闭传感器
+// 控制器未启动时也可以调用，用于在初始化失败时恢复已打开的风扇；可以重复调用
 func (c *TempController) Stop() {
-	if !c.running {
-		return
+	c.mu.Lock()
+	wasRunning := c.running
+	if c.running {
+		close(c.stopChan)
+		c.running = false
 	}
+	c.stopped = true
+	c.mu.Unlock()
+
+	if wasRunning {
+		<-c.loopDone
+	}
+	c.release()
+}
 
-	close(c.stopChan)
-	c.running = false
+// Done 返回在控制循环退出时关闭的channel，控制循环因异常退出时 Err 返回原因
+func (c *TempController) Done() <-chan struct{} {
+	return c.loopDone
+}
+
+// Err 返回控制循环异常退出的原因，正常运行或正常停止时返回nil
+func (c *TempController) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
 }
 
 // controlLoop 控制循环
 func (c *TempController) controlLoop() {
+	defer close(c.loopDone)
+
+	// 控制循环中的panic不能让风扇停留在手动模式和较低的PWM，立即按退出策略处理风扇
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("控制循环异常退出: %v\n%s", r, debug.Stack())
+			c.mu.Lock()
+			c.err = fmt.Errorf("控制循环异常退出: %v", r)
+			c.running = false
+			c.stopped = true
+			c.mu.Unlock()
+			c.release()
+		}
+	}()
+
 	ticker := time.NewTicker(c.interval)
 	defer ticker.Stop()
 
@@ -348,49 +834,142 @@ func (c *TempController) controlLoop() {
 	}
 }
 
-// adjustFanSpeed 根据温度调整风扇速度
+// adjustFanSpeed 读取所有传感器的温度，并调整每个风扇的速度
 func (c *TempController) adjustFanSpeed() {
-	// 读取当前温度
-	temp, err := c.sensor.GetTemperature()
-	if err != nil {
-		log.Printf("读取温度失败: %v\n", err)
-		return
+	// 每个传感器每轮只读取一次，供所有风扇共享
+	start := time.Now()
+
+	// 传感器在运行时不会增减，读取时不需要持有锁
+	temps := make(map[string]float64, len(c.sensors))
+	errs := make(map[string]error)
+	for _, name := range c.sensorNames {
+		temp, err := c.sensors[name].GetTemperature()
+		if err == nil {
+			err = c.failsafe.Check(temp)
+		}
+		if err != nil {
+			log.Printf("读取温度失败 (%s): %v\n", name, err)
+			c.metrics.sensorError(name)
+			errs[name] = err
+			continue
+		}
+		temps[name] = temp
+		c.metrics.sensorTemp(name, temp)
 	}
 
-	// 计算目标PWM值
-	pwm := c.calculatePWM(temp)
+	c.adjustZones(temps, errs)
 
-	if c.verbose {
-		currentSpeed, _ := c.fan.GetSpeed()
-		fmt.Printf("温度: %.1f°C, 当前PWM: %d, 目标PWM: %d\n", temp, currentSpeed, pwm)
-	} else {
-		log.Printf("温度: %.1f°C, PWM: %d\n", temp, pwm)
+	c.metrics.loopDone(time.Since(start))
+}
+
+// adjustZones 根据本轮读取的温度调整所有风扇
+// 风扇的读写（exec、IPMI等后端每次可能需要数秒）不持有mu，避免阻塞管理接口、指标和 Stop：
+// 先读回本轮需要的转速和PWM，再持有mu计算每个风扇的输出，释放mu后执行写入和告警回调
+func (c *TempController) adjustZones(temps map[string]float64, errs map[string]error) {
+	// 一轮调整中不允许手动覆盖写入风扇，保证写入的顺序与计算时的状态一致
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	// 风扇在运行时不会增减，遍历时不需要持有锁
+	now := time.Now()
+	needs := c.readsNeeded(now)
+	readings := make([]fanReading, len(c.zones))
+	for i, zone := range c.zones {
+		readings[i] = readFan(zone.Fan, needs[i])
 	}
 
-	// 设置风扇速度
-	if err := c.fan.SetSpeed(pwm); err != nil {
-		log.Printf("设置风扇速度失败: %v\n", err)
+	writes, alarms, onAlarm := c.planZones(temps, errs, readings, now)
+	for _, w := range writes {
+		c.write(w)
+	}
+	if onAlarm != nil {
+		for _, a := range alarms {
+			onAlarm(a)
+		}
 	}
 }
 
-// calculatePWM 根据温度计算PWM值
-func (c *TempController) calculatePWM(temp float64) int {
-	minPWM := c.fan.GetMinSpeed()
-	maxPWM := c.fan.GetMaxSpeed()
+// planZones 持有mu计算所有风扇本轮的输出，返回需要执行的写入、本轮的告警和告警回调
+func (c *TempController) planZones(temps map[string]float64, errs map[string]error, readings []fanReading, now time.Time) ([]fanWrite, []Alarm, func(Alarm)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.lastTemps = temps
+	c.lastErrs = errs
+	c.updateFailures(errs)
+	for i, zone := range c.zones {
+		c.adjustZone(zone, temps, readings[i], now)
+	}
+
+	writes, alarms := c.pending, c.alarms
+	c.pending, c.alarms = nil, nil
+	return writes, alarms, c.onAlarm
+}
+
+// adjustZone 根据温度计算单个风扇的输出，需要持有mu
+// 对风扇的写入通过 queue 排队，由 adjustZones 在释放mu后执行
+func (c *TempController) adjustZone(zone *FanZone, temps map[string]float64, r fanReading, now time.Time) {
+	// 风扇停转时先尝试全速启动，启动期间不按温度调整
+	if c.checkStall(zone, r, now) {
+		return
+	}
+
+	// 传感器失效时不再信任其余读数，直接以保护占空比运行
+	if failed := c.failedInputs(zone); len(failed) > 0 {
+		c.applyFailsafe(zone, failed)
+		return
+	}
+	c.leaveFailsafe(zone)
+
+	temp, err := zone.aggregate(temps)
+	if err != nil {
+		log.Printf("%s%v\n", zone.logPrefix(), err)
+		return
+	}
+
+	zone.lastTemp = temp
+	zone.hasTemp = true
 
-	// 温度低于低温阈值，使用最小PWM
-	if temp <= c.lowTemp {
-		return minPWM
+	dt := c.interval
+	if !zone.lastUpdate.IsZero() {
+		dt = now.Sub(zone.lastUpdate)
 	}
+	zone.lastUpdate = now
+
+	var target, pwm int
+	if zone.override != nil {
+		c.leaveZeroRPM(zone)
+
+		// 手动覆盖：直接使用指定的PWM，解除覆盖后从该值开始按速率限制逼近
+		target, pwm = *zone.override, *zone.override
+		zone.rampPWM = float64(pwm)
+		zone.rampInit = true
+	} else {
+		// 零转速模式：停转期间和启动过程中不按温度调整
+		if c.applyZeroRPM(zone, temp, r, now) {
+			return
+		}
 
-	// 温度高于高温阈值，使用最大PWM
-	if temp >= c.highTemp {
-		return maxPWM
+		// 温度回差：温度变化未超过回差带时沿用上次的温度，避免风扇在阈值附近反复调速
+		effective := zone.applyHysteresis(temp)
+
+		// 计算目标PWM值
+		target = zone.calculatePWM(effective, dt)
+		pwm = zone.applyRamp(target, dt, r)
 	}
+	zone.lastTarget = pwm
 
-	// 在低温和高温之间线性插值
-	ratio := (temp - c.lowTemp) / (c.highTemp - c.lowTemp)
-	pwm := minPWM + int(float64(maxPWM-minPWM)*ratio)
+	if c.verbose {
+		if pwm != target {
+			fmt.Printf("%s温度: %.1f°C, 当前PWM: %d, 目标PWM: %d (限速后: %d)\n", zone.logPrefix(), temp, r.speed, target, pwm)
+		} else {
+			fmt.Printf("%s温度: %.1f°C, 当前PWM: %d, 目标PWM: %d\n", zone.logPrefix(), temp, r.speed, pwm)
+		}
+	} else {
+		log.Printf("%s温度: %.1f°C, PWM: %d\n", zone.logPrefix(), temp, pwm)
+	}
 
-	return pwm
+	// 设置风扇速度
+	c.metrics.fanTarget(zone, pwm)
+	c.queue(zone, writeSpeed, pwm)
 }
@@ -0,0 +1,101 @@
+package controller
+
+import (
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/fanap/pkg/filter"
+	"github.com/fanap/pkg/sysfs"
+	"github.com/fanap/pkg/sysfs/fixture"
+)
+
+// atomicSensor 记录读取次数的传感器，可以在后台采样中读取
+type atomicSensor struct {
+	reads  atomic.Int64
+	closed atomic.Int64
+}
+
+func (s *atomicSensor) GetTemperature() (float64, error) {
+	s.reads.Add(1)
+	return 45, nil
+}
+
+func (s *atomicSensor) Close() error {
+	s.closed.Add(1)
+	return nil
+}
+
+func TestSetFilterReplacesSampler(t *testing.T) {
+	c := NewMulti(time.Second, false)
+	raw := &atomicSensor{}
+	if err := c.AddSensor("cpu", raw); err != nil {
+		t.Fatal(err)
+	}
+
+	specs := []filter.Spec{{Type: filter.TypeMedian, Window: 3}}
+	if err := c.SetFilter("cpu", specs, 5*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.SetFilter("cpu", specs, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	// 原来的后台采样已停止，不再读取底层传感器
+	reads := raw.reads.Load()
+	time.Sleep(50 * time.Millisecond)
+	if got := raw.reads.Load(); got != reads {
+		t.Errorf("重新设置滤波链后仍在后台采样: 读取次数 %d -> %d", reads, got)
+	}
+
+	if _, err := c.sensors["cpu"].GetTemperature(); err != nil {
+		t.Fatal(err)
+	}
+	c.closeSensors()
+	if got := raw.closed.Load(); got != 1 {
+		t.Errorf("关闭次数 = %d, 期望 1", got)
+	}
+}
+
+func TestFanControllerLevelsMaxPWM(t *testing.T) {
+	tree, err := fixture.Laptop(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	sysfs.SetRoot(tree.Root)
+	t.Cleanup(func() { sysfs.SetRoot("") })
+	dell := fixture.Node{Dir: filepath.Join(tree.Root, "sys/class/hwmon/hwmon1")}
+
+	// 超过最大PWM的部分不会向上取整到更高的档位，全速运行不受最大PWM限制
+	fc, err := NewFanController("hwmon:dell_smm/pwm1", 1, 200, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, step := range []struct {
+		set  func() error
+		want int
+	}{
+		{func() error { return fc.SetSpeed(150) }, 128},
+		{func() error { return fc.SetSpeed(30) }, 128},
+		{fc.SetFullSpeed, 255},
+	} {
+		if err := step.set(); err != nil {
+			t.Fatal(err)
+		}
+		if pwm, _ := dell.GetInt("pwm1"); pwm != step.want {
+			t.Errorf("pwm1 = %d, 期望 %d", pwm, step.want)
+		}
+	}
+	if err := fc.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// PWM范围内没有档位
+	if _, err := NewFanController("hwmon:dell_smm/pwm1", 150, 200, false); err == nil {
+		t.Error("PWM范围内没有档位时 NewFanController 应返回错误")
+	}
+	if mode, _ := dell.GetInt("pwm1_enable"); mode != 2 {
+		t.Errorf("创建失败后 pwm1_enable = %d, 期望恢复为 2", mode)
+	}
+}
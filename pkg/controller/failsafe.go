@@ -0,0 +1,121 @@
+package controller
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/fanap/pkg/settings"
+)
+
+// 失效保护的默认值
+const (
+	DefaultFailsafeReads   = settings.DefaultFailsafeReads
+	DefaultFailsafeDuty    = settings.DefaultFailsafeDuty
+	DefaultFailsafeMinTemp = settings.DefaultFailsafeMinTemp
+	DefaultFailsafeMaxTemp = settings.DefaultFailsafeMaxTemp
+)
+
+// Failsafe 传感器失效保护，见 settings.Failsafe
+type Failsafe = settings.Failsafe
+
+// DefaultFailsafe 返回默认的失效保护：连续3次失败后全速运行
+func DefaultFailsafe() Failsafe {
+	return settings.DefaultFailsafe()
+}
+
+// SetFailsafe 设置传感器失效保护
+func (c *TempController) SetFailsafe(f Failsafe) error {
+	if err := f.Validate(); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.running {
+		return fmt.Errorf("控制器运行中，无法修改失效保护")
+	}
+	c.failsafe = f
+	return nil
+}
+
+// updateFailures 更新每个传感器的连续失败次数，需要持有mu
+func (c *TempController) updateFailures(errs map[string]error) {
+	for _, name := range c.sensorNames {
+		if _, failed := errs[name]; failed {
+			c.failures[name]++
+			if c.failures[name] == c.failsafe.MaxFailures {
+				c.alarm(Alarm{
+					Event:   AlarmSensorFailed,
+					Sensor:  name,
+					Message: fmt.Sprintf("告警: 传感器 %s 连续%d次读取失败: %v", name, c.failures[name], errs[name]),
+				})
+			}
+			continue
+		}
+		if c.failsafe.MaxFailures > 0 && c.failures[name] >= c.failsafe.MaxFailures {
+			c.alarm(Alarm{
+				Event:   AlarmSensorRecovered,
+				Sensor:  name,
+				Message: fmt.Sprintf("传感器 %s 读数已恢复正常", name),
+			})
+		}
+		c.failures[name] = 0
+	}
+}
+
+// failedInputs 返回风扇跟随的传感器中已触发失效保护的传感器
+func (c *TempController) failedInputs(zone *FanZone) []string {
+	if c.failsafe.MaxFailures == 0 {
+		return nil
+	}
+
+	var failed []string
+	for _, in := range zone.Inputs {
+		if c.failures[in.Sensor] >= c.failsafe.MaxFailures {
+			failed = append(failed, in.Sensor)
+		}
+	}
+	return failed
+}
+
+// applyFailsafe 让风扇以保护占空比运行，进入保护时记录告警，需要持有mu
+// 保护PWM达到风扇的最大PWM时与停转启动一样全速运行，不受max_pwm限制
+func (c *TempController) applyFailsafe(zone *FanZone, failed []string) {
+	pwm := c.failsafe.PWM()
+	op := writeSpeed
+	if pwm >= zone.Fan.GetMaxSpeed() {
+		pwm, op = 255, writeFull
+	}
+	c.leaveZeroRPM(zone)
+
+	if !zone.failsafe {
+		zone.failsafe = true
+		log.Printf("%s告警: 传感器 %s 失效，风扇进入保护模式 (PWM %d)", zone.logPrefix(), strings.Join(failed, ", "), pwm)
+		c.metrics.fanFailsafe(zone, true)
+	}
+
+	// 保护模式不经过速率限制，恢复后从保护PWM开始逼近
+	zone.lastTarget = pwm
+	zone.rampPWM = float64(pwm)
+	zone.rampInit = true
+
+	c.metrics.fanTarget(zone, pwm)
+	c.queue(zone, op, pwm)
+}
+
+// leaveFailsafe 传感器恢复后退出保护模式
+func (c *TempController) leaveFailsafe(zone *FanZone) {
+	if !zone.failsafe {
+		return
+	}
+
+	zone.failsafe = false
+	if zone.PID != nil {
+		zone.PID.Reset()
+	}
+	zone.hystInit = false
+	log.Printf("%s传感器已恢复，风扇退出保护模式", zone.logPrefix())
+	c.metrics.fanFailsafe(zone, false)
+}
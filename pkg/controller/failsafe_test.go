@@ -0,0 +1,105 @@
+package controller
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fanap/pkg/virtual"
+)
+
+// failingSensor 读取总是失败的传感器
+type failingSensor struct{}
+
+func (failingSensor) GetTemperature() (float64, error) { return 0, errors.New("读取失败") }
+func (failingSensor) Close() error                     { return nil }
+
+func TestFailsafeWrite(t *testing.T) {
+	tests := []struct {
+		name string
+		duty float64
+		want string
+	}{
+		// recordingFan 的最大PWM为200，保护占空比达到最大PWM时全速运行
+		{"全速", 100, "full"},
+		{"超过最大PWM", 90, "full"},
+		{"低于最大PWM", 50, "set 128"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewMulti(time.Second, false)
+			if err := c.AddSensor("cpu", failingSensor{}); err != nil {
+				t.Fatal(err)
+			}
+			f := &recordingFan{}
+			if err := c.AddFan(FanZone{Name: "cpu", Fan: f, Inputs: []SensorInput{{Sensor: "cpu"}}, LowTemp: 40, HighTemp: 70}); err != nil {
+				t.Fatal(err)
+			}
+			fs := DefaultFailsafe()
+			fs.Duty = tt.duty
+			if err := c.SetFailsafe(fs); err != nil {
+				t.Fatal(err)
+			}
+
+			for i := 0; i < fs.MaxFailures; i++ {
+				c.adjustFanSpeed()
+			}
+			if got := strings.Join(f.calls, " "); got != tt.want {
+				t.Errorf("调用 = %q, 期望 %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDeltaFailsafeRange(t *testing.T) {
+	tests := []struct {
+		name    string
+		minTemp float64
+		wantErr bool
+	}{
+		// delta的结果同样按合理温度范围检查，默认下限可以容纳负的温差
+		{"默认下限", DefaultFailsafe().MinTemp, false},
+		{"下限高于温差", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewMulti(time.Second, false)
+			for name, temp := range map[string]float64{"cpu": 30, "intake": 35} {
+				if err := c.AddSensor(name, &countingSensor{temp: temp}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			var inputs []virtual.Input
+			for _, name := range []string{"cpu", "intake"} {
+				src, _ := c.shareSensor(name)
+				inputs = append(inputs, virtual.Input{Name: name, Source: src})
+			}
+			v, err := virtual.New(virtual.OpDelta, inputs)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := c.AddSensor("cpu_rise", v); err != nil {
+				t.Fatal(err)
+			}
+			fs := DefaultFailsafe()
+			fs.MinTemp = tt.minTemp
+			if err := c.SetFailsafe(fs); err != nil {
+				t.Fatal(err)
+			}
+
+			c.adjustFanSpeed()
+			c.mu.Lock()
+			err, temp := c.lastErrs["cpu_rise"], c.lastTemps["cpu_rise"]
+			c.mu.Unlock()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("cpu_rise 错误 = %v, 期望错误 %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && temp != -5 {
+				t.Errorf("cpu_rise = %v, 期望 -5", temp)
+			}
+		})
+	}
+}
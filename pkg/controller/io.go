@@ -0,0 +1,103 @@
+package controller
+
+import (
+	"errors"
+	"log"
+	"time"
+)
+
+// errNotRead 本轮调整前没有读取该值（如读取后才启用了停转检测或速率限制）
+var errNotRead = errors.New("本轮未读取")
+
+// fanReading 本轮调整前从风扇读回的状态，没有读取的值对应的错误为 errNotRead
+type fanReading struct {
+	rpm      int
+	rpmErr   error
+	speed    int
+	speedErr error
+}
+
+// fanNeeds 本轮调整需要从风扇读回的值
+type fanNeeds struct {
+	rpm   bool
+	speed bool
+}
+
+// readsNeeded 根据各风扇的状态确定本轮需要读回的值
+// 转速用于停转检测和零转速模式的启动确认，PWM用于速率限制的起点和详细日志
+func (c *TempController) readsNeeded(now time.Time) []fanNeeds {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	needs := make([]fanNeeds, len(c.zones))
+	for i, zone := range c.zones {
+		needs[i].rpm = (zone.stall != nil && !now.Before(zone.kickUntil)) ||
+			(zone.starting && !now.Before(zone.startUntil))
+		needs[i].speed = c.verbose || ((zone.Ramp.Up != 0 || zone.Ramp.Down != 0) && !zone.rampInit)
+	}
+	return needs
+}
+
+// readFan 读回风扇的转速和PWM，不持有mu
+func readFan(f FanController, n fanNeeds) fanReading {
+	r := fanReading{rpmErr: errNotRead, speedErr: errNotRead}
+	if rr, ok := f.(RPMReader); ok && n.rpm {
+		r.rpm, r.rpmErr = rr.GetRPM()
+	}
+	if n.speed {
+		r.speed, r.speedErr = f.GetSpeed()
+	}
+	return r
+}
+
+// writeOp 对风扇的写入操作
+type writeOp int
+
+const (
+	// writeSpeed 以指定的PWM运行（受风扇PWM范围限制）
+	writeSpeed writeOp = iota
+	// writeFull 全速运行，不受最大PWM限制
+	writeFull
+	// writeStop 停转，不受最小PWM限制
+	writeStop
+)
+
+// fanWrite 控制循环对风扇的一次写入
+type fanWrite struct {
+	zone *FanZone
+	op   writeOp
+	pwm  int
+}
+
+// queue 排队一次对风扇的写入，需要持有mu；adjustZones 在释放mu后按顺序执行
+func (c *TempController) queue(zone *FanZone, op writeOp, pwm int) {
+	c.pending = append(c.pending, fanWrite{zone: zone, op: op, pwm: pwm})
+}
+
+// write 执行一次写入并读回风扇状态，不持有mu
+func (c *TempController) write(w fanWrite) {
+	zone := w.zone
+
+	var err error
+	switch w.op {
+	case writeFull:
+		if f, ok := zone.Fan.(FullSpeeder); ok {
+			err = f.SetFullSpeed()
+		} else {
+			err = zone.Fan.SetSpeed(zone.Fan.GetMaxSpeed())
+		}
+	case writeStop:
+		if f, ok := zone.Fan.(Stopper); ok {
+			err = f.StopFan()
+		} else {
+			err = zone.Fan.SetSpeed(0)
+		}
+	default:
+		err = zone.Fan.SetSpeed(w.pwm)
+	}
+	if err != nil {
+		log.Printf("%s设置风扇速度失败: %v\n", zone.logPrefix(), err)
+		c.metrics.fanWriteError(zone)
+	}
+	c.metrics.fanState(zone)
+}
@@ -0,0 +1,153 @@
+package controller
+
+import (
+	"fmt"
+	"log"
+)
+
+// ExitPolicy 控制器停止时对风扇的处理方式
+type ExitPolicy string
+
+// 退出策略
+const (
+	// ExitRestore 恢复风扇打开时的模式和PWM（pwmN_enable、cooling_device的cur_state）
+	ExitRestore ExitPolicy = "restore"
+	// ExitFull 风扇保持手动模式并全速运行
+	ExitFull ExitPolicy = "full"
+	// ExitLeave 保持风扇最后的状态不变
+	ExitLeave ExitPolicy = "leave"
+)
+
+// ParseExitPolicy 解析退出策略，空字符串表示 ExitRestore
+func ParseExitPolicy(s string) (ExitPolicy, error) {
+	switch p := ExitPolicy(s); p {
+	case "":
+		return ExitRestore, nil
+	case ExitRestore, ExitFull, ExitLeave:
+		return p, nil
+	}
+	return "", fmt.Errorf("未知的退出策略 %q（可选: restore、full、leave）", s)
+}
+
+// FullSpeeder 支持不受PWM范围限制地全速运行的风扇控制器
+type FullSpeeder interface {
+	SetFullSpeed() error
+}
+
+// Releaser 释放风扇资源而不恢复原来控制方式的风扇控制器
+// 退出策略为full或leave时代替 Close，风扇保持最后设置的状态；
+// 后端持有进程、导出的通道或看门狗时需要实现，Release 和 Close 只有第一次调用生效
+type Releaser interface {
+	Release() error
+}
+
+// SetFullSpeed 以PWM 255全速运行，不受最大PWM限制
+func (fc *FanControllerImpl) SetFullSpeed() error {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+
+	if err := fc.fan.SetSpeed(255); err != nil {
+		return err
+	}
+	fc.lastPWM = 255
+	return nil
+}
+
+// SetFullSpeed 设置为最大冷却级别
+func (cc *CoolingDeviceController) SetFullSpeed() error {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+
+	maxLevel, err := cc.cooling.GetMaxLevel()
+	if err != nil {
+		return err
+	}
+	if err := cc.cooling.SetLevel(maxLevel); err != nil {
+		return err
+	}
+	cc.lastLevel = maxLevel
+	return nil
+}
+
+// SetExitPolicy 设置控制器停止时对风扇的处理方式
+func (c *TempController) SetExitPolicy(p ExitPolicy) error {
+	if _, err := ParseExitPolicy(string(p)); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.exitPolicy = p
+	return nil
+}
+
+// release 按退出策略处理所有风扇并关闭传感器，只执行一次
+// 单个风扇处理失败时继续处理其余风扇
+// 调用前需要设置stopped；持有writeMu，进行中的手动覆盖写入完成后才处理风扇，之后的覆盖请求返回错误
+func (c *TempController) release() {
+	c.releaseOnce.Do(func() {
+		c.writeMu.Lock()
+		defer c.writeMu.Unlock()
+
+		c.mu.Lock()
+		policy := c.exitPolicy
+		c.mu.Unlock()
+
+		for _, zone := range c.zones {
+			if err := releaseFan(zone.Fan, policy); err != nil {
+				log.Printf("%s退出时处理风扇失败 (%s): %v", zone.logPrefix(), policy, err)
+				continue
+			}
+			switch policy {
+			case ExitRestore:
+				log.Printf("%s已恢复风扇原始模式", zone.logPrefix())
+			case ExitFull:
+				log.Printf("%s风扇已设置为全速", zone.logPrefix())
+			}
+		}
+
+		c.closeSensors()
+	})
+}
+
+// closeSensors 关闭所有已添加的传感器
+func (c *TempController) closeSensors() {
+	for _, name := range c.sensorNames {
+		if err := c.sensors[name].Close(); err != nil {
+			log.Printf("关闭传感器失败 (%s): %v", name, err)
+		}
+	}
+}
+
+// releaseFan 按退出策略处理单个风扇：restore关闭风扇并恢复原来的控制方式，
+// full设置为全速后释放资源，leave直接释放资源
+func releaseFan(f FanController, policy ExitPolicy) error {
+	switch policy {
+	case ExitFull:
+		err := setFullSpeed(f)
+		if rerr := releaseOnly(f); err == nil {
+			err = rerr
+		}
+		return err
+	case ExitLeave:
+		return releaseOnly(f)
+	default:
+		return f.Close()
+	}
+}
+
+// setFullSpeed 以风扇支持的最大速度运行
+func setFullSpeed(f FanController) error {
+	if fs, ok := f.(FullSpeeder); ok {
+		return fs.SetFullSpeed()
+	}
+	return f.SetSpeed(f.GetMaxSpeed())
+}
+
+// releaseOnly 释放风扇资源，不恢复原来的控制方式；没有需要释放的资源时不做任何事
+func releaseOnly(f FanController) error {
+	if r, ok := f.(Releaser); ok {
+		return r.Release()
+	}
+	return nil
+}
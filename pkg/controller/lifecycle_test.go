@@ -0,0 +1,88 @@
+package controller
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// recordingFan 记录调用的风扇控制器
+type recordingFan struct {
+	calls []string
+}
+
+func (f *recordingFan) SetSpeed(pwm int) error {
+	f.calls = append(f.calls, fmt.Sprintf("set %d", pwm))
+	return nil
+}
+
+func (f *recordingFan) GetSpeed() (int, error) { return 0, nil }
+func (f *recordingFan) GetMinSpeed() int       { return 0 }
+func (f *recordingFan) GetMaxSpeed() int       { return 200 }
+
+func (f *recordingFan) SetFullSpeed() error {
+	f.calls = append(f.calls, "full")
+	return nil
+}
+
+func (f *recordingFan) Release() error {
+	f.calls = append(f.calls, "release")
+	return nil
+}
+
+func (f *recordingFan) Close() error {
+	f.calls = append(f.calls, "close")
+	return nil
+}
+
+func TestReleaseFan(t *testing.T) {
+	tests := []struct {
+		policy ExitPolicy
+		want   string
+	}{
+		{ExitRestore, "close"},
+		{ExitFull, "full release"},
+		{ExitLeave, "release"},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.policy), func(t *testing.T) {
+			f := &recordingFan{}
+			if err := releaseFan(f, tt.policy); err != nil {
+				t.Fatal(err)
+			}
+			if got := strings.Join(f.calls, " "); got != tt.want {
+				t.Errorf("调用 = %q, 期望 %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOverrideAfterStop(t *testing.T) {
+	c := NewMulti(time.Second, false)
+	if err := c.AddSensor("cpu", &countingSensor{temp: 50}); err != nil {
+		t.Fatal(err)
+	}
+	f := &recordingFan{}
+	if err := c.AddFan(FanZone{Name: "cpu", Fan: f, Inputs: []SensorInput{{Sensor: "cpu"}}, LowTemp: 40, HighTemp: 70}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.SetExitPolicy(ExitFull); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.SetOverride("cpu", 100); err != nil {
+		t.Fatal(err)
+	}
+	c.Stop()
+	c.Stop()
+
+	// 释放后的手动覆盖不能再写入风扇
+	if err := c.SetOverride("cpu", 50); err == nil {
+		t.Error("停止后 SetOverride 期望返回错误")
+	}
+	if got, want := strings.Join(f.calls, " "), "set 100 full release"; got != want {
+		t.Errorf("调用 = %q, 期望 %q", got, want)
+	}
+}
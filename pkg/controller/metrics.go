@@ -0,0 +1,170 @@
+package controller
+
+import (
+	"time"
+
+	"github.com/fanap/pkg/metrics"
+)
+
+// RPMReader 支持读取转速的风扇控制器
+type RPMReader interface {
+	GetRPM() (int, error)
+}
+
+// LevelReader 支持读取冷却级别的风扇控制器
+type LevelReader interface {
+	GetLevel() (int, error)
+}
+
+// controllerMetrics 控制器导出的指标，为nil时所有记录方法都不做任何事
+type controllerMetrics struct {
+	temperature  *metrics.GaugeVec
+	readErrors   *metrics.CounterVec
+	targetPWM    *metrics.GaugeVec
+	pwm          *metrics.GaugeVec
+	rpm          *metrics.GaugeVec
+	coolingLevel *metrics.GaugeVec
+	writeErrors  *metrics.CounterVec
+	failsafe     *metrics.GaugeVec
+	stalled      *metrics.GaugeVec
+	kicks        *metrics.CounterVec
+	zeroRPM      *metrics.GaugeVec
+	loopDuration *metrics.HistogramVec
+}
+
+// EnableMetrics 在reg中注册控制器的指标，控制循环每轮更新一次
+func (c *TempController) EnableMetrics(reg *metrics.Registry) {
+	m := &controllerMetrics{
+		temperature:  reg.NewGauge("fanap_sensor_temperature_celsius", "传感器温度（摄氏度，滤波后）", "sensor"),
+		readErrors:   reg.NewCounter("fanap_sensor_read_errors_total", "传感器读取失败次数", "sensor"),
+		targetPWM:    reg.NewGauge("fanap_fan_target_pwm", "控制器计算出的目标PWM（0-255）", "fan"),
+		pwm:          reg.NewGauge("fanap_fan_pwm", "从设备读回的PWM（0-255）", "fan"),
+		rpm:          reg.NewGauge("fanap_fan_rpm", "风扇转速（fanN_input）", "fan"),
+		coolingLevel: reg.NewGauge("fanap_cooling_device_level", "冷却设备当前级别（cur_state）", "fan"),
+		writeErrors:  reg.NewCounter("fanap_fan_write_errors_total", "设置风扇速度失败次数", "fan"),
+		failsafe:     reg.NewGauge("fanap_fan_failsafe", "风扇是否因传感器失效处于保护模式（1=是）", "fan"),
+		stalled:      reg.NewGauge("fanap_fan_stalled", "风扇是否停转或转速过低（1=是，只导出启用了停转检测的风扇）", "fan"),
+		kicks:        reg.NewCounter("fanap_fan_kicks_total", "停转后尝试全速启动风扇的次数", "fan"),
+		zeroRPM:      reg.NewGauge("fanap_fan_zero_rpm", "风扇是否因零转速模式停转（1=是，只导出启用了零转速模式的风扇）", "fan"),
+		loopDuration: reg.NewHistogram("fanap_control_loop_duration_seconds", "一轮控制循环的耗时（秒）", metrics.DefaultBuckets),
+	}
+
+	// 错误计数从0开始导出，便于告警规则使用 increase()
+	for _, name := range c.sensorNames {
+		m.readErrors.Init(name)
+	}
+	for _, zone := range c.zones {
+		m.writeErrors.Init(zone.displayName())
+		m.failsafe.Set(0, zone.displayName())
+		if zone.stall != nil {
+			m.stalled.Set(0, zone.displayName())
+			m.kicks.Init(zone.displayName())
+		}
+		if zone.zeroRPM != nil {
+			v := 0.0
+			if zone.zeroStopped {
+				v = 1
+			}
+			m.zeroRPM.Set(v, zone.displayName())
+		}
+	}
+
+	c.metrics = m
+}
+
+func (m *controllerMetrics) sensorTemp(name string, temp float64) {
+	if m == nil {
+		return
+	}
+	m.temperature.Set(temp, name)
+}
+
+func (m *controllerMetrics) sensorError(name string) {
+	if m == nil {
+		return
+	}
+	m.readErrors.Inc(name)
+}
+
+func (m *controllerMetrics) fanTarget(zone *FanZone, pwm int) {
+	if m == nil {
+		return
+	}
+	m.targetPWM.Set(float64(pwm), zone.displayName())
+}
+
+func (m *controllerMetrics) fanWriteError(zone *FanZone) {
+	if m == nil {
+		return
+	}
+	m.writeErrors.Inc(zone.displayName())
+}
+
+// fanState 读回风扇的实际状态（PWM、转速、冷却级别），读取失败时不更新
+func (m *controllerMetrics) fanState(zone *FanZone) {
+	if m == nil {
+		return
+	}
+	name := zone.displayName()
+
+	if pwm, err := zone.Fan.GetSpeed(); err == nil {
+		m.pwm.Set(float64(pwm), name)
+	}
+	if r, ok := zone.Fan.(RPMReader); ok {
+		if rpm, err := r.GetRPM(); err == nil {
+			m.rpm.Set(float64(rpm), name)
+		}
+	}
+	if r, ok := zone.Fan.(LevelReader); ok {
+		if level, err := r.GetLevel(); err == nil {
+			m.coolingLevel.Set(float64(level), name)
+		}
+	}
+}
+
+func (m *controllerMetrics) fanFailsafe(zone *FanZone, active bool) {
+	if m == nil {
+		return
+	}
+	v := 0.0
+	if active {
+		v = 1
+	}
+	m.failsafe.Set(v, zone.displayName())
+}
+
+func (m *controllerMetrics) fanStalled(zone *FanZone, stalled bool) {
+	if m == nil {
+		return
+	}
+	v := 0.0
+	if stalled {
+		v = 1
+	}
+	m.stalled.Set(v, zone.displayName())
+}
+
+func (m *controllerMetrics) fanZeroRPM(zone *FanZone, stopped bool) {
+	if m == nil {
+		return
+	}
+	v := 0.0
+	if stopped {
+		v = 1
+	}
+	m.zeroRPM.Set(v, zone.displayName())
+}
+
+func (m *controllerMetrics) fanKick(zone *FanZone) {
+	if m == nil {
+		return
+	}
+	m.kicks.Inc(zone.displayName())
+}
+
+func (m *controllerMetrics) loopDone(d time.Duration) {
+	if m == nil {
+		return
+	}
+	m.loopDuration.Observe(d.Seconds())
+}
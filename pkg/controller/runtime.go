@@ -0,0 +1,332 @@
+package controller
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/fanap/pkg/curve"
+	"github.com/fanap/pkg/pid"
+	"github.com/fanap/pkg/settings"
+)
+
+// DefaultProfile 启动时的控制规则对应的控制方案名称
+const DefaultProfile = "default"
+
+// Control 风扇的控制规则：PID、曲线或低温/高温阈值之间的线性控制，优先级依次降低
+type Control struct {
+	LowTemp  float64
+	HighTemp float64
+	Curve    curve.Curve
+	PID      *pid.Controller
+}
+
+// Validate 验证控制规则
+func (ctl Control) Validate() error {
+	if ctl.PID != nil && ctl.Curve != nil {
+		return fmt.Errorf("PID和曲线不能同时设置")
+	}
+	if ctl.PID == nil && ctl.Curve == nil {
+		return settings.Thresholds(ctl.LowTemp, ctl.HighTemp)
+	}
+	return nil
+}
+
+// control 返回风扇当前的控制规则
+func (z *FanZone) control() Control {
+	return Control{LowTemp: z.LowTemp, HighTemp: z.HighTemp, Curve: z.Curve, PID: z.PID}
+}
+
+// setControl 替换风扇的控制规则，并重置依赖上一个规则的状态
+func (z *FanZone) setControl(ctl Control) {
+	z.LowTemp = ctl.LowTemp
+	z.HighTemp = ctl.HighTemp
+	z.Curve = ctl.Curve
+	z.PID = ctl.PID
+	if z.PID != nil {
+		z.PID.Reset()
+	}
+	z.hystInit = false
+}
+
+// SetControl 在运行时替换风扇的控制规则，name为空时表示单风扇控制器中的风扇
+func (c *TempController) SetControl(name string, ctl Control) error {
+	if err := ctl.Validate(); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	zone, err := c.zone(name)
+	if err != nil {
+		return err
+	}
+	zone.setControl(ctl)
+	log.Printf("%s控制规则已更新: %s", zone.logPrefix(), describeControl(*zone))
+	return nil
+}
+
+// SetOverride 手动指定风扇的PWM，立即生效并一直保持到 ClearOverride
+// PWM仍然受风扇控制器的PWM范围限制
+func (c *TempController) SetOverride(name string, pwm int) error {
+	if pwm < 0 || pwm > 255 {
+		return fmt.Errorf("PWM值必须在0-255之间")
+	}
+
+	// 与控制循环的写入串行执行，写入风扇时不持有mu
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	c.mu.Lock()
+	stopped := c.stopped
+	zone, err := c.zone(name)
+	c.mu.Unlock()
+	if stopped {
+		// 风扇已经或即将按退出策略处理，不能再写入
+		return fmt.Errorf("控制器已停止")
+	}
+	if err != nil {
+		return err
+	}
+	if err := zone.Fan.SetSpeed(pwm); err != nil {
+		c.metrics.fanWriteError(zone)
+		return fmt.Errorf("设置风扇速度失败: %w", err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	zone.override = &pwm
+	zone.lastTarget = pwm
+	zone.rampPWM = float64(pwm)
+	zone.rampInit = true
+	c.metrics.fanTarget(zone, pwm)
+	log.Printf("%s手动覆盖PWM: %d", zone.logPrefix(), pwm)
+	return nil
+}
+
+// ClearOverride 解除手动PWM覆盖，恢复自动控制
+func (c *TempController) ClearOverride(name string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	zone, err := c.zone(name)
+	if err != nil {
+		return err
+	}
+	if zone.override == nil {
+		return nil
+	}
+
+	zone.override = nil
+	if zone.PID != nil {
+		zone.PID.Reset()
+	}
+	zone.hystInit = false
+	log.Printf("%s已解除手动覆盖，恢复自动控制", zone.logPrefix())
+	return nil
+}
+
+// AddProfile 添加控制方案，fans为风扇名称到控制规则的映射，未列出的风扇使用启动时的控制规则
+func (c *TempController) AddProfile(name string, fans map[string]Control) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if name == "" {
+		return fmt.Errorf("控制方案名称不能为空")
+	}
+	if _, ok := c.profiles[name]; ok || name == DefaultProfile {
+		return fmt.Errorf("控制方案名称重复: %s", name)
+	}
+	for fan, ctl := range fans {
+		if _, err := c.zone(fan); err != nil {
+			return fmt.Errorf("控制方案 %s: %w", name, err)
+		}
+		if err := ctl.Validate(); err != nil {
+			return fmt.Errorf("控制方案 %s, 风扇 %s: %w", name, fan, err)
+		}
+	}
+
+	c.profiles[name] = fans
+	c.profileNames = append(c.profileNames, name)
+	return nil
+}
+
+// SetProfile 切换控制方案，DefaultProfile 表示启动时的控制规则
+// 切换会替换运行时通过 SetControl 所做的修改，但不影响手动PWM覆盖
+func (c *TempController) SetProfile(name string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	profile, ok := c.profiles[name]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrProfileNotFound, name)
+	}
+
+	base := c.profiles[DefaultProfile]
+	for _, zone := range c.zones {
+		fan := zone.displayName()
+		ctl, ok := profile[fan]
+		if !ok {
+			ctl, ok = base[fan]
+		}
+		if ok {
+			zone.setControl(ctl)
+		}
+	}
+
+	c.profile = name
+	log.Printf("已切换到控制方案: %s", name)
+	return nil
+}
+
+// Profiles 返回所有控制方案的名称（第一个为 DefaultProfile）和当前的控制方案
+func (c *TempController) Profiles() ([]string, string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	names := append([]string{DefaultProfile}, c.profileNames...)
+	return names, c.profile
+}
+
+// snapshotControls 保存所有风扇当前的控制规则
+func (c *TempController) snapshotControls() map[string]Control {
+	controls := make(map[string]Control, len(c.zones))
+	for _, zone := range c.zones {
+		controls[zone.displayName()] = zone.control()
+	}
+	return controls
+}
+
+// Status 控制器的运行状态
+type Status struct {
+	Running  bool           `json:"running"`
+	Interval string         `json:"interval"`
+	Profile  string         `json:"profile"`
+	Profiles []string       `json:"profiles"`
+	Sensors  []SensorStatus `json:"sensors"`
+	Fans     []FanStatus    `json:"fans"`
+}
+
+// SensorStatus 传感器的最近一次读数
+type SensorStatus struct {
+	Name     string   `json:"name"`
+	Temp     *float64 `json:"temp,omitempty"`
+	Error    string   `json:"error,omitempty"`
+	Failures int      `json:"failures,omitempty"`
+}
+
+// FanStatus 风扇的状态和控制规则
+type FanStatus struct {
+	Name      string       `json:"name"`
+	Mode      string       `json:"mode"`
+	Sensors   []string     `json:"sensors"`
+	Temp      *float64     `json:"temp,omitempty"`
+	TargetPWM *int         `json:"target_pwm,omitempty"`
+	PWM       *int         `json:"pwm,omitempty"`
+	RPM       *int         `json:"rpm,omitempty"`
+	Override  *int         `json:"override,omitempty"`
+	Stalled   bool         `json:"stalled,omitempty"`
+	Stopped   bool         `json:"stopped,omitempty"`
+	LowTemp   *float64     `json:"low_temp,omitempty"`
+	HighTemp  *float64     `json:"high_temp,omitempty"`
+	Curve     *CurveStatus `json:"curve,omitempty"`
+	PID       *PIDStatus   `json:"pid,omitempty"`
+	MinPWM    int          `json:"min_pwm"`
+	MaxPWM    int          `json:"max_pwm"`
+}
+
+// CurveStatus 风扇曲线
+type CurveStatus struct {
+	Type   string        `json:"type"`
+	Points []curve.Point `json:"points"`
+}
+
+// PIDStatus PID控制参数
+type PIDStatus struct {
+	Target float64 `json:"target"`
+	Kp     float64 `json:"kp"`
+	Ki     float64 `json:"ki"`
+	Kd     float64 `json:"kd"`
+}
+
+// Status 返回控制器的运行状态，风扇的PWM和转速为调用时从设备读回的值
+func (c *TempController) Status() Status {
+	c.mu.Lock()
+	st := Status{
+		Running:  c.running,
+		Interval: c.interval.String(),
+		Profile:  c.profile,
+		Profiles: append([]string{DefaultProfile}, c.profileNames...),
+	}
+
+	for _, name := range c.sensorNames {
+		ss := SensorStatus{Name: name, Failures: c.failures[name]}
+		if t, ok := c.lastTemps[name]; ok {
+			ss.Temp = &t
+		}
+		if err, ok := c.lastErrs[name]; ok {
+			ss.Error = err.Error()
+		}
+		st.Sensors = append(st.Sensors, ss)
+	}
+
+	fans := make([]FanController, len(c.zones))
+	for i, zone := range c.zones {
+		st.Fans = append(st.Fans, zone.status())
+		fans[i] = zone.Fan
+	}
+	c.mu.Unlock()
+
+	// 读回设备状态时不持有锁，避免慢速设备阻塞控制循环
+	for i, f := range fans {
+		fs := &st.Fans[i]
+		if pwm, err := f.GetSpeed(); err == nil {
+			fs.PWM = &pwm
+		}
+		if r, ok := f.(RPMReader); ok {
+			if rpm, err := r.GetRPM(); err == nil {
+				fs.RPM = &rpm
+			}
+		}
+	}
+
+	return st
+}
+
+// status 返回风扇的状态（不包括需要从设备读回的值）
+func (z *FanZone) status() FanStatus {
+	fs := FanStatus{
+		Name:   z.displayName(),
+		Mode:   z.Mode(),
+		MinPWM: z.Fan.GetMinSpeed(),
+		MaxPWM: z.Fan.GetMaxSpeed(),
+	}
+	for _, in := range z.Inputs {
+		fs.Sensors = append(fs.Sensors, in.Sensor)
+	}
+	if z.hasTemp {
+		t, target := z.lastTemp, z.lastTarget
+		fs.Temp = &t
+		fs.TargetPWM = &target
+	}
+	if z.override != nil {
+		pwm := *z.override
+		fs.Override = &pwm
+	}
+	fs.Stalled = z.stalled
+	fs.Stopped = z.zeroStopped
+
+	switch {
+	case z.PID != nil:
+		g := z.PID.Gains()
+		fs.PID = &PIDStatus{Target: z.PID.Target(), Kp: g.Kp, Ki: g.Ki, Kd: g.Kd}
+	case z.Curve != nil:
+		fs.Curve = &CurveStatus{Type: z.Curve.Type(), Points: z.Curve.Points()}
+	default:
+		low, high := z.LowTemp, z.HighTemp
+		fs.LowTemp = &low
+		fs.HighTemp = &high
+	}
+	return fs
+}
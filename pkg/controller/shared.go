@@ -0,0 +1,72 @@
+package controller
+
+import (
+	"sync"
+
+	"github.com/fanap/pkg/virtual"
+)
+
+// sharedSensor 被虚拟传感器引用的传感器
+// 控制循环每轮读取一次并保存读数，虚拟传感器使用同一轮的读数而不再次读取，
+// 保证每个传感器每轮只读取一次（带滤波的传感器每轮只送入一个读数）
+type sharedSensor struct {
+	TempSensor
+
+	mu   sync.Mutex
+	read bool
+	temp float64
+	err  error
+}
+
+// GetTemperature 读取温度并保存读数
+func (s *sharedSensor) GetTemperature() (float64, error) {
+	temp, err := s.TempSensor.GetTemperature()
+
+	s.mu.Lock()
+	s.read, s.temp, s.err = true, temp, err
+	s.mu.Unlock()
+	return temp, err
+}
+
+// last 返回最近一次读取的温度，还没有读取过时读取一次
+func (s *sharedSensor) last() (float64, error) {
+	s.mu.Lock()
+	if s.read {
+		defer s.mu.Unlock()
+		return s.temp, s.err
+	}
+	s.mu.Unlock()
+	return s.GetTemperature()
+}
+
+// sharedRef 虚拟传感器对共享传感器的引用，关闭时不关闭传感器本身（由控制器关闭）
+type sharedRef struct {
+	s *sharedSensor
+}
+
+func (r sharedRef) GetTemperature() (float64, error) {
+	return r.s.last()
+}
+
+func (r sharedRef) Close() error {
+	return nil
+}
+
+// shareSensor 返回已添加的传感器的引用，供虚拟传感器作为输入，传感器不存在时返回false
+// 只在控制器运行前调用；虚拟传感器需要在其输入之后添加，控制循环按添加顺序读取传感器，
+// 虚拟传感器读到的是同一轮的读数
+func (c *TempController) shareSensor(name string) (virtual.Source, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	sensor, ok := c.sensors[name]
+	if !ok {
+		return nil, false
+	}
+	s, ok := sensor.(*sharedSensor)
+	if !ok {
+		s = &sharedSensor{TempSensor: sensor}
+		c.sensors[name] = s
+	}
+	return sharedRef{s: s}, true
+}
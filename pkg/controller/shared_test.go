@@ -0,0 +1,104 @@
+package controller
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fanap/pkg/config"
+	"github.com/fanap/pkg/virtual"
+)
+
+// countingSensor 记录读取次数的传感器
+type countingSensor struct {
+	temp   float64
+	reads  int
+	closed int
+}
+
+func (s *countingSensor) GetTemperature() (float64, error) {
+	s.reads++
+	return s.temp, nil
+}
+
+func (s *countingSensor) Close() error {
+	s.closed++
+	return nil
+}
+
+func TestSharedSensor(t *testing.T) {
+	c := NewMulti(time.Second, false)
+	cpu := &countingSensor{temp: 60}
+	intake := &countingSensor{temp: 35}
+	for name, s := range map[string]TempSensor{"cpu": cpu, "intake": intake} {
+		if err := c.AddSensor(name, s); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var inputs []virtual.Input
+	for _, name := range []string{"cpu", "intake"} {
+		src, ok := c.shareSensor(name)
+		if !ok {
+			t.Fatalf("shareSensor(%q) 没有找到传感器", name)
+		}
+		inputs = append(inputs, virtual.Input{Name: name, Source: src})
+	}
+	if _, ok := c.shareSensor("disk"); ok {
+		t.Error("shareSensor(\"disk\") 应返回false")
+	}
+	v, err := virtual.New(virtual.OpDelta, inputs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.AddSensor("cpu_rise", v); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 1; i <= 3; i++ {
+		cpu.temp++
+		c.adjustFanSpeed()
+
+		// 虚拟传感器使用同一轮的读数，不再次读取输入
+		if cpu.reads != i || intake.reads != i {
+			t.Fatalf("第%d轮后读取次数 = %d, %d, 期望 %d", i, cpu.reads, intake.reads, i)
+		}
+		if got, want := c.lastTemps["cpu_rise"], cpu.temp-intake.temp; got != want {
+			t.Errorf("第%d轮 cpu_rise = %.1f, 期望 %.1f", i, got, want)
+		}
+	}
+
+	// 传感器由控制器关闭，虚拟传感器不重复关闭
+	c.closeSensors()
+	if cpu.closed != 1 || intake.closed != 1 {
+		t.Errorf("关闭次数 = %d, %d, 期望 1", cpu.closed, intake.closed)
+	}
+}
+
+func TestSensorOrder(t *testing.T) {
+	cfg, err := config.Parse([]byte(`{
+		"sensors": [
+			{"name": "blend", "type": "virtual", "op": "max", "inputs": ["cores", "cpu"]},
+			{"name": "unused"},
+			{"name": "cpu"},
+			{"name": "cores", "type": "virtual", "op": "max", "inputs": ["hwmon:coretemp/Core *"]},
+			{"name": "intake"}
+		],
+		"fans": [
+			{"name": "cpu", "sensor": "blend"},
+			{"name": "case", "sensor": "intake"}
+		]
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	for _, sc := range sensorOrder(cfg) {
+		names = append(names, sc.Name)
+	}
+	// 虚拟传感器排在其输入之后，未被引用的传感器跳过
+	if got, want := strings.Join(names, ","), "cores,cpu,blend,intake"; got != want {
+		t.Errorf("sensorOrder() = %s, 期望 %s", got, want)
+	}
+}
@@ -0,0 +1,142 @@
+package controller
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/fanap/pkg/settings"
+)
+
+// 停转检测的默认值
+const (
+	DefaultStallMinPWM = settings.DefaultStallMinPWM
+	DefaultStallChecks = settings.DefaultStallChecks
+	DefaultStallKick   = settings.DefaultStallKick
+)
+
+// MinRPMReader 支持读取最低转速阈值（fanN_min）的风扇控制器
+type MinRPMReader interface {
+	GetMinRPM() (int, error)
+}
+
+// StallDetect 风扇停转检测，见 settings.StallDetect
+type StallDetect = settings.StallDetect
+
+// DefaultStallDetect 返回默认的停转检测设置
+func DefaultStallDetect() StallDetect {
+	return settings.DefaultStallDetect()
+}
+
+// SetStallDetect 为风扇启用停转检测，name为空时表示单风扇控制器中的风扇
+// 风扇不支持读取转速时返回错误
+func (c *TempController) SetStallDetect(name string, s StallDetect) error {
+	if err := s.Validate(); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	zone, err := c.zone(name)
+	if err != nil {
+		return err
+	}
+	r, ok := zone.Fan.(RPMReader)
+	if !ok {
+		return fmt.Errorf("风扇 %s 不支持读取转速，无法检测停转", zone.displayName())
+	}
+	if _, err := r.GetRPM(); err != nil {
+		return fmt.Errorf("风扇 %s 无法检测停转: %w", zone.displayName(), err)
+	}
+
+	minRPM := s.MinRPM
+	if minRPM == 0 {
+		if m, ok := zone.Fan.(MinRPMReader); ok {
+			if v, err := m.GetMinRPM(); err == nil {
+				minRPM = v
+			}
+		}
+	}
+
+	zone.stall = &s
+	zone.stallMinRPM = minRPM
+	zone.stallCount = 0
+	c.metrics.fanStalled(zone, zone.stalled)
+	return nil
+}
+
+// checkStall 根据上一轮输出的PWM和本轮读回的转速检查风扇是否停转，需要持有mu
+// 正在全速启动风扇时返回true，本轮不再按温度调整该风扇
+func (c *TempController) checkStall(zone *FanZone, r fanReading, now time.Time) bool {
+	s := zone.stall
+	if s == nil {
+		return false
+	}
+	if now.Before(zone.kickUntil) {
+		return true
+	}
+	zone.kickUntil = time.Time{}
+
+	rpm, err := r.rpm, r.rpmErr
+	if err != nil {
+		log.Printf("%s读取风扇转速失败: %v\n", zone.logPrefix(), err)
+		return false
+	}
+
+	if rpm > 0 && rpm >= zone.stallMinRPM {
+		zone.stallCount = 0
+		if zone.stalled {
+			zone.stalled = false
+			c.metrics.fanStalled(zone, false)
+			c.alarm(Alarm{
+				Event:   AlarmFanRecovered,
+				Fan:     zone.displayName(),
+				Message: fmt.Sprintf("%s风扇已恢复转动 (%d RPM)", zone.logPrefix(), rpm),
+			})
+		}
+		return false
+	}
+
+	if zone.lastTarget < s.MinPWM {
+		zone.stallCount = 0
+		return false
+	}
+
+	zone.stallCount++
+	if zone.stallCount < s.Checks {
+		return false
+	}
+	zone.stallCount = 0
+
+	if !zone.stalled {
+		zone.stalled = true
+		c.metrics.fanStalled(zone, true)
+		c.alarm(Alarm{
+			Event: AlarmFanStall,
+			Fan:   zone.displayName(),
+			Message: fmt.Sprintf("%s告警: 风扇停转或转速过低 (%d RPM，PWM %d，最低转速 %d RPM)",
+				zone.logPrefix(), rpm, zone.lastTarget, zone.stallMinRPM),
+		})
+	}
+
+	if s.Kick == 0 {
+		return false
+	}
+	c.kick(zone, now, s.Kick)
+	return true
+}
+
+// kick 全速运行风扇d时长，尝试让停转的风扇重新启动，需要持有mu
+func (c *TempController) kick(zone *FanZone, now time.Time, d time.Duration) {
+	log.Printf("%s尝试全速启动风扇 (%v)", zone.logPrefix(), d)
+	c.queue(zone, writeFull, 255)
+
+	// 启动结束后从风扇的实际速度开始按速率限制逼近
+	zone.kickUntil = now.Add(d)
+	zone.lastTarget = 255
+	zone.rampInit = false
+
+	c.metrics.fanKick(zone)
+	c.metrics.fanTarget(zone, 255)
+}
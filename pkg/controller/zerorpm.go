@@ -0,0 +1,184 @@
+package controller
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/fanap/pkg/settings"
+)
+
+// 零转速模式的默认值
+const (
+	DefaultZeroRPMStopTemp  = settings.DefaultZeroRPMStopTemp
+	DefaultZeroRPMStartTemp = settings.DefaultZeroRPMStartTemp
+	DefaultZeroRPMKickPWM   = settings.DefaultZeroRPMKickPWM
+	DefaultZeroRPMKick      = settings.DefaultZeroRPMKick
+)
+
+// Stopper 可以完全停转的风扇控制器，停转时不受最小PWM限制
+type Stopper interface {
+	StopFan() error
+}
+
+// ZeroRPM 零转速模式，见 settings.ZeroRPM
+type ZeroRPM = settings.ZeroRPM
+
+// DefaultZeroRPM 返回默认的零转速模式设置
+func DefaultZeroRPM() ZeroRPM {
+	return settings.DefaultZeroRPM()
+}
+
+// SetZeroRPM 为风扇启用零转速模式，name为空时表示单风扇控制器中的风扇
+func (c *TempController) SetZeroRPM(name string, z ZeroRPM) error {
+	if err := z.Validate(); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	zone, err := c.zone(name)
+	if err != nil {
+		return err
+	}
+	zone.zeroRPM = &z
+	zone.zeroStopped = false
+	zone.starting = false
+	c.metrics.fanZeroRPM(zone, false)
+	return nil
+}
+
+// applyZeroRPM 按零转速模式停转或启动风扇，需要持有mu
+// 风扇处于停转或启动过程中时返回true，本轮不再按温度调整该风扇
+func (c *TempController) applyZeroRPM(zone *FanZone, temp float64, r fanReading, now time.Time) bool {
+	z := zone.zeroRPM
+	if z == nil {
+		return false
+	}
+	if now.Before(zone.startUntil) {
+		return true
+	}
+	zone.startUntil = time.Time{}
+
+	if zone.starting {
+		zone.starting = false
+		if !c.verifyStart(zone, r, now) {
+			return true
+		}
+	}
+
+	if zone.zeroStopped {
+		if temp < z.StartTemp {
+			return true
+		}
+		zone.zeroStopped = false
+		c.metrics.fanZeroRPM(zone, false)
+		log.Printf("%s温度升到 %.1f°C，启动风扇", zone.logPrefix(), temp)
+		return c.startFan(zone, now, z.KickPWM)
+	}
+
+	if temp > z.StopTemp {
+		return false
+	}
+	zone.zeroStopped = true
+	c.metrics.fanZeroRPM(zone, true)
+	log.Printf("%s温度降到 %.1f°C，风扇停转", zone.logPrefix(), temp)
+	c.stopFan(zone)
+	return true
+}
+
+// stopFan 让风扇停转，需要持有mu
+func (c *TempController) stopFan(zone *FanZone) {
+	c.queue(zone, writeStop, 0)
+
+	// 重新启动后从风扇的实际速度开始按速率限制逼近，PID从零开始积分
+	zone.lastTarget = 0
+	zone.rampInit = false
+	zone.hystInit = false
+	if zone.PID != nil {
+		zone.PID.Reset()
+	}
+
+	c.metrics.fanTarget(zone, 0)
+}
+
+// startFan 以pwm运行风扇Kick时长，需要持有mu
+// Kick为0时返回false，本轮直接按温度计算的PWM运行
+func (c *TempController) startFan(zone *FanZone, now time.Time, pwm int) bool {
+	z := zone.zeroRPM
+	_, zone.starting = zone.Fan.(RPMReader)
+	if z.Kick == 0 {
+		return false
+	}
+
+	log.Printf("%s以PWM %d 启动风扇 (%v)", zone.logPrefix(), pwm, z.Kick)
+	c.queue(zone, writeSpeed, pwm)
+
+	zone.startUntil = now.Add(z.Kick)
+	zone.lastTarget = pwm
+	zone.rampInit = false
+
+	c.metrics.fanTarget(zone, pwm)
+	return true
+}
+
+// verifyStart 根据本轮读回的转速检查风扇是否已经启动，需要持有mu
+// 转速仍为0时记录告警并以最大PWM重新启动风扇，返回false
+func (c *TempController) verifyStart(zone *FanZone, r fanReading, now time.Time) bool {
+	rpm, err := r.rpm, r.rpmErr
+	if err != nil {
+		// 没有转速输入的风扇同样实现了RPMReader，读取失败时不再确认，避免风扇一直停留在启动过程中
+		log.Printf("%s读取风扇转速失败，无法确认风扇已启动: %v\n", zone.logPrefix(), err)
+		return true
+	}
+
+	if rpm > 0 {
+		if zone.startFailed {
+			zone.startFailed = false
+			c.alarm(Alarm{
+				Event:   AlarmFanRecovered,
+				Fan:     zone.displayName(),
+				Message: fmt.Sprintf("%s风扇已启动 (%d RPM)", zone.logPrefix(), rpm),
+			})
+		}
+		return true
+	}
+
+	if !zone.startFailed {
+		zone.startFailed = true
+		c.alarm(Alarm{
+			Event: AlarmFanStartFailed,
+			Fan:   zone.displayName(),
+			Message: fmt.Sprintf("%s告警: 风扇未能从停转状态启动 (0 RPM，启动PWM %d)",
+				zone.logPrefix(), zone.zeroRPM.KickPWM),
+		})
+	}
+	return !c.startFan(zone, now, zone.Fan.GetMaxSpeed())
+}
+
+// leaveZeroRPM 手动覆盖或保护模式接管风扇时结束零转速模式的停转和启动过程，需要持有mu
+func (c *TempController) leaveZeroRPM(zone *FanZone) {
+	if zone.zeroRPM == nil {
+		return
+	}
+	zone.startUntil = time.Time{}
+	zone.starting = false
+	if zone.zeroStopped {
+		zone.zeroStopped = false
+		c.metrics.fanZeroRPM(zone, false)
+	}
+}
+
+// StopFan 让风扇停转，不受最小PWM和校准结果的限制
+func (fc *FanControllerImpl) StopFan() error {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+
+	if err := fc.fan.SetSpeed(0); err != nil {
+		return err
+	}
+	fc.lastPWM = 0
+	fc.kickUntil = time.Time{}
+	return nil
+}
@@ -0,0 +1,242 @@
+package controller
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/fanap/pkg/curve"
+	"github.com/fanap/pkg/pid"
+	"github.com/fanap/pkg/settings"
+)
+
+// 多传感器聚合方式
+const (
+	// AggregateMax 跟随所有传感器中的最高温度
+	AggregateMax = "max"
+	// AggregateWeighted 跟随所有传感器温度的加权平均
+	AggregateWeighted = "weighted"
+)
+
+// SensorInput 风扇跟随的一个传感器
+type SensorInput struct {
+	// Sensor 传感器名称
+	Sensor string
+	// Weight 加权平均时的权重，0表示1
+	Weight float64
+}
+
+// FanZone 一个风扇及其控制规则
+type FanZone struct {
+	// Name 风扇名称
+	Name string
+	// Fan 风扇控制器
+	Fan FanController
+	// Inputs 风扇跟随的传感器
+	Inputs []SensorInput
+	// Aggregate 多个传感器的聚合方式，默认为 AggregateMax
+	Aggregate string
+	// LowTemp 低温阈值，低于此温度使用最小PWM
+	LowTemp float64
+	// HighTemp 高温阈值，高于此温度使用最大PWM
+	HighTemp float64
+	// Curve 风扇曲线，设置后忽略LowTemp和HighTemp
+	Curve curve.Curve
+	// PID 目标温度闭环控制，设置后忽略Curve、LowTemp和HighTemp
+	PID *pid.Controller
+	// Hysteresis 温度回差（PID模式下不使用）
+	Hysteresis Hysteresis
+	// Ramp PWM变化速率限制
+	Ramp Ramp
+
+	lastUpdate time.Time
+	hystTemp   float64
+	hystInit   bool
+	rampPWM    float64
+	rampInit   bool
+	override   *int
+	failsafe   bool
+	lastTemp   float64
+	lastTarget int
+	hasTemp    bool
+
+	stall       *StallDetect
+	stallMinRPM int
+	stallCount  int
+	stalled     bool
+	kickUntil   time.Time
+
+	zeroRPM     *ZeroRPM
+	zeroStopped bool
+	starting    bool
+	startFailed bool
+	startUntil  time.Time
+}
+
+// Ramp PWM变化速率限制，见 settings.Ramp
+type Ramp = settings.Ramp
+
+// applyRamp 按速率限制从上次输出的PWM向目标PWM逼近，r为本轮调整前读回的风扇状态
+func (z *FanZone) applyRamp(target int, dt time.Duration, r fanReading) int {
+	if z.Ramp.Up == 0 && z.Ramp.Down == 0 {
+		return target
+	}
+
+	if !z.rampInit {
+		// 从风扇当前的实际速度开始逼近
+		current := r.speed
+		if r.speedErr != nil {
+			current = target
+		}
+		z.rampPWM = float64(current)
+		z.rampInit = true
+	}
+
+	next := float64(target)
+	seconds := dt.Seconds()
+	if delta := next - z.rampPWM; delta > 0 && z.Ramp.Up > 0 {
+		if step := z.Ramp.Up * seconds; delta > step {
+			next = z.rampPWM + step
+		}
+	} else if delta < 0 && z.Ramp.Down > 0 {
+		if step := z.Ramp.Down * seconds; -delta > step {
+			next = z.rampPWM - step
+		}
+	}
+
+	// 保留小数部分，保证较低的速率在较短的间隔下也能累积
+	z.rampPWM = next
+	return int(next + 0.5)
+}
+
+// Hysteresis 温度回差，见 settings.Hysteresis
+type Hysteresis = settings.Hysteresis
+
+// applyHysteresis 返回经过回差处理的温度
+func (z *FanZone) applyHysteresis(temp float64) float64 {
+	if z.PID != nil || (z.Hysteresis.Rise == 0 && z.Hysteresis.Fall == 0) {
+		return temp
+	}
+
+	if !z.hystInit || temp >= z.hystTemp+z.Hysteresis.Rise || temp <= z.hystTemp-z.Hysteresis.Fall {
+		z.hystTemp = temp
+		z.hystInit = true
+	}
+	return z.hystTemp
+}
+
+// 控制模式
+const (
+	ModeLinear   = "linear"
+	ModeCurve    = "curve"
+	ModePID      = "pid"
+	ModeManual   = "manual"
+	ModeFailsafe = "failsafe"
+)
+
+// Mode 返回风扇当前的控制模式
+func (z *FanZone) Mode() string {
+	switch {
+	case z.failsafe:
+		return ModeFailsafe
+	case z.override != nil:
+		return ModeManual
+	case z.PID != nil:
+		return ModePID
+	case z.Curve != nil:
+		return ModeCurve
+	default:
+		return ModeLinear
+	}
+}
+
+// aggregate 根据各传感器的温度计算风扇的输入温度
+// 部分传感器读取失败时使用其余传感器，全部失败时返回错误
+func (z *FanZone) aggregate(temps map[string]float64) (float64, error) {
+	var (
+		result    float64
+		weightSum float64
+		missing   []string
+		found     bool
+	)
+
+	for _, in := range z.Inputs {
+		t, ok := temps[in.Sensor]
+		if !ok {
+			missing = append(missing, in.Sensor)
+			continue
+		}
+
+		switch z.Aggregate {
+		case AggregateWeighted:
+			result += t * in.Weight
+			weightSum += in.Weight
+		default:
+			if !found || t > result {
+				result = t
+			}
+		}
+		found = true
+	}
+
+	if !found {
+		return 0, fmt.Errorf("没有可用的温度读数: %s", strings.Join(missing, ", "))
+	}
+
+	if z.Aggregate == AggregateWeighted {
+		if weightSum == 0 {
+			return 0, fmt.Errorf("可用传感器的权重之和为0")
+		}
+		result /= weightSum
+	}
+
+	return result, nil
+}
+
+// calculatePWM 根据温度计算PWM值，dt为距上次计算的时间间隔
+func (z *FanZone) calculatePWM(temp float64, dt time.Duration) int {
+	minPWM := z.Fan.GetMinSpeed()
+	maxPWM := z.Fan.GetMaxSpeed()
+
+	if z.PID != nil {
+		// 输出范围由风扇控制器决定
+		z.PID.SetLimits(float64(minPWM), float64(maxPWM))
+		return int(z.PID.Update(temp, dt) + 0.5)
+	}
+
+	if z.Curve != nil {
+		return z.Curve.PWM(temp)
+	}
+
+	// 温度低于低温阈值，使用最小PWM
+	if temp <= z.LowTemp {
+		return minPWM
+	}
+
+	// 温度高于高温阈值，使用最大PWM
+	if temp >= z.HighTemp {
+		return maxPWM
+	}
+
+	// 在低温和高温之间线性插值
+	ratio := (temp - z.LowTemp) / (z.HighTemp - z.LowTemp)
+	pwm := minPWM + int(float64(maxPWM-minPWM)*ratio)
+
+	return pwm
+}
+
+// displayName 指标和管理接口中风扇的名称，未命名的风扇（单风扇模式）使用 "default"
+func (z *FanZone) displayName() string {
+	if z.Name == "" {
+		return defaultSensor
+	}
+	return z.Name
+}
+
+// logPrefix 日志前缀，未命名的风扇（单风扇模式）不加前缀
+func (z *FanZone) logPrefix() string {
+	if z.Name == "" {
+		return ""
+	}
+	return "[" + z.Name + "] "
+}
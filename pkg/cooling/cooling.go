@@ -6,6 +6,8 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+
+	"github.com/fanap/pkg/sysfs"
 )
 
 // Device 冷却设备接口
@@ -22,6 +24,7 @@ type CoolingDevice struct {
 	typePath   string
 	maxState   int
 	curState   int
+	origState  int
 	verbose    bool
 }
 
@@ -33,10 +36,10 @@ func NewDevice(deviceName string, verbose bool) (*CoolingDevice, error) {
 
 	// 如果deviceName已经是完整路径
 	if filepath.IsAbs(deviceName) {
-		if _, err := os.Stat(deviceName); err != nil {
+		devicePath = sysfs.Path(deviceName)
+		if _, err := os.Stat(devicePath); err != nil {
 			return nil, fmt.Errorf("冷却设备路径不存在: %w", err)
 		}
-		devicePath = deviceName
 	} else {
 		// 自动查找风扇类型的cooling device
 		var err error
@@ -89,6 +92,7 @@ func NewDevice(deviceName string, verbose bool) (*CoolingDevice, error) {
 		typePath:   typePath,
 		maxState:   maxState,
 		curState:   curState,
+		origState:  curState,
 		verbose:    verbose,
 	}, nil
 }
@@ -139,15 +143,21 @@ func (d *CoolingDevice) GetMaxLevel() (int, error) {
 	return d.maxState, nil
 }
 
-// Close 关闭冷却设备
+// Close 关闭冷却设备，恢复打开时的冷却级别
 func (d *CoolingDevice) Close() error {
-	// 冷却设备无需特殊关闭
+	if err := d.SetLevel(d.origState); err != nil {
+		return fmt.Errorf("恢复冷却级别失败: %w", err)
+	}
+
+	if d.verbose {
+		fmt.Printf("已恢复冷却级别: %d\n", d.origState)
+	}
 	return nil
 }
 
 // findCoolingDevice 查找风扇类型的冷却设备
 func findCoolingDevice(verbose bool) (string, error) {
-	thermalPath := "/sys/class/thermal"
+	thermalPath := sysfs.ThermalPath()
 
 	entries, err := os.ReadDir(thermalPath)
 	if err != nil {
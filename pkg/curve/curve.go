@@ -0,0 +1,232 @@
+// Package curve 实现温度到PWM的风扇曲线：多点分段线性曲线和阶梯曲线
+package curve
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// 曲线类型
+const (
+	TypeLinear = "linear"
+	TypeStep   = "step"
+)
+
+// Curve 风扇曲线接口
+type Curve interface {
+	// PWM 返回温度对应的PWM值 (0-255)
+	PWM(temp float64) int
+	// Points 返回曲线的控制点
+	Points() []Point
+	// Type 返回曲线类型
+	Type() string
+}
+
+// Point 曲线控制点
+type Point struct {
+	Temp float64 `json:"temp"`
+	PWM  int     `json:"pwm"`
+}
+
+// PointError 指向具体控制点的验证错误
+type PointError struct {
+	// Index 控制点序号，-1表示整条曲线
+	Index int
+	// Field 出错的字段: 控制点的temp、pwm，或整条曲线的type、points
+	Field string
+	Msg   string
+}
+
+func (e *PointError) Error() string {
+	if e.Index < 0 {
+		return e.Msg
+	}
+	return fmt.Sprintf("第%d个控制点: %s", e.Index+1, e.Msg)
+}
+
+// Validate 验证控制点：温度严格递增，PWM在0-255之间且单调不减
+func Validate(curveType string, points []Point) error {
+	minPoints := 2
+	switch curveType {
+	case TypeLinear:
+	case TypeStep:
+		minPoints = 1
+	default:
+		return &PointError{Index: -1, Field: "type", Msg: fmt.Sprintf("未知的曲线类型 %q（可选: linear、step）", curveType)}
+	}
+
+	if len(points) < minPoints {
+		return &PointError{Index: -1, Field: "points", Msg: fmt.Sprintf("%s曲线至少需要%d个控制点", curveType, minPoints)}
+	}
+
+	for i, p := range points {
+		if p.PWM < 0 || p.PWM > 255 {
+			return &PointError{Index: i, Field: "pwm", Msg: "PWM值必须在0-255之间"}
+		}
+		if i == 0 {
+			continue
+		}
+		if p.Temp <= points[i-1].Temp {
+			return &PointError{Index: i, Field: "temp", Msg: fmt.Sprintf("温度必须严格递增（%.1f ≤ %.1f）", p.Temp, points[i-1].Temp)}
+		}
+		if p.PWM < points[i-1].PWM {
+			return &PointError{Index: i, Field: "pwm", Msg: fmt.Sprintf("PWM值不能随温度升高而降低（%d < %d）", p.PWM, points[i-1].PWM)}
+		}
+	}
+
+	return nil
+}
+
+// New 根据类型创建曲线
+func New(curveType string, points []Point) (Curve, error) {
+	if err := Validate(curveType, points); err != nil {
+		return nil, err
+	}
+
+	pts := make([]Point, len(points))
+	copy(pts, points)
+
+	if curveType == TypeStep {
+		return &Step{points: pts}, nil
+	}
+	return &Linear{points: pts}, nil
+}
+
+// Linear 多点分段线性曲线
+// 低于第一个控制点使用第一个点的PWM，高于最后一个控制点使用最后一个点的PWM，
+// 控制点之间线性插值
+type Linear struct {
+	points []Point
+}
+
+// NewLinear 创建分段线性曲线
+func NewLinear(points []Point) (*Linear, error) {
+	c, err := New(TypeLinear, points)
+	if err != nil {
+		return nil, err
+	}
+	return c.(*Linear), nil
+}
+
+// PWM 返回温度对应的PWM值
+func (c *Linear) PWM(temp float64) int {
+	first, last := c.points[0], c.points[len(c.points)-1]
+	if temp <= first.Temp {
+		return first.PWM
+	}
+	if temp >= last.Temp {
+		return last.PWM
+	}
+
+	for i := 1; i < len(c.points); i++ {
+		lo, hi := c.points[i-1], c.points[i]
+		if temp <= hi.Temp {
+			ratio := (temp - lo.Temp) / (hi.Temp - lo.Temp)
+			return lo.PWM + int(float64(hi.PWM-lo.PWM)*ratio)
+		}
+	}
+	return last.PWM
+}
+
+// Points 返回控制点
+func (c *Linear) Points() []Point {
+	return append([]Point(nil), c.points...)
+}
+
+// Type 返回曲线类型
+func (c *Linear) Type() string {
+	return TypeLinear
+}
+
+// Step 阶梯曲线
+// 温度达到某个控制点后使用该点的PWM，直到达到下一个控制点；低于第一个控制点使用第一个点的PWM
+type Step struct {
+	points []Point
+}
+
+// NewStep 创建阶梯曲线
+func NewStep(points []Point) (*Step, error) {
+	c, err := New(TypeStep, points)
+	if err != nil {
+		return nil, err
+	}
+	return c.(*Step), nil
+}
+
+// PWM 返回温度对应的PWM值
+func (c *Step) PWM(temp float64) int {
+	pwm := c.points[0].PWM
+	for _, p := range c.points {
+		if temp < p.Temp {
+			break
+		}
+		pwm = p.PWM
+	}
+	return pwm
+}
+
+// Points 返回控制点
+func (c *Step) Points() []Point {
+	return append([]Point(nil), c.points...)
+}
+
+// Type 返回曲线类型
+func (c *Step) Type() string {
+	return TypeStep
+}
+
+// Parse 解析 "温度:PWM" 格式的控制点列表，如 "40:50,55:50,70:150,80:255"
+// PWM可以用百分比表示，如 "70:60%"
+func Parse(s string) ([]Point, error) {
+	var points []Point
+	for i, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		parts := strings.SplitN(item, ":", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("第%d个控制点 %q 格式错误，应为 温度:PWM", i+1, item)
+		}
+
+		temp, err := strconv.ParseFloat(strings.TrimSpace(parts[0]), 64)
+		if err != nil {
+			return nil, fmt.Errorf("第%d个控制点的温度 %q 无效", i+1, parts[0])
+		}
+
+		pwm, err := parseDuty(strings.TrimSpace(parts[1]))
+		if err != nil {
+			return nil, fmt.Errorf("第%d个控制点的PWM %q 无效: %v", i+1, parts[1], err)
+		}
+
+		points = append(points, Point{Temp: temp, PWM: pwm})
+	}
+	return points, nil
+}
+
+// Format 将控制点格式化为 Parse 可以解析的字符串
+func Format(points []Point) string {
+	items := make([]string, len(points))
+	for i, p := range points {
+		items[i] = strconv.FormatFloat(p.Temp, 'f', -1, 64) + ":" + strconv.Itoa(p.PWM)
+	}
+	return strings.Join(items, ",")
+}
+
+// parseDuty 解析PWM值（0-255）或百分比（0%-100%）
+func parseDuty(s string) (int, error) {
+	if strings.HasSuffix(s, "%") {
+		pct, err := strconv.ParseFloat(strings.TrimSuffix(s, "%"), 64)
+		if err != nil {
+			return 0, err
+		}
+		if pct < 0 || pct > 100 {
+			return 0, fmt.Errorf("百分比必须在0%%-100%%之间")
+		}
+		return int(math.Round(pct * 255 / 100)), nil
+	}
+	return strconv.Atoi(s)
+}
@@ -0,0 +1,163 @@
+package curve
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name      string
+		typ       string
+		points    []Point
+		wantIndex int
+		wantField string
+	}{
+		{"线性", TypeLinear, []Point{{40, 50}, {60, 150}, {80, 255}}, 0, ""},
+		{"水平段", TypeLinear, []Point{{40, 100}, {60, 100}}, 0, ""},
+		{"单点阶梯", TypeStep, []Point{{50, 128}}, 0, ""},
+		{"未知类型", "spline", []Point{{40, 50}, {60, 150}}, -1, "type"},
+		{"线性只有一个点", TypeLinear, []Point{{40, 50}}, -1, "points"},
+		{"阶梯没有控制点", TypeStep, nil, -1, "points"},
+		{"PWM超出范围", TypeLinear, []Point{{40, 50}, {60, 256}}, 1, "pwm"},
+		{"负的PWM", TypeStep, []Point{{40, -1}}, 0, "pwm"},
+		{"温度相同", TypeLinear, []Point{{40, 50}, {40, 150}}, 1, "temp"},
+		{"温度递减", TypeLinear, []Point{{40, 50}, {60, 100}, {50, 150}}, 2, "temp"},
+		{"PWM递减", TypeStep, []Point{{40, 150}, {60, 100}}, 1, "pwm"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(tt.typ, tt.points)
+			if tt.wantField == "" {
+				if err != nil {
+					t.Fatalf("Validate() = %v, 期望没有错误", err)
+				}
+				return
+			}
+			var pe *PointError
+			if !errors.As(err, &pe) {
+				t.Fatalf("Validate() = %v, 期望 *PointError", err)
+			}
+			if pe.Index != tt.wantIndex || pe.Field != tt.wantField {
+				t.Errorf("Validate() 错误指向 %d/%s, 期望 %d/%s", pe.Index, pe.Field, tt.wantIndex, tt.wantField)
+			}
+		})
+	}
+}
+
+func TestLinearPWM(t *testing.T) {
+	c, err := NewLinear([]Point{{40, 50}, {55, 50}, {70, 150}, {80, 255}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		temp float64
+		want int
+	}{
+		{-10, 50},
+		{40, 50},
+		{50, 50},
+		{55, 50},
+		{62.5, 100},
+		{70, 150},
+		{75, 202},
+		{80, 255},
+		{120, 255},
+	}
+	for _, tt := range tests {
+		if got := c.PWM(tt.temp); got != tt.want {
+			t.Errorf("PWM(%.1f) = %d, 期望 %d", tt.temp, got, tt.want)
+		}
+	}
+}
+
+func TestStepPWM(t *testing.T) {
+	c, err := NewStep([]Point{{40, 60}, {60, 128}, {75, 255}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		temp float64
+		want int
+	}{
+		{-10, 60},
+		{39.9, 60},
+		{40, 60},
+		{59.9, 60},
+		{60, 128},
+		{74.9, 128},
+		{75, 255},
+		{120, 255},
+	}
+	for _, tt := range tests {
+		if got := c.PWM(tt.temp); got != tt.want {
+			t.Errorf("PWM(%.1f) = %d, 期望 %d", tt.temp, got, tt.want)
+		}
+	}
+}
+
+func TestNewCopiesPoints(t *testing.T) {
+	points := []Point{{40, 50}, {80, 255}}
+	c, err := New(TypeLinear, points)
+	if err != nil {
+		t.Fatal(err)
+	}
+	points[1].PWM = 100
+	if got := c.PWM(80); got != 255 {
+		t.Errorf("修改控制点后 PWM(80) = %d, 期望 255", got)
+	}
+	c.Points()[0].PWM = 0
+	if got := c.PWM(40); got != 50 {
+		t.Errorf("修改 Points() 的结果后 PWM(40) = %d, 期望 50", got)
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    []Point
+		wantErr bool
+	}{
+		{"40:50,55:50,70:150,80:255", []Point{{40, 50}, {55, 50}, {70, 150}, {80, 255}}, false},
+		{" 40 : 50 , 72.5:128 ,", []Point{{40, 50}, {72.5, 128}}, false},
+		{"40:0%,60:50%,70:60%,80:100%", []Point{{40, 0}, {60, 128}, {70, 153}, {80, 255}}, false},
+		{"50:12.5%", []Point{{50, 32}}, false},
+		{"", nil, false},
+		{"40", nil, true},
+		{"abc:50", nil, true},
+		{"40:high", nil, true},
+		{"40:101%", nil, true},
+		{"40:-1%", nil, true},
+		{"40:x%", nil, true},
+	}
+
+	for _, tt := range tests {
+		got, err := Parse(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Parse(%q) 错误 = %v, 期望出错 %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Parse(%q) = %v, 期望 %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestFormat(t *testing.T) {
+	points := []Point{{40, 50}, {62.5, 100}, {80, 255}}
+	s := Format(points)
+	if s != "40:50,62.5:100,80:255" {
+		t.Errorf("Format() = %q", s)
+	}
+	got, err := Parse(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, points) {
+		t.Errorf("Parse(Format()) = %v, 期望 %v", got, points)
+	}
+}
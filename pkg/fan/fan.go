@@ -5,9 +5,11 @@ import (
 	"log"
 	"os"
 	"path/filepath"
-	"regexp"
 	"strconv"
 	"strings"
+
+	"github.com/fanap/pkg/hwmon"
+	"github.com/fanap/pkg/sysfs"
 )
 
 // Fan 风扇接口
@@ -21,29 +23,49 @@ type Fan interface {
 type PWMFan struct {
 	pwmPath      string
 	enablePath   string
+	rpmPath      string
 	originalMode int
+	originalPWM  int
 	verbose      bool
+
+	// levels 只支持几档转速的风扇各档位的PWM值，为空表示连续调速
+	levels []int
 }
 
 // NewPWMFan 创建新的PWM风扇控制器
-// deviceName 可以是具体的hwmon路径（如 "/sys/class/hwmon/hwmon0/pwm1"）
-// 或设备名称
+// deviceName 为风扇选择器（见 Selector），如具体的hwmon路径（"/sys/class/hwmon/hwmon0/pwm1"）、
+// "hwmon:nct6775/pwm2" 或 "auto"
 func NewPWMFan(deviceName string, verbose bool) (*PWMFan, error) {
-	// 如果deviceName已经是完整路径
-	if filepath.IsAbs(deviceName) {
-		if _, err := os.Stat(deviceName); err != nil {
+	sel, err := ParseSelector(deviceName)
+	if err != nil {
+		return nil, err
+	}
+
+	if sel.Kind == SelectAuto {
+		// 自动查找PWM风扇
+		pwmPath, err := findPWMDevice(deviceName)
+		if err != nil {
+			return nil, fmt.Errorf("查找PWM设备失败: %w", err)
+		}
+		return createPWMFan(pwmPath, verbose)
+	}
+
+	// 不含通配符的绝对路径保持原来的错误提示
+	if sel.Kind == SelectPath && !strings.ContainsAny(sel.Path, "*?[") {
+		if _, err := os.Stat(sysfs.Path(sel.Path)); err != nil {
 			return nil, fmt.Errorf("PWM设备路径不存在: %w", err)
 		}
-		return createPWMFan(deviceName, verbose)
 	}
 
-	// 自动查找PWM风扇
-	pwmPath, err := findPWMDevice(deviceName)
+	target, err := sel.Resolve()
 	if err != nil {
 		return nil, fmt.Errorf("查找PWM设备失败: %w", err)
 	}
+	if sel.Kind != SelectPath || target.Path != sysfs.Path(sel.Path) {
+		log.Printf("PWM风扇 %s: %s", sel, target)
+	}
 
-	return createPWMFan(pwmPath, verbose)
+	return createPWMFan(target.Path, verbose)
 }
 
 // createPWMFan 创建PWM风扇并设置为手动控制模式
@@ -54,22 +76,42 @@ func createPWMFan(pwmPath string, verbose bool) (*PWMFan, error) {
 	fan := &PWMFan{
 		pwmPath:    pwmPath,
 		enablePath: enablePath,
+		rpmPath:    rpmInputPath(pwmPath),
 		verbose:    verbose,
 	}
 
+	// 只支持几档转速的驱动按档位设置
+	name, _ := os.ReadFile(filepath.Join(filepath.Dir(pwmPath), "name"))
+	chip := strings.TrimSpace(string(name))
+	if n, ok := discreteChips[chip]; ok {
+		fan.levels, _ = LevelValues(n)
+		log.Printf("PWM风扇 %s（%s）只支持%d档转速，PWM向上取整到 %v", pwmPath, chip, n, fan.levels)
+	}
+
 	// 读取原始模式
 	data, err := os.ReadFile(enablePath)
-	if err != nil {
+	if os.IsNotExist(err) && fan.levels != nil {
+		// dell_smm只在BIOS允许时提供pwmN_enable，没有时直接写入pwmN，BIOS可能会覆盖设置
+		log.Printf("PWM风扇 %s 没有 %s，BIOS可能会覆盖设置的档位", pwmPath, filepath.Base(enablePath))
+		fan.enablePath = ""
+	} else if err != nil {
 		return nil, fmt.Errorf("读取风扇模式失败: %w", err)
+	} else if fan.originalMode, err = strconv.Atoi(strings.TrimSpace(string(data))); err != nil {
+		return nil, fmt.Errorf("解析风扇模式失败: %w", err)
 	}
 
-	fan.originalMode, err = strconv.Atoi(string(data[:len(data)-1]))
+	// 读取原始PWM值，原始模式为手动控制时退出后需要恢复
+	fan.originalPWM, err = fan.GetSpeed()
 	if err != nil {
-		return nil, fmt.Errorf("解析风扇模式失败: %w", err)
+		return nil, err
 	}
 
 	if verbose {
-		fmt.Printf("原始风扇模式: %d\n", fan.originalMode)
+		fmt.Printf("原始风扇模式: %d, PWM: %d\n", fan.originalMode, fan.originalPWM)
+	}
+
+	if fan.enablePath == "" {
+		return fan, nil
 	}
 
 	// 设置为手动控制模式 (1 = manual)
@@ -89,6 +131,9 @@ func (f *PWMFan) SetSpeed(pwm int) error {
 	if pwm < 0 || pwm > 255 {
 		return fmt.Errorf("PWM值必须在0-255之间")
 	}
+	if f.levels != nil {
+		pwm = Quantize(pwm, f.levels)
+	}
 
 	pwmStr := strconv.Itoa(pwm) + "\n"
 	if err := os.WriteFile(f.pwmPath, []byte(pwmStr), 0644); err != nil {
@@ -109,7 +154,7 @@ func (f *PWMFan) GetSpeed() (int, error) {
 		return 0, fmt.Errorf("读取风扇速度失败: %w", err)
 	}
 
-	pwm, err := strconv.Atoi(string(data[:len(data)-1]))
+	pwm, err := strconv.Atoi(strings.TrimSpace(string(data)))
 	if err != nil {
 		return 0, fmt.Errorf("解析PWM值失败: %w", err)
 	}
@@ -117,8 +162,110 @@ func (f *PWMFan) GetSpeed() (int, error) {
 	return pwm, nil
 }
 
-// Close 关闭风扇控制器，恢复原始模式
+// GetRPM 获取风扇转速（RPM），读取与pwmN同编号的fanN_input
+func (f *PWMFan) GetRPM() (int, error) {
+	if f.rpmPath == "" {
+		return 0, fmt.Errorf("风扇没有转速输入: %s", f.pwmPath)
+	}
+
+	data, err := os.ReadFile(f.rpmPath)
+	if err != nil {
+		return 0, fmt.Errorf("读取风扇转速失败: %w", err)
+	}
+
+	rpm, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return 0, fmt.Errorf("解析风扇转速失败: %w", err)
+	}
+
+	return rpm, nil
+}
+
+// SetTach 指定风扇的转速输入（默认为与pwmN同编号的fanN_input）
+// tach为风扇选择器，通道写成 fanN、N 或风扇标签（如 "hwmon:nct6775/fan2"），也可以是fanN_input的绝对路径；
+// 很多Super-I/O芯片上pwmN控制的并不是fanN，可以用 fanap probe 测出实际的对应关系
+func (f *PWMFan) SetTach(tach string) error {
+	sel, err := ParseSelector(tach)
+	if err != nil {
+		return err
+	}
+	if sel.Kind == SelectAuto {
+		f.rpmPath = rpmInputPath(f.pwmPath)
+		return nil
+	}
+
+	target, err := sel.ResolveTach()
+	if err != nil {
+		return fmt.Errorf("查找转速输入失败: %w", err)
+	}
+	f.rpmPath = target.Path
+	log.Printf("PWM风扇 %s 的转速输入: %s", f.pwmPath, target)
+	return nil
+}
+
+// SetLevels 指定风扇支持的档位数，PWM向上取整到各档位；0表示连续调速
+// 默认按驱动识别（如dell_smm为3档），驱动不能识别或机型的档位数不同时使用
+func (f *PWMFan) SetLevels(n int) error {
+	if n == 0 {
+		f.levels = nil
+		return nil
+	}
+	levels, err := LevelValues(n)
+	if err != nil {
+		return err
+	}
+	f.levels = levels
+	return nil
+}
+
+// Levels 返回各档位的PWM值，连续调速时为空
+func (f *PWMFan) Levels() []int {
+	return f.levels
+}
+
+// PWMPath 返回pwmN文件的路径
+func (f *PWMFan) PWMPath() string {
+	return f.pwmPath
+}
+
+// TachPath 返回转速输入文件的路径，没有转速输入时返回空字符串
+func (f *PWMFan) TachPath() string {
+	return f.rpmPath
+}
+
+// GetMinRPM 获取风扇的最低转速阈值（fanN_min），不存在时返回0
+func (f *PWMFan) GetMinRPM() (int, error) {
+	if f.rpmPath == "" {
+		return 0, nil
+	}
+
+	data, err := os.ReadFile(strings.TrimSuffix(f.rpmPath, "_input") + "_min")
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("读取风扇最低转速失败: %w", err)
+	}
+
+	rpm, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return 0, fmt.Errorf("解析风扇最低转速失败: %w", err)
+	}
+
+	return rpm, nil
+}
+
+// Close 关闭风扇控制器，恢复原始PWM值和模式
 func (f *PWMFan) Close() error {
+	// 先恢复PWM值，原始模式为手动控制时风扇会保持该值
+	if err := f.SetSpeed(f.originalPWM); err != nil {
+		return fmt.Errorf("恢复风扇PWM失败: %w", err)
+	}
+
+	if f.enablePath == "" {
+		return nil
+	}
+
 	// 恢复原始模式
 	modeStr := strconv.Itoa(f.originalMode) + "\n"
 	if err := os.WriteFile(f.enablePath, []byte(modeStr), 0644); err != nil {
@@ -132,53 +279,43 @@ func (f *PWMFan) Close() error {
 	return nil
 }
 
+// rpmInputPath 返回pwmN对应的fanN_input路径，不存在时返回空字符串
+func rpmInputPath(pwmPath string) string {
+	channel := strings.TrimPrefix(filepath.Base(pwmPath), "pwm")
+	if _, err := strconv.Atoi(channel); err != nil {
+		return ""
+	}
+
+	rpmPath := filepath.Join(filepath.Dir(pwmPath), "fan"+channel+"_input")
+	if _, err := os.Stat(rpmPath); err != nil {
+		return ""
+	}
+	return rpmPath
+}
+
 // findPWMDevice 查找PWM风扇设备
 func findPWMDevice(deviceName string) (string, error) {
-	hwmonPath := "/sys/class/hwmon"
-
-	entries, err := os.ReadDir(hwmonPath)
+	chips, err := hwmon.Scan()
 	if err != nil {
-		return "", fmt.Errorf("读取hwmon目录失败: %w", err)
+		return "", err
 	}
 
-	log.Printf("查找PWM风扇设备，检查 %d 个hwmon设备", len(entries))
+	log.Printf("查找PWM风扇设备，检查 %d 个hwmon设备", len(chips))
 
 	var availablePWMs []string
 
-	// 遍历所有hwmon设备
-	for _, entry := range entries {
-		if !entry.IsDir() {
-			continue
-		}
+	for _, chip := range chips {
+		log.Printf("检查设备: %s (名称: %s)", chip.ID, chip.Name)
 
-		devicePath := filepath.Join(hwmonPath, entry.Name(), "name")
-		deviceNameStr := "unknown"
-		if data, err := os.ReadFile(devicePath); err == nil {
-			deviceNameStr = strings.TrimSpace(string(data))
-		}
+		for _, p := range chip.PWMs {
+			pwmInfo := fmt.Sprintf("%s/%s (%s - %s)", chip.ID, p.Name(), chip.Name, p.Label)
+			availablePWMs = append(availablePWMs, pwmInfo)
+			log.Printf("  找到PWM: %s", pwmInfo)
 
-		log.Printf("检查设备: %s (名称: %s)", entry.Name(), deviceNameStr)
-
-		// 查找PWM输出
-		for i := 1; i <= 10; i++ {
-			pwmPath := filepath.Join(hwmonPath, entry.Name(), fmt.Sprintf("pwm%d", i))
-			if _, err := os.Stat(pwmPath); err == nil {
-				// 查找风扇标签
-				labelPath := filepath.Join(hwmonPath, entry.Name(), fmt.Sprintf("fan%d_label", i))
-				label := ""
-				if data, err := os.ReadFile(labelPath); err == nil {
-					label = strings.TrimSpace(string(data))
-				}
-
-				pwmInfo := fmt.Sprintf("%s/pwm%d (%s - %s)", entry.Name(), i, deviceNameStr, label)
-				availablePWMs = append(availablePWMs, pwmInfo)
-				log.Printf("  找到PWM: %s", pwmInfo)
-
-				// 匹配风扇相关的设备名称
-				if isFanDevice(deviceNameStr) {
-					log.Printf("  ✓ 选择此PWM设备")
-					return pwmPath, nil
-				}
+			// 匹配风扇相关的设备名称
+			if hwmon.IsFanDevice(chip.Name) {
+				log.Printf("  ✓ 选择此PWM设备")
+				return p.Path, nil
 			}
 		}
 	}
@@ -195,32 +332,3 @@ func findPWMDevice(deviceName string) (string, error) {
 
 	return "", fmt.Errorf("未找到可用的PWM风扇设备，请使用-pwm参数指定完整路径，例如: /sys/class/hwmon/hwmon0/pwm1")
 }
-
-// isFanDevice 判断是否为风扇设备
-func isFanDevice(deviceName string) bool {
-	fanPatterns := []string{
-		`(?i)fan`,
-		`(?i)pwm`,
-		`(?i)asus`,
-		`(?i)nct6775`,
-		`(?i)it87`,
-	}
-
-	for _, pattern := range fanPatterns {
-		if regexp.MustCompile(pattern).MatchString(deviceName) {
-			return true
-		}
-	}
-	return false
-}
-
-// findPWMOutput 查找PWM输出文件
-func findPWMOutput(hwmonPath string) string {
-	for i := 1; i <= 10; i++ {
-		pwmPath := filepath.Join(hwmonPath, fmt.Sprintf("pwm%d", i))
-		if _, err := os.Stat(pwmPath); err == nil {
-			return pwmPath
-		}
-	}
-	return ""
-}
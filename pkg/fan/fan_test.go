@@ -0,0 +1,94 @@
+package fan
+
+import (
+	"testing"
+
+	"github.com/fanap/pkg/sysfs"
+	"github.com/fanap/pkg/sysfs/fixture"
+)
+
+func TestPWMFanClose(t *testing.T) {
+	tests := []struct {
+		name     string
+		chip     string
+		pwm      int
+		enable   int
+		set      int
+		wantSet  int
+		wantPWM  int
+		wantMode int
+	}{
+		{"自动模式", "nct6775", 120, 5, 200, 200, 120, 5},
+		{"BIOS模式", "it87", 80, 2, 30, 30, 80, 2},
+		{"原本为手动模式", "nct6775", 77, 1, 255, 255, 77, 1},
+		{"PWM为0", "nct6775", 0, 2, 100, 100, 0, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tree, err := fixture.New(t.TempDir())
+			if err != nil {
+				t.Fatal(err)
+			}
+			sysfs.SetRoot(tree.Root)
+			t.Cleanup(func() { sysfs.SetRoot("") })
+
+			h, err := tree.AddHWMon(tt.chip, "platform/"+tt.chip)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := h.AddPWM(1, tt.pwm, tt.enable); err != nil {
+				t.Fatal(err)
+			}
+
+			f, err := NewPWMFan("/sys/class/hwmon/hwmon0/pwm1", false)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// 打开后切换为手动模式
+			if mode, _ := h.GetInt("pwm1_enable"); mode != 1 {
+				t.Errorf("打开后 pwm1_enable = %d, 期望 1", mode)
+			}
+
+			if err := f.SetSpeed(tt.set); err != nil {
+				t.Fatal(err)
+			}
+			if pwm, _ := h.GetInt("pwm1"); pwm != tt.wantSet {
+				t.Errorf("SetSpeed(%d) 后 pwm1 = %d, 期望 %d", tt.set, pwm, tt.wantSet)
+			}
+
+			if err := f.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if pwm, _ := h.GetInt("pwm1"); pwm != tt.wantPWM {
+				t.Errorf("关闭后 pwm1 = %d, 期望 %d", pwm, tt.wantPWM)
+			}
+			if mode, _ := h.GetInt("pwm1_enable"); mode != tt.wantMode {
+				t.Errorf("关闭后 pwm1_enable = %d, 期望 %d", mode, tt.wantMode)
+			}
+		})
+	}
+}
+
+func TestPWMFanNoEnable(t *testing.T) {
+	tree, err := fixture.New(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	sysfs.SetRoot(tree.Root)
+	t.Cleanup(func() { sysfs.SetRoot("") })
+
+	h, err := tree.AddHWMon("nct6775", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := h.SetInt("pwm1", 100); err != nil {
+		t.Fatal(err)
+	}
+
+	// 连续调速的芯片必须有pwmN_enable
+	if _, err := NewPWMFan("/sys/class/hwmon/hwmon0/pwm1", false); err == nil {
+		t.Error("没有 pwm1_enable 时应返回错误")
+	}
+}
@@ -0,0 +1,55 @@
+package fan
+
+import "fmt"
+
+// discreteChips 只支持少数几档转速的hwmon驱动及其档位数
+// dell-smm-hwmon（dell_smm）把pwmN映射到BIOS的风扇档位，大多数机型为关、低、高三档
+var discreteChips = map[string]int{
+	"dell_smm": 3,
+}
+
+// LevelValues 返回n档风扇各档位对应的PWM值，与dell-smm-hwmon的换算一致（3档为0、128、255）
+func LevelValues(n int) ([]int, error) {
+	if n < 2 || n > 256 {
+		return nil, fmt.Errorf("档位数必须在2-256之间")
+	}
+	step := (255 + n - 2) / (n - 1)
+	values := make([]int, n)
+	for i := range values {
+		values[i] = i * step
+		if values[i] > 255 {
+			values[i] = 255
+		}
+	}
+	values[n-1] = 255
+	return values, nil
+}
+
+// Quantize 把PWM向上取整到不低于它的档位，保证冷却能力不低于计算结果；只有0对应最低档
+func Quantize(pwm int, values []int) int {
+	for _, v := range values {
+		if v >= pwm {
+			return v
+		}
+	}
+	return values[len(values)-1]
+}
+
+// QuantizeRange 把PWM向上取整到 [min, max] 内的档位，高于范围内最高的档位时取该档位，不超过最大PWM
+// 范围内没有档位时返回false
+func QuantizeRange(pwm, min, max int, values []int) (int, bool) {
+	level, ok := 0, false
+	for _, v := range values {
+		if v < min {
+			continue
+		}
+		if v > max {
+			break
+		}
+		level, ok = v, true
+		if v >= pwm {
+			break
+		}
+	}
+	return level, ok
+}
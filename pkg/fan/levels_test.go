@@ -0,0 +1,127 @@
+package fan
+
+import (
+	"testing"
+
+	"github.com/fanap/pkg/sysfs"
+	"github.com/fanap/pkg/sysfs/fixture"
+)
+
+func TestQuantize(t *testing.T) {
+	three, err := LevelValues(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		pwm  int
+		want int
+	}{
+		{0, 0},
+		{1, 128},
+		{128, 128},
+		{129, 255},
+		{255, 255},
+	}
+	for _, tt := range tests {
+		if got := Quantize(tt.pwm, three); got != tt.want {
+			t.Errorf("Quantize(%d, %v) = %d, 期望 %d", tt.pwm, three, got, tt.want)
+		}
+	}
+
+	if _, err := LevelValues(1); err == nil {
+		t.Error("LevelValues(1) 应返回错误")
+	}
+}
+
+func TestQuantizeRange(t *testing.T) {
+	three, err := LevelValues(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		pwm, min, max int
+		want          int
+		wantOK        bool
+	}{
+		{0, 0, 255, 0, true},
+		{129, 0, 255, 255, true},
+		{129, 0, 200, 128, true},
+		{200, 0, 200, 128, true},
+		{1, 1, 200, 128, true},
+		{0, 1, 255, 128, true},
+		{50, 0, 127, 0, true},
+		{150, 150, 200, 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := QuantizeRange(tt.pwm, tt.min, tt.max, three)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("QuantizeRange(%d, %d, %d) = %d, %v, 期望 %d, %v", tt.pwm, tt.min, tt.max, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestPWMFanLevels(t *testing.T) {
+	tests := []struct {
+		name     string
+		enable   int // -1 表示没有pwm1_enable
+		set      int
+		wantSet  int
+		wantMode int
+	}{
+		{"dell_smm档位", 2, 132, 255, 2},
+		{"dell_smm没有enable", -1, 10, 128, -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tree, err := fixture.New(t.TempDir())
+			if err != nil {
+				t.Fatal(err)
+			}
+			sysfs.SetRoot(tree.Root)
+			t.Cleanup(func() { sysfs.SetRoot("") })
+
+			h, err := tree.AddHWMon("dell_smm", "platform/dell_smm")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := h.AddPWM(1, 128, tt.enable); err != nil {
+				t.Fatal(err)
+			}
+			if tt.enable < 0 {
+				if err := h.Remove("pwm1_enable"); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			f, err := NewPWMFan("/sys/class/hwmon/hwmon0/pwm1", false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if f.Levels() == nil {
+				t.Error("dell_smm 风扇期望按档位调速")
+			}
+
+			if err := f.SetSpeed(tt.set); err != nil {
+				t.Fatal(err)
+			}
+			if pwm, _ := h.GetInt("pwm1"); pwm != tt.wantSet {
+				t.Errorf("SetSpeed(%d) 后 pwm1 = %d, 期望 %d", tt.set, pwm, tt.wantSet)
+			}
+
+			if err := f.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if pwm, _ := h.GetInt("pwm1"); pwm != 128 {
+				t.Errorf("关闭后 pwm1 = %d, 期望 128", pwm)
+			}
+			if tt.wantMode >= 0 {
+				if mode, _ := h.GetInt("pwm1_enable"); mode != tt.wantMode {
+					t.Errorf("关闭后 pwm1_enable = %d, 期望 %d", mode, tt.wantMode)
+				}
+			}
+		})
+	}
+}
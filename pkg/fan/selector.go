@@ -0,0 +1,285 @@
+package fan
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/fanap/pkg/hwmon"
+	"github.com/fanap/pkg/sysfs"
+)
+
+// 风扇选择器类型
+const (
+	SelectAuto   = "auto"
+	SelectPath   = "path"
+	SelectHWMon  = "hwmon"
+	SelectDevice = "device"
+)
+
+// Selector PWM风扇选择器
+//
+//	auto                                    自动检测
+//	/sys/class/hwmon/hwmon1/pwm1            pwmN的绝对路径，可以包含通配符
+//	hwmon:nct6775/pwm2                      按hwmon芯片名称（name）和通道
+//	hwmon:nct6775/CPU Fan                   按芯片名称和风扇标签（fanN_label）
+//	hwmon:nct6775                           芯片的第一个PWM通道
+//	device:platform/nct6775.656/pwm2        按hwmon所属设备的路径（/sys/devices下，可以只写末尾部分）和通道
+//	device:0000:01:00.0/2                   PCI设备的第2个PWM通道
+//
+// 通道可以写成 pwmN 或 N；芯片名称、设备路径和标签可以使用 filepath.Match 的通配符。
+// 选择器不依赖hwmonN的编号，重启后编号变化时仍然选中同一个风扇
+type Selector struct {
+	// Kind 选择器类型
+	Kind string
+	// Path SelectPath 的绝对路径
+	Path string
+	// Chip SelectHWMon 的芯片名称
+	Chip string
+	// Device SelectDevice 的设备路径
+	Device string
+	// Channel 通道（pwmN、N）或风扇标签，为空表示第一个PWM通道
+	Channel string
+}
+
+// ParseSelector 解析风扇选择器
+func ParseSelector(s string) (Selector, error) {
+	s = strings.TrimSpace(s)
+	switch {
+	case s == "" || s == SelectAuto:
+		return Selector{Kind: SelectAuto}, nil
+	case filepath.IsAbs(s):
+		if _, err := filepath.Match(s, ""); err != nil {
+			return Selector{}, fmt.Errorf("无效的路径通配符 %q", s)
+		}
+		return Selector{Kind: SelectPath, Path: s}, nil
+	}
+
+	kind, rest, ok := strings.Cut(s, ":")
+	if !ok {
+		return Selector{}, fmt.Errorf("无效的风扇选择器 %q（可选: auto、绝对路径、hwmon:芯片/通道、device:设备路径/通道）", s)
+	}
+
+	var sel Selector
+	switch kind {
+	case SelectHWMon:
+		chip, channel, _ := strings.Cut(rest, "/")
+		if chip == "" {
+			return Selector{}, fmt.Errorf("风扇选择器 %q 缺少芯片名称", s)
+		}
+		sel = Selector{Kind: SelectHWMon, Chip: chip, Channel: channel}
+	case SelectDevice:
+		i := strings.LastIndex(rest, "/")
+		if i <= 0 || i == len(rest)-1 {
+			return Selector{}, fmt.Errorf("风扇选择器 %q 需要同时指定设备路径和通道（如 device:platform/nct6775.656/pwm1）", s)
+		}
+		sel = Selector{Kind: SelectDevice, Device: strings.Trim(rest[:i], "/"), Channel: rest[i+1:]}
+	default:
+		return Selector{}, fmt.Errorf("未知的风扇选择器类型 %q（可选: hwmon、device）", kind)
+	}
+
+	for _, pattern := range []string{sel.Chip, sel.Device, sel.Channel} {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return Selector{}, fmt.Errorf("风扇选择器 %q 中的通配符无效", s)
+		}
+	}
+	return sel, nil
+}
+
+// String 返回选择器的文本形式
+func (s Selector) String() string {
+	switch s.Kind {
+	case SelectPath:
+		return s.Path
+	case SelectHWMon:
+		if s.Channel == "" {
+			return SelectHWMon + ":" + s.Chip
+		}
+		return SelectHWMon + ":" + s.Chip + "/" + s.Channel
+	case SelectDevice:
+		return SelectDevice + ":" + s.Device + "/" + s.Channel
+	}
+	return SelectAuto
+}
+
+// Target 选择器解析出的PWM输出或转速输入
+type Target struct {
+	// Path pwmN或fanN_input文件（已映射到sysfs根目录下）
+	Path string
+	// Chip hwmon芯片名称
+	Chip string
+	// Device hwmon所属设备在 /sys/devices 下的路径，虚拟设备为空
+	Device string
+	// Label 风扇标签（fanN_label），没有标签时为通道名（pwmN、fanN）
+	Label string
+}
+
+// String 描述通道，用于日志
+func (t Target) String() string {
+	if t.Device == "" {
+		return fmt.Sprintf("%s (%s/%s)", t.Path, t.Chip, t.Label)
+	}
+	return fmt.Sprintf("%s (%s/%s, 设备 %s)", t.Path, t.Chip, t.Label, t.Device)
+}
+
+// channelKind 选择器可以解析的通道类型：PWM输出（pwmN）或转速输入（fanN_input）
+type channelKind struct {
+	// prefix 通道名前缀，通道只写编号时补上
+	prefix string
+	// what 通道类型的名称，用于日志和错误
+	what string
+	// list 返回芯片中该类型的所有通道
+	list func(hwmon.Chip) []channel
+}
+
+// channel 芯片中的一个通道
+type channel struct {
+	// name 通道名，如 "pwm1"、"fan2"
+	name   string
+	target Target
+}
+
+var (
+	pwmChannels = channelKind{prefix: "pwm", what: "PWM输出", list: func(chip hwmon.Chip) []channel {
+		var chs []channel
+		for _, pwm := range chip.PWMs {
+			chs = append(chs, channel{name: pwm.Name(), target: newTarget(chip, pwm.Path, pwm.Label, pwm.Name())})
+		}
+		return chs
+	}}
+	tachChannels = channelKind{prefix: "fan", what: "转速输入", list: func(chip hwmon.Chip) []channel {
+		var chs []channel
+		for _, f := range chip.Fans {
+			chs = append(chs, channel{name: f.Name(), target: newTarget(chip, f.Input, f.Label, f.Name())})
+		}
+		return chs
+	}}
+)
+
+// Resolve 解析选择器，找到对应的PWM输出
+// 有多个匹配时使用第一个，并记录所有匹配项
+func (s Selector) Resolve() (Target, error) {
+	return s.resolve(pwmChannels)
+}
+
+// ResolveTach 按同样的选择器格式查找转速输入（fanN_input），通道写成 fanN、N 或风扇标签，
+// 如 "hwmon:nct6775/fan2"、"device:platform/nct6775.656/2"
+func (s Selector) ResolveTach() (Target, error) {
+	return s.resolve(tachChannels)
+}
+
+// resolve 解析选择器，找到对应类型的通道
+func (s Selector) resolve(kind channelKind) (Target, error) {
+	var matches []Target
+	switch s.Kind {
+	case SelectPath:
+		paths, err := filepath.Glob(sysfs.Path(s.Path))
+		if err != nil {
+			return Target{}, fmt.Errorf("无效的路径通配符 %q", s.Path)
+		}
+		sort.Strings(paths)
+		for _, p := range paths {
+			chip, err := hwmon.Open(filepath.Dir(p))
+			if err != nil {
+				continue
+			}
+			// 通配符可能同时匹配到 pwmN_enable 等属性文件，只保留对应类型的通道
+			for _, ch := range kind.list(chip) {
+				if ch.target.Path == p {
+					matches = append(matches, ch.target)
+				}
+			}
+		}
+	case SelectHWMon, SelectDevice:
+		var err error
+		matches, err = s.resolveHWMon(kind)
+		if err != nil {
+			return Target{}, err
+		}
+	default:
+		return Target{}, fmt.Errorf("选择器 %q 需要自动检测", s)
+	}
+
+	if len(matches) == 0 {
+		return Target{}, fmt.Errorf("未找到与 %q 匹配的%s", s, kind.what)
+	}
+	if len(matches) > 1 {
+		log.Printf("风扇选择器 %q 匹配到 %d 个%s，使用第一个:", s, len(matches), kind.what)
+		for _, m := range matches {
+			log.Printf("  %s", m)
+		}
+	}
+	return matches[0], nil
+}
+
+// resolveHWMon 按芯片名称或设备路径以及通道查找
+func (s Selector) resolveHWMon(kind channelKind) ([]Target, error) {
+	chips, err := hwmon.Scan()
+	if err != nil {
+		return nil, err
+	}
+
+	var targets []Target
+	for _, chip := range chips {
+		switch s.Kind {
+		case SelectHWMon:
+			if ok, _ := filepath.Match(s.Chip, chip.Name); !ok {
+				continue
+			}
+		case SelectDevice:
+			if !matchDevice(s.Device, chip.Device) {
+				continue
+			}
+		}
+
+		for _, ch := range kind.list(chip) {
+			if s.Channel != "" && !matchChannel(s.Channel, kind.prefix, ch.name, ch.target.Label) {
+				continue
+			}
+			targets = append(targets, ch.target)
+			if s.Channel == "" {
+				// 未指定通道时使用第一个通道
+				break
+			}
+		}
+	}
+	return targets, nil
+}
+
+// newTarget 返回通道的信息，没有标签时使用通道名
+func newTarget(chip hwmon.Chip, path, label, name string) Target {
+	t := Target{Path: path, Chip: chip.Name, Device: chip.Device, Label: label}
+	if t.Label == "" {
+		t.Label = name
+	}
+	return t
+}
+
+// matchChannel 通道可以写成通道名（pwmN、fanN）、编号N或风扇标签
+func matchChannel(pattern, prefix, channel, label string) bool {
+	if _, err := strconv.Atoi(pattern); err == nil {
+		pattern = prefix + pattern
+	}
+	if ok, _ := filepath.Match(pattern, channel); ok {
+		return true
+	}
+	ok, _ := filepath.Match(pattern, label)
+	return ok
+}
+
+// matchDevice 设备路径与完整路径或其末尾的若干级匹配
+func matchDevice(pattern, device string) bool {
+	if device == "" {
+		return false
+	}
+	parts := strings.Split(device, "/")
+	for i := range parts {
+		if ok, _ := filepath.Match(pattern, strings.Join(parts[i:], "/")); ok {
+			return true
+		}
+	}
+	return false
+}
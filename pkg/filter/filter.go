@@ -0,0 +1,312 @@
+// Package filter 实现温度读数的滤波：移动平均、指数平滑、中值滤波和尖峰抑制，
+// 多个滤波器可以组合成一条滤波链
+package filter
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// 滤波器类型
+const (
+	TypeMovingAverage = "average"
+	TypeEMA           = "ema"
+	TypeMedian        = "median"
+	TypeSpike         = "spike"
+)
+
+// Filter 滤波器接口
+type Filter interface {
+	// Apply 输入一个新的读数，返回滤波后的值
+	Apply(v float64) float64
+	// Reset 清除历史状态
+	Reset()
+}
+
+// Spec 滤波器参数
+type Spec struct {
+	// Type 滤波器类型: average、ema、median、spike
+	Type string `json:"type"`
+	// Window average和median的窗口大小
+	Window int `json:"window,omitempty"`
+	// Alpha ema的平滑系数 (0-1]，越小越平滑
+	Alpha float64 `json:"alpha,omitempty"`
+	// MaxDelta spike允许的单次最大变化（°C）
+	MaxDelta float64 `json:"max_delta,omitempty"`
+	// MaxRejects spike连续丢弃多少个读数后接受新的温度水平，0表示3
+	MaxRejects int `json:"max_rejects,omitempty"`
+}
+
+// SpecError 指向具体参数的错误
+type SpecError struct {
+	// Field 出错的参数: type、window、alpha、max_delta、max_rejects
+	Field string
+	Msg   string
+}
+
+func (e *SpecError) Error() string {
+	return e.Msg
+}
+
+// Validate 验证滤波器参数
+func (s Spec) Validate() error {
+	switch s.Type {
+	case TypeMovingAverage, TypeMedian:
+		if s.Window < 1 {
+			return &SpecError{Field: "window", Msg: fmt.Sprintf("%s滤波的窗口大小必须大于0", s.Type)}
+		}
+	case TypeEMA:
+		if s.Alpha <= 0 || s.Alpha > 1 {
+			return &SpecError{Field: "alpha", Msg: "ema滤波的alpha必须在(0, 1]之间"}
+		}
+	case TypeSpike:
+		if s.MaxDelta <= 0 {
+			return &SpecError{Field: "max_delta", Msg: "spike滤波的max_delta必须大于0"}
+		}
+		if s.MaxRejects < 0 {
+			return &SpecError{Field: "max_rejects", Msg: "spike滤波的max_rejects不能为负数"}
+		}
+	default:
+		return &SpecError{Field: "type", Msg: fmt.Sprintf("未知的滤波器类型 %q（可选: average、ema、median、spike）", s.Type)}
+	}
+	return nil
+}
+
+// New 根据参数创建滤波器
+func New(s Spec) (Filter, error) {
+	if err := s.Validate(); err != nil {
+		return nil, err
+	}
+
+	switch s.Type {
+	case TypeMovingAverage:
+		return NewMovingAverage(s.Window), nil
+	case TypeMedian:
+		return NewMedian(s.Window), nil
+	case TypeEMA:
+		return NewEMA(s.Alpha), nil
+	default:
+		return NewSpikeReject(s.MaxDelta, s.MaxRejects), nil
+	}
+}
+
+// NewChain 根据参数列表创建滤波链
+func NewChain(specs []Spec) (Filter, error) {
+	var filters []Filter
+	for i, s := range specs {
+		f, err := New(s)
+		if err != nil {
+			return nil, fmt.Errorf("第%d个滤波器: %w", i+1, err)
+		}
+		filters = append(filters, f)
+	}
+	return Chain(filters), nil
+}
+
+// Parse 解析 "类型:参数,..." 格式的滤波链，如 "spike:10,median:5,ema:0.3"
+// average和median的参数是窗口大小，ema的参数是alpha，spike的参数是max_delta
+func Parse(s string) ([]Spec, error) {
+	var specs []Spec
+	for i, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		parts := strings.SplitN(item, ":", 2)
+		spec := Spec{Type: strings.TrimSpace(parts[0])}
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("第%d个滤波器 %q 格式错误，应为 类型:参数", i+1, item)
+		}
+		arg := strings.TrimSpace(parts[1])
+
+		var err error
+		switch spec.Type {
+		case TypeMovingAverage, TypeMedian:
+			spec.Window, err = strconv.Atoi(arg)
+		case TypeEMA:
+			spec.Alpha, err = strconv.ParseFloat(arg, 64)
+		case TypeSpike:
+			spec.MaxDelta, err = strconv.ParseFloat(arg, 64)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("第%d个滤波器的参数 %q 无效", i+1, arg)
+		}
+		if err := spec.Validate(); err != nil {
+			return nil, fmt.Errorf("第%d个滤波器: %w", i+1, err)
+		}
+
+		specs = append(specs, spec)
+	}
+	return specs, nil
+}
+
+// Chain 依次应用多个滤波器
+type Chain []Filter
+
+// Apply 依次应用所有滤波器
+func (c Chain) Apply(v float64) float64 {
+	for _, f := range c {
+		v = f.Apply(v)
+	}
+	return v
+}
+
+// Reset 清除所有滤波器的状态
+func (c Chain) Reset() {
+	for _, f := range c {
+		f.Reset()
+	}
+}
+
+// MovingAverage 移动平均
+type MovingAverage struct {
+	window []float64
+	size   int
+	next   int
+	count  int
+}
+
+// NewMovingAverage 创建窗口大小为n的移动平均滤波器
+func NewMovingAverage(n int) *MovingAverage {
+	return &MovingAverage{window: make([]float64, n), size: n}
+}
+
+// Apply 返回最近n个读数的平均值
+func (f *MovingAverage) Apply(v float64) float64 {
+	f.window[f.next] = v
+	f.next = (f.next + 1) % f.size
+	if f.count < f.size {
+		f.count++
+	}
+
+	sum := 0.0
+	for i := 0; i < f.count; i++ {
+		sum += f.window[i]
+	}
+	return sum / float64(f.count)
+}
+
+// Reset 清除历史读数
+func (f *MovingAverage) Reset() {
+	f.next, f.count = 0, 0
+}
+
+// EMA 指数移动平均
+type EMA struct {
+	alpha float64
+	value float64
+	init  bool
+}
+
+// NewEMA 创建平滑系数为alpha的指数移动平均滤波器
+func NewEMA(alpha float64) *EMA {
+	return &EMA{alpha: alpha}
+}
+
+// Apply 返回 alpha*v + (1-alpha)*上一个值
+func (f *EMA) Apply(v float64) float64 {
+	if !f.init {
+		f.value, f.init = v, true
+		return v
+	}
+	f.value = f.alpha*v + (1-f.alpha)*f.value
+	return f.value
+}
+
+// Reset 清除历史状态
+func (f *EMA) Reset() {
+	f.init = false
+}
+
+// Median 中值滤波
+type Median struct {
+	window []float64
+	size   int
+	next   int
+	count  int
+}
+
+// NewMedian 创建窗口大小为n的中值滤波器
+func NewMedian(n int) *Median {
+	return &Median{window: make([]float64, n), size: n}
+}
+
+// Apply 返回最近n个读数的中值
+func (f *Median) Apply(v float64) float64 {
+	f.window[f.next] = v
+	f.next = (f.next + 1) % f.size
+	if f.count < f.size {
+		f.count++
+	}
+
+	sorted := append([]float64(nil), f.window[:f.count]...)
+	sort.Float64s(sorted)
+	mid := f.count / 2
+	if f.count%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
+	}
+	return sorted[mid]
+}
+
+// Reset 清除历史读数
+func (f *Median) Reset() {
+	f.next, f.count = 0, 0
+}
+
+// DefaultMaxRejects 尖峰抑制默认连续丢弃的读数个数
+const DefaultMaxRejects = 3
+
+// SpikeReject 尖峰抑制
+// 与上一个接受的读数相差超过maxDelta的读数被视为尖峰并丢弃（返回上一个接受的值），
+// 连续丢弃maxRejects个读数后认为温度确实发生了变化，接受新的读数
+type SpikeReject struct {
+	maxDelta   float64
+	maxRejects int
+	last       float64
+	init       bool
+	rejects    int
+}
+
+// NewSpikeReject 创建尖峰抑制滤波器，maxRejects为0时使用默认值
+func NewSpikeReject(maxDelta float64, maxRejects int) *SpikeReject {
+	if maxRejects == 0 {
+		maxRejects = DefaultMaxRejects
+	}
+	return &SpikeReject{maxDelta: maxDelta, maxRejects: maxRejects}
+}
+
+// Apply 丢弃单次突变的读数
+func (f *SpikeReject) Apply(v float64) float64 {
+	if !f.init || math.Abs(v-f.last) <= f.maxDelta || f.rejects >= f.maxRejects {
+		f.last, f.init, f.rejects = v, true, 0
+		return v
+	}
+
+	f.rejects++
+	return f.last
+}
+
+// Reset 清除历史状态
+func (f *SpikeReject) Reset() {
+	f.init, f.rejects = false, 0
+}
+
+// Describe 将滤波器参数格式化为 Parse 可以解析的字符串
+func Describe(specs []Spec) string {
+	items := make([]string, len(specs))
+	for i, s := range specs {
+		switch s.Type {
+		case TypeMovingAverage, TypeMedian:
+			items[i] = fmt.Sprintf("%s:%d", s.Type, s.Window)
+		case TypeEMA:
+			items[i] = fmt.Sprintf("%s:%g", s.Type, s.Alpha)
+		default:
+			items[i] = fmt.Sprintf("%s:%g", s.Type, s.MaxDelta)
+		}
+	}
+	return strings.Join(items, ",")
+}
@@ -0,0 +1,107 @@
+package filter
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestChain(t *testing.T) {
+	tests := []struct {
+		name  string
+		specs []Spec
+		in    []float64
+		want  []float64
+	}{
+		{"移动平均", []Spec{{Type: TypeMovingAverage, Window: 3}}, []float64{10, 20, 30, 40}, []float64{10, 15, 20, 30}},
+		{"指数平滑", []Spec{{Type: TypeEMA, Alpha: 0.5}}, []float64{10, 20, 30}, []float64{10, 15, 22.5}},
+		{"中值", []Spec{{Type: TypeMedian, Window: 3}}, []float64{10, 50, 20, 30, 40}, []float64{10, 30, 20, 30, 30}},
+		{"尖峰抑制", []Spec{{Type: TypeSpike, MaxDelta: 5, MaxRejects: 2}}, []float64{40, 41, 80, 42, 80, 80, 80}, []float64{40, 41, 41, 42, 42, 42, 80}},
+		{"尖峰抑制默认次数", []Spec{{Type: TypeSpike, MaxDelta: 5}}, []float64{40, 80, 80, 80, 80}, []float64{40, 40, 40, 40, 80}},
+		{"中值后平滑", []Spec{{Type: TypeMedian, Window: 3}, {Type: TypeEMA, Alpha: 0.5}}, []float64{40, 42, 90, 44}, []float64{40, 40.5, 41.25, 42.625}},
+		{"空的滤波链", nil, []float64{40, 90}, []float64{40, 90}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := NewChain(tt.specs)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for round := 0; round < 2; round++ {
+				got := make([]float64, len(tt.in))
+				for i, v := range tt.in {
+					got[i] = f.Apply(v)
+				}
+				if !reflect.DeepEqual(got, tt.want) {
+					t.Fatalf("第%d轮输出 = %v, 期望 %v", round+1, got, tt.want)
+				}
+				// Reset 后与新建的滤波链输出相同
+				f.Reset()
+			}
+		})
+	}
+}
+
+func TestNewChainErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		specs     []Spec
+		wantField string
+	}{
+		{"未知类型", []Spec{{Type: "kalman"}}, "type"},
+		{"窗口为0", []Spec{{Type: TypeMedian}}, "window"},
+		{"alpha超出范围", []Spec{{Type: TypeEMA, Alpha: 1.5}}, "alpha"},
+		{"alpha为0", []Spec{{Type: TypeEMA}}, "alpha"},
+		{"max_delta为0", []Spec{{Type: TypeSpike}}, "max_delta"},
+		{"负的max_rejects", []Spec{{Type: TypeSpike, MaxDelta: 5, MaxRejects: -1}}, "max_rejects"},
+		{"第二个滤波器", []Spec{{Type: TypeMedian, Window: 3}, {Type: TypeMovingAverage, Window: -1}}, "window"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewChain(tt.specs)
+			var se *SpecError
+			if !errors.As(err, &se) {
+				t.Fatalf("NewChain() = %v, 期望 *SpecError", err)
+			}
+			if se.Field != tt.wantField {
+				t.Errorf("错误指向 %s, 期望 %s", se.Field, tt.wantField)
+			}
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    []Spec
+		wantErr bool
+	}{
+		{"spike:10,median:5,ema:0.3", []Spec{{Type: TypeSpike, MaxDelta: 10}, {Type: TypeMedian, Window: 5}, {Type: TypeEMA, Alpha: 0.3}}, false},
+		{" average : 4 ,", []Spec{{Type: TypeMovingAverage, Window: 4}}, false},
+		{"", nil, false},
+		{"median", nil, true},
+		{"median:x", nil, true},
+		{"median:0", nil, true},
+		{"ema:2", nil, true},
+		{"kalman:1", nil, true},
+	}
+
+	for _, tt := range tests {
+		got, err := Parse(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Parse(%q) 错误 = %v, 期望出错 %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Parse(%q) = %+v, 期望 %+v", tt.in, got, tt.want)
+		}
+	}
+
+	specs := []Spec{{Type: TypeSpike, MaxDelta: 7.5}, {Type: TypeMedian, Window: 5}, {Type: TypeEMA, Alpha: 0.25}}
+	got, err := Parse(Describe(specs))
+	if err != nil || !reflect.DeepEqual(got, specs) {
+		t.Errorf("Parse(Describe()) = %+v, %v, 期望 %+v", got, err, specs)
+	}
+}
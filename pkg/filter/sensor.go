@@ -0,0 +1,122 @@
+package filter
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Source 温度来源
+type Source interface {
+	GetTemperature() (float64, error)
+	Close() error
+}
+
+// Sensor 带滤波的温度传感器
+// sampleInterval大于0时在后台以该间隔采样并滤波，GetTemperature返回最新的滤波结果，
+// 这样可以以比控制间隔更高的频率采样；否则每次GetTemperature时读取并滤波
+type Sensor struct {
+	src    Source
+	filter Filter
+
+	mu      sync.Mutex
+	value   float64
+	lastErr error
+
+	stopChan chan struct{}
+	done     chan struct{}
+}
+
+// NewSensor 创建带滤波的温度传感器
+func NewSensor(src Source, f Filter, sampleInterval time.Duration) *Sensor {
+	s := &Sensor{src: src, filter: f}
+
+	if sampleInterval > 0 {
+		s.stopChan = make(chan struct{})
+		s.done = make(chan struct{})
+		s.sample()
+		go s.sampleLoop(sampleInterval)
+	}
+
+	return s
+}
+
+// GetTemperature 返回滤波后的温度
+// 最近一次采样失败时返回该错误
+func (s *Sensor) GetTemperature() (float64, error) {
+	if s.stopChan == nil {
+		s.sample()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.lastErr != nil {
+		return 0, s.lastErr
+	}
+	return s.value, nil
+}
+
+// Raw 返回底层的温度来源
+func (s *Sensor) Raw() Source {
+	return s.src
+}
+
+// Stop 停止后台采样，不关闭底层传感器，之后每次GetTemperature时读取并滤波
+// 重新设置滤波链时用于丢弃原来的包装，底层传感器继续使用
+func (s *Sensor) Stop() {
+	if s.stopChan != nil {
+		close(s.stopChan)
+		<-s.done
+		s.stopChan = nil
+	}
+}
+
+// Close 停止后台采样并关闭底层传感器
+func (s *Sensor) Close() error {
+	s.Stop()
+	return s.src.Close()
+}
+
+// sampleLoop 后台采样循环
+func (s *Sensor) sampleLoop(interval time.Duration) {
+	defer close(s.done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stopChan:
+			return
+		case <-ticker.C:
+			s.safeSample()
+		}
+	}
+}
+
+// safeSample 在后台goroutine中采样，panic时记录为采样错误，不让整个进程崩溃
+func (s *Sensor) safeSample() {
+	defer func() {
+		if r := recover(); r != nil {
+			s.mu.Lock()
+			s.lastErr = fmt.Errorf("采样异常: %v", r)
+			s.mu.Unlock()
+		}
+	}()
+	s.sample()
+}
+
+// sample 读取一次温度并送入滤波器
+func (s *Sensor) sample() {
+	v, err := s.src.GetTemperature()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lastErr = err
+	if err != nil {
+		return
+	}
+	s.value = s.filter.Apply(v)
+}
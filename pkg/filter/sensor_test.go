@@ -0,0 +1,148 @@
+package filter
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeSource 返回预设读数的温度来源，读数用完后重复最后一个
+type fakeSource struct {
+	mu     sync.Mutex
+	temps  []float64
+	err    error
+	panic  bool
+	reads  int
+	closed int
+}
+
+func (s *fakeSource) GetTemperature() (float64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.reads++
+	if s.panic {
+		panic("读取异常")
+	}
+	if s.err != nil {
+		return 0, s.err
+	}
+	v := s.temps[0]
+	if len(s.temps) > 1 {
+		s.temps = s.temps[1:]
+	}
+	return v, nil
+}
+
+func (s *fakeSource) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed++
+	return nil
+}
+
+func (s *fakeSource) set(fn func(s *fakeSource)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fn(s)
+}
+
+func TestSensorSync(t *testing.T) {
+	src := &fakeSource{temps: []float64{40, 80, 42}}
+	s := NewSensor(src, NewMedian(3), 0)
+
+	// 没有后台采样时每次读取都采样一次
+	for i, want := range []float64{40, 60, 42} {
+		got, err := s.GetTemperature()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("第%d次读取 = %v, 期望 %v", i+1, got, want)
+		}
+	}
+	if src.reads != 3 {
+		t.Errorf("读取次数 = %d, 期望 3", src.reads)
+	}
+
+	// 读取失败时返回错误，恢复后继续滤波
+	src.set(func(s *fakeSource) { s.err = errors.New("读取失败") })
+	if _, err := s.GetTemperature(); err == nil {
+		t.Error("来源读取失败时期望返回错误")
+	}
+	src.set(func(s *fakeSource) { s.err = nil })
+	if got, err := s.GetTemperature(); err != nil || got != 42 {
+		t.Errorf("恢复后读取 = %v, %v, 期望 42", got, err)
+	}
+
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if src.closed != 1 {
+		t.Errorf("关闭次数 = %d, 期望 1", src.closed)
+	}
+}
+
+func TestSensorBackground(t *testing.T) {
+	src := &fakeSource{temps: []float64{40}}
+	s := NewSensor(src, NewEMA(0.5), 5*time.Millisecond)
+
+	// 创建时立即采样一次
+	if got, err := s.GetTemperature(); err != nil || got != 40 {
+		t.Fatalf("创建后读取 = %v, %v, 期望 40", got, err)
+	}
+
+	// 后台采样逐渐逼近新的温度，读取时不再访问来源
+	src.set(func(s *fakeSource) { s.temps = []float64{60} })
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		got, err := s.GetTemperature()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got > 59.9 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("后台采样没有更新滤波结果: %v", got)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	// 采样时的panic记录为采样错误
+	src.set(func(s *fakeSource) { s.panic = true })
+	deadline = time.Now().Add(2 * time.Second)
+	for {
+		if _, err := s.GetTemperature(); err != nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("采样异常没有记录为错误")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	src.set(func(s *fakeSource) { s.panic = false })
+
+	// 停止后不再后台采样，底层来源没有关闭
+	s.Stop()
+	src.mu.Lock()
+	reads := src.reads
+	src.mu.Unlock()
+	time.Sleep(30 * time.Millisecond)
+	src.mu.Lock()
+	if src.reads != reads || src.closed != 0 {
+		t.Errorf("停止后读取次数 %d -> %d, 关闭次数 %d", reads, src.reads, src.closed)
+	}
+	src.mu.Unlock()
+
+	if s.Raw() != src {
+		t.Error("Raw() 应返回底层来源")
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if src.closed != 1 {
+		t.Errorf("关闭次数 = %d, 期望 1", src.closed)
+	}
+}
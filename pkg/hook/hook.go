@@ -0,0 +1,54 @@
+// Package hook 在发生告警时执行外部命令（如发送邮件、推送通知）
+package hook
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/exec"
+	"sort"
+	"time"
+)
+
+// DefaultTimeout 命令的默认超时时间
+const DefaultTimeout = 30 * time.Second
+
+// Command 告警命令，通过 /bin/sh -c 执行，事件信息通过环境变量传给命令
+type Command struct {
+	command string
+	timeout time.Duration
+}
+
+// NewCommand 创建告警命令，timeout为0时使用 DefaultTimeout
+func NewCommand(command string, timeout time.Duration) *Command {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	return &Command{command: command, timeout: timeout}
+}
+
+// Run 在后台执行命令，env中的变量追加到当前进程的环境变量之后
+// 命令失败或超时只记录日志，不影响风扇控制
+func (c *Command) Run(env map[string]string) {
+	vars := os.Environ()
+	keys := make([]string, 0, len(env))
+	for k := range env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		vars = append(vars, k+"="+env[k])
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+		defer cancel()
+
+		cmd := exec.CommandContext(ctx, "/bin/sh", "-c", c.command)
+		cmd.Env = vars
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			log.Printf("执行告警命令失败: %v: %s", err, out)
+		}
+	}()
+}
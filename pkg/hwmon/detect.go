@@ -0,0 +1,23 @@
+package hwmon
+
+import "regexp"
+
+var (
+	// cpuChipPattern 可能提供CPU温度的芯片名称
+	// k10temp: AMD处理器；nct6775、it87、asus: 常见主板传感器；acpi: ACPI接口
+	cpuChipPattern = regexp.MustCompile(`(?i)cpu|coretemp|k10temp|nct6775|asus|it87|acpi`)
+	// cpuLabelPattern CPU温度通道的标签
+	cpuLabelPattern = regexp.MustCompile(`(?i)cpu.*temp|core.*temp|package.*id|tccd`)
+	// fanChipPattern 可能提供风扇控制的芯片名称
+	fanChipPattern = regexp.MustCompile(`(?i)fan|pwm|asus|nct6775|it87`)
+)
+
+// IsCPUSensor 根据芯片名称和通道标签判断是否为CPU温度传感器
+func IsCPUSensor(chip, label string) bool {
+	return cpuChipPattern.MatchString(chip) || cpuLabelPattern.MatchString(label)
+}
+
+// IsFanDevice 根据芯片名称判断是否为风扇控制芯片
+func IsFanDevice(chip string) bool {
+	return fanChipPattern.MatchString(chip)
+}
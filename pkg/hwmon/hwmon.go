@@ -0,0 +1,292 @@
+// Package hwmon 枚举 /sys/class/hwmon 下的硬件监控芯片，
+// 以及每个芯片的所有温度、风扇转速和PWM通道及其属性
+//
+// /sys/class/hwmon 下的 hwmonN 通常是指向 /sys/devices 下设备目录的符号链接，
+// 通道编号也不一定连续或从1开始，所有需要查找hwmon设备的代码都应该使用这里的 Scan
+package hwmon
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/fanap/pkg/sysfs"
+)
+
+// Chip 一个hwmon芯片
+type Chip struct {
+	// ID hwmon目录名称，如 "hwmon1"，重启后可能变化
+	ID string
+	// Name 芯片名称（name文件），如 "nct6775"
+	Name string
+	// Path hwmon目录（/sys/class/hwmon/hwmonN，已映射到sysfs根目录下）
+	Path string
+	// Device 芯片所属设备在 /sys/devices 下的路径（如 "platform/nct6775.656"），虚拟设备为空
+	Device string
+	// Temps 温度通道，按编号排序
+	Temps []Temp
+	// Fans 风扇转速通道，按编号排序
+	Fans []Fan
+	// PWMs PWM输出通道，按编号排序
+	PWMs []PWM
+}
+
+// Temp 温度通道 tempN
+type Temp struct {
+	// Index 通道编号N
+	Index int
+	// Label 通道标签（tempN_label），可能为空
+	Label string
+	// Input 温度文件（tempN_input）
+	Input string
+	// Min、Max、Crit 温度阈值（摄氏度），驱动没有提供时为nil
+	Min  *float64
+	Max  *float64
+	Crit *float64
+}
+
+// Fan 风扇转速通道 fanN
+type Fan struct {
+	// Index 通道编号N
+	Index int
+	// Label 通道标签（fanN_label），可能为空
+	Label string
+	// Input 转速文件（fanN_input）
+	Input string
+	// Min、Max 转速阈值（RPM），驱动没有提供时为nil
+	Min *int
+	Max *int
+}
+
+// PWM PWM输出通道 pwmN
+type PWM struct {
+	// Index 通道编号N
+	Index int
+	// Label 对应风扇的标签（fanN_label），可能为空
+	Label string
+	// Path PWM文件（pwmN）
+	Path string
+	// Enable 控制模式（pwmN_enable），驱动没有提供时为nil
+	Enable *int
+	// Mode 输出模式（pwmN_mode: 0=DC, 1=PWM），驱动没有提供时为nil
+	Mode *int
+	// FanInput 同编号的转速文件（fanN_input），不存在时为空
+	FanInput string
+}
+
+// Name 返回通道名称，如 "temp1"
+func (t Temp) Name() string { return fmt.Sprintf("temp%d", t.Index) }
+
+// Name 返回通道名称，如 "fan1"
+func (f Fan) Name() string { return fmt.Sprintf("fan%d", f.Index) }
+
+// Name 返回通道名称，如 "pwm1"
+func (p PWM) Name() string { return fmt.Sprintf("pwm%d", p.Index) }
+
+// Read 读取当前温度（摄氏度）
+func (t Temp) Read() (float64, error) {
+	v, err := readInt(t.Input)
+	if err != nil {
+		return 0, err
+	}
+	return float64(v) / 1000, nil
+}
+
+// Read 读取当前转速（RPM）
+func (f Fan) Read() (int, error) {
+	return readInt(f.Input)
+}
+
+// Read 读取当前PWM值（0-255）
+func (p PWM) Read() (int, error) {
+	return readInt(p.Path)
+}
+
+// Scan 枚举所有hwmon芯片，按hwmonN的编号排序
+// 单个芯片读取失败（如设备正在移除）时跳过该芯片
+func Scan() ([]Chip, error) {
+	hwmonPath := sysfs.HWMonPath()
+	entries, err := os.ReadDir(hwmonPath)
+	if err != nil {
+		return nil, fmt.Errorf("读取hwmon目录失败: %w", err)
+	}
+
+	var chips []Chip
+	for _, entry := range entries {
+		chip, err := Open(filepath.Join(hwmonPath, entry.Name()))
+		if err != nil {
+			continue
+		}
+		chips = append(chips, chip)
+	}
+
+	sort.Slice(chips, func(i, j int) bool {
+		ni, ei := strconv.Atoi(strings.TrimPrefix(chips[i].ID, "hwmon"))
+		nj, ej := strconv.Atoi(strings.TrimPrefix(chips[j].ID, "hwmon"))
+		if ei != nil || ej != nil {
+			return chips[i].ID < chips[j].ID
+		}
+		return ni < nj
+	})
+	return chips, nil
+}
+
+// Open 读取一个hwmon目录（可以是符号链接）中的芯片信息和所有通道
+func Open(dir string) (Chip, error) {
+	// os.Stat 跟随符号链接，DirEntry.IsDir 对符号链接返回false
+	fi, err := os.Stat(dir)
+	if err != nil {
+		return Chip{}, err
+	}
+	if !fi.IsDir() {
+		return Chip{}, fmt.Errorf("%s 不是目录", dir)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return Chip{}, fmt.Errorf("读取hwmon设备目录失败: %w", err)
+	}
+
+	chip := Chip{
+		ID:     filepath.Base(dir),
+		Name:   readString(filepath.Join(dir, "name")),
+		Path:   dir,
+		Device: devicePath(dir),
+	}
+
+	var temps, fans, pwms []int
+	for _, e := range entries {
+		if m := inputPattern.FindStringSubmatch(e.Name()); m != nil {
+			n, _ := strconv.Atoi(m[2])
+			if m[1] == "temp" {
+				temps = append(temps, n)
+			} else {
+				fans = append(fans, n)
+			}
+		} else if m := pwmPattern.FindStringSubmatch(e.Name()); m != nil {
+			n, _ := strconv.Atoi(m[1])
+			pwms = append(pwms, n)
+		}
+	}
+	sort.Ints(temps)
+	sort.Ints(fans)
+	sort.Ints(pwms)
+
+	for _, n := range temps {
+		attr := func(s string) string { return filepath.Join(dir, fmt.Sprintf("temp%d_%s", n, s)) }
+		chip.Temps = append(chip.Temps, Temp{
+			Index: n,
+			Label: readString(attr("label")),
+			Input: attr("input"),
+			Min:   readMilli(attr("min")),
+			Max:   readMilli(attr("max")),
+			Crit:  readMilli(attr("crit")),
+		})
+	}
+	for _, n := range fans {
+		attr := func(s string) string { return filepath.Join(dir, fmt.Sprintf("fan%d_%s", n, s)) }
+		chip.Fans = append(chip.Fans, Fan{
+			Index: n,
+			Label: readString(attr("label")),
+			Input: attr("input"),
+			Min:   readIntPtr(attr("min")),
+			Max:   readIntPtr(attr("max")),
+		})
+	}
+	for _, n := range pwms {
+		path := filepath.Join(dir, fmt.Sprintf("pwm%d", n))
+		p := PWM{
+			Index:  n,
+			Label:  readString(filepath.Join(dir, fmt.Sprintf("fan%d_label", n))),
+			Path:   path,
+			Enable: readIntPtr(path + "_enable"),
+			Mode:   readIntPtr(path + "_mode"),
+		}
+		if f, ok := chip.Fan(n); ok {
+			p.FanInput = f.Input
+		}
+		chip.PWMs = append(chip.PWMs, p)
+	}
+
+	return chip, nil
+}
+
+// Fan 按编号查找风扇转速通道
+func (c Chip) Fan(index int) (Fan, bool) {
+	for _, f := range c.Fans {
+		if f.Index == index {
+			return f, true
+		}
+	}
+	return Fan{}, false
+}
+
+var (
+	// inputPattern 匹配温度和转速通道的输入文件 tempN_input、fanN_input
+	inputPattern = regexp.MustCompile(`^(temp|fan)(\d+)_input$`)
+	// pwmPattern 匹配PWM输出文件 pwmN
+	pwmPattern = regexp.MustCompile(`^pwm(\d+)$`)
+)
+
+// devicePath 返回hwmon芯片所属设备在 /sys/devices 下的相对路径（如 "platform/nct6775.656"），
+// 虚拟设备或无法解析时返回空字符串
+func devicePath(dir string) string {
+	dev, err := filepath.EvalSymlinks(filepath.Join(dir, "device"))
+	if err != nil {
+		return ""
+	}
+	devices, err := filepath.EvalSymlinks(sysfs.Path("/sys/devices"))
+	if err != nil {
+		return ""
+	}
+	rel, err := filepath.Rel(devices, dev)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return ""
+	}
+	return filepath.ToSlash(rel)
+}
+
+// readString 读取属性文件，失败时返回空字符串
+func readString(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// readInt 读取整数属性文件
+func readInt(path string) (int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	v, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return 0, fmt.Errorf("解析 %s 失败: %w", path, err)
+	}
+	return v, nil
+}
+
+// readIntPtr 读取可选的整数属性，不存在或无法解析时返回nil
+func readIntPtr(path string) *int {
+	v, err := readInt(path)
+	if err != nil {
+		return nil
+	}
+	return &v
+}
+
+// readMilli 读取可选的毫摄氏度属性并转换为摄氏度，不存在或无法解析时返回nil
+func readMilli(path string) *float64 {
+	v, err := readInt(path)
+	if err != nil {
+		return nil
+	}
+	c := float64(v) / 1000
+	return &c
+}
@@ -0,0 +1,214 @@
+package hwmon
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/fanap/pkg/sysfs"
+	"github.com/fanap/pkg/sysfs/fixture"
+)
+
+// newTree 创建空的模拟目录树并把sysfs根目录指向它
+func newTree(t *testing.T) *fixture.Tree {
+	t.Helper()
+
+	tree, err := fixture.New(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	sysfs.SetRoot(tree.Root)
+	t.Cleanup(func() { sysfs.SetRoot("") })
+	return tree
+}
+
+func TestScanDemo(t *testing.T) {
+	tree, err := fixture.Demo(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	sysfs.SetRoot(tree.Root)
+	t.Cleanup(func() { sysfs.SetRoot("") })
+
+	chips, err := Scan()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		id     string
+		name   string
+		device string
+		temps  []string
+		fans   int
+		pwms   int
+	}{
+		{"hwmon0", "coretemp", "platform/coretemp.0", []string{"Package id 0", "Core 0", "Core 1"}, 0, 0},
+		{"hwmon1", "nct6775", "platform/nct6775.656", []string{"SYSTIN"}, 2, 2},
+		{"hwmon2", "acpitz", "", []string{""}, 0, 0},
+	}
+	if len(chips) != len(tests) {
+		t.Fatalf("找到 %d 个芯片, 期望 %d", len(chips), len(tests))
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.id, func(t *testing.T) {
+			c := chips[i]
+			if c.ID != tt.id || c.Name != tt.name {
+				t.Errorf("芯片 = %s (%s), 期望 %s (%s)", c.ID, c.Name, tt.id, tt.name)
+			}
+			if c.Device != tt.device {
+				t.Errorf("Device = %q, 期望 %q", c.Device, tt.device)
+			}
+			if want := filepath.Join(tree.Root, "sys/class/hwmon", tt.id); c.Path != want {
+				t.Errorf("Path = %q, 期望 %q", c.Path, want)
+			}
+			if len(c.Temps) != len(tt.temps) {
+				t.Fatalf("温度通道 %d 个, 期望 %d", len(c.Temps), len(tt.temps))
+			}
+			for j, label := range tt.temps {
+				if c.Temps[j].Label != label {
+					t.Errorf("temp%d 标签 = %q, 期望 %q", c.Temps[j].Index, c.Temps[j].Label, label)
+				}
+			}
+			if len(c.Fans) != tt.fans || len(c.PWMs) != tt.pwms {
+				t.Errorf("风扇/PWM通道 = %d/%d, 期望 %d/%d", len(c.Fans), len(c.PWMs), tt.fans, tt.pwms)
+			}
+		})
+	}
+
+	// 阈值和PWM属性
+	pkg := chips[0].Temps[0]
+	if pkg.Max == nil || *pkg.Max != 80 || pkg.Crit == nil || *pkg.Crit != 100 || pkg.Min != nil {
+		t.Errorf("Package id 0 阈值 = %v/%v/%v, 期望 nil/80/100", pkg.Min, pkg.Max, pkg.Crit)
+	}
+	if v, err := pkg.Read(); err != nil || v != 45 {
+		t.Errorf("Package id 0 温度 = %v (%v), 期望 45", v, err)
+	}
+	pwm := chips[1].PWMs[0]
+	if pwm.Enable == nil || *pwm.Enable != 5 || pwm.Mode == nil || *pwm.Mode != 1 {
+		t.Errorf("pwm1 enable/mode = %v/%v, 期望 5/1", pwm.Enable, pwm.Mode)
+	}
+	if want := filepath.Join(chips[1].Path, "fan1_input"); pwm.FanInput != want {
+		t.Errorf("pwm1 FanInput = %q, 期望 %q", pwm.FanInput, want)
+	}
+}
+
+func TestScanChannels(t *testing.T) {
+	tree := newTree(t)
+
+	// 通道编号不连续、PWM没有同编号的转速输入、没有pwmN_enable
+	h, err := tree.AddHWMon("it87", "platform/it87.2608")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, ch := range []int{3, 1} {
+		if err := h.AddFan(ch, 900*ch, ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := h.SetInt("pwm3", 100); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.SetInt("pwm5", 200); err != nil {
+		t.Fatal(err)
+	}
+	// 不是通道的文件不应被识别
+	for _, attr := range []string{"pwm3_auto_point1_pwm", "fan1_alarm", "temp_input"} {
+		if err := h.SetInt(attr, 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	chips, err := Scan()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(chips) != 1 {
+		t.Fatalf("找到 %d 个芯片, 期望 1", len(chips))
+	}
+	c := chips[0]
+
+	tests := []struct {
+		name     string
+		got      []int
+		want     []int
+		fanInput []bool
+	}{
+		{"fans", fanIndexes(c.Fans), []int{1, 3}, nil},
+		{"pwms", pwmIndexes(c.PWMs), []int{3, 5}, []bool{true, false}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if len(tt.got) != len(tt.want) {
+				t.Fatalf("通道 = %v, 期望 %v", tt.got, tt.want)
+			}
+			for i := range tt.want {
+				if tt.got[i] != tt.want[i] {
+					t.Fatalf("通道 = %v, 期望 %v", tt.got, tt.want)
+				}
+			}
+			for i, want := range tt.fanInput {
+				if got := c.PWMs[i].FanInput != ""; got != want {
+					t.Errorf("pwm%d 有转速输入 = %v, 期望 %v", c.PWMs[i].Index, got, want)
+				}
+			}
+		})
+	}
+
+	if c.PWMs[0].Enable != nil {
+		t.Errorf("pwm3 Enable = %d, 期望 nil", *c.PWMs[0].Enable)
+	}
+	if rpm, err := c.Fans[1].Read(); err != nil || rpm != 2700 {
+		t.Errorf("fan3 转速 = %d (%v), 期望 2700", rpm, err)
+	}
+}
+
+func TestScanOrder(t *testing.T) {
+	tree := newTree(t)
+
+	// hwmon10 应排在 hwmon2 之后
+	for i := 0; i < 11; i++ {
+		if _, err := tree.AddHWMon("chip", ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	chips, err := Scan()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(chips) != 11 {
+		t.Fatalf("找到 %d 个芯片, 期望 11", len(chips))
+	}
+	for i, c := range chips {
+		if want := fmt.Sprintf("hwmon%d", i); c.ID != want {
+			t.Errorf("第 %d 个芯片 = %s, 期望 %s", i, c.ID, want)
+		}
+	}
+}
+
+func TestScanMissingClass(t *testing.T) {
+	sysfs.SetRoot(t.TempDir())
+	t.Cleanup(func() { sysfs.SetRoot("") })
+
+	if _, err := Scan(); err == nil {
+		t.Error("没有 /sys/class/hwmon 时应返回错误")
+	}
+}
+
+func fanIndexes(fans []Fan) []int {
+	var n []int
+	for _, f := range fans {
+		n = append(n, f.Index)
+	}
+	return n
+}
+
+func pwmIndexes(pwms []PWM) []int {
+	var n []int
+	for _, p := range pwms {
+		n = append(n, p.Index)
+	}
+	return n
+}
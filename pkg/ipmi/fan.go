@@ -0,0 +1,252 @@
+package ipmi
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// FanOptions BMC风扇的设置
+type FanOptions struct {
+	// Preset raw命令
+	Preset Preset
+	// Zone 风扇区域（0-255）
+	Zone int
+	// Tach sdr中的转速传感器名称（如 "FAN1"），为空时不支持读取转速
+	Tach string
+	// Cache 读取转速时sdr读数的缓存时间
+	Cache time.Duration
+	// MinPWM、MaxPWM PWM范围
+	MinPWM int
+	MaxPWM int
+}
+
+// Fan 通过BMC控制的风扇区域
+type Fan struct {
+	client *Client
+	opts   FanOptions
+	mode   byte
+
+	// auto 恢复自动模式的raw请求，为nil时关闭时不恢复
+	auto []byte
+
+	mu      sync.Mutex
+	lastPWM int
+	closed  bool
+}
+
+// NewFan 读取BMC当前的风扇模式并切换到手动模式
+func NewFan(client *Client, opts FanOptions) (*Fan, error) {
+	p := opts.Preset
+	if p.Set == "" {
+		return nil, fmt.Errorf("未指定设置占空比的raw命令")
+	}
+	if opts.Zone < 0 || opts.Zone > 255 {
+		return nil, fmt.Errorf("风扇区域必须在0-255之间")
+	}
+	if opts.MinPWM < 0 || opts.MaxPWM > 255 || opts.MinPWM >= opts.MaxPWM {
+		return nil, fmt.Errorf("无效的PWM范围 %d-%d", opts.MinPWM, opts.MaxPWM)
+	}
+	for kind, cmd := range map[string]string{"mode": p.Mode, "manual": p.Manual, "set": p.Set, "get": p.Get, "auto": p.Auto} {
+		if cmd == "" {
+			continue
+		}
+		if err := ValidateRaw(kind, cmd); err != nil {
+			return nil, fmt.Errorf("%s: %w", kind, err)
+		}
+	}
+
+	f := &Fan{client: client, opts: opts, mode: p.AutoMode, lastPWM: -1}
+
+	if p.Mode != "" {
+		req, err := ParseRaw(p.Mode, nil)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := client.InitialRaw(req)
+		if err != nil {
+			return nil, fmt.Errorf("读取风扇模式失败: %w", err)
+		}
+		if len(resp) == 0 {
+			return nil, fmt.Errorf("读取风扇模式失败: 响应为空")
+		}
+		f.mode = resp[0]
+		for _, m := range p.ManualModes {
+			if resp[0] == m {
+				log.Printf("BMC风扇模式为0x%02x（手动），退出时恢复为0x%02x", resp[0], p.AutoMode)
+				f.mode = p.AutoMode
+			}
+		}
+	}
+
+	if p.Auto != "" {
+		req, err := f.request(p.Auto, nil)
+		if err != nil {
+			return nil, err
+		}
+		f.auto = req
+	}
+
+	if p.Manual != "" {
+		if _, err := f.raw(p.Manual, nil); err != nil {
+			return nil, fmt.Errorf("切换到手动模式失败: %w", err)
+		}
+	}
+	if f.auto != nil {
+		client.holdManual(f.auto)
+	}
+	return f, nil
+}
+
+// request 替换占位符，返回raw请求
+func (f *Fan) request(cmd string, vars map[string]byte) ([]byte, error) {
+	all := map[string]byte{"zone": byte(f.opts.Zone), "mode": f.mode}
+	for k, v := range vars {
+		all[k] = v
+	}
+	return ParseRaw(cmd, all)
+}
+
+// raw 替换占位符后发送raw命令
+func (f *Fan) raw(cmd string, vars map[string]byte) ([]byte, error) {
+	req, err := f.request(cmd, vars)
+	if err != nil {
+		return nil, err
+	}
+	return f.client.Raw(req)
+}
+
+// SetSpeed 设置风扇速度，限制在PWM范围内
+func (f *Fan) SetSpeed(pwm int) error {
+	if pwm < f.opts.MinPWM {
+		pwm = f.opts.MinPWM
+	}
+	if pwm > f.opts.MaxPWM {
+		pwm = f.opts.MaxPWM
+	}
+	return f.set(pwm)
+}
+
+// SetFullSpeed 以100%占空比运行，不受最大PWM限制
+func (f *Fan) SetFullSpeed() error {
+	return f.set(255)
+}
+
+// StopFan 以0%占空比让风扇停转（零转速模式），不受最小PWM限制
+// 多数BMC会把占空比限制在风扇的最低转速以上，风扇是否停转取决于BMC
+func (f *Fan) StopFan() error {
+	return f.set(0)
+}
+
+// set 发送PWM对应的占空比
+// 每次都发送，BMC重启或自行切回自动模式后下一次设置即可恢复
+func (f *Fan) set(pwm int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	vars := map[string]byte{"duty": pwmToDuty(pwm), "pwm": byte(pwm)}
+	if _, err := f.raw(f.opts.Preset.Set, vars); err != nil {
+		return err
+	}
+	f.lastPWM = pwm
+	return nil
+}
+
+// GetSpeed 获取当前风扇速度，预设没有Get命令时返回上次设置的值
+func (f *Fan) GetSpeed() (int, error) {
+	if f.opts.Preset.Get != "" {
+		resp, err := f.raw(f.opts.Preset.Get, nil)
+		if err != nil {
+			return 0, err
+		}
+		if len(resp) == 0 {
+			return 0, fmt.Errorf("读取占空比失败: 响应为空")
+		}
+		return dutyToPWM(resp[0]), nil
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.lastPWM < 0 {
+		return 0, fmt.Errorf("尚未设置风扇速度")
+	}
+	return f.lastPWM, nil
+}
+
+// GetRPM 从sdr读取风扇转速
+func (f *Fan) GetRPM() (int, error) {
+	if f.opts.Tach == "" {
+		return 0, fmt.Errorf("未指定转速传感器")
+	}
+	r, err := f.client.Reading(f.opts.Tach, f.opts.Cache)
+	if err != nil {
+		return 0, err
+	}
+	if r.Unit != UnitRPM {
+		return 0, fmt.Errorf("传感器 %q 不是转速传感器 (%s)", f.opts.Tach, r.Raw)
+	}
+	return int(r.Value), nil
+}
+
+// GetMinSpeed 获取最小速度
+func (f *Fan) GetMinSpeed() int {
+	return f.opts.MinPWM
+}
+
+// GetMaxSpeed 获取最大速度
+func (f *Fan) GetMaxSpeed() int {
+	return f.opts.MaxPWM
+}
+
+// Close 恢复BMC的自动风扇控制
+// 多个区域共用同一个恢复命令时（如整个BMC的模式），只在最后一个区域关闭时恢复
+func (f *Fan) Close() error {
+	f.mu.Lock()
+	closed := f.closed
+	f.closed = true
+	f.mu.Unlock()
+
+	if f.auto == nil || closed {
+		return nil
+	}
+	if !f.client.releaseManual(f.auto) {
+		log.Printf("%s: 其他区域仍在手动控制，暂不恢复BMC自动模式", f)
+		return nil
+	}
+	if _, err := f.client.Raw(f.auto); err != nil {
+		return fmt.Errorf("恢复BMC自动模式失败: %w", err)
+	}
+	return nil
+}
+
+// Release 结束本区域的手动控制但不恢复BMC自动模式，风扇保持最后设置的占空比
+func (f *Fan) Release() error {
+	f.mu.Lock()
+	closed := f.closed
+	f.closed = true
+	f.mu.Unlock()
+
+	if f.auto != nil && !closed {
+		f.client.releaseManual(f.auto)
+	}
+	return nil
+}
+
+// String 描述风扇，用于日志
+func (f *Fan) String() string {
+	return fmt.Sprintf("IPMI %s 区域 0x%02x (%s)", f.opts.Preset.Name, f.opts.Zone, f.client)
+}
+
+// pwmToDuty 把PWM（0-255）换算为占空比（0-100），四舍五入
+func pwmToDuty(pwm int) byte {
+	return byte((pwm*100 + 127) / 255)
+}
+
+// dutyToPWM 把占空比换算为PWM，四舍五入
+func dutyToPWM(duty byte) int {
+	if duty > 100 {
+		duty = 100
+	}
+	return (int(duty)*255 + 50) / 100
+}
@@ -0,0 +1,114 @@
+package ipmi
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeIPMITool 创建记录raw请求的ipmitool脚本，读取风扇模式时返回mode
+func fakeIPMITool(t *testing.T, mode string) (*Client, func() []string) {
+	t.Helper()
+
+	dir := t.TempDir()
+	logFile := filepath.Join(dir, "requests.log")
+	script := filepath.Join(dir, "ipmitool")
+	body := `#!/bin/sh
+shift
+echo "$*" >> ` + logFile + `
+if [ "$*" = "0x30 0x45 0x00" ]; then echo " ` + mode + `"; fi
+`
+	if err := os.WriteFile(script, []byte(body), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := Open(script, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	requests := func() []string {
+		data, _ := os.ReadFile(logFile)
+		os.Remove(logFile)
+		return strings.Fields(strings.ReplaceAll(strings.TrimSpace(string(data)), " ", "_"))
+	}
+	return c, requests
+}
+
+func TestFanCloseSharedMode(t *testing.T) {
+	tests := []struct {
+		name     string
+		preset   string
+		mode     string
+		zones    []int
+		wantAuto string
+	}{
+		{"supermicro两个区域", PresetSupermicro, "00", []int{0, 1}, "0x30_0x45_0x01_0x00"},
+		{"supermicro启动时为手动模式", PresetSupermicro, "01", []int{0, 1}, "0x30_0x45_0x01_0x02"},
+		{"dell三个风扇", PresetDell, "", []int{0, 1, 2}, "0x30_0x30_0x01_0x01"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, requests := fakeIPMITool(t, tt.mode)
+			preset, err := LookupPreset(tt.preset)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var fans []*Fan
+			for _, zone := range tt.zones {
+				f, err := NewFan(client, FanOptions{Preset: preset, Zone: zone, MinPWM: 0, MaxPWM: 255})
+				if err != nil {
+					t.Fatal(err)
+				}
+				fans = append(fans, f)
+			}
+			requests()
+
+			// 其他区域仍在手动控制时不恢复自动模式，重复关闭不影响计数
+			for _, f := range fans[:len(fans)-1] {
+				for i := 0; i < 2; i++ {
+					if err := f.Close(); err != nil {
+						t.Fatal(err)
+					}
+				}
+				if got := requests(); len(got) != 0 {
+					t.Errorf("关闭部分区域后发送了 %v, 期望不发送请求", got)
+				}
+			}
+
+			// 最后一个区域关闭时恢复一次
+			if err := fans[len(fans)-1].Close(); err != nil {
+				t.Fatal(err)
+			}
+			if got := requests(); len(got) != 1 || got[0] != tt.wantAuto {
+				t.Errorf("关闭最后一个区域后发送了 %v, 期望 [%s]", got, tt.wantAuto)
+			}
+		})
+	}
+}
+
+func TestFanRelease(t *testing.T) {
+	client, requests := fakeIPMITool(t, "00")
+	preset, err := LookupPreset(PresetSupermicro)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := NewFan(client, FanOptions{Preset: preset, Zone: 0, MinPWM: 0, MaxPWM: 255})
+	if err != nil {
+		t.Fatal(err)
+	}
+	requests()
+
+	// 释放时不恢复BMC自动模式，之后的 Close 也不再恢复
+	if err := f.Release(); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got := requests(); len(got) != 0 {
+		t.Errorf("释放后发送了 %v, 期望不发送请求", got)
+	}
+}
@@ -0,0 +1,229 @@
+// Package ipmi 通过ipmitool与BMC通信的风扇和温度传感器
+//
+// 服务器主板（Supermicro、Dell等）的风扇通常由BMC控制，没有hwmon的PWM接口。
+// 风扇通过 ipmitool raw 命令切换到手动模式并设置占空比，退出时恢复BMC的自动模式；
+// 温度和风扇转速从 ipmitool sdr elist full 的输出中读取。
+//
+// ipmitool命令可以包含连接参数，如 "ipmitool -I lanplus -H 10.0.0.5 -U admin -f /etc/fanap/ipmi.pass"。
+// 命令相同的传感器和风扇共用一个客户端，请求串行发送给BMC，sdr读数在缓存时间内共用。
+package ipmi
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// DefaultCommand 默认的ipmitool命令
+const DefaultCommand = "ipmitool"
+
+// DefaultTimeout 每次执行ipmitool的默认超时时间
+const DefaultTimeout = 10 * time.Second
+
+// Client 执行ipmitool的客户端
+type Client struct {
+	command []string
+	timeout time.Duration
+
+	mu     sync.Mutex
+	sdrAt  time.Time
+	sdr    []Reading
+	sdrErr error
+
+	modeMu sync.Mutex
+	modes  map[string][]byte
+	manual map[string]int
+}
+
+var (
+	clientsMu sync.Mutex
+	clients   = make(map[string]*Client)
+)
+
+// Open 返回执行command的客户端，command相同时返回同一个客户端（超时时间以第一次打开时为准）
+// timeout为0时使用 DefaultTimeout
+func Open(command string, timeout time.Duration) (*Client, error) {
+	fields := strings.Fields(command)
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("未指定ipmitool命令")
+	}
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+
+	clientsMu.Lock()
+	defer clientsMu.Unlock()
+
+	key := strings.Join(fields, " ")
+	if c, ok := clients[key]; ok {
+		return c, nil
+	}
+	c := &Client{command: fields, timeout: timeout}
+	clients[key] = c
+	return c, nil
+}
+
+// Run 执行ipmitool，args追加在命令之后，返回标准输出
+func (c *Client) Run(args ...string) ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.run(args...)
+}
+
+// run 执行ipmitool，需要持有mu
+func (c *Client) run(args ...string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	var stderr strings.Builder
+	argv := append(append([]string{}, c.command[1:]...), args...)
+	cmd := exec.CommandContext(ctx, c.command[0], argv...)
+	// 在单独的进程组中运行，终端的Ctrl+C不会中断退出时恢复自动模式的ipmitool；
+	// 超时时终止整个进程组（命令可能是包装脚本），避免子进程占用输出管道
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+	cmd.Stderr = &stderr
+	cmd.WaitDelay = time.Second
+	out, err := cmd.Output()
+	if ctx.Err() != nil {
+		return nil, fmt.Errorf("ipmitool %s 超时 (%v)", strings.Join(args, " "), c.timeout)
+	}
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("ipmitool %s: %w: %s", strings.Join(args, " "), err, msg)
+		}
+		return nil, fmt.Errorf("ipmitool %s: %w", strings.Join(args, " "), err)
+	}
+	return out, nil
+}
+
+// Raw 发送raw请求（netfn、命令和数据字节），返回响应中的数据字节
+func (c *Client) Raw(req []byte) ([]byte, error) {
+	args := make([]string, 0, len(req)+1)
+	args = append(args, "raw")
+	for _, b := range req {
+		args = append(args, fmt.Sprintf("0x%02x", b))
+	}
+	out, err := c.Run(args...)
+	if err != nil {
+		return nil, err
+	}
+	return parseRawResponse(out)
+}
+
+// InitialRaw 与 Raw 相同，但同一请求只发送一次，之后返回第一次的响应
+// 用于读取启动时BMC的风扇模式：同一BMC上的多个风扇区域共用，后打开的区域不会读到已经切换的手动模式
+func (c *Client) InitialRaw(req []byte) ([]byte, error) {
+	c.modeMu.Lock()
+	defer c.modeMu.Unlock()
+
+	if resp, ok := c.modes[string(req)]; ok {
+		return resp, nil
+	}
+	resp, err := c.Raw(req)
+	if err != nil {
+		return nil, err
+	}
+	if c.modes == nil {
+		c.modes = make(map[string][]byte)
+	}
+	c.modes[string(req)] = resp
+	return resp, nil
+}
+
+// holdManual 记录一个风扇区域进入手动模式，auto为该区域恢复自动模式的raw请求
+// 同一BMC上恢复命令相同（不包含区域）的多个区域共用一次计数
+func (c *Client) holdManual(auto []byte) {
+	c.modeMu.Lock()
+	defer c.modeMu.Unlock()
+
+	if c.manual == nil {
+		c.manual = make(map[string]int)
+	}
+	c.manual[string(auto)]++
+}
+
+// releaseManual 记录一个风扇区域退出手动模式，返回是否为最后一个使用该恢复命令的区域
+// 只有最后一个区域关闭时才恢复自动模式，避免先关闭的区域让其他仍在控制的区域回到自动模式
+func (c *Client) releaseManual(auto []byte) bool {
+	c.modeMu.Lock()
+	defer c.modeMu.Unlock()
+
+	key := string(auto)
+	if c.manual[key] > 1 {
+		c.manual[key]--
+		return false
+	}
+	delete(c.manual, key)
+	return true
+}
+
+// parseRawResponse 解析 ipmitool raw 输出的十六进制字节（如 " 01 64"）
+func parseRawResponse(out []byte) ([]byte, error) {
+	fields := strings.Fields(string(out))
+	resp := make([]byte, 0, len(fields))
+	for _, f := range fields {
+		v, err := strconv.ParseUint(strings.TrimPrefix(f, "0x"), 16, 8)
+		if err != nil {
+			return nil, fmt.Errorf("无效的raw响应 %q", strings.TrimSpace(string(out)))
+		}
+		resp = append(resp, byte(v))
+	}
+	return resp, nil
+}
+
+// SDR 读取所有full类型传感器的读数，maxAge内读取过时返回上次的结果（失败同样缓存）
+func (c *Client) SDR(maxAge time.Duration) ([]Reading, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	if !c.sdrAt.IsZero() && now.Sub(c.sdrAt) < maxAge {
+		return c.sdr, c.sdrErr
+	}
+
+	out, err := c.run("sdr", "elist", "full")
+	if err == nil {
+		c.sdr, c.sdrErr = ParseSDR(out), nil
+	} else {
+		c.sdr, c.sdrErr = nil, err
+	}
+	c.sdrAt = now
+	return c.sdr, c.sdrErr
+}
+
+// Reading 按名称读取一个传感器，maxAge同 SDR
+func (c *Client) Reading(name string, maxAge time.Duration) (Reading, error) {
+	readings, err := c.SDR(maxAge)
+	if err != nil {
+		return Reading{}, err
+	}
+	for _, r := range readings {
+		if r.Name != name {
+			continue
+		}
+		if !r.Available() {
+			return r, fmt.Errorf("传感器 %q 没有读数 (%s)", name, r.Raw)
+		}
+		return r, nil
+	}
+	return Reading{}, fmt.Errorf("BMC中没有传感器 %q", name)
+}
+
+// String 描述客户端，用于日志；-P后面的密码以***代替
+func (c *Client) String() string {
+	fields := append([]string{}, c.command...)
+	for i := 1; i < len(fields); i++ {
+		if fields[i-1] == "-P" {
+			fields[i] = "***"
+		}
+	}
+	return strings.Join(fields, " ")
+}
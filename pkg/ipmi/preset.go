@@ -0,0 +1,136 @@
+package ipmi
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Preset 一类BMC的风扇控制raw命令
+//
+// 命令由空格分隔的字节组成（netfn、命令和数据，如 "0x30 0x45 0x01 0x01"），可以包含以下占位符：
+//
+//	{zone}  风扇区域
+//	{duty}  占空比（0-100）
+//	{pwm}   PWM（0-255）
+//	{mode}  启动时读取到的风扇模式（仅Auto）
+type Preset struct {
+	// Name 名称，用于日志
+	Name string
+	// Mode 读取BMC当前风扇模式的命令，响应的第一个字节在退出时作为Auto中的{mode}；为空时不读取
+	Mode string
+	// Manual 切换到手动模式的命令，为空时不切换
+	Manual string
+	// Set 设置占空比的命令
+	Set string
+	// Get 读取占空比的命令，响应的第一个字节为占空比（0-100）；为空时返回上次设置的值
+	Get string
+	// Auto 恢复BMC自动模式的命令，为空时退出时不恢复
+	Auto string
+	// AutoMode 没有读取到风扇模式，或读取到的是ManualModes之一时{mode}的值
+	AutoMode byte
+	// ManualModes 表示手动控制的风扇模式，启动时BMC已经处于这些模式（如上次异常退出）时退出后恢复为AutoMode
+	ManualModes []byte
+	// Zone 默认的风扇区域
+	Zone int
+}
+
+// 预设名称
+const (
+	PresetSupermicro = "supermicro"
+	PresetDell       = "dell"
+	PresetCustom     = "custom"
+)
+
+// presets 内置的预设
+var presets = map[string]Preset{
+	// Supermicro X9/X10/X11/X12: 风扇模式 0x00 标准、0x01 全速、0x02 最佳、0x04 重IO；
+	// 全速模式下可以按区域设置占空比，区域0为CPU风扇（FAN1-FANn），区域1为外设风扇（FANA-FANx）
+	PresetSupermicro: {
+		Name:        PresetSupermicro,
+		Mode:        "0x30 0x45 0x00",
+		Manual:      "0x30 0x45 0x01 0x01",
+		Set:         "0x30 0x70 0x66 0x01 {zone} {duty}",
+		Get:         "0x30 0x70 0x66 0x00 {zone}",
+		Auto:        "0x30 0x45 0x01 {mode}",
+		AutoMode:    0x02,
+		ManualModes: []byte{0x01},
+		Zone:        0,
+	},
+	// Dell PowerEdge（iDRAC 7/8/9）: 关闭自动控制后设置占空比，区域0xff表示所有风扇，0x00起为单个风扇
+	PresetDell: {
+		Name:   PresetDell,
+		Manual: "0x30 0x30 0x01 0x00",
+		Set:    "0x30 0x30 0x02 {zone} {duty}",
+		Auto:   "0x30 0x30 0x01 0x01",
+		Zone:   0xff,
+	},
+}
+
+// LookupPreset 按名称查找内置预设，custom返回空的预设（所有命令都需要自行指定）
+func LookupPreset(name string) (Preset, error) {
+	if name == PresetCustom {
+		return Preset{Name: PresetCustom}, nil
+	}
+	p, ok := presets[name]
+	if !ok {
+		return Preset{}, fmt.Errorf("未知的IPMI预设 %q（可选: %s）", name, strings.Join(PresetNames(), "、"))
+	}
+	return p, nil
+}
+
+// PresetNames 返回所有预设的名称
+func PresetNames() []string {
+	names := make([]string, 0, len(presets)+1)
+	for name := range presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return append(names, PresetCustom)
+}
+
+// Placeholders 各命令可以使用的占位符
+var Placeholders = map[string][]string{
+	"mode":   nil,
+	"manual": {"zone"},
+	"set":    {"zone", "duty", "pwm"},
+	"get":    {"zone"},
+	"auto":   {"zone", "mode"},
+}
+
+// ParseRaw 把raw命令解析为字节，占位符按vars替换；vars中没有的占位符视为错误
+func ParseRaw(cmd string, vars map[string]byte) ([]byte, error) {
+	fields := strings.Fields(cmd)
+	if len(fields) < 2 {
+		return nil, fmt.Errorf("raw命令至少包含netfn和命令两个字节: %q", cmd)
+	}
+
+	req := make([]byte, 0, len(fields))
+	for _, f := range fields {
+		if strings.HasPrefix(f, "{") && strings.HasSuffix(f, "}") {
+			v, ok := vars[f[1:len(f)-1]]
+			if !ok {
+				return nil, fmt.Errorf("raw命令中不能使用占位符 %s", f)
+			}
+			req = append(req, v)
+			continue
+		}
+		v, err := strconv.ParseUint(f, 0, 8)
+		if err != nil {
+			return nil, fmt.Errorf("raw命令中无效的字节 %q", f)
+		}
+		req = append(req, byte(v))
+	}
+	return req, nil
+}
+
+// ValidateRaw 验证raw命令，kind为mode、manual、set、get、auto之一
+func ValidateRaw(kind, cmd string) error {
+	vars := make(map[string]byte)
+	for _, name := range Placeholders[kind] {
+		vars[name] = 0
+	}
+	_, err := ParseRaw(cmd, vars)
+	return err
+}
@@ -0,0 +1,106 @@
+package ipmi
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestParseRaw(t *testing.T) {
+	vars := map[string]byte{"zone": 1, "duty": 50}
+
+	tests := []struct {
+		cmd     string
+		want    []byte
+		wantErr bool
+	}{
+		{"0x30 0x45 0x01 0x01", []byte{0x30, 0x45, 0x01, 0x01}, false},
+		{"0x30 0x70 0x66 0x01 {zone} {duty}", []byte{0x30, 0x70, 0x66, 0x01, 1, 50}, false},
+		{"48 0x45 010", []byte{0x30, 0x45, 0x08}, false},
+		{"0x30", nil, true},
+		{"", nil, true},
+		{"0x30 0x45 {mode}", nil, true},
+		{"0x30 0x45 0x100", nil, true},
+		{"0x30 0x45 zone", nil, true},
+		{"0x30 0x45 {zone", nil, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseRaw(tt.cmd, vars)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseRaw(%q) 错误 = %v, 期望出错 %v", tt.cmd, err, tt.wantErr)
+			continue
+		}
+		if !bytes.Equal(got, tt.want) {
+			t.Errorf("ParseRaw(%q) = % x, 期望 % x", tt.cmd, got, tt.want)
+		}
+	}
+}
+
+func TestValidateRaw(t *testing.T) {
+	tests := []struct {
+		kind    string
+		cmd     string
+		wantErr bool
+	}{
+		{"mode", "0x30 0x45 0x00", false},
+		{"mode", "0x30 0x45 {zone}", true},
+		{"manual", "0x30 0x45 0x01 {zone}", false},
+		{"set", "0x30 0x70 0x66 0x01 {zone} {duty}", false},
+		{"set", "0x30 0x30 0x02 {zone} {pwm}", false},
+		{"set", "0x30 0x30 0x02 {zone} {mode}", true},
+		{"get", "0x30 0x70 0x66 0x00 {zone}", false},
+		{"get", "0x30 0x70 0x66 0x00 {duty}", true},
+		{"auto", "0x30 0x45 0x01 {mode}", false},
+		{"auto", "0x30 0x45 0x01 {pwm}", true},
+	}
+	for _, tt := range tests {
+		if err := ValidateRaw(tt.kind, tt.cmd); (err != nil) != tt.wantErr {
+			t.Errorf("ValidateRaw(%q, %q) 错误 = %v, 期望出错 %v", tt.kind, tt.cmd, err, tt.wantErr)
+		}
+	}
+
+	// 内置预设的命令都能通过验证
+	for _, name := range PresetNames() {
+		p, err := LookupPreset(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for kind, cmd := range map[string]string{"mode": p.Mode, "manual": p.Manual, "set": p.Set, "get": p.Get, "auto": p.Auto} {
+			if cmd == "" {
+				continue
+			}
+			if err := ValidateRaw(kind, cmd); err != nil {
+				t.Errorf("预设 %s 的 %s 命令: %v", name, kind, err)
+			}
+		}
+	}
+}
+
+func TestDutyConversion(t *testing.T) {
+	tests := []struct {
+		pwm  int
+		duty byte
+	}{
+		{0, 0},
+		{1, 0},
+		{2, 1},
+		{128, 50},
+		{191, 75},
+		{254, 100},
+		{255, 100},
+	}
+	for _, tt := range tests {
+		if got := pwmToDuty(tt.pwm); got != tt.duty {
+			t.Errorf("pwmToDuty(%d) = %d, 期望 %d", tt.pwm, got, tt.duty)
+		}
+	}
+
+	// 每个占空比换算为PWM后再换算回来不变，超过100的占空比按100处理
+	for duty := 0; duty <= 100; duty++ {
+		if got := pwmToDuty(dutyToPWM(byte(duty))); int(got) != duty {
+			t.Errorf("占空比 %d -> PWM %d -> 占空比 %d", duty, dutyToPWM(byte(duty)), got)
+		}
+	}
+	if got := dutyToPWM(150); got != 255 {
+		t.Errorf("dutyToPWM(150) = %d, 期望 255", got)
+	}
+}
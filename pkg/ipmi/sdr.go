@@ -0,0 +1,117 @@
+package ipmi
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 传感器读数的单位
+const (
+	UnitCelsius = "degrees C"
+	UnitRPM     = "RPM"
+)
+
+// Reading 一个SDR传感器的读数
+type Reading struct {
+	// Name 传感器名称，如 "CPU Temp"、"FAN1"
+	Name string
+	// Value 读数
+	Value float64
+	// Unit 单位，如 "degrees C"、"RPM"；没有读数时为空
+	Unit string
+	// Status 状态，如 ok、nc、cr、ns
+	Status string
+	// Raw 读数的原始文本，如 "42 degrees C"、"No Reading"
+	Raw string
+}
+
+// Available 是否有可用的读数
+func (r Reading) Available() bool {
+	return r.Unit != "" && r.Status != "ns"
+}
+
+// ParseSDR 解析ipmitool sdr的输出，支持以下两种格式：
+//
+//	CPU Temp         | 42 degrees C      | ok                         (sdr list)
+//	CPU Temp         | 01h | ok  |  3.1 | 42 degrees C              (sdr elist)
+//
+// 无法识别的行被忽略
+func ParseSDR(out []byte) []Reading {
+	var readings []Reading
+	for _, line := range strings.Split(string(out), "\n") {
+		fields := strings.Split(line, "|")
+		for i := range fields {
+			fields[i] = strings.TrimSpace(fields[i])
+		}
+
+		var r Reading
+		switch len(fields) {
+		case 3:
+			r = Reading{Name: fields[0], Raw: fields[1], Status: fields[2]}
+		case 5:
+			r = Reading{Name: fields[0], Status: fields[2], Raw: fields[4]}
+		default:
+			continue
+		}
+		if r.Name == "" {
+			continue
+		}
+		if v, unit, ok := parseValue(r.Raw); ok {
+			r.Value, r.Unit = v, unit
+		}
+		readings = append(readings, r)
+	}
+	return readings
+}
+
+// parseValue 解析 "42 degrees C"、"1400 RPM" 形式的读数
+func parseValue(s string) (float64, string, bool) {
+	num, unit, ok := strings.Cut(s, " ")
+	if !ok {
+		return 0, "", false
+	}
+	v, err := strconv.ParseFloat(num, 64)
+	if err != nil {
+		return 0, "", false
+	}
+	return v, strings.TrimSpace(unit), true
+}
+
+// Sensor 从BMC读取温度的传感器
+type Sensor struct {
+	client *Client
+	name   string
+	cache  time.Duration
+}
+
+// NewSensor 创建BMC温度传感器，name为sdr中的传感器名称，cache内共用同一次sdr读取
+func NewSensor(client *Client, name string, cache time.Duration) (*Sensor, error) {
+	if name == "" {
+		return nil, fmt.Errorf("未指定传感器名称")
+	}
+	return &Sensor{client: client, name: name, cache: cache}, nil
+}
+
+// GetTemperature 获取当前温度（摄氏度）
+func (s *Sensor) GetTemperature() (float64, error) {
+	r, err := s.client.Reading(s.name, s.cache)
+	if err != nil {
+		return 0, err
+	}
+	if r.Unit != UnitCelsius {
+		return 0, fmt.Errorf("传感器 %q 不是温度传感器 (%s)", s.name, r.Raw)
+	}
+	return r.Value, nil
+}
+
+// Close 关闭传感器
+func (s *Sensor) Close() error {
+	return nil
+}
+
+// String 描述传感器，用于日志
+func (s *Sensor) String() string {
+	return fmt.Sprintf("BMC传感器 %q (%s)", s.name, s.client)
+}
@@ -0,0 +1,163 @@
+package ipmi
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const sdrList = `CPU Temp         | 42 degrees C      | ok
+System Temp      | 31 degrees C      | ok
+FAN1             | 1400 RPM          | ok
+FAN2             | no reading        | ns
+PS1 Status       | 0x01              | ok
+`
+
+const sdrElist = `CPU Temp         | 01h | ok  |  3.1 | 42 degrees C
+Peripheral Temp  | 0Bh | ok  |  7.1 | 37.5 degrees C
+FAN1             | 41h | ok  | 29.1 | 1400 RPM
+FAN3             | 43h | ns  | 29.3 | No Reading
+Inlet Temp       | 04h | ns  |  7.1 | 25 degrees C
+PS1 Status       | C8h | ok  | 10.1 | Presence detected
+`
+
+func TestParseSDR(t *testing.T) {
+	tests := []struct {
+		name  string
+		out   string
+		want  []Reading
+		avail []bool
+	}{
+		{
+			"sdr list", sdrList,
+			[]Reading{
+				{Name: "CPU Temp", Value: 42, Unit: UnitCelsius, Status: "ok", Raw: "42 degrees C"},
+				{Name: "System Temp", Value: 31, Unit: UnitCelsius, Status: "ok", Raw: "31 degrees C"},
+				{Name: "FAN1", Value: 1400, Unit: UnitRPM, Status: "ok", Raw: "1400 RPM"},
+				{Name: "FAN2", Status: "ns", Raw: "no reading"},
+				{Name: "PS1 Status", Status: "ok", Raw: "0x01"},
+			},
+			[]bool{true, true, true, false, false},
+		},
+		{
+			"sdr elist", sdrElist,
+			[]Reading{
+				{Name: "CPU Temp", Value: 42, Unit: UnitCelsius, Status: "ok", Raw: "42 degrees C"},
+				{Name: "Peripheral Temp", Value: 37.5, Unit: UnitCelsius, Status: "ok", Raw: "37.5 degrees C"},
+				{Name: "FAN1", Value: 1400, Unit: UnitRPM, Status: "ok", Raw: "1400 RPM"},
+				{Name: "FAN3", Status: "ns", Raw: "No Reading"},
+				{Name: "Inlet Temp", Value: 25, Unit: UnitCelsius, Status: "ns", Raw: "25 degrees C"},
+				{Name: "PS1 Status", Status: "ok", Raw: "Presence detected"},
+			},
+			[]bool{true, true, true, false, false, false},
+		},
+		{"无法识别的行", "Get SDR 0042 command failed\n | 1 | ok\na | b\n\n", nil, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ParseSDR([]byte(tt.out))
+			if len(got) != len(tt.want) {
+				t.Fatalf("ParseSDR() 返回 %d 个读数, 期望 %d: %+v", len(got), len(tt.want), got)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("第%d个读数 = %+v, 期望 %+v", i+1, got[i], tt.want[i])
+				}
+				if a := got[i].Available(); a != tt.avail[i] {
+					t.Errorf("%s Available() = %v, 期望 %v", got[i].Name, a, tt.avail[i])
+				}
+			}
+		})
+	}
+}
+
+// fakeSDR 创建输出固定sdr的ipmitool脚本
+func fakeSDR(t *testing.T, out string) *Client {
+	t.Helper()
+
+	dir := t.TempDir()
+	data := filepath.Join(dir, "sdr.txt")
+	if err := os.WriteFile(data, []byte(out), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	script := filepath.Join(dir, "ipmitool")
+	if err := os.WriteFile(script, []byte("#!/bin/sh\ncat "+data+"\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	c, err := Open(script, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestSensorReading(t *testing.T) {
+	client := fakeSDR(t, sdrElist)
+
+	tests := []struct {
+		name    string
+		want    float64
+		wantErr bool
+	}{
+		{"CPU Temp", 42, false},
+		{"Peripheral Temp", 37.5, false},
+		{"FAN1", 0, true},
+		{"FAN3", 0, true},
+		{"Inlet Temp", 0, true},
+		{"PS1 Status", 0, true},
+		{"GPU Temp", 0, true},
+	}
+	for _, tt := range tests {
+		s, err := NewSensor(client, tt.name, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := s.GetTemperature()
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: GetTemperature() 错误 = %v, 期望出错 %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s: GetTemperature() = %v, 期望 %v", tt.name, got, tt.want)
+		}
+	}
+
+	if _, err := NewSensor(client, "", 0); err == nil {
+		t.Error("没有传感器名称时 NewSensor 应返回错误")
+	}
+}
+
+func TestFanRPMReading(t *testing.T) {
+	client := fakeSDR(t, sdrElist)
+	preset, err := LookupPreset(PresetCustom)
+	if err != nil {
+		t.Fatal(err)
+	}
+	preset.Set = "0x30 0x70 0x66 0x01 {zone} {duty}"
+
+	tests := []struct {
+		tach    string
+		want    int
+		wantErr bool
+	}{
+		{"FAN1", 1400, false},
+		{"CPU Temp", 0, true},
+		{"FAN3", 0, true},
+		{"", 0, true},
+	}
+	for _, tt := range tests {
+		f, err := NewFan(client, FanOptions{Preset: preset, Tach: tt.tach, MinPWM: 0, MaxPWM: 255})
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := f.GetRPM()
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%q: GetRPM() 错误 = %v, 期望出错 %v", tt.tach, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%q: GetRPM() = %d, 期望 %d", tt.tach, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package metrics
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"time"
+)
+
+// Server 指标HTTP服务
+type Server struct {
+	srv *http.Server
+}
+
+// Serve 在addr上启动指标HTTP服务，指标路径为 /metrics
+func Serve(addr string, reg *Registry) (*Server, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("监听指标地址 %s 失败: %w", addr, err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", reg)
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(`<html><body><a href="/metrics">/metrics</a></body></html>`))
+	})
+
+	s := &Server{srv: &http.Server{
+		Addr:              addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
+	}}
+
+	go func() {
+		if err := s.srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("指标服务异常退出: %v", err)
+		}
+	}()
+
+	log.Printf("指标服务监听: http://%s/metrics", ln.Addr())
+	return s, nil
+}
+
+// Close 关闭指标HTTP服务
+func (s *Server) Close() error {
+	return s.srv.Close()
+}
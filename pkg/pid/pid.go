@@ -0,0 +1,147 @@
+// Package pid 实现用于保持目标温度的PID控制器
+package pid
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// 默认增益（单位: PWM/°C、PWM/(°C·s)、PWM·s/°C）
+const (
+	DefaultKp = 10.0
+	DefaultKi = 0.5
+	DefaultKd = 0.0
+)
+
+// Gains PID增益
+type Gains struct {
+	Kp float64 `json:"kp"`
+	Ki float64 `json:"ki"`
+	Kd float64 `json:"kd"`
+}
+
+// DefaultGains 返回默认增益
+func DefaultGains() Gains {
+	return Gains{Kp: DefaultKp, Ki: DefaultKi, Kd: DefaultKd}
+}
+
+// Validate 验证增益
+func (g Gains) Validate() error {
+	if g.Kp < 0 || g.Ki < 0 || g.Kd < 0 {
+		return fmt.Errorf("PID增益不能为负数")
+	}
+	if g.Kp == 0 && g.Ki == 0 {
+		return fmt.Errorf("kp和ki不能同时为0")
+	}
+	return nil
+}
+
+// Controller PID控制器
+// 温度高于目标值时增大输出（风扇PWM），低于目标值时减小输出
+type Controller struct {
+	target float64
+	gains  Gains
+
+	min, max float64
+
+	integral    float64
+	lastInput   float64
+	initialized bool
+}
+
+// New 创建PID控制器
+func New(target float64, gains Gains) (*Controller, error) {
+	if err := gains.Validate(); err != nil {
+		return nil, err
+	}
+	return &Controller{
+		target: target,
+		gains:  gains,
+		min:    0,
+		max:    255,
+	}, nil
+}
+
+// Target 返回目标温度
+func (c *Controller) Target() float64 {
+	return c.target
+}
+
+// Gains 返回当前增益
+func (c *Controller) Gains() Gains {
+	return c.gains
+}
+
+// SetTarget 修改目标温度
+func (c *Controller) SetTarget(target float64) {
+	c.target = target
+}
+
+// SetGains 修改增益，保留积分状态以避免输出突变
+func (c *Controller) SetGains(gains Gains) error {
+	if err := gains.Validate(); err != nil {
+		return err
+	}
+	c.gains = gains
+	return nil
+}
+
+// SetLimits 设置输出范围
+func (c *Controller) SetLimits(min, max float64) {
+	c.min, c.max = min, max
+	c.integral = clamp(c.integral, min, max)
+}
+
+// Reset 清除积分和微分状态
+func (c *Controller) Reset() {
+	c.integral = 0
+	c.initialized = false
+}
+
+// Update 根据测量温度和距上次更新的时间间隔计算新的输出
+func (c *Controller) Update(measured float64, dt time.Duration) float64 {
+	e := measured - c.target
+
+	// 第一次更新时从最小输出开始，且没有微分项
+	if !c.initialized {
+		c.integral = c.min
+		c.lastInput = measured
+		c.initialized = true
+	}
+
+	seconds := dt.Seconds()
+
+	p := c.gains.Kp * e
+
+	// 微分项基于测量值计算，避免修改目标温度时输出突变
+	d := 0.0
+	if seconds > 0 {
+		d = c.gains.Kd * (measured - c.lastInput) / seconds
+	}
+	c.lastInput = measured
+
+	// 抗积分饱和：积分只累积到输出刚好达到上限（或下限）为止，误差会使输出更饱和时不再继续积分，
+	// 但也不会反向减小已有的积分；积分项本身也限制在输出范围内
+	integral := clamp(c.integral+c.gains.Ki*e*seconds, c.min, c.max)
+	out := p + integral + d
+	switch {
+	case out > c.max && e > 0:
+		integral = math.Max(c.integral, c.max-p-d)
+	case out < c.min && e < 0:
+		integral = math.Min(c.integral, c.min-p-d)
+	}
+	c.integral = clamp(integral, c.min, c.max)
+
+	return clamp(p+c.integral+d, c.min, c.max)
+}
+
+func clamp(v, min, max float64) float64 {
+	if v < min {
+		return min
+	}
+	if v > max {
+		return max
+	}
+	return v
+}
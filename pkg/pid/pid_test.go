@@ -0,0 +1,146 @@
+package pid
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGainsValidate(t *testing.T) {
+	tests := []struct {
+		gains   Gains
+		wantErr bool
+	}{
+		{DefaultGains(), false},
+		{Gains{Kp: 5}, false},
+		{Gains{Ki: 0.1}, false},
+		{Gains{Kd: 1}, true},
+		{Gains{}, true},
+		{Gains{Kp: -1, Ki: 1}, true},
+		{Gains{Kp: 1, Kd: -0.1}, true},
+	}
+	for _, tt := range tests {
+		if err := tt.gains.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("%+v.Validate() 错误 = %v, 期望出错 %v", tt.gains, err, tt.wantErr)
+		}
+		if _, err := New(50, tt.gains); (err != nil) != tt.wantErr {
+			t.Errorf("New(50, %+v) 错误 = %v, 期望出错 %v", tt.gains, err, tt.wantErr)
+		}
+	}
+}
+
+// run 以1秒间隔依次输入温度，返回每次的输出
+func run(c *Controller, temps ...float64) []float64 {
+	out := make([]float64, len(temps))
+	for i, temp := range temps {
+		out[i] = c.Update(temp, time.Second)
+	}
+	return out
+}
+
+func TestAntiWindup(t *testing.T) {
+	c, err := New(50, Gains{Kp: 1, Ki: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 积分每秒增加50，达到输出上限后停止积分
+	got := run(c, 55, 55, 55, 55, 55, 55, 55, 55, 55, 55)
+	want := []float64{55, 105, 155, 205, 255, 255, 255, 255, 255, 255}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("饱和前后的输出 = %v, 期望 %v", got, want)
+		}
+	}
+
+	// 温度低于目标后立即从饱和中恢复，不需要先消耗累积的积分
+	if out := c.Update(45, time.Second); out != 195 {
+		t.Errorf("低于目标温度后的输出 = %v, 期望 195", out)
+	}
+
+	// 持续低于目标时降到下限并保持，积分只保留使输出刚好为下限的部分
+	got = run(c, 45, 45, 45, 45, 45, 45)
+	want = []float64{145, 95, 45, 0, 0, 0}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("低于目标温度后的输出 = %v, 期望 %v", got, want)
+		}
+	}
+	if out := c.Update(51, time.Second); out != 16 {
+		t.Errorf("重新高于目标温度后的输出 = %v, 期望 16", out)
+	}
+}
+
+func TestDerivativeOnMeasurement(t *testing.T) {
+	c, err := New(50, Gains{Kp: 1, Kd: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 第一次更新没有微分项
+	if out := c.Update(60, time.Second); out != 10 {
+		t.Errorf("第一次更新的输出 = %v, 期望 10", out)
+	}
+	// 温度上升2°C/s，微分项为20
+	if out := c.Update(62, time.Second); out != 32 {
+		t.Errorf("温度上升时的输出 = %v, 期望 32", out)
+	}
+	// 修改目标温度不产生微分冲击
+	c.SetTarget(40)
+	if out := c.Update(62, time.Second); out != 22 {
+		t.Errorf("修改目标温度后的输出 = %v, 期望 22", out)
+	}
+	// 温度下降时微分项减小输出
+	if out := c.Update(61, 500*time.Millisecond); out != 1 {
+		t.Errorf("温度下降时的输出 = %v, 期望 1", out)
+	}
+	// 时间间隔为0时没有微分项
+	if out := c.Update(70, 0); out != 30 {
+		t.Errorf("时间间隔为0时的输出 = %v, 期望 30", out)
+	}
+}
+
+func TestReset(t *testing.T) {
+	c, err := New(50, Gains{Kp: 1, Ki: 10, Kd: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	run(c, 55, 60, 65, 70)
+
+	// 清除积分和微分状态后与新建的控制器输出相同
+	c.Reset()
+	fresh, err := New(50, Gains{Kp: 1, Ki: 10, Kd: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	temps := []float64{55, 56, 54}
+	got, want := run(c, temps...), run(fresh, temps...)
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("Reset 后的输出 = %v, 期望 %v", got, want)
+		}
+	}
+}
+
+func TestSetLimits(t *testing.T) {
+	c, err := New(50, Gains{Kp: 1, Ki: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.SetLimits(80, 200)
+
+	// 从下限开始，输出限制在范围内
+	got := run(c, 50, 55, 55, 55, 30)
+	want := []float64{80, 135, 185, 200, 80}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("输出 = %v, 期望 %v", got, want)
+		}
+	}
+
+	// 缩小范围时积分限制在新的范围内
+	run(c, 55, 55, 55)
+	c.SetLimits(0, 100)
+	if out := c.Update(50, time.Second); out != 100 {
+		t.Errorf("缩小范围后的输出 = %v, 期望 100", out)
+	}
+}
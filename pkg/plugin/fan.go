@@ -0,0 +1,204 @@
+package plugin
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FanOptions 命令风扇的设置
+type FanOptions struct {
+	// Command 通过 /bin/sh -c 执行的命令
+	Command string
+	// Timeout 命令或请求的超时时间，0表示 DefaultTimeout
+	Timeout time.Duration
+	// Persistent 以常驻进程运行命令，使用JSON行协议
+	Persistent bool
+	// RestoreCommand 单次命令方式下退出时执行的命令，用于恢复风扇原来的控制方式，为空时不执行
+	RestoreCommand string
+	// MinPWM、MaxPWM PWM范围
+	MinPWM int
+	MaxPWM int
+}
+
+// Fan 通过外部命令设置转速的风扇
+type Fan struct {
+	name string
+	opts FanOptions
+	proc *Process
+
+	mu      sync.Mutex
+	lastPWM int
+	closed  bool
+}
+
+// NewFan 创建命令风扇，name为风扇名称，通过环境变量 FANAP_FAN_NAME 传给单次命令
+func NewFan(name string, opts FanOptions) (*Fan, error) {
+	if strings.TrimSpace(opts.Command) == "" {
+		return nil, fmt.Errorf("未指定命令")
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultTimeout
+	}
+	if opts.MinPWM < 0 || opts.MaxPWM > 255 || opts.MinPWM >= opts.MaxPWM {
+		return nil, fmt.Errorf("无效的PWM范围 %d-%d", opts.MinPWM, opts.MaxPWM)
+	}
+	if opts.Persistent && opts.RestoreCommand != "" {
+		return nil, fmt.Errorf("常驻插件通过restore请求恢复风扇，不使用恢复命令")
+	}
+
+	f := &Fan{name: name, opts: opts, lastPWM: -1}
+	if opts.Persistent {
+		f.proc = NewProcess(name, opts.Command, opts.Timeout)
+	}
+	return f, nil
+}
+
+// SetSpeed 设置风扇速度，限制在PWM范围内
+func (f *Fan) SetSpeed(pwm int) error {
+	if pwm < f.opts.MinPWM {
+		pwm = f.opts.MinPWM
+	}
+	if pwm > f.opts.MaxPWM {
+		pwm = f.opts.MaxPWM
+	}
+	return f.set(pwm)
+}
+
+// SetFullSpeed 以PWM 255全速运行，不受最大PWM限制
+func (f *Fan) SetFullSpeed() error {
+	return f.set(255)
+}
+
+// StopFan 以PWM 0让风扇停转（零转速模式），不受最小PWM限制
+func (f *Fan) StopFan() error {
+	return f.set(0)
+}
+
+// set 把PWM值发给插件
+// 单次命令方式下与上次相同时不再执行命令；常驻插件每次都发送，插件进程重新启动后也能收到当前的值
+func (f *Fan) set(pwm int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if pwm == f.lastPWM && f.proc == nil {
+		return nil
+	}
+
+	duty := float64(pwm) * 100 / 255
+	if f.proc != nil {
+		if _, err := f.proc.Call(MethodSet, &pwm, &duty); err != nil {
+			return err
+		}
+	} else {
+		env := map[string]string{
+			"FANAP_FAN_NAME":    f.name,
+			"FANAP_TARGET_PWM":  strconv.Itoa(pwm),
+			"FANAP_TARGET_DUTY": strconv.FormatFloat(duty, 'f', 1, 64),
+		}
+		if _, err := run(f.opts.Command, f.opts.Timeout, env); err != nil {
+			return err
+		}
+	}
+
+	f.lastPWM = pwm
+	return nil
+}
+
+// GetSpeed 获取当前风扇速度
+// 常驻插件在get响应中提供pwm时使用插件的值，否则返回上次设置的值
+func (f *Fan) GetSpeed() (int, error) {
+	if f.proc != nil {
+		resp, err := f.proc.Call(MethodGet, nil, nil)
+		if err != nil {
+			return 0, err
+		}
+		if resp.PWM != nil {
+			return *resp.PWM, nil
+		}
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.lastPWM < 0 {
+		return 0, fmt.Errorf("尚未设置风扇速度")
+	}
+	return f.lastPWM, nil
+}
+
+// GetRPM 获取风扇转速，只有在get响应中提供rpm的常驻插件支持
+func (f *Fan) GetRPM() (int, error) {
+	if f.proc == nil {
+		return 0, fmt.Errorf("单次命令方式的插件不提供转速")
+	}
+	resp, err := f.proc.Call(MethodGet, nil, nil)
+	if err != nil {
+		return 0, err
+	}
+	if resp.RPM == nil {
+		return 0, fmt.Errorf("插件响应中没有转速")
+	}
+	return *resp.RPM, nil
+}
+
+// GetMinSpeed 获取最小速度
+func (f *Fan) GetMinSpeed() int {
+	return f.opts.MinPWM
+}
+
+// GetMaxSpeed 获取最大速度
+func (f *Fan) GetMaxSpeed() int {
+	return f.opts.MaxPWM
+}
+
+// Close 恢复风扇原来的控制方式并关闭常驻插件进程，只有第一次调用（包括 Release）生效
+func (f *Fan) Close() error {
+	if !f.markClosed() {
+		return nil
+	}
+	if f.proc != nil {
+		_, err := f.proc.Call(MethodRestore, nil, nil)
+		if cerr := f.proc.Close(); err == nil {
+			err = cerr
+		}
+		return err
+	}
+
+	if f.opts.RestoreCommand == "" {
+		return nil
+	}
+	if _, err := run(f.opts.RestoreCommand, f.opts.Timeout, map[string]string{"FANAP_FAN_NAME": f.name}); err != nil {
+		return fmt.Errorf("执行恢复命令失败: %w", err)
+	}
+	return nil
+}
+
+// Release 关闭常驻插件进程，不发送restore请求也不执行恢复命令，风扇保持最后设置的状态
+func (f *Fan) Release() error {
+	if !f.markClosed() || f.proc == nil {
+		return nil
+	}
+	return f.proc.Close()
+}
+
+// markClosed 标记风扇已关闭，已经关闭过时返回false
+func (f *Fan) markClosed() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.closed {
+		return false
+	}
+	f.closed = true
+	return true
+}
+
+// String 描述风扇，用于日志
+func (f *Fan) String() string {
+	if f.proc != nil {
+		return fmt.Sprintf("常驻插件 %q", f.opts.Command)
+	}
+	return fmt.Sprintf("命令 %q", f.opts.Command)
+}
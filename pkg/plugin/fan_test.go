@@ -0,0 +1,170 @@
+package plugin
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// readLines 读取测试命令记录的行
+func readLines(t *testing.T, path string) []string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.Split(strings.TrimSpace(string(data)), "\n")
+}
+
+func TestFanCommandEnv(t *testing.T) {
+	dir := t.TempDir()
+	log := filepath.Join(dir, "set.log")
+	restore := filepath.Join(dir, "restore.log")
+
+	f, err := NewFan("cpu", FanOptions{
+		Command:        `echo "$FANAP_FAN_NAME $FANAP_TARGET_PWM $FANAP_TARGET_DUTY" >> ` + log,
+		RestoreCommand: `echo "restore $FANAP_FAN_NAME" >> ` + restore,
+		MinPWM:         30,
+		MaxPWM:         200,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 与上次相同的值不再执行命令；超出范围的值限制在PWM范围内，全速不受最大PWM限制
+	steps := []func() error{
+		func() error { return f.SetSpeed(128) },
+		func() error { return f.SetSpeed(128) },
+		func() error { return f.SetSpeed(10) },
+		func() error { return f.SetSpeed(250) },
+		func() error { return f.SetSpeed(200) },
+		f.SetFullSpeed,
+		f.SetFullSpeed,
+	}
+	for i, step := range steps {
+		if err := step(); err != nil {
+			t.Fatalf("第%d步 错误: %v", i+1, err)
+		}
+	}
+
+	want := []string{"cpu 128 50.2", "cpu 30 11.8", "cpu 200 78.4", "cpu 255 100.0"}
+	if got := readLines(t, log); strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("命令收到 %q, 期望 %q", got, want)
+	}
+	if pwm, err := f.GetSpeed(); err != nil || pwm != 255 {
+		t.Errorf("GetSpeed() = %d, %v, 期望 255", pwm, err)
+	}
+
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got := readLines(t, restore); len(got) != 1 || got[0] != "restore cpu" {
+		t.Errorf("恢复命令收到 %q, 期望 [\"restore cpu\"]", got)
+	}
+}
+
+func TestFanCommandFailure(t *testing.T) {
+	f, err := NewFan("cpu", FanOptions{Command: "exit 1", MinPWM: 0, MaxPWM: 255})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := f.SetSpeed(100); err == nil {
+		t.Fatal("命令失败时期望返回错误")
+	}
+	// 失败的值不记录，之后相同的值仍然执行命令
+	if _, err := f.GetSpeed(); err == nil {
+		t.Error("没有设置成功时 GetSpeed 期望返回错误")
+	}
+	if err := f.SetSpeed(100); err == nil {
+		t.Error("重复设置失败的值时期望再次执行命令并返回错误")
+	}
+	if err := f.Close(); err != nil {
+		t.Errorf("没有恢复命令时 Close() = %v", err)
+	}
+}
+
+func TestFanPersistent(t *testing.T) {
+	log := filepath.Join(t.TempDir(), "requests.log")
+	script := `while read line; do echo "$line" >> ` + log + `; id=$(echo "$line" | sed 's/.*"id":\([0-9]*\).*/\1/'); ` +
+		`case "$line" in *'"get"'*) echo "{\"id\": $id, \"pwm\": 77, \"rpm\": 1500}";; *) echo "{\"id\": $id}";; esac; done`
+
+	f, err := NewFan("gpu", FanOptions{Command: script, Persistent: true, MinPWM: 0, MaxPWM: 255})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 常驻插件每次都发送，插件重新启动后也能收到当前的值
+	for i := 0; i < 2; i++ {
+		if err := f.SetSpeed(128); err != nil {
+			t.Fatalf("SetSpeed 错误: %v", err)
+		}
+	}
+	if pwm, err := f.GetSpeed(); err != nil || pwm != 77 {
+		t.Errorf("GetSpeed() = %d, %v, 期望插件提供的 77", pwm, err)
+	}
+	if rpm, err := f.GetRPM(); err != nil || rpm != 1500 {
+		t.Errorf("GetRPM() = %d, %v, 期望 1500", rpm, err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	var methods []string
+	for _, line := range readLines(t, log) {
+		for _, m := range []string{MethodSet, MethodGet, MethodRestore} {
+			if strings.Contains(line, `"method":"`+m+`"`) {
+				methods = append(methods, m)
+			}
+		}
+	}
+	want := []string{MethodSet, MethodSet, MethodGet, MethodGet, MethodRestore}
+	if strings.Join(methods, " ") != strings.Join(want, " ") {
+		t.Errorf("插件收到的请求 %v, 期望 %v", methods, want)
+	}
+}
+
+func TestFanRelease(t *testing.T) {
+	dir := t.TempDir()
+	restore := filepath.Join(dir, "restore.log")
+	log := filepath.Join(dir, "requests.log")
+
+	// 单次命令方式释放时不执行恢复命令，之后的 Close 也不再执行
+	f, err := NewFan("cpu", FanOptions{Command: "true", RestoreCommand: "echo restore >> " + restore, MinPWM: 0, MaxPWM: 255})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Release(); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got := readLines(t, restore); got != nil {
+		t.Errorf("释放后执行了恢复命令: %q", got)
+	}
+
+	// 常驻插件释放时关闭进程但不发送restore请求
+	script := `while read line; do echo "$line" >> ` + log + `; echo '{}'; done`
+	f, err = NewFan("gpu", FanOptions{Command: script, Persistent: true, MinPWM: 0, MaxPWM: 255})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := f.SetFullSpeed(); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Release(); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range readLines(t, log) {
+		if strings.Contains(line, MethodRestore) {
+			t.Errorf("释放后插件收到了restore请求: %s", line)
+		}
+	}
+}
@@ -0,0 +1,277 @@
+// Package plugin 通过外部命令实现的温度传感器和风扇
+//
+// 插件有两种运行方式：
+//
+// 单次命令：每次读取温度或设置风扇时通过 /bin/sh -c 执行一次命令。
+// 传感器命令的标准输出为摄氏温度（如 "45.5"），或一行JSON（如 {"temp": 45.5}）；
+// 风扇命令通过环境变量 FANAP_TARGET_PWM（0-255）和 FANAP_TARGET_DUTY（0-100）获得目标转速。
+// 命令以非0状态退出或超时视为失败。
+//
+// 常驻进程：启动一次插件进程，通过标准输入输出逐行交换JSON消息，每个请求一行，每个响应一行：
+//
+//	{"id": 1, "method": "read"}                        读取温度，响应 {"id": 1, "temp": 45.5}
+//	{"id": 2, "method": "set", "pwm": 128, "duty": 50.2}  设置风扇，响应 {"id": 2}
+//	{"id": 3, "method": "get"}                         读取风扇状态，响应 {"id": 3, "pwm": 128, "rpm": 1200}，两项都可以省略
+//	{"id": 4, "method": "restore"}                     退出前恢复风扇原来的控制方式，响应 {"id": 4}
+//
+// 失败时响应 {"id": N, "error": "说明"}。响应中的id可以省略，id与当前请求不一致的响应会被忽略。
+// 标准输出中不是JSON对象的行被忽略（记录到日志），日志请写到标准错误。
+// 插件在超时时间内没有响应时fanap终止插件进程，下一个请求时重新启动；fanap退出时关闭插件的标准输入。
+package plugin
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// DefaultTimeout 命令或请求的默认超时时间
+const DefaultTimeout = 5 * time.Second
+
+// 常驻进程的请求方法
+const (
+	MethodRead    = "read"
+	MethodSet     = "set"
+	MethodGet     = "get"
+	MethodRestore = "restore"
+)
+
+// Request 发给常驻插件进程的请求
+type Request struct {
+	ID     int      `json:"id"`
+	Method string   `json:"method"`
+	PWM    *int     `json:"pwm,omitempty"`
+	Duty   *float64 `json:"duty,omitempty"`
+}
+
+// Response 常驻插件进程的响应，也是单次命令可选的JSON输出格式
+type Response struct {
+	ID    int      `json:"id,omitempty"`
+	Temp  *float64 `json:"temp,omitempty"`
+	PWM   *int     `json:"pwm,omitempty"`
+	RPM   *int     `json:"rpm,omitempty"`
+	Error string   `json:"error,omitempty"`
+}
+
+// command 创建通过 /bin/sh -c 执行的命令
+// 命令在单独的进程组中运行，超时时终止整个进程组，避免子进程占用输出管道
+func command(ctx context.Context, line string, env map[string]string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, "/bin/sh", "-c", line)
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+	cmd.WaitDelay = time.Second
+
+	if len(env) > 0 {
+		vars := os.Environ()
+		keys := make([]string, 0, len(env))
+		for k := range env {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			vars = append(vars, k+"="+env[k])
+		}
+		cmd.Env = vars
+	}
+	return cmd
+}
+
+// run 执行单次命令，返回标准输出
+func run(line string, timeout time.Duration, env map[string]string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var stderr strings.Builder
+	cmd := command(ctx, line, env)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if ctx.Err() != nil {
+		return nil, fmt.Errorf("命令超时 (%v)", timeout)
+	}
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%w: %s", err, msg)
+		}
+		return nil, err
+	}
+	return out, nil
+}
+
+// Process 按行交换JSON消息的常驻插件进程，进程退出或超时后在下一个请求时重新启动
+type Process struct {
+	name    string
+	command string
+	timeout time.Duration
+
+	mu     sync.Mutex
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	lines  chan string
+	exited chan struct{}
+	nextID int
+}
+
+// NewProcess 创建常驻插件进程，第一个请求时启动；name用于日志
+func NewProcess(name, command string, timeout time.Duration) *Process {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	return &Process{name: name, command: command, timeout: timeout}
+}
+
+// Call 发送请求并等待响应，插件返回error时作为错误返回
+func (p *Process) Call(method string, pwm *int, duty *float64) (Response, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.cmd != nil {
+		select {
+		case <-p.exited:
+			// 插件进程在两次请求之间退出，重新启动
+			p.stop()
+		default:
+		}
+	}
+	if p.cmd == nil {
+		if err := p.start(); err != nil {
+			return Response{}, err
+		}
+	}
+
+	p.nextID++
+	req := Request{ID: p.nextID, Method: method, PWM: pwm, Duty: duty}
+	data, err := json.Marshal(req)
+	if err != nil {
+		return Response{}, err
+	}
+	if _, err := p.stdin.Write(append(data, '\n')); err != nil {
+		p.stop()
+		return Response{}, fmt.Errorf("发送请求失败: %w", err)
+	}
+
+	timer := time.NewTimer(p.timeout)
+	defer timer.Stop()
+	for {
+		select {
+		case line, ok := <-p.lines:
+			if !ok {
+				p.stop()
+				return Response{}, fmt.Errorf("插件进程已退出")
+			}
+			var resp Response
+			if err := json.Unmarshal([]byte(line), &resp); err != nil {
+				// 插件输出到标准输出的日志等，继续等待响应，直到超时后重新启动插件
+				if strings.TrimSpace(line) != "" {
+					log.Printf("插件 %s 输出了无效的响应，已忽略: %q", p.name, line)
+				}
+				continue
+			}
+			if resp.ID != 0 && resp.ID != req.ID {
+				// 之前超时的请求的响应
+				continue
+			}
+			if resp.Error != "" {
+				return resp, errors.New(resp.Error)
+			}
+			return resp, nil
+		case <-timer.C:
+			log.Printf("插件 %s 超过 %v 没有响应，终止插件进程", p.name, p.timeout)
+			p.stop()
+			return Response{}, fmt.Errorf("插件响应超时 (%v)", p.timeout)
+		}
+	}
+}
+
+// Close 关闭插件的标准输入，等待插件进程退出，超时后终止
+func (p *Process) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.cmd == nil {
+		return nil
+	}
+	p.stdin.Close()
+
+	timer := time.NewTimer(p.timeout)
+	defer timer.Stop()
+	for {
+		select {
+		case _, ok := <-p.lines:
+			if ok {
+				continue
+			}
+			p.cmd = nil
+			return nil
+		case <-timer.C:
+			p.stop()
+			return fmt.Errorf("插件 %s 没有在 %v 内退出", p.name, p.timeout)
+		}
+	}
+}
+
+// start 启动插件进程，需要持有mu
+func (p *Process) start() error {
+	cmd := command(context.Background(), p.command, nil)
+	cmd.Stderr = os.Stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("启动插件失败: %w", err)
+	}
+
+	lines := make(chan string)
+	exited := make(chan struct{})
+	go func() {
+		defer close(lines)
+		scanner := bufio.NewScanner(stdout)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+		if err := cmd.Wait(); err != nil {
+			log.Printf("插件 %s 已退出: %v", p.name, err)
+		}
+		close(exited)
+	}()
+
+	log.Printf("插件 %s 已启动 (pid %d)", p.name, cmd.Process.Pid)
+	p.cmd = cmd
+	p.stdin = stdin
+	p.lines = lines
+	p.exited = exited
+	return nil
+}
+
+// stop 终止插件进程，需要持有mu
+func (p *Process) stop() {
+	if p.cmd == nil {
+		return
+	}
+	syscall.Kill(-p.cmd.Process.Pid, syscall.SIGKILL)
+	p.stdin.Close()
+
+	// 丢弃剩余的输出，等待读取goroutine结束
+	go func(lines chan string) {
+		for range lines {
+		}
+	}(p.lines)
+	p.cmd = nil
+}
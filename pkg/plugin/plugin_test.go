@@ -0,0 +1,47 @@
+package plugin
+
+import (
+	"testing"
+	"time"
+)
+
+func TestProcessSkipsNonJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		script  string
+		want    float64
+		wantErr bool
+	}{
+		{
+			"响应前输出日志",
+			`while read line; do id=$(echo "$line" | sed 's/.*"id":\([0-9]*\).*/\1/'); echo "reading sensor..."; echo; echo 42; echo "{\"id\": $id, \"temp\": 42.5}"; done`,
+			42.5, false,
+		},
+		{
+			"只输出日志",
+			`while read line; do echo "not a response"; done`,
+			0, true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewProcess("test", tt.script, 300*time.Millisecond)
+			defer p.Close()
+
+			// 第二次请求验证超时重新启动后或跳过无效行后仍能正常通信
+			for i := 0; i < 2; i++ {
+				resp, err := p.Call(MethodRead, nil, nil)
+				if (err != nil) != tt.wantErr {
+					t.Fatalf("第%d次请求 错误 = %v, 期望出错 %v", i+1, err, tt.wantErr)
+				}
+				if err != nil {
+					continue
+				}
+				if resp.Temp == nil || *resp.Temp != tt.want {
+					t.Errorf("第%d次请求 响应 = %+v, 期望温度 %.1f", i+1, resp, tt.want)
+				}
+			}
+		})
+	}
+}
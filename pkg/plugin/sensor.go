@@ -0,0 +1,128 @@
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SensorOptions 命令温度传感器的设置
+type SensorOptions struct {
+	// Command 通过 /bin/sh -c 执行的命令
+	Command string
+	// Timeout 命令或请求的超时时间，0表示 DefaultTimeout
+	Timeout time.Duration
+	// Cache 读数的缓存时间，缓存期间不再执行命令（失败的结果同样缓存），0表示每次读取都执行
+	Cache time.Duration
+	// Persistent 以常驻进程运行命令，使用JSON行协议
+	Persistent bool
+}
+
+// Sensor 通过外部命令读取温度的传感器
+type Sensor struct {
+	name string
+	opts SensorOptions
+	proc *Process
+
+	mu     sync.Mutex
+	readAt time.Time
+	value  float64
+	err    error
+}
+
+// NewSensor 创建命令温度传感器，name为传感器名称，通过环境变量 FANAP_SENSOR_NAME 传给单次命令
+func NewSensor(name string, opts SensorOptions) (*Sensor, error) {
+	if strings.TrimSpace(opts.Command) == "" {
+		return nil, fmt.Errorf("未指定命令")
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultTimeout
+	}
+	if opts.Cache < 0 {
+		return nil, fmt.Errorf("缓存时间不能为负数")
+	}
+
+	s := &Sensor{name: name, opts: opts}
+	if opts.Persistent {
+		s.proc = NewProcess(name, opts.Command, opts.Timeout)
+	}
+	return s, nil
+}
+
+// GetTemperature 获取当前温度（摄氏度），缓存期间返回上次的结果
+func (s *Sensor) GetTemperature() (float64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if !s.readAt.IsZero() && now.Sub(s.readAt) < s.opts.Cache {
+		return s.value, s.err
+	}
+
+	s.value, s.err = s.read()
+	s.readAt = now
+	return s.value, s.err
+}
+
+// read 执行一次命令或请求
+func (s *Sensor) read() (float64, error) {
+	if s.proc != nil {
+		resp, err := s.proc.Call(MethodRead, nil, nil)
+		if err != nil {
+			return 0, err
+		}
+		if resp.Temp == nil {
+			return 0, fmt.Errorf("插件响应中没有温度")
+		}
+		return *resp.Temp, nil
+	}
+
+	out, err := run(s.opts.Command, s.opts.Timeout, map[string]string{"FANAP_SENSOR_NAME": s.name})
+	if err != nil {
+		return 0, err
+	}
+	return parseTemp(out)
+}
+
+// parseTemp 解析单次命令的输出：摄氏温度或一行JSON
+func parseTemp(out []byte) (float64, error) {
+	text := strings.TrimSpace(string(out))
+	if strings.HasPrefix(text, "{") {
+		var resp Response
+		if err := json.Unmarshal([]byte(text), &resp); err != nil {
+			return 0, fmt.Errorf("无效的输出 %q: %w", text, err)
+		}
+		if resp.Error != "" {
+			return 0, fmt.Errorf("%s", resp.Error)
+		}
+		if resp.Temp == nil {
+			return 0, fmt.Errorf("输出中没有温度: %q", text)
+		}
+		return *resp.Temp, nil
+	}
+
+	t, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return 0, fmt.Errorf("无效的温度 %q", text)
+	}
+	return t, nil
+}
+
+// Close 关闭常驻插件进程
+func (s *Sensor) Close() error {
+	if s.proc != nil {
+		return s.proc.Close()
+	}
+	return nil
+}
+
+// String 描述传感器，用于日志
+func (s *Sensor) String() string {
+	if s.proc != nil {
+		return fmt.Sprintf("常驻插件 %q", s.opts.Command)
+	}
+	return fmt.Sprintf("命令 %q", s.opts.Command)
+}
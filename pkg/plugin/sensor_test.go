@@ -0,0 +1,36 @@
+package plugin
+
+import (
+	"testing"
+)
+
+func TestParseTemp(t *testing.T) {
+	tests := []struct {
+		name    string
+		out     string
+		want    float64
+		wantErr bool
+	}{
+		{"数字", "45.5\n", 45.5, false},
+		{"带空白的整数", "  42 \n", 42, false},
+		{"负数", "-5", -5, false},
+		{"JSON", `{"temp": 38.25}` + "\n", 38.25, false},
+		{"JSON错误", `{"error": "读取失败"}`, 0, true},
+		{"JSON没有温度", `{"pwm": 100}`, 0, true},
+		{"无效的JSON", `{"temp": }`, 0, true},
+		{"无效的数字", "warm", 0, true},
+		{"空输出", "", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseTemp([]byte(tt.out))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseTemp(%q) 错误 = %v, 期望出错 %v", tt.out, err, tt.wantErr)
+			}
+			if err == nil && got != tt.want {
+				t.Errorf("parseTemp(%q) = %v, 期望 %v", tt.out, got, tt.want)
+			}
+		})
+	}
+}
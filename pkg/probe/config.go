@@ -0,0 +1,164 @@
+package probe
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/fanap/pkg/config"
+	"github.com/fanap/pkg/fan"
+	"github.com/fanap/pkg/hwmon"
+)
+
+// WriteConfig 把探测结果写入配置文件，返回所做修改的说明
+// 配置文件已存在时，为device与探测的PWM通道对应的pwm风扇设置tach，原文件备份为 .bak；
+// 配置文件不存在时，为每个测出风扇的PWM通道生成一个跟随CPU温度的风扇
+func WriteConfig(path string, r *Result) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return generateConfig(path, r)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取配置文件失败: %w", err)
+	}
+
+	cfg, err := config.Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("配置文件 %s 无效:\n%w", path, err)
+	}
+
+	var notes []string
+	values := make(map[string]interface{})
+	used := make(map[string]bool)
+	for _, fc := range cfg.Fans {
+		if fc.Type != config.FanPWM {
+			continue
+		}
+		sel, err := fan.ParseSelector(fc.Device)
+		if err != nil {
+			continue
+		}
+		target, err := sel.Resolve()
+		if err != nil {
+			notes = append(notes, fmt.Sprintf("跳过风扇 %s: %v", fc.Name, err))
+			continue
+		}
+		p, ok := r.Find(target.Path)
+		if !ok {
+			continue
+		}
+		used[p.Path] = true
+		tach := p.Tach()
+		switch {
+		case tach == "":
+			notes = append(notes, fmt.Sprintf("风扇 %s (%s) 没有测出转速输入，保持不变", fc.Name, p.Selector))
+		case tach == fc.Tach:
+			notes = append(notes, fmt.Sprintf("风扇 %s 的转速输入已是 %s", fc.Name, tach))
+		default:
+			values[fc.Name] = tach
+			notes = append(notes, fmt.Sprintf("风扇 %s: tach = %s", fc.Name, tach))
+		}
+	}
+	for _, p := range r.PWMs {
+		if !used[p.Path] && p.Tach() != "" {
+			notes = append(notes, fmt.Sprintf("%s 控制 %s，但配置文件中没有使用该通道的pwm风扇", p.Selector, p.Tach()))
+		}
+	}
+	if len(values) == 0 {
+		return notes, nil
+	}
+
+	out, err := config.SetFanValues(data, "tach", values)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := config.Parse(out); err != nil {
+		return nil, fmt.Errorf("更新后的配置无效: %w", err)
+	}
+	if err := os.WriteFile(path+".bak", data, 0644); err != nil {
+		return nil, fmt.Errorf("备份配置文件失败: %w", err)
+	}
+	if err := os.WriteFile(path, out, 0644); err != nil {
+		return nil, fmt.Errorf("写入配置文件失败: %w", err)
+	}
+	notes = append(notes, fmt.Sprintf("已更新 %s（原文件备份为 %s.bak）", path, path))
+	return notes, nil
+}
+
+// 生成配置文件用到的结构，只输出需要的键
+type (
+	genConfig struct {
+		Sensors []genSensor `json:"sensors"`
+		Fans    []genFan    `json:"fans"`
+	}
+	genSensor struct {
+		Name string `json:"name"`
+		Path string `json:"path"`
+	}
+	genFan struct {
+		Name   string `json:"name"`
+		Type   string `json:"type"`
+		Device string `json:"device"`
+		Tach   string `json:"tach"`
+		Sensor string `json:"sensor"`
+	}
+)
+
+// generateConfig 根据探测结果生成新的配置文件
+func generateConfig(path string, r *Result) ([]string, error) {
+	cfg := genConfig{Sensors: []genSensor{{Name: "cpu", Path: cpuSensor()}}}
+	var notes []string
+	for _, p := range r.PWMs {
+		tach := p.Tach()
+		if tach == "" {
+			continue
+		}
+		name := p.Channel
+		if p.Chip != "" {
+			name = p.Chip + "_" + p.Channel
+		}
+		cfg.Fans = append(cfg.Fans, genFan{Name: name, Type: config.FanPWM, Device: p.Selector, Tach: tach, Sensor: "cpu"})
+		notes = append(notes, fmt.Sprintf("风扇 %s: device = %s, tach = %s", name, p.Selector, tach))
+	}
+	if len(cfg.Fans) == 0 {
+		return nil, fmt.Errorf("没有测出任何受PWM控制的风扇，不生成配置文件")
+	}
+
+	out, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	out = append(out, '\n')
+	if _, err := config.Parse(out); err != nil {
+		return nil, fmt.Errorf("生成的配置无效: %w", err)
+	}
+	if err := os.WriteFile(path, out, 0644); err != nil {
+		return nil, fmt.Errorf("写入配置文件失败: %w", err)
+	}
+	notes = append(notes, fmt.Sprintf("已生成 %s，请按需调整温度阈值", path))
+	return notes, nil
+}
+
+// cpuSensor 返回CPU温度传感器的选择器，没有找到时使用自动检测
+func cpuSensor() string {
+	chips, err := hwmon.Scan()
+	if err != nil {
+		return "auto"
+	}
+	for _, c := range chips {
+		if c.Name == "" {
+			continue
+		}
+		for _, t := range c.Temps {
+			if !hwmon.IsCPUSensor(c.Name, t.Label) {
+				continue
+			}
+			if t.Label != "" {
+				return "hwmon:" + c.Name + "/" + t.Label
+			}
+			return "hwmon:" + c.Name + "/" + t.Name()
+		}
+	}
+	return "auto"
+}
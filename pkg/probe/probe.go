@@ -0,0 +1,373 @@
+// Package probe 测出每个PWM通道实际控制的风扇（类似lm-sensors的pwmconfig）
+//
+// 很多Super-I/O芯片上pwmN控制的并不是fanN。探测时先让所有PWM通道全速运行，记录每个转速输入的基准转速，
+// 再逐个把PWM通道降到低速，转速明显下降的转速输入即为该通道控制的风扇。探测结束后恢复所有通道的原始模式和PWM值。
+package probe
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/fanap/pkg/fan"
+	"github.com/fanap/pkg/hwmon"
+)
+
+// 默认参数
+const (
+	// DefaultSettle 改变PWM后等待风扇转速稳定的时间
+	DefaultSettle = 5 * time.Second
+	// DefaultLowPWM 测试时把PWM通道降到的值
+	DefaultLowPWM = 0
+	// DefaultDrop 转速下降超过基准转速的该比例时认为风扇受该通道控制
+	DefaultDrop = 0.25
+)
+
+// Options 探测参数
+type Options struct {
+	// PWMs 只探测这些风扇选择器对应的PWM通道，为空时探测所有PWM通道
+	PWMs []string
+	// Settle 改变PWM后等待转速稳定的时间
+	Settle time.Duration
+	// LowPWM 测试时把PWM通道降到的值
+	LowPWM int
+	// Drop 转速下降比例的阈值（0-1）
+	Drop float64
+}
+
+// DefaultOptions 返回默认的探测参数
+func DefaultOptions() Options {
+	return Options{Settle: DefaultSettle, LowPWM: DefaultLowPWM, Drop: DefaultDrop}
+}
+
+// Validate 检查探测参数
+func (o Options) Validate() error {
+	if o.Settle <= 0 {
+		return fmt.Errorf("等待时间必须大于0")
+	}
+	if o.LowPWM < 0 || o.LowPWM > 254 {
+		return fmt.Errorf("测试PWM值必须在0-254之间")
+	}
+	if o.Drop <= 0 || o.Drop >= 1 {
+		return fmt.Errorf("转速下降比例必须在0-1之间")
+	}
+	return nil
+}
+
+// Result 探测结果
+type Result struct {
+	PWMs []PWMResult `json:"pwms"`
+}
+
+// PWMResult 一个PWM通道的探测结果
+type PWMResult struct {
+	Chip    string `json:"chip"`
+	Channel string `json:"channel"`
+	Path    string `json:"path"`
+	// Selector、DeviceSelector 可以直接用于 -pwm 或配置文件中风扇的 device
+	Selector       string `json:"selector"`
+	DeviceSelector string `json:"device_selector,omitempty"`
+	// Fans 该通道控制的风扇，按转速下降幅度从大到小排序
+	Fans []FanResult `json:"fans"`
+	// Error 探测该通道失败的原因
+	Error string `json:"error,omitempty"`
+}
+
+// FanResult 受PWM通道控制的转速输入
+type FanResult struct {
+	Chip    string `json:"chip"`
+	Channel string `json:"channel"`
+	Label   string `json:"label,omitempty"`
+	Path    string `json:"path"`
+	// Selector 可以直接用于 -tach 或配置文件中风扇的 tach
+	Selector string `json:"selector"`
+	// FullRPM PWM全速时的转速，LowRPM 降到测试PWM值后的转速
+	FullRPM int `json:"full_rpm"`
+	LowRPM  int `json:"low_rpm"`
+}
+
+// Tach 返回PWM通道控制的第一个风扇的转速输入选择器，没有找到时返回空字符串
+func (r PWMResult) Tach() string {
+	if len(r.Fans) == 0 {
+		return ""
+	}
+	return r.Fans[0].Selector
+}
+
+// Find 按pwmN文件路径查找探测结果
+func (r *Result) Find(path string) (PWMResult, bool) {
+	for _, p := range r.PWMs {
+		if p.Path == path {
+			return p, true
+		}
+	}
+	return PWMResult{}, false
+}
+
+// pwmChannel 探测的PWM通道及其原始状态
+type pwmChannel struct {
+	chip hwmon.Chip
+	pwm  hwmon.PWM
+	// origEnable、origPWM 探测前的模式和PWM值，没有pwmN_enable时origEnable为-1
+	origEnable int
+	origPWM    int
+}
+
+// tach 一个转速输入
+type tach struct {
+	chip hwmon.Chip
+	fan  hwmon.Fan
+	full int
+}
+
+// Run 执行探测，ctx结束时中止探测并恢复所有PWM通道
+// 探测期间风扇会停转或低速运行，应在系统空闲时执行
+func Run(ctx context.Context, opts Options) (*Result, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+
+	chips, err := hwmon.Scan()
+	if err != nil {
+		return nil, err
+	}
+	channels, err := selectPWMs(chips, opts.PWMs)
+	if err != nil {
+		return nil, err
+	}
+	if len(channels) == 0 {
+		return nil, fmt.Errorf("未找到任何PWM通道")
+	}
+
+	var tachs []*tach
+	for _, c := range chips {
+		for _, f := range c.Fans {
+			tachs = append(tachs, &tach{chip: c, fan: f})
+		}
+	}
+	if len(tachs) == 0 {
+		return nil, fmt.Errorf("未找到任何转速输入（fanN_input），无法测出PWM通道控制的风扇")
+	}
+
+	// 无论探测是否成功，都恢复所有通道的原始状态
+	defer restore(channels)
+
+	log.Printf("探测 %d 个PWM通道与 %d 个转速输入的对应关系，期间风扇会短暂停转或低速运行", len(channels), len(tachs))
+	for _, ch := range channels {
+		if err := ch.set(255); err != nil {
+			return nil, err
+		}
+	}
+	if err := sleep(ctx, opts.Settle); err != nil {
+		return nil, err
+	}
+	for _, t := range tachs {
+		t.full, _ = t.fan.Read()
+		log.Printf("  %s/%s 全速转速: %d RPM", t.chip.Name, t.fan.Name(), t.full)
+	}
+
+	result := &Result{}
+	for _, ch := range channels {
+		r := PWMResult{
+			Chip:     ch.chip.Name,
+			Channel:  ch.pwm.Name(),
+			Path:     ch.pwm.Path,
+			Selector: selector(ch.chip, ch.pwm.Name(), ch.pwm.Path),
+			Fans:     []FanResult{},
+		}
+		if ch.chip.Device != "" {
+			r.DeviceSelector = "device:" + ch.chip.Device + "/" + ch.pwm.Name()
+		}
+
+		log.Printf("探测 %s: PWM降到 %d", r.Selector, opts.LowPWM)
+		fans, err := probeChannel(ctx, ch, tachs, opts)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			r.Error = err.Error()
+			log.Printf("  探测失败: %v", err)
+		}
+		r.Fans = append(r.Fans, fans...)
+		for _, f := range fans {
+			log.Printf("  ✓ 控制 %s (%d -> %d RPM)", f.Selector, f.FullRPM, f.LowRPM)
+		}
+		if err == nil && len(fans) == 0 {
+			log.Printf("  ✗ 没有转速输入响应")
+		}
+		result.PWMs = append(result.PWMs, r)
+	}
+	return result, nil
+}
+
+// probeChannel 把一个PWM通道降到低速，找出转速明显下降的转速输入，然后恢复全速
+func probeChannel(ctx context.Context, ch *pwmChannel, tachs []*tach, opts Options) ([]FanResult, error) {
+	if err := ch.set(opts.LowPWM); err != nil {
+		return nil, err
+	}
+	waitErr := sleep(ctx, opts.Settle)
+
+	var fans []FanResult
+	if waitErr == nil {
+		for _, t := range tachs {
+			if t.full <= 0 {
+				// 没有连接风扇的转速输入
+				continue
+			}
+			rpm, err := t.fan.Read()
+			if err != nil {
+				continue
+			}
+			if float64(rpm) <= float64(t.full)*(1-opts.Drop) {
+				fans = append(fans, FanResult{
+					Chip:     t.chip.Name,
+					Channel:  t.fan.Name(),
+					Label:    t.fan.Label,
+					Path:     t.fan.Input,
+					Selector: selector(t.chip, t.fan.Name(), t.fan.Input),
+					FullRPM:  t.full,
+					LowRPM:   rpm,
+				})
+			}
+		}
+	}
+
+	// 恢复全速，等风扇重新转起来后再探测下一个通道
+	if err := ch.set(255); err != nil {
+		return fans, err
+	}
+	if waitErr != nil {
+		return nil, waitErr
+	}
+	if err := sleep(ctx, opts.Settle); err != nil {
+		return fans, err
+	}
+
+	// 转速下降幅度大的排在前面
+	sort.SliceStable(fans, func(i, j int) bool { return drop(fans[i]) > drop(fans[j]) })
+	return fans, nil
+}
+
+// drop 转速下降的比例
+func drop(f FanResult) float64 {
+	return 1 - float64(f.LowRPM)/float64(f.FullRPM)
+}
+
+// selectPWMs 选出需要探测的PWM通道并记录其原始状态
+func selectPWMs(chips []hwmon.Chip, selectors []string) ([]*pwmChannel, error) {
+	want := make(map[string]bool)
+	for _, s := range selectors {
+		sel, err := fan.ParseSelector(s)
+		if err != nil {
+			return nil, err
+		}
+		if sel.Kind == fan.SelectAuto {
+			return nil, fmt.Errorf("探测需要指定具体的PWM通道，或不指定以探测所有通道")
+		}
+		target, err := sel.Resolve()
+		if err != nil {
+			return nil, err
+		}
+		want[target.Path] = true
+	}
+
+	var channels []*pwmChannel
+	for _, c := range chips {
+		for _, p := range c.PWMs {
+			if len(want) > 0 && !want[p.Path] {
+				continue
+			}
+			ch := &pwmChannel{chip: c, pwm: p, origEnable: -1}
+			if p.Enable != nil {
+				ch.origEnable = *p.Enable
+			}
+			v, err := p.Read()
+			if err != nil {
+				return nil, fmt.Errorf("读取 %s 失败: %w", p.Path, err)
+			}
+			ch.origPWM = v
+			channels = append(channels, ch)
+		}
+	}
+	return channels, nil
+}
+
+// set 切换到手动模式并设置PWM值
+func (ch *pwmChannel) set(pwm int) error {
+	if ch.origEnable >= 0 {
+		if err := writeInt(ch.pwm.Path+"_enable", 1); err != nil {
+			return fmt.Errorf("设置 %s 为手动模式失败: %w", ch.pwm.Path, err)
+		}
+	}
+	if err := writeInt(ch.pwm.Path, pwm); err != nil {
+		return fmt.Errorf("设置 %s 失败: %w", ch.pwm.Path, err)
+	}
+	return nil
+}
+
+// restore 恢复所有通道的原始PWM值和模式
+func restore(channels []*pwmChannel) {
+	for _, ch := range channels {
+		if err := writeInt(ch.pwm.Path, ch.origPWM); err != nil {
+			log.Printf("警告: 恢复 %s 的PWM值失败: %v", ch.pwm.Path, err)
+		}
+		if ch.origEnable >= 0 {
+			if err := writeInt(ch.pwm.Path+"_enable", ch.origEnable); err != nil {
+				log.Printf("警告: 恢复 %s 的模式失败: %v", ch.pwm.Path, err)
+			}
+		}
+	}
+	log.Printf("已恢复 %d 个PWM通道的原始模式", len(channels))
+}
+
+// selector 返回通道的选择器，芯片没有名称时使用路径
+func selector(chip hwmon.Chip, channel, path string) string {
+	if chip.Name == "" {
+		return path
+	}
+	return "hwmon:" + chip.Name + "/" + channel
+}
+
+// sleep 等待d，ctx结束时提前返回错误
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+func writeInt(path string, v int) error {
+	return os.WriteFile(path, []byte(strconv.Itoa(v)+"\n"), 0644)
+}
+
+// WriteTable 以文本形式输出探测结果
+func (r *Result) WriteTable(w io.Writer) error {
+	fmt.Fprint(w, "=== PWM通道与风扇的对应关系 ===\n\n")
+	for _, p := range r.PWMs {
+		fmt.Fprintf(w, "%s (%s)\n", p.Selector, p.Path)
+		switch {
+		case p.Error != "":
+			fmt.Fprintf(w, "  ✗ 探测失败: %s\n", p.Error)
+		case len(p.Fans) == 0:
+			fmt.Fprintln(w, "  ✗ 没有转速输入响应（未连接风扇，或风扇没有测速线）")
+		}
+		for _, f := range p.Fans {
+			label := ""
+			if f.Label != "" {
+				label = " (" + f.Label + ")"
+			}
+			fmt.Fprintf(w, "  ✓ %s%s: %d RPM -> %d RPM\n", f.Selector, label, f.FullRPM, f.LowRPM)
+		}
+		fmt.Fprintln(w)
+	}
+	return nil
+}
@@ -0,0 +1,305 @@
+package pwmchip
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultPeriod 默认周期，25kHz是4线PWM风扇的标准频率
+const DefaultPeriod = 40 * time.Microsecond
+
+// 极性
+const (
+	PolarityNormal   = "normal"
+	PolarityInversed = "inversed"
+)
+
+// exportTimeout 导出通道后等待pwmM目录出现（以及udev修改权限）的时间
+const exportTimeout = 2 * time.Second
+
+// Options PWM通道的设置
+type Options struct {
+	// Period 周期，0表示 DefaultPeriod
+	Period time.Duration
+	// Polarity 极性，为空时保持不变
+	Polarity string
+	// MinPWM、MaxPWM PWM范围
+	MinPWM int
+	MaxPWM int
+}
+
+// state 通道的设置，用于恢复已被导出的通道
+type state struct {
+	period   int64
+	duty     int64
+	polarity string
+	enable   int64
+}
+
+// Fan 由PWM子系统的一个通道驱动的风扇
+type Fan struct {
+	spec   Spec
+	dir    string
+	chip   string
+	opts   Options
+	period int64
+
+	// exported 通道由fanap导出，关闭时取消导出；否则恢复orig
+	exported bool
+	orig     state
+
+	mu     sync.Mutex
+	closed bool
+}
+
+// NewFan 导出并设置PWM通道，spec见 ParseSpec
+func NewFan(spec string, opts Options) (*Fan, error) {
+	sp, err := ParseSpec(spec)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Period == 0 {
+		opts.Period = DefaultPeriod
+	}
+	if opts.Period < 0 {
+		return nil, fmt.Errorf("周期不能为负数")
+	}
+	switch opts.Polarity {
+	case "", PolarityNormal, PolarityInversed:
+	default:
+		return nil, fmt.Errorf("未知的极性 %q（可选: normal、inversed）", opts.Polarity)
+	}
+	if opts.MinPWM < 0 || opts.MaxPWM > 255 || opts.MinPWM >= opts.MaxPWM {
+		return nil, fmt.Errorf("无效的PWM范围 %d-%d", opts.MinPWM, opts.MaxPWM)
+	}
+
+	chip, err := sp.ChipPath()
+	if err != nil {
+		return nil, err
+	}
+	npwm, err := readInt(filepath.Join(chip, "npwm"))
+	if err != nil {
+		return nil, fmt.Errorf("读取通道数失败: %w", err)
+	}
+	if int64(sp.Channel) >= npwm {
+		return nil, fmt.Errorf("%s 只有 %d 个通道", filepath.Base(chip), npwm)
+	}
+
+	f := &Fan{
+		spec:   sp,
+		chip:   chip,
+		dir:    filepath.Join(chip, fmt.Sprintf("pwm%d", sp.Channel)),
+		opts:   opts,
+		period: opts.Period.Nanoseconds(),
+	}
+
+	if _, err := os.Stat(f.dir); err == nil {
+		if f.orig, err = f.readState(); err != nil {
+			return nil, err
+		}
+	} else {
+		if err := f.export(); err != nil {
+			return nil, err
+		}
+		f.exported = true
+	}
+
+	if err := f.configure(); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return f, nil
+}
+
+// export 导出通道并等待其属性文件可写
+func (f *Fan) export() error {
+	if err := writeAttr(filepath.Join(f.chip, "export"), strconv.Itoa(f.spec.Channel)); err != nil {
+		return fmt.Errorf("导出PWM通道失败: %w", err)
+	}
+
+	deadline := time.Now().Add(exportTimeout)
+	for {
+		file, err := os.OpenFile(f.attr("enable"), os.O_WRONLY, 0)
+		if err == nil {
+			file.Close()
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("导出PWM通道后 %v 内没有出现可写的 %s: %w", exportTimeout, f.dir, err)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+// configure 设置周期和极性并开始输出，初始占空比为0
+// duty_cycle不能大于period、polarity只能在停止输出时修改，按此顺序写入
+func (f *Fan) configure() error {
+	if err := writeAttr(f.attr("duty_cycle"), "0"); err != nil {
+		return err
+	}
+	if err := writeAttr(f.attr("period"), strconv.FormatInt(f.period, 10)); err != nil {
+		return err
+	}
+	if f.opts.Polarity != "" {
+		if err := writeAttr(f.attr("enable"), "0"); err != nil {
+			return err
+		}
+		if err := writeAttr(f.attr("polarity"), f.opts.Polarity); err != nil {
+			return fmt.Errorf("%w（驱动可能不支持反相输出）", err)
+		}
+	}
+	return writeAttr(f.attr("enable"), "1")
+}
+
+// readState 读取通道当前的设置
+func (f *Fan) readState() (state, error) {
+	var st state
+	var err error
+	if st.period, err = readInt(f.attr("period")); err != nil {
+		return st, fmt.Errorf("读取周期失败: %w", err)
+	}
+	if st.duty, err = readInt(f.attr("duty_cycle")); err != nil {
+		return st, fmt.Errorf("读取占空比失败: %w", err)
+	}
+	if st.enable, err = readInt(f.attr("enable")); err != nil {
+		return st, fmt.Errorf("读取输出状态失败: %w", err)
+	}
+	if data, err := os.ReadFile(f.attr("polarity")); err == nil {
+		st.polarity = strings.TrimSpace(string(data))
+	}
+	return st, nil
+}
+
+// attr 返回通道属性文件的路径
+func (f *Fan) attr(name string) string {
+	return filepath.Join(f.dir, name)
+}
+
+// SetSpeed 设置风扇速度，限制在PWM范围内
+func (f *Fan) SetSpeed(pwm int) error {
+	if pwm < f.opts.MinPWM {
+		pwm = f.opts.MinPWM
+	}
+	if pwm > f.opts.MaxPWM {
+		pwm = f.opts.MaxPWM
+	}
+	return f.set(pwm)
+}
+
+// SetFullSpeed 以100%占空比运行，不受最大PWM限制
+func (f *Fan) SetFullSpeed() error {
+	return f.set(255)
+}
+
+// StopFan 以0%占空比让风扇停转（零转速模式），不受最小PWM限制
+func (f *Fan) StopFan() error {
+	return f.set(0)
+}
+
+// set 把PWM值（0-255）换算为duty_cycle（纳秒）
+func (f *Fan) set(pwm int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	duty := f.period * int64(pwm) / 255
+	return writeAttr(f.attr("duty_cycle"), strconv.FormatInt(duty, 10))
+}
+
+// GetSpeed 读取duty_cycle并换算为PWM值
+func (f *Fan) GetSpeed() (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	duty, err := readInt(f.attr("duty_cycle"))
+	if err != nil {
+		return 0, fmt.Errorf("读取占空比失败: %w", err)
+	}
+	return int((duty*255 + f.period/2) / f.period), nil
+}
+
+// GetMinSpeed 获取最小速度
+func (f *Fan) GetMinSpeed() int {
+	return f.opts.MinPWM
+}
+
+// GetMaxSpeed 获取最大速度
+func (f *Fan) GetMaxSpeed() int {
+	return f.opts.MaxPWM
+}
+
+// Close 停止输出并取消导出由fanap导出的通道；已被导出的通道恢复原来的周期、占空比、极性和输出状态
+// 只有第一次调用（包括 Release）生效
+func (f *Fan) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.closed {
+		return nil
+	}
+	f.closed = true
+
+	if f.exported {
+		writeAttr(f.attr("enable"), "0")
+		if err := writeAttr(filepath.Join(f.chip, "unexport"), strconv.Itoa(f.spec.Channel)); err != nil {
+			return fmt.Errorf("取消导出PWM通道失败: %w", err)
+		}
+		return nil
+	}
+
+	// 先停止输出并清零占空比，才能修改周期和极性
+	o := f.orig
+	if err := writeAttr(f.attr("enable"), "0"); err != nil {
+		return fmt.Errorf("恢复PWM通道失败: %w", err)
+	}
+	writes := [][2]string{{"duty_cycle", "0"}}
+	if o.period > 0 {
+		writes = append(writes, [2]string{"period", strconv.FormatInt(o.period, 10)})
+	}
+	if o.polarity != "" {
+		writes = append(writes, [2]string{"polarity", o.polarity})
+	}
+	writes = append(writes,
+		[2]string{"duty_cycle", strconv.FormatInt(o.duty, 10)},
+		[2]string{"enable", strconv.FormatInt(o.enable, 10)},
+	)
+	for _, w := range writes {
+		if err := writeAttr(f.attr(w[0]), w[1]); err != nil {
+			return fmt.Errorf("恢复PWM通道失败: %w", err)
+		}
+	}
+	return nil
+}
+
+// Release 取消导出由fanap导出的通道，不停止输出也不恢复原来的设置，风扇保持最后设置的占空比
+// 取消导出后输出是否保持取决于PWM控制器的驱动；已被导出的通道保持不变
+func (f *Fan) Release() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.closed {
+		return nil
+	}
+	f.closed = true
+
+	if !f.exported {
+		return nil
+	}
+	if err := writeAttr(filepath.Join(f.chip, "unexport"), strconv.Itoa(f.spec.Channel)); err != nil {
+		return fmt.Errorf("取消导出PWM通道失败: %w", err)
+	}
+	return nil
+}
+
+// String 描述风扇，用于日志
+func (f *Fan) String() string {
+	s := fmt.Sprintf("%s 通道%d (周期 %v", f.chip, f.spec.Channel, f.opts.Period)
+	if f.opts.Polarity != "" {
+		s += ", " + f.opts.Polarity
+	}
+	return s + ")"
+}
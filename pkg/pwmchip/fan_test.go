@@ -0,0 +1,235 @@
+package pwmchip
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/fanap/pkg/sysfs"
+	"github.com/fanap/pkg/sysfs/fixture"
+)
+
+// newChip 创建只有一个PWM控制器的模拟目录树，并在后台模拟通道的导出
+func newChip(t *testing.T) (*fixture.Tree, *fixture.PWMChip) {
+	t.Helper()
+
+	tree, err := fixture.New(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	chip, err := tree.AddPWMChip("platform/fe6e0000.pwm", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sysfs.SetRoot(tree.Root)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		tree.Simulate(ctx, 10*time.Millisecond)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+		sysfs.SetRoot("")
+	})
+	return tree, chip
+}
+
+func TestParseSpec(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Spec
+		wantErr bool
+	}{
+		{"pwmchip0/1", Spec{Chip: "pwmchip0", Channel: 1}, false},
+		{"pwmchip0", Spec{Chip: "pwmchip0", Channel: 0}, false},
+		{"pwmchip2/pwm3/", Spec{Chip: "pwmchip2", Channel: 3}, false},
+		{"fe6e0000.pwm/0", Spec{Chip: "fe6e0000.pwm", Channel: 0}, false},
+		{"/sys/class/pwm/pwmchip0/pwm1", Spec{Chip: "/sys/class/pwm/pwmchip0", Channel: 1}, false},
+		{"", Spec{}, true},
+		{"/1", Spec{}, true},
+		{"[/0", Spec{}, true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseSpec(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseSpec(%q) 错误 = %v, 期望出错 %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseSpec(%q) = %+v, 期望 %+v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestFanExport(t *testing.T) {
+	tests := []struct {
+		name string
+		spec string
+	}{
+		{"pwmchipN", "pwmchip0/1"},
+		{"设备名称", "fe6e0000.pwm/1"},
+		{"绝对路径", "/sys/class/pwm/pwmchip0/pwm1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, chip := newChip(t)
+
+			f, err := NewFan(tt.spec, Options{MinPWM: 0, MaxPWM: 255})
+			if err != nil {
+				t.Fatal(err)
+			}
+			ch := fixture.Node{Dir: chip.Path("pwm1")}
+			for attr, want := range map[string]int{"period": 40000, "duty_cycle": 0, "enable": 1} {
+				if v, _ := ch.GetInt(attr); v != want {
+					t.Errorf("打开后 %s = %d, 期望 %d", attr, v, want)
+				}
+			}
+
+			if err := f.SetSpeed(51); err != nil {
+				t.Fatal(err)
+			}
+			if v, _ := ch.GetInt("duty_cycle"); v != 8000 {
+				t.Errorf("SetSpeed(51) 后 duty_cycle = %d, 期望 8000", v)
+			}
+			if pwm, err := f.GetSpeed(); err != nil || pwm != 51 {
+				t.Errorf("GetSpeed() = %d (%v), 期望 51", pwm, err)
+			}
+
+			if err := f.Close(); err != nil {
+				t.Fatal(err)
+			}
+			// 由fanap导出的通道关闭后取消导出
+			deadline := time.Now().Add(time.Second)
+			for {
+				if _, err := os.Stat(ch.Dir); os.IsNotExist(err) {
+					break
+				}
+				if time.Now().After(deadline) {
+					t.Fatal("关闭后通道没有取消导出")
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+		})
+	}
+}
+
+func TestFanRestore(t *testing.T) {
+	_, chip := newChip(t)
+	if err := chip.Export(0); err != nil {
+		t.Fatal(err)
+	}
+	ch := fixture.Node{Dir: chip.Path("pwm0")}
+	orig := map[string]string{"period": "1000000", "duty_cycle": "250000", "polarity": "normal", "enable": "1"}
+	for attr, v := range orig {
+		if err := ch.Set(attr, v); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	f, err := NewFan("pwmchip0", Options{Polarity: PolarityInversed, MinPWM: 0, MaxPWM: 255})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := ch.Get("polarity"); v != PolarityInversed {
+		t.Errorf("打开后 polarity = %q, 期望 %q", v, PolarityInversed)
+	}
+	if err := f.SetSpeed(255); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// 已被导出的通道不取消导出，恢复原来的设置
+	if _, err := os.Stat(ch.Dir); err != nil {
+		t.Fatalf("关闭后通道目录不存在: %v", err)
+	}
+	for attr, want := range orig {
+		if v, _ := ch.Get(attr); v != want {
+			t.Errorf("关闭后 %s = %q, 期望 %q", attr, v, want)
+		}
+	}
+}
+
+func TestFanRelease(t *testing.T) {
+	_, chip := newChip(t)
+	if err := chip.Export(0); err != nil {
+		t.Fatal(err)
+	}
+	pre := fixture.Node{Dir: chip.Path("pwm0")}
+	if err := pre.Set("duty_cycle", "0"); err != nil {
+		t.Fatal(err)
+	}
+
+	// 已被导出的通道保持最后设置的占空比，之后的 Close 不再恢复
+	f, err := NewFan("pwmchip0/0", Options{MinPWM: 0, MaxPWM: 255})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := f.SetFullSpeed(); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Release(); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	for attr, want := range map[string]int{"duty_cycle": 40000, "enable": 1} {
+		if v, _ := pre.GetInt(attr); v != want {
+			t.Errorf("释放后 %s = %d, 期望 %d", attr, v, want)
+		}
+	}
+
+	// 由fanap导出的通道释放后取消导出
+	f, err = NewFan("pwmchip0/1", Options{MinPWM: 0, MaxPWM: 255})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Release(); err != nil {
+		t.Fatal(err)
+	}
+	ch := fixture.Node{Dir: chip.Path("pwm1")}
+	deadline := time.Now().Add(time.Second)
+	for {
+		if _, err := os.Stat(ch.Dir); os.IsNotExist(err) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("释放后通道没有取消导出")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err := f.Close(); err != nil {
+		t.Errorf("释放后 Close() = %v, 期望不再处理通道", err)
+	}
+}
+
+func TestNewFanErrors(t *testing.T) {
+	newChip(t)
+
+	tests := []struct {
+		name string
+		spec string
+		opts Options
+	}{
+		{"通道不存在", "pwmchip0/2", Options{MaxPWM: 255}},
+		{"控制器不存在", "pwmchip1/0", Options{MaxPWM: 255}},
+		{"设备不存在", "ff000000.pwm/0", Options{MaxPWM: 255}},
+		{"未知的极性", "pwmchip0/0", Options{Polarity: "reversed", MaxPWM: 255}},
+		{"负的周期", "pwmchip0/0", Options{Period: -time.Microsecond, MaxPWM: 255}},
+		{"无效的PWM范围", "pwmchip0/0", Options{MinPWM: 200, MaxPWM: 100}},
+	}
+
+	for _, tt := range tests {
+		if _, err := NewFan(tt.spec, tt.opts); err == nil {
+			t.Errorf("%s: NewFan(%q) 应返回错误", tt.name, tt.spec)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+// Package pwmchip 通过Linux PWM子系统（/sys/class/pwm）控制的风扇
+//
+// 树莓派、Rockchip等单板计算机的风扇通常直接由SoC的PWM输出驱动，没有hwmon接口。
+// 通道需要先导出（向 pwmchipN/export 写入通道号），之后在 pwmchipN/pwmM 下设置：
+//
+//	period      周期（纳秒）
+//	duty_cycle  有效电平的时间（纳秒），不能大于period
+//	polarity    normal或inversed，只能在enable为0时修改
+//	enable      1为输出
+//
+// 0-255的PWM值按比例换算为duty_cycle。关闭时取消导出由fanap导出的通道，已被导出的通道恢复原来的设置
+package pwmchip
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/fanap/pkg/sysfs"
+)
+
+// Spec PWM通道
+//
+//	pwmchip0/1                      pwmchip0的通道1
+//	pwmchip0                        pwmchip0的通道0
+//	fe6e0000.pwm/0                  按PWM控制器所属设备的名称（device符号链接的目标，可以使用通配符）
+//	/sys/class/pwm/pwmchip0/pwm1    绝对路径，pwmM可以写成M
+//
+// pwmchipN的编号由驱动加载顺序决定，有多个PWM控制器时推荐使用设备名称
+type Spec struct {
+	// Chip PWM控制器：pwmchipN、设备名称或绝对路径
+	Chip string
+	// Channel 通道号
+	Channel int
+}
+
+var chipName = regexp.MustCompile(`^pwmchip[0-9]+$`)
+
+// ParseSpec 解析PWM通道，省略通道时为0
+func ParseSpec(s string) (Spec, error) {
+	s = strings.TrimSuffix(strings.TrimSpace(s), "/")
+	if s == "" {
+		return Spec{}, fmt.Errorf("未指定PWM控制器")
+	}
+
+	chip, channel := s, "0"
+	if i := strings.LastIndex(s, "/"); i >= 0 {
+		last := strings.TrimPrefix(s[i+1:], "pwm")
+		if _, err := strconv.Atoi(last); err == nil {
+			chip, channel = s[:i], last
+		}
+	}
+	if chip == "" {
+		return Spec{}, fmt.Errorf("无效的PWM通道 %q", s)
+	}
+	if _, err := filepath.Match(chip, ""); err != nil {
+		return Spec{}, fmt.Errorf("无效的通配符 %q", chip)
+	}
+
+	n, err := strconv.Atoi(channel)
+	if err != nil || n < 0 {
+		return Spec{}, fmt.Errorf("无效的PWM通道号 %q", channel)
+	}
+	return Spec{Chip: chip, Channel: n}, nil
+}
+
+// String 返回通道的文本形式
+func (s Spec) String() string {
+	return fmt.Sprintf("%s/%d", s.Chip, s.Channel)
+}
+
+// ChipPath 查找PWM控制器目录
+func (s Spec) ChipPath() (string, error) {
+	if filepath.IsAbs(s.Chip) {
+		dir := sysfs.Path(s.Chip)
+		if _, err := os.Stat(filepath.Join(dir, "npwm")); err != nil {
+			return "", fmt.Errorf("%s 不是PWM控制器: %w", s.Chip, err)
+		}
+		return dir, nil
+	}
+	if chipName.MatchString(s.Chip) {
+		dir := filepath.Join(sysfs.PWMPath(), s.Chip)
+		if _, err := os.Stat(dir); err != nil {
+			return "", fmt.Errorf("PWM控制器不存在: %w", err)
+		}
+		return dir, nil
+	}
+
+	chips, err := Chips()
+	if err != nil {
+		return "", err
+	}
+	var matches []string
+	for _, c := range chips {
+		if ok, _ := filepath.Match(s.Chip, c.Device); ok {
+			matches = append(matches, c.Path)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("没有找到设备名称匹配 %q 的PWM控制器", s.Chip)
+	case 1:
+	default:
+		log.Printf("PWM控制器 %s 匹配到多个设备，使用 %s: %s", s.Chip, matches[0], strings.Join(matches, ", "))
+	}
+	return matches[0], nil
+}
+
+// Chip PWM控制器
+type Chip struct {
+	// Path pwmchipN目录
+	Path string
+	// Device 所属设备的名称，如 "fe6e0000.pwm"
+	Device string
+	// NPWM 通道数
+	NPWM int
+}
+
+// Chips 列出所有PWM控制器，按编号排序
+func Chips() ([]Chip, error) {
+	dirs, err := filepath.Glob(filepath.Join(sysfs.PWMPath(), "pwmchip*"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(dirs, func(i, j int) bool {
+		return chipIndex(dirs[i]) < chipIndex(dirs[j])
+	})
+
+	chips := make([]Chip, 0, len(dirs))
+	for _, dir := range dirs {
+		c := Chip{Path: dir}
+		if target, err := filepath.EvalSymlinks(filepath.Join(dir, "device")); err == nil {
+			c.Device = filepath.Base(target)
+		}
+		if n, err := readInt(filepath.Join(dir, "npwm")); err == nil {
+			c.NPWM = int(n)
+		}
+		chips = append(chips, c)
+	}
+	return chips, nil
+}
+
+// chipIndex 返回pwmchipN的编号
+func chipIndex(dir string) int {
+	n, _ := strconv.Atoi(strings.TrimPrefix(filepath.Base(dir), "pwmchip"))
+	return n
+}
+
+// readInt 读取整数属性
+func readInt(path string) (int64, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+}
+
+// writeAttr 写入属性文件
+func writeAttr(path, value string) error {
+	if err := os.WriteFile(path, []byte(value), 0644); err != nil {
+		return fmt.Errorf("写入 %s 失败: %w", filepath.Base(path), err)
+	}
+	return nil
+}
@@ -0,0 +1,266 @@
+// Package sensor 按选择器定位温度传感器
+//
+// 选择器格式：
+//
+//	auto                           自动检测
+//	/sys/class/hwmon/hwmon0/temp1_input   绝对路径（hwmon的tempN_input或thermal_zone目录），可以包含通配符
+//	hwmon:coretemp/Package id 0    按hwmon芯片名称（name）和通道标签（tempN_label）或通道名（tempN）
+//	hwmon:k10temp                  芯片的第一个温度通道
+//	thermal:x86_pkg_temp           按thermal_zone的类型（type）
+//
+// 芯片名称、通道标签和类型都可以使用 filepath.Match 的通配符（如 "hwmon:nct*/CPUTIN"）。
+// 选择器在启动时解析，不依赖hwmonN的编号；设备消失（如驱动重新加载后编号变化）时重新解析
+package sensor
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/fanap/pkg/hwmon"
+	"github.com/fanap/pkg/sysfs"
+)
+
+// 选择器类型
+const (
+	KindAuto    = "auto"
+	KindPath    = "path"
+	KindHWMon   = "hwmon"
+	KindThermal = "thermal"
+)
+
+// Selector 温度传感器选择器
+type Selector struct {
+	// Kind 选择器类型
+	Kind string
+	// Path KindPath 的绝对路径，可以包含通配符
+	Path string
+	// Chip KindHWMon 的芯片名称
+	Chip string
+	// Label KindHWMon 的通道标签或通道名，为空表示芯片的第一个温度通道
+	Label string
+	// Type KindThermal 的温度区域类型
+	Type string
+}
+
+// Parse 解析选择器
+func Parse(s string) (Selector, error) {
+	s = strings.TrimSpace(s)
+	switch {
+	case s == "" || s == KindAuto:
+		return Selector{Kind: KindAuto}, nil
+	case filepath.IsAbs(s):
+		if _, err := filepath.Match(s, ""); err != nil {
+			return Selector{}, fmt.Errorf("无效的路径通配符 %q", s)
+		}
+		return Selector{Kind: KindPath, Path: s}, nil
+	}
+
+	kind, rest, ok := strings.Cut(s, ":")
+	if !ok {
+		return Selector{}, fmt.Errorf("无效的传感器选择器 %q（可选: auto、绝对路径、hwmon:芯片/标签、thermal:类型）", s)
+	}
+
+	var sel Selector
+	switch kind {
+	case KindHWMon:
+		chip, label, _ := strings.Cut(rest, "/")
+		sel = Selector{Kind: KindHWMon, Chip: chip, Label: label}
+		if chip == "" {
+			return Selector{}, fmt.Errorf("传感器选择器 %q 缺少芯片名称", s)
+		}
+	case KindThermal:
+		sel = Selector{Kind: KindThermal, Type: rest}
+		if rest == "" {
+			return Selector{}, fmt.Errorf("传感器选择器 %q 缺少温度区域类型", s)
+		}
+	default:
+		return Selector{}, fmt.Errorf("未知的传感器选择器类型 %q（可选: hwmon、thermal）", kind)
+	}
+
+	for _, pattern := range []string{sel.Chip, sel.Label, sel.Type} {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return Selector{}, fmt.Errorf("传感器选择器 %q 中的通配符无效", s)
+		}
+	}
+	return sel, nil
+}
+
+// String 返回选择器的文本形式
+func (s Selector) String() string {
+	switch s.Kind {
+	case KindPath:
+		return s.Path
+	case KindHWMon:
+		if s.Label == "" {
+			return KindHWMon + ":" + s.Chip
+		}
+		return KindHWMon + ":" + s.Chip + "/" + s.Label
+	case KindThermal:
+		return KindThermal + ":" + s.Type
+	}
+	return KindAuto
+}
+
+// Target 选择器解析出的传感器
+type Target struct {
+	// Kind KindHWMon 或 KindThermal
+	Kind string
+	// Path hwmon的tempN_input文件或thermal_zone目录（已映射到sysfs根目录下）
+	Path string
+	// Chip hwmon芯片名称或thermal_zone类型
+	Chip string
+	// Label hwmon通道标签，没有标签时为通道名（tempN）
+	Label string
+}
+
+// String 描述传感器，用于日志
+func (t Target) String() string {
+	if t.Kind == KindThermal {
+		return fmt.Sprintf("%s (thermal: %s)", t.Path, t.Chip)
+	}
+	return fmt.Sprintf("%s (%s/%s)", t.Path, t.Chip, t.Label)
+}
+
+// Resolve 解析选择器，找到对应的传感器
+// 有多个匹配时使用排序后的第一个，并记录所有匹配项
+func (s Selector) Resolve() (Target, error) {
+	matches, err := s.ResolveAll()
+	if err != nil {
+		return Target{}, err
+	}
+	if len(matches) > 1 {
+		log.Printf("传感器选择器 %q 匹配到 %d 个传感器，使用第一个:", s, len(matches))
+		for _, m := range matches {
+			log.Printf("  %s", m)
+		}
+	}
+	return matches[0], nil
+}
+
+// ResolveAll 解析选择器，返回所有匹配的传感器
+func (s Selector) ResolveAll() ([]Target, error) {
+	var matches []Target
+	var err error
+	switch s.Kind {
+	case KindPath:
+		matches, err = s.resolvePath()
+	case KindHWMon:
+		matches, err = s.resolveHWMon()
+	case KindThermal:
+		matches, err = s.resolveThermal()
+	default:
+		return nil, fmt.Errorf("选择器 %q 需要自动检测", s)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("未找到与 %q 匹配的温度传感器", s)
+	}
+	return matches, nil
+}
+
+// resolvePath 按绝对路径（可以包含通配符）查找传感器
+func (s Selector) resolvePath() ([]Target, error) {
+	paths, err := filepath.Glob(sysfs.Path(s.Path))
+	if err != nil {
+		return nil, fmt.Errorf("无效的路径通配符 %q", s.Path)
+	}
+	sort.Strings(paths)
+
+	var targets []Target
+	for _, p := range paths {
+		targets = append(targets, pathTarget(p))
+	}
+	return targets, nil
+}
+
+// pathTarget 根据路径判断传感器类型：thermal_zone目录（或其中的temp文件）或hwmon温度文件
+func pathTarget(p string) Target {
+	dir, base := filepath.Split(p)
+	dir = filepath.Clean(dir)
+	if base == "temp" && strings.HasPrefix(filepath.Base(dir), "thermal_zone") {
+		p, base = dir, filepath.Base(dir)
+	}
+	if strings.HasPrefix(base, "thermal_zone") {
+		return Target{Kind: KindThermal, Path: p, Chip: readString(filepath.Join(p, "type"))}
+	}
+
+	t := Target{Kind: KindHWMon, Path: p, Chip: readString(filepath.Join(dir, "name"))}
+	channel := strings.TrimSuffix(base, "_input")
+	if t.Label = readString(filepath.Join(dir, channel+"_label")); t.Label == "" {
+		t.Label = channel
+	}
+	return t
+}
+
+// resolveHWMon 按芯片名称和通道标签查找hwmon温度传感器
+func (s Selector) resolveHWMon() ([]Target, error) {
+	chips, err := hwmon.Scan()
+	if err != nil {
+		return nil, err
+	}
+
+	var targets []Target
+	for _, chip := range chips {
+		if ok, _ := filepath.Match(s.Chip, chip.Name); !ok {
+			continue
+		}
+
+		for _, t := range chip.Temps {
+			label := t.Label
+			if s.Label != "" {
+				okLabel, _ := filepath.Match(s.Label, label)
+				okChannel, _ := filepath.Match(s.Label, t.Name())
+				if !okLabel && !okChannel {
+					continue
+				}
+			}
+			if label == "" {
+				label = t.Name()
+			}
+			targets = append(targets, Target{Kind: KindHWMon, Path: t.Input, Chip: chip.Name, Label: label})
+			if s.Label == "" {
+				// 只指定芯片时使用芯片的第一个温度通道
+				break
+			}
+		}
+	}
+	return targets, nil
+}
+
+// resolveThermal 按类型查找thermal_zone
+func (s Selector) resolveThermal() ([]Target, error) {
+	thermalPath := sysfs.ThermalPath()
+	entries, err := os.ReadDir(thermalPath)
+	if err != nil {
+		return nil, fmt.Errorf("读取thermal目录失败: %w", err)
+	}
+
+	var targets []Target
+	for _, entry := range entries {
+		if !strings.HasPrefix(entry.Name(), "thermal_zone") {
+			continue
+		}
+		dir := filepath.Join(thermalPath, entry.Name())
+		zoneType := readString(filepath.Join(dir, "type"))
+		if ok, _ := filepath.Match(s.Type, zoneType); !ok {
+			continue
+		}
+		targets = append(targets, Target{Kind: KindThermal, Path: dir, Chip: zoneType})
+	}
+	return targets, nil
+}
+
+// readString 读取sysfs属性文件，失败时返回空字符串
+func readString(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
@@ -0,0 +1,152 @@
+package sensor
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"sync"
+
+	"github.com/fanap/pkg/temp"
+	"github.com/fanap/pkg/thermal"
+)
+
+// source 温度来源（hwmon或thermal_zone）
+type source interface {
+	GetTemperature() (float64, error)
+	Close() error
+}
+
+// Sensor 按选择器定位的温度传感器
+// 读取时传感器文件不存在（如驱动重新加载后hwmon编号变化）会重新解析选择器并重试一次
+type Sensor struct {
+	sel Selector
+
+	mu     sync.Mutex
+	target Target
+	src    source
+}
+
+// New 解析选择器并打开对应的温度传感器，不支持 "auto"
+func New(selector string) (*Sensor, error) {
+	sel, err := Parse(selector)
+	if err != nil {
+		return nil, err
+	}
+	return Open(sel)
+}
+
+// Open 打开选择器对应的温度传感器，不支持 KindAuto
+func Open(sel Selector) (*Sensor, error) {
+	target, err := sel.Resolve()
+	if err != nil {
+		return nil, err
+	}
+	src, err := openTarget(target)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Sensor{sel: sel, target: target, src: src}
+
+	// 按hwmonN路径指定的传感器在编号变化后改为按芯片名称和通道查找
+	if sel.Kind == KindPath && target.Kind == KindHWMon && target.Chip != "" {
+		s.sel = Selector{Kind: KindHWMon, Chip: target.Chip, Label: target.Label}
+	}
+	return s, nil
+}
+
+// OpenAll 打开选择器匹配的所有温度传感器，不支持 KindAuto
+// 用于组合多个传感器（如所有CPU核心）；芯片名称和通道唯一的传感器在编号变化后按它们重新定位，
+// 不唯一的（如多个drivetemp硬盘）不重新定位
+func OpenAll(sel Selector) ([]*Sensor, error) {
+	targets, err := sel.ResolveAll()
+	if err != nil {
+		return nil, err
+	}
+
+	count := make(map[string]int)
+	for _, t := range targets {
+		count[t.Chip+"/"+t.Label]++
+	}
+
+	var sensors []*Sensor
+	for _, t := range targets {
+		src, err := openTarget(t)
+		if err != nil {
+			for _, s := range sensors {
+				s.Close()
+			}
+			return nil, err
+		}
+
+		s := &Sensor{target: t, src: src}
+		if t.Kind == KindHWMon && t.Chip != "" && count[t.Chip+"/"+t.Label] == 1 {
+			s.sel = Selector{Kind: KindHWMon, Chip: t.Chip, Label: t.Label}
+		}
+		sensors = append(sensors, s)
+	}
+	return sensors, nil
+}
+
+// GetTemperature 获取当前温度（摄氏度）
+func (s *Sensor) GetTemperature() (float64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, err := s.src.GetTemperature()
+	if err == nil || !errors.Is(err, fs.ErrNotExist) {
+		return t, err
+	}
+
+	if rerr := s.reresolve(); rerr != nil {
+		return 0, fmt.Errorf("%w（重新查找传感器失败: %v）", err, rerr)
+	}
+	return s.src.GetTemperature()
+}
+
+// Target 返回当前使用的传感器
+func (s *Sensor) Target() Target {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.target
+}
+
+// Close 关闭传感器
+func (s *Sensor) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.src.Close()
+}
+
+// reresolve 重新解析选择器，需要持有mu
+func (s *Sensor) reresolve() error {
+	if s.sel.Kind == "" {
+		return fmt.Errorf("传感器 %s 无法重新定位", s.target.Path)
+	}
+	target, err := s.sel.Resolve()
+	if err != nil {
+		return err
+	}
+	if target.Path == s.target.Path {
+		return fmt.Errorf("传感器仍然是 %s", target.Path)
+	}
+	src, err := openTarget(target)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("传感器 %s 已重新定位: %s -> %s", s.sel, s.target.Path, target.Path)
+	s.src.Close()
+	s.src = src
+	s.target = target
+	return nil
+}
+
+// openTarget 打开解析出的传感器
+func openTarget(t Target) (source, error) {
+	if t.Kind == KindThermal {
+		return thermal.NewZone(t.Path)
+	}
+	return temp.NewSensor(t.Path)
+}
@@ -0,0 +1,264 @@
+// Package settings 定义控制器设置的默认值和取值范围
+// 命令行参数、配置文件和控制器使用同一份默认值和验证规则
+package settings
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// 默认值
+const (
+	DefaultInterval = 5 * time.Second
+	DefaultLowTemp  = 40.0
+	DefaultHighTemp = 75.0
+	DefaultMinPWM   = 50
+	DefaultMaxPWM   = 255
+	DefaultOnPWM    = 128
+	DefaultOffPWM   = 127
+
+	DefaultFailsafeReads   = 3
+	DefaultFailsafeDuty    = 100.0
+	DefaultFailsafeMinTemp = -40.0
+	DefaultFailsafeMaxTemp = 150.0
+
+	DefaultStallMinPWM = 80
+	DefaultStallChecks = 3
+	DefaultStallKick   = 2 * time.Second
+
+	DefaultZeroRPMStopTemp  = 40.0
+	DefaultZeroRPMStartTemp = 45.0
+	DefaultZeroRPMKickPWM   = 255
+	DefaultZeroRPMKick      = 2 * time.Second
+)
+
+// Error 设置项的验证错误
+// 一次验证发现多个错误时用 errors.Join 合并返回
+type Error struct {
+	// Field 出错的设置项，对应配置文件中的键名（相对于所在的配置段），空表示整个配置段
+	Field string
+	Msg   string
+}
+
+func (e *Error) Error() string {
+	return e.Msg
+}
+
+// errorList 收集验证错误
+type errorList []error
+
+func (l *errorList) add(field, format string, args ...interface{}) {
+	*l = append(*l, &Error{Field: field, Msg: fmt.Sprintf(format, args...)})
+}
+
+func (l errorList) err() error {
+	return errors.Join(l...)
+}
+
+// PWMRange 验证风扇的PWM范围
+func PWMRange(minPWM, maxPWM int) error {
+	var errs errorList
+	if minPWM < 0 || minPWM > 255 {
+		errs.add("min_pwm", "最小PWM值必须在0-255之间")
+	}
+	if maxPWM < 0 || maxPWM > 255 {
+		errs.add("max_pwm", "最大PWM值必须在0-255之间")
+	}
+	if minPWM >= maxPWM {
+		errs.add("min_pwm", "最小PWM值必须小于最大PWM值")
+	}
+	return errs.err()
+}
+
+// Thresholds 验证线性控制的低温/高温阈值
+func Thresholds(lowTemp, highTemp float64) error {
+	if lowTemp >= highTemp {
+		return &Error{Field: "low_temp", Msg: "低温阈值必须小于高温阈值"}
+	}
+	return nil
+}
+
+// OnOff 验证2级冷却设备的开/关阈值
+func OnOff(onPWM, offPWM int) error {
+	switch {
+	case onPWM < 0 || onPWM > 255:
+		return &Error{Field: "on_pwm", Msg: "打开阈值必须在0-255之间"}
+	case offPWM < 0 || offPWM > 255:
+		return &Error{Field: "off_pwm", Msg: "关闭阈值必须在0-255之间"}
+	case offPWM >= onPWM:
+		return &Error{Field: "off_pwm", Msg: "关闭阈值必须小于打开阈值"}
+	}
+	return nil
+}
+
+// Failsafe 传感器失效保护
+// 传感器连续 MaxFailures 次读取失败或读数超出 [MinTemp, MaxTemp] 时，
+// 跟随该传感器的风扇以 Duty 运行并记录告警，读数恢复正常后自动恢复控制
+type Failsafe struct {
+	// MaxFailures 进入保护前允许的连续失败次数，0表示不启用失效保护
+	MaxFailures int
+	// Duty 保护时的占空比（%），不低于风扇的最小PWM；达到风扇的最大PWM时全速运行，不受最大PWM限制
+	Duty float64
+	// MinTemp 合理温度的下限（摄氏度）
+	MinTemp float64
+	// MaxTemp 合理温度的上限（摄氏度）
+	MaxTemp float64
+}
+
+// DefaultFailsafe 返回默认的失效保护：连续3次失败后全速运行
+func DefaultFailsafe() Failsafe {
+	return Failsafe{
+		MaxFailures: DefaultFailsafeReads,
+		Duty:        DefaultFailsafeDuty,
+		MinTemp:     DefaultFailsafeMinTemp,
+		MaxTemp:     DefaultFailsafeMaxTemp,
+	}
+}
+
+// Validate 验证失效保护设置
+func (f Failsafe) Validate() error {
+	var errs errorList
+	if f.MaxFailures < 0 {
+		errs.add("max_failures", "失效保护的连续失败次数不能为负数")
+	}
+	if f.Duty < 0 || f.Duty > 100 {
+		errs.add("duty", "失效保护的占空比必须在0-100之间")
+	}
+	if f.MinTemp >= f.MaxTemp {
+		errs.add("min_temp", "合理温度的下限必须小于上限")
+	}
+	return errs.err()
+}
+
+// PWM 返回保护时的PWM值
+func (f Failsafe) PWM() int {
+	return int(f.Duty*255/100 + 0.5)
+}
+
+// Check 检查读数是否合理
+func (f Failsafe) Check(temp float64) error {
+	if temp < f.MinTemp || temp > f.MaxTemp {
+		return fmt.Errorf("温度读数不合理: %.1f°C（合理范围 %.1f°C - %.1f°C）", temp, f.MinTemp, f.MaxTemp)
+	}
+	return nil
+}
+
+// StallDetect 风扇停转检测
+// 上一轮输出的PWM不低于 MinPWM 而转速连续 Checks 次为0或低于最低转速时判定为停转：
+// 记录告警并全速运行 Kick 时长尝试重新启动风扇；风扇仍然不转时每 Checks 次检查重试一次
+type StallDetect struct {
+	// MinPWM 只在PWM不低于该值时检查转速，低PWM下风扇可能正常停转
+	MinPWM int
+	// MinRPM 最低转速，0表示使用fanN_min（不存在时只有0转视为停转）
+	MinRPM int
+	// Checks 连续多少次检查异常后判定为停转
+	Checks int
+	// Kick 全速启动的时长，0表示不尝试重新启动
+	Kick time.Duration
+}
+
+// DefaultStallDetect 返回默认的停转检测设置
+func DefaultStallDetect() StallDetect {
+	return StallDetect{
+		MinPWM: DefaultStallMinPWM,
+		Checks: DefaultStallChecks,
+		Kick:   DefaultStallKick,
+	}
+}
+
+// Validate 验证停转检测设置
+func (s StallDetect) Validate() error {
+	var errs errorList
+	if s.MinPWM < 0 || s.MinPWM > 255 {
+		errs.add("min_pwm", "停转检测的PWM阈值必须在0-255之间")
+	}
+	if s.MinRPM < 0 {
+		errs.add("min_rpm", "最低转速不能为负数")
+	}
+	if s.Checks < 1 {
+		errs.add("checks", "停转检测的检查次数必须大于0")
+	}
+	if s.Kick < 0 {
+		errs.add("kick", "启动时长不能为负数")
+	}
+	return errs.err()
+}
+
+// ZeroRPM 零转速模式
+// 温度降到 StopTemp 及以下时风扇停转；停转后温度升到 StartTemp 及以上时先以 KickPWM 运行 Kick 时长，
+// 再按温度计算的PWM运行。能读取转速的风扇在启动结束后检查转速，仍为0时记录告警并全速重新启动
+type ZeroRPM struct {
+	// StopTemp 停转温度
+	StopTemp float64
+	// StartTemp 重新启动的温度，必须高于StopTemp
+	StartTemp float64
+	// KickPWM 启动时的PWM
+	KickPWM int
+	// Kick 以KickPWM运行的时长，0表示直接以计算出的PWM启动
+	Kick time.Duration
+}
+
+// DefaultZeroRPM 返回默认的零转速模式设置
+func DefaultZeroRPM() ZeroRPM {
+	return ZeroRPM{
+		StopTemp:  DefaultZeroRPMStopTemp,
+		StartTemp: DefaultZeroRPMStartTemp,
+		KickPWM:   DefaultZeroRPMKickPWM,
+		Kick:      DefaultZeroRPMKick,
+	}
+}
+
+// Validate 验证零转速模式设置
+func (z ZeroRPM) Validate() error {
+	var errs errorList
+	if z.StopTemp >= z.StartTemp {
+		errs.add("", "零转速模式的停转温度必须低于启动温度")
+	}
+	if z.KickPWM < 1 || z.KickPWM > 255 {
+		errs.add("kick_pwm", "启动PWM必须在1-255之间")
+	}
+	if z.Kick < 0 {
+		errs.add("kick", "启动时长不能为负数")
+	}
+	return errs.err()
+}
+
+// Ramp PWM变化速率限制（PWM/秒），0表示不限制
+// 例如 Up=40、Down=5 表示升温时快速提速，降温时缓慢降速
+type Ramp struct {
+	Up   float64
+	Down float64
+}
+
+// Validate 验证速率限制
+func (r Ramp) Validate() error {
+	var errs errorList
+	if r.Up < 0 {
+		errs.add("up", "PWM变化速率不能为负数")
+	}
+	if r.Down < 0 {
+		errs.add("down", "PWM变化速率不能为负数")
+	}
+	return errs.err()
+}
+
+// Hysteresis 温度回差
+// 温度比上次采用的温度升高Rise或降低Fall以上时才重新计算PWM，
+// 例如 Rise=0、Fall=3 表示升温立即响应，降温3°C以上才降速
+type Hysteresis struct {
+	Rise float64
+	Fall float64
+}
+
+// Validate 验证回差
+func (h Hysteresis) Validate() error {
+	var errs errorList
+	if h.Rise < 0 {
+		errs.add("rise", "温度回差不能为负数")
+	}
+	if h.Fall < 0 {
+		errs.add("fall", "温度回差不能为负数")
+	}
+	return errs.err()
+}
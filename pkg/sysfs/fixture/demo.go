@@ -0,0 +1,84 @@
+package fixture
+
+import "fmt"
+
+// Demo 创建一个典型的演示目录树：
+//   - hwmon0: coretemp（符号链接布局），包含Package和两个Core温度及其max/crit阈值
+//   - hwmon1: nct6775（符号链接布局），包含主板温度、两路PWM（PWM输出模式）和两路转速，
+//     模拟运行时pwm1驱动fan2（PWM低于60停转，停转后需要90才能启动）、pwm2驱动fan1（PWM低于40停转）（见 Tree.Simulate）
+//   - hwmon2: acpitz（普通目录布局），包含一个温度
+//   - thermal_zone0: x86_pkg_temp
+//   - cooling_device0: Fan（0-1两级）
+//   - pwmchip0: fe6e0000.pwm（两个通道，未导出；模拟运行时处理导出和取消导出）
+func Demo(root string) (*Tree, error) {
+	t, err := New(root)
+	if err != nil {
+		return nil, err
+	}
+
+	cpu, err := t.AddHWMon("coretemp", "platform/coretemp.0")
+	if err != nil {
+		return nil, err
+	}
+	for _, temp := range []struct {
+		channel int
+		milliC  int
+		label   string
+	}{
+		{1, 45000, "Package id 0"},
+		{2, 42000, "Core 0"},
+		{3, 41000, "Core 1"},
+	} {
+		if err := cpu.AddTemp(temp.channel, temp.milliC, temp.label); err != nil {
+			return nil, err
+		}
+		if err := cpu.SetInt(fmt.Sprintf("temp%d_max", temp.channel), 80000); err != nil {
+			return nil, err
+		}
+		if err := cpu.SetInt(fmt.Sprintf("temp%d_crit", temp.channel), 100000); err != nil {
+			return nil, err
+		}
+	}
+
+	board, err := t.AddHWMon("nct6775", "platform/nct6775.656")
+	if err != nil {
+		return nil, err
+	}
+	if err := board.AddTemp(1, 36000, "SYSTIN"); err != nil {
+		return nil, err
+	}
+	for channel, rpm := range map[int]int{1: 1200, 2: 800} {
+		if err := board.AddPWM(channel, 120, 5); err != nil {
+			return nil, err
+		}
+		if err := board.SetInt(fmt.Sprintf("pwm%d_mode", channel), 1); err != nil {
+			return nil, err
+		}
+		if err := board.AddFan(channel, rpm, ""); err != nil {
+			return nil, err
+		}
+	}
+
+	t.LinkFan(board, 1, 2, 1500, 60, 90)
+	t.LinkFan(board, 2, 1, 2400, 40, 40)
+
+	acpi, err := t.AddHWMon("acpitz", "")
+	if err != nil {
+		return nil, err
+	}
+	if err := acpi.AddTemp(1, 38000, ""); err != nil {
+		return nil, err
+	}
+
+	if _, err := t.AddThermalZone("x86_pkg_temp", 45000); err != nil {
+		return nil, err
+	}
+	if _, err := t.AddCoolingDevice("Fan", 1, 0); err != nil {
+		return nil, err
+	}
+	if _, err := t.AddPWMChip("platform/fe6e0000.pwm", 2); err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
@@ -0,0 +1,318 @@
+// Package fixture 在普通目录中构建模拟的hwmon/thermal/pwm sysfs目录树（以及 /proc/acpi/ibm/fan），
+// 配合 sysfs.SetRoot 使用，可以在没有真实硬件的环境中测试fanap及其集成
+package fixture
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Tree 模拟的sysfs目录树
+type Tree struct {
+	Root string
+	// Links 模拟风扇，见 Simulate
+	Links []FanLink
+	// PWMChips PWM控制器，Simulate 运行时模拟其通道的导出和取消导出
+	PWMChips []*PWMChip
+	// Discrete 只支持几档转速的PWM通道，见 AddDiscretePWM
+	Discrete []DiscretePWM
+	// ThinkPad 模拟的ThinkPad风扇控制文件，见 AddThinkPadFan
+	ThinkPad *ThinkPadFan
+
+	hwmonCount   int
+	zoneCount    int
+	coolingCount int
+}
+
+// Node 目录树中的一个设备目录
+type Node struct {
+	// Dir 设备目录的实际路径
+	Dir string
+	// ClassPath 设备在 /sys/class 下的路径（可能是符号链接）
+	ClassPath string
+}
+
+// HWMon 模拟的hwmon设备
+type HWMon struct {
+	Node
+	Name  string
+	Index int
+}
+
+// ThermalZone 模拟的thermal_zone
+type ThermalZone struct {
+	Node
+	Index int
+}
+
+// CoolingDevice 模拟的cooling_device
+type CoolingDevice struct {
+	Node
+	Index int
+}
+
+// PWMChip 模拟的PWM子系统控制器
+type PWMChip struct {
+	Node
+	Index int
+	NPWM  int
+}
+
+// New 在root目录下创建空的sysfs目录树
+func New(root string) (*Tree, error) {
+	abs, err := filepath.Abs(root)
+	if err != nil {
+		return nil, fmt.Errorf("解析根目录失败: %w", err)
+	}
+
+	for _, dir := range []string{"sys/class/hwmon", "sys/class/thermal", "sys/class/pwm", "sys/devices"} {
+		if err := os.MkdirAll(filepath.Join(abs, dir), 0755); err != nil {
+			return nil, fmt.Errorf("创建目录失败: %w", err)
+		}
+	}
+
+	return &Tree{Root: abs}, nil
+}
+
+// AddHWMon 添加一个hwmon设备
+// device 为空时直接在 /sys/class/hwmon 下创建普通目录；
+// 否则按内核的布局在 /sys/devices/<device>/hwmon/hwmonN 下创建设备目录，
+// 并在 /sys/class/hwmon 下创建指向它的符号链接（如 device="platform/nct6775.656"）
+func (t *Tree) AddHWMon(name, device string) (*HWMon, error) {
+	index := t.hwmonCount
+	hwmonName := fmt.Sprintf("hwmon%d", index)
+	classPath := filepath.Join(t.Root, "sys/class/hwmon", hwmonName)
+
+	dir := classPath
+	if device != "" {
+		deviceDir := filepath.Join(t.Root, "sys/devices", device)
+		dir = filepath.Join(deviceDir, "hwmon", hwmonName)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, fmt.Errorf("创建hwmon目录失败: %w", err)
+		}
+		if err := relSymlink(dir, classPath); err != nil {
+			return nil, err
+		}
+		if err := relSymlink(deviceDir, filepath.Join(dir, "device")); err != nil {
+			return nil, err
+		}
+	} else if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("创建hwmon目录失败: %w", err)
+	}
+
+	h := &HWMon{
+		Node:  Node{Dir: dir, ClassPath: classPath},
+		Name:  name,
+		Index: index,
+	}
+	if err := h.Set("name", name); err != nil {
+		return nil, err
+	}
+
+	t.hwmonCount++
+	return h, nil
+}
+
+// AddThermalZone 添加一个thermal_zone，温度单位为毫摄氏度
+func (t *Tree) AddThermalZone(zoneType string, milliC int) (*ThermalZone, error) {
+	index := t.zoneCount
+	node, err := t.addThermalNode(fmt.Sprintf("thermal_zone%d", index))
+	if err != nil {
+		return nil, err
+	}
+
+	z := &ThermalZone{Node: node, Index: index}
+	if err := z.Set("type", zoneType); err != nil {
+		return nil, err
+	}
+	if err := z.SetInt("temp", milliC); err != nil {
+		return nil, err
+	}
+
+	t.zoneCount++
+	return z, nil
+}
+
+// AddCoolingDevice 添加一个cooling_device
+func (t *Tree) AddCoolingDevice(deviceType string, maxState, curState int) (*CoolingDevice, error) {
+	index := t.coolingCount
+	node, err := t.addThermalNode(fmt.Sprintf("cooling_device%d", index))
+	if err != nil {
+		return nil, err
+	}
+
+	d := &CoolingDevice{Node: node, Index: index}
+	if err := d.Set("type", deviceType); err != nil {
+		return nil, err
+	}
+	if err := d.SetInt("max_state", maxState); err != nil {
+		return nil, err
+	}
+	if err := d.SetInt("cur_state", curState); err != nil {
+		return nil, err
+	}
+
+	t.coolingCount++
+	return d, nil
+}
+
+// AddPWMChip 添加一个PWM控制器，按内核的布局在 /sys/devices/<device>/pwm/pwmchipN 下创建，
+// 并在 /sys/class/pwm 下创建指向它的符号链接（如 device="platform/fe6e0000.pwm"）
+// 通道需要通过export导出，导出由 Step 模拟
+func (t *Tree) AddPWMChip(device string, npwm int) (*PWMChip, error) {
+	index := len(t.PWMChips)
+	name := fmt.Sprintf("pwmchip%d", index)
+	deviceDir := filepath.Join(t.Root, "sys/devices", device)
+	dir := filepath.Join(deviceDir, "pwm", name)
+	classPath := filepath.Join(t.Root, "sys/class/pwm", name)
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("创建pwmchip目录失败: %w", err)
+	}
+	if err := relSymlink(dir, classPath); err != nil {
+		return nil, err
+	}
+	if err := relSymlink(deviceDir, filepath.Join(dir, "device")); err != nil {
+		return nil, err
+	}
+
+	c := &PWMChip{Node: Node{Dir: dir, ClassPath: classPath}, Index: index, NPWM: npwm}
+	if err := c.SetInt("npwm", npwm); err != nil {
+		return nil, err
+	}
+	for _, attr := range []string{"export", "unexport"} {
+		if err := os.WriteFile(c.Path(attr), nil, 0644); err != nil {
+			return nil, fmt.Errorf("写入 %s 失败: %w", attr, err)
+		}
+	}
+
+	t.PWMChips = append(t.PWMChips, c)
+	return c, nil
+}
+
+// Export 导出通道，创建 pwmN 目录及其属性文件（周期、占空比为0，未输出），已导出时不做任何事
+func (c *PWMChip) Export(channel int) error {
+	if channel < 0 || channel >= c.NPWM {
+		return fmt.Errorf("通道 %d 不存在", channel)
+	}
+	dir := c.Path(fmt.Sprintf("pwm%d", channel))
+	if _, err := os.Stat(dir); err == nil {
+		return nil
+	}
+	if err := os.Mkdir(dir, 0755); err != nil {
+		return fmt.Errorf("创建通道目录失败: %w", err)
+	}
+	n := Node{Dir: dir, ClassPath: filepath.Join(c.ClassPath, filepath.Base(dir))}
+	for attr, value := range map[string]string{"period": "0", "duty_cycle": "0", "polarity": "normal", "enable": "0"} {
+		if err := n.Set(attr, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Unexport 取消导出通道，删除 pwmN 目录
+func (c *PWMChip) Unexport(channel int) error {
+	return os.RemoveAll(c.Path(fmt.Sprintf("pwm%d", channel)))
+}
+
+// addThermalNode 按内核布局创建 /sys/devices/virtual/thermal/<name> 及其符号链接
+func (t *Tree) addThermalNode(name string) (Node, error) {
+	dir := filepath.Join(t.Root, "sys/devices/virtual/thermal", name)
+	classPath := filepath.Join(t.Root, "sys/class/thermal", name)
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return Node{}, fmt.Errorf("创建thermal目录失败: %w", err)
+	}
+	if err := relSymlink(dir, classPath); err != nil {
+		return Node{}, err
+	}
+
+	return Node{Dir: dir, ClassPath: classPath}, nil
+}
+
+// AddTemp 添加温度通道 tempN_input（毫摄氏度），label为空时不创建标签文件
+func (h *HWMon) AddTemp(channel, milliC int, label string) error {
+	if err := h.SetInt(fmt.Sprintf("temp%d_input", channel), milliC); err != nil {
+		return err
+	}
+	if label != "" {
+		return h.Set(fmt.Sprintf("temp%d_label", channel), label)
+	}
+	return nil
+}
+
+// AddPWM 添加PWM通道 pwmN 及 pwmN_enable
+func (h *HWMon) AddPWM(channel, pwm, enable int) error {
+	if err := h.SetInt(fmt.Sprintf("pwm%d", channel), pwm); err != nil {
+		return err
+	}
+	return h.SetInt(fmt.Sprintf("pwm%d_enable", channel), enable)
+}
+
+// AddFan 添加转速通道 fanN_input（RPM），label为空时不创建标签文件
+func (h *HWMon) AddFan(channel, rpm int, label string) error {
+	if err := h.SetInt(fmt.Sprintf("fan%d_input", channel), rpm); err != nil {
+		return err
+	}
+	if label != "" {
+		return h.Set(fmt.Sprintf("fan%d_label", channel), label)
+	}
+	return nil
+}
+
+// Path 返回属性文件的实际路径
+func (n Node) Path(attr string) string {
+	return filepath.Join(n.Dir, attr)
+}
+
+// Set 写入属性文件（自动追加换行符，与sysfs一致）
+func (n Node) Set(attr, value string) error {
+	if err := os.WriteFile(n.Path(attr), []byte(value+"\n"), 0644); err != nil {
+		return fmt.Errorf("写入 %s 失败: %w", attr, err)
+	}
+	return nil
+}
+
+// SetInt 写入整数属性
+func (n Node) SetInt(attr string, value int) error {
+	return n.Set(attr, strconv.Itoa(value))
+}
+
+// Get 读取属性文件内容（去掉首尾空白）
+func (n Node) Get(attr string) (string, error) {
+	data, err := os.ReadFile(n.Path(attr))
+	if err != nil {
+		return "", fmt.Errorf("读取 %s 失败: %w", attr, err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// GetInt 读取整数属性
+func (n Node) GetInt(attr string) (int, error) {
+	s, err := n.Get(attr)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(s)
+}
+
+// Remove 删除属性文件，用于模拟传感器消失等故障
+func (n Node) Remove(attr string) error {
+	return os.Remove(n.Path(attr))
+}
+
+// relSymlink 创建从link指向target的相对符号链接
+func relSymlink(target, link string) error {
+	rel, err := filepath.Rel(filepath.Dir(link), target)
+	if err != nil {
+		return fmt.Errorf("计算符号链接路径失败: %w", err)
+	}
+	if err := os.Symlink(rel, link); err != nil {
+		return fmt.Errorf("创建符号链接失败: %w", err)
+	}
+	return nil
+}
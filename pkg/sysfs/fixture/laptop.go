@@ -0,0 +1,178 @@
+package fixture
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ThinkPadFan 模拟的thinkpad_acpi风扇控制文件（/proc/acpi/ibm/fan）
+// Step 处理写入的level和watchdog命令；两次 Step 之间写入的多条命令只处理最后一条
+type ThinkPadFan struct {
+	// Path 风扇控制文件的实际路径
+	Path string
+	// Level 当前档位
+	Level string
+	// Watchdog 看门狗超时时间（秒），0表示不启用
+	Watchdog int
+	// MaxRPM 档位7时的转速
+	MaxRPM int
+
+	lastCmd time.Time
+}
+
+// DiscretePWM 只支持几档转速的PWM通道（如dell_smm），Step 把写入的PWM值按驱动的方式换算为档位
+type DiscretePWM struct {
+	HWMon *HWMon
+	// PWM PWM通道编号
+	PWM int
+	// States 档位数
+	States int
+}
+
+// AddThinkPadFan 创建 /proc/acpi/ibm/fan，风扇处于auto档位，可以写入命令（相当于以fan_control=1加载thinkpad_acpi）
+func (t *Tree) AddThinkPadFan(maxRPM int) (*ThinkPadFan, error) {
+	path := filepath.Join(t.Root, "proc/acpi/ibm/fan")
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("创建目录失败: %w", err)
+	}
+
+	f := &ThinkPadFan{Path: path, Level: "auto", MaxRPM: maxRPM}
+	if err := f.write(); err != nil {
+		return nil, err
+	}
+	t.ThinkPad = f
+	return f, nil
+}
+
+// Speed 按档位计算转速，auto按档位3计算
+func (f *ThinkPadFan) Speed() int {
+	switch f.Level {
+	case "auto":
+		return f.MaxRPM * 3 / 7
+	case "full-speed":
+		return f.MaxRPM
+	case "disengaged":
+		return f.MaxRPM * 11 / 10
+	}
+	n, _ := strconv.Atoi(f.Level)
+	return f.MaxRPM * n / 7
+}
+
+// write 按thinkpad_acpi的格式写入风扇状态
+func (f *ThinkPadFan) write() error {
+	content := fmt.Sprintf("status:\t\tenabled\nspeed:\t\t%d\nlevel:\t\t%s\n"+
+		"commands:\tlevel <level> (<level> is 0-7, auto, disengaged, full-speed)\n"+
+		"commands:\tenable, disable\n"+
+		"commands:\twatchdog <timeout> (<timeout> is 0 (off), 1-120 (seconds))\n",
+		f.Speed(), f.Level)
+	if err := os.WriteFile(f.Path, []byte(content), 0644); err != nil {
+		return fmt.Errorf("写入风扇状态失败: %w", err)
+	}
+	return nil
+}
+
+// step 处理写入的命令，看门狗超时后切回auto
+func (f *ThinkPadFan) step(now time.Time) error {
+	data, err := os.ReadFile(f.Path)
+	if err != nil {
+		return nil
+	}
+
+	changed := false
+	if cmd := strings.Fields(string(data)); len(cmd) == 2 && cmd[0] != "status:" {
+		switch cmd[0] {
+		case "level":
+			f.Level = cmd[1]
+			f.lastCmd = now
+		case "watchdog":
+			f.Watchdog, _ = strconv.Atoi(cmd[1])
+			f.lastCmd = now
+		default:
+			log.Printf("模拟ThinkPad风扇: 不支持的命令 %q", strings.Join(cmd, " "))
+		}
+		changed = true
+	}
+
+	if f.Watchdog > 0 && f.Level != "auto" && now.Sub(f.lastCmd) > time.Duration(f.Watchdog)*time.Second {
+		log.Printf("模拟ThinkPad风扇: 看门狗超时，切回auto")
+		f.Level = "auto"
+		changed = true
+	}
+
+	if !changed {
+		return nil
+	}
+	return f.write()
+}
+
+// AddDiscretePWM 把hwmon的PWM通道模拟为只支持states档转速（dell_smm为3档）
+func (t *Tree) AddDiscretePWM(h *HWMon, pwm, states int) {
+	t.Discrete = append(t.Discrete, DiscretePWM{HWMon: h, PWM: pwm, States: states})
+}
+
+// step 按dell-smm-hwmon的方式把PWM值换算为最接近的档位
+func (d DiscretePWM) step() error {
+	attr := fmt.Sprintf("pwm%d", d.PWM)
+	pwm, err := d.HWMon.GetInt(attr)
+	if err != nil {
+		return nil
+	}
+	mult := (255 + d.States - 2) / (d.States - 1)
+	state := (pwm + mult/2) / mult
+	if state > d.States-1 {
+		state = d.States - 1
+	}
+	value := state * mult
+	if value > 255 {
+		value = 255
+	}
+	if value == pwm {
+		return nil
+	}
+	return d.HWMon.SetInt(attr, value)
+}
+
+// Laptop 创建一个笔记本的演示目录树：
+//   - hwmon0: coretemp，包含Package温度
+//   - hwmon1: dell_smm，包含CPU温度、3档的pwm1（自动模式）和fan1，模拟运行时pwm1驱动fan1
+//   - /proc/acpi/ibm/fan: ThinkPad风扇（auto档位），模拟运行时处理写入的命令和看门狗
+func Laptop(root string) (*Tree, error) {
+	t, err := New(root)
+	if err != nil {
+		return nil, err
+	}
+
+	cpu, err := t.AddHWMon("coretemp", "platform/coretemp.0")
+	if err != nil {
+		return nil, err
+	}
+	if err := cpu.AddTemp(1, 52000, "Package id 0"); err != nil {
+		return nil, err
+	}
+
+	dell, err := t.AddHWMon("dell_smm", "platform/dell_smm_hwmon")
+	if err != nil {
+		return nil, err
+	}
+	if err := dell.AddTemp(1, 50000, "CPU"); err != nil {
+		return nil, err
+	}
+	if err := dell.AddPWM(1, 128, 2); err != nil {
+		return nil, err
+	}
+	if err := dell.AddFan(1, 2400, "Processor Fan"); err != nil {
+		return nil, err
+	}
+	t.AddDiscretePWM(dell, 1, 3)
+	t.LinkFan(dell, 1, 1, 4800, 1, 1)
+
+	if _, err := t.AddThinkPadFan(5600); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
@@ -0,0 +1,116 @@
+package fixture
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// FanLink 模拟一个由PWM通道驱动、由转速通道测速的风扇
+// PWM通道和转速通道的编号可以不同，用于模拟Super-I/O芯片上fanN与pwmN不对应的情况
+type FanLink struct {
+	HWMon *HWMon
+	// PWM 驱动风扇的PWM通道编号
+	PWM int
+	// Fan 测量风扇转速的通道编号
+	Fan int
+	// MaxRPM PWM为255时的转速
+	MaxRPM int
+	// StopPWM PWM低于该值时风扇停转
+	StopPWM int
+	// StartPWM 风扇停转后PWM达到该值才能重新启动，小于StopPWM时按StopPWM处理
+	StartPWM int
+}
+
+// RPM 按PWM值计算风扇转速，running为风扇当前是否在转动
+func (l FanLink) RPM(pwm int, running bool) int {
+	threshold := l.StopPWM
+	if !running && l.StartPWM > threshold {
+		threshold = l.StartPWM
+	}
+	if pwm < threshold || pwm <= 0 {
+		return 0
+	}
+	return l.MaxRPM * pwm / 255
+}
+
+// LinkFan 添加一个模拟风扇，Simulate 运行时根据pwmN更新fanM_input
+func (t *Tree) LinkFan(h *HWMon, pwm, fan, maxRPM, stopPWM, startPWM int) {
+	t.Links = append(t.Links, FanLink{HWMon: h, PWM: pwm, Fan: fan, MaxRPM: maxRPM, StopPWM: stopPWM, StartPWM: startPWM})
+}
+
+// Step 根据当前PWM值更新一次所有模拟风扇的转速，暂时读不到PWM值或转速的风扇跳过；
+// 并处理写入各PWM控制器export和unexport的通道号、把只支持几档的PWM通道换算为档位、处理写入ThinkPad风扇的命令
+func (t *Tree) Step() error {
+	for _, c := range t.PWMChips {
+		if err := c.step(); err != nil {
+			return err
+		}
+	}
+	for _, d := range t.Discrete {
+		if err := d.step(); err != nil {
+			return err
+		}
+	}
+	if t.ThinkPad != nil {
+		if err := t.ThinkPad.step(time.Now()); err != nil {
+			return err
+		}
+	}
+	for _, l := range t.Links {
+		// 文件可能正被fanap写入（截断后尚未写入新值），读取失败时等下一次更新
+		pwm, err := l.HWMon.GetInt(fmt.Sprintf("pwm%d", l.PWM))
+		if err != nil {
+			continue
+		}
+		input := fmt.Sprintf("fan%d_input", l.Fan)
+		rpm, err := l.HWMon.GetInt(input)
+		if err != nil {
+			continue
+		}
+		if err := l.HWMon.SetInt(input, l.RPM(pwm, rpm > 0)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// step 导出或取消导出写入export、unexport的通道，处理后清空这两个文件
+func (c *PWMChip) step() error {
+	for attr, apply := range map[string]func(int) error{"export": c.Export, "unexport": c.Unexport} {
+		value, err := c.Get(attr)
+		if err != nil || value == "" {
+			continue
+		}
+		if channel, err := strconv.Atoi(value); err == nil {
+			if err := apply(channel); err != nil {
+				log.Printf("模拟%s %s失败: %v", filepath.Base(c.Dir), attr, err)
+			}
+		}
+		if err := os.WriteFile(c.Path(attr), nil, 0644); err != nil {
+			return fmt.Errorf("清空 %s 失败: %w", attr, err)
+		}
+	}
+	return nil
+}
+
+// Simulate 每隔interval更新一次模拟风扇的转速，直到ctx结束
+func (t *Tree) Simulate(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := t.Step(); err != nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package sysfs
+
+import (
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+const (
+	// EnvRoot 指定sysfs根目录的环境变量
+	EnvRoot = "FANAP_SYSFS_ROOT"
+
+	// HWMonClass hwmon设备类目录
+	HWMonClass = "/sys/class/hwmon"
+	// ThermalClass thermal设备类目录
+	ThermalClass = "/sys/class/thermal"
+	// PWMClass PWM子系统设备类目录
+	PWMClass = "/sys/class/pwm"
+)
+
+var (
+	mu   sync.RWMutex
+	root string
+)
+
+// SetRoot 设置sysfs根目录
+// 为空时使用真实的系统路径；设置为其他目录（如 "./hwmon-test"）时，
+// 所有 /sys 下的路径都会映射到该目录下，用于在没有真实硬件时测试
+func SetRoot(dir string) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if dir == "" || dir == "/" {
+		root = ""
+		return
+	}
+	if abs, err := filepath.Abs(dir); err == nil {
+		dir = abs
+	}
+	root = filepath.Clean(dir)
+}
+
+// Root 返回当前sysfs根目录（空字符串表示真实系统）
+func Root() string {
+	mu.RLock()
+	defer mu.RUnlock()
+	return root
+}
+
+// Path 将系统绝对路径（如 "/sys/class/hwmon/hwmon0/pwm1"）映射到当前根目录下
+// 已位于根目录下的路径和相对路径保持不变
+func Path(p string) string {
+	r := Root()
+	if r == "" || !filepath.IsAbs(p) {
+		return p
+	}
+	if p == r || strings.HasPrefix(p, r+string(filepath.Separator)) {
+		return p
+	}
+	return filepath.Join(r, p)
+}
+
+// HWMonPath 返回hwmon设备类目录
+func HWMonPath() string {
+	return Path(HWMonClass)
+}
+
+// ThermalPath 返回thermal设备类目录
+func ThermalPath() string {
+	return Path(ThermalClass)
+}
+
+// PWMPath 返回PWM子系统设备类目录
+func PWMPath() string {
+	return Path(PWMClass)
+}
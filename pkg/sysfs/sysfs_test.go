@@ -0,0 +1,106 @@
+package sysfs
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fanap/pkg/sysfs/fixture"
+)
+
+func TestPath(t *testing.T) {
+	root := t.TempDir()
+
+	tests := []struct {
+		name string
+		root string
+		path string
+		want string
+	}{
+		{"真实系统", "", "/sys/class/hwmon/hwmon0/pwm1", "/sys/class/hwmon/hwmon0/pwm1"},
+		{"根目录为/", "/", "/sys/class/hwmon", "/sys/class/hwmon"},
+		{"映射绝对路径", root, "/sys/class/hwmon/hwmon0/pwm1", filepath.Join(root, "sys/class/hwmon/hwmon0/pwm1")},
+		{"映射proc", root, "/proc/acpi/ibm/fan", filepath.Join(root, "proc/acpi/ibm/fan")},
+		{"已在根目录下", root, filepath.Join(root, "sys/class/hwmon"), filepath.Join(root, "sys/class/hwmon")},
+		{"根目录本身", root, root, root},
+		{"相对路径不变", root, "hwmon0/pwm1", "hwmon0/pwm1"},
+		{"前缀相同的其他目录", root, root + "-other/sys", filepath.Join(root, root+"-other/sys")},
+		{"根目录末尾的斜杠", root + "/", "/sys/class/pwm", filepath.Join(root, "sys/class/pwm")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			SetRoot(tt.root)
+			t.Cleanup(func() { SetRoot("") })
+
+			if got := Path(tt.path); got != tt.want {
+				t.Errorf("Path(%q) = %q, 期望 %q", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetRootRelative(t *testing.T) {
+	dir := t.TempDir()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	SetRoot("./hwmon-test")
+	t.Cleanup(func() { SetRoot("") })
+
+	// 相对路径按当前目录转换为绝对路径，之后切换目录不影响映射
+	want, err := filepath.Abs("hwmon-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := Root(); got != want {
+		t.Errorf("Root() = %q, 期望 %q", got, want)
+	}
+}
+
+func TestClassPaths(t *testing.T) {
+	tree, err := fixture.Demo(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	SetRoot(tree.Root)
+	t.Cleanup(func() { SetRoot("") })
+
+	tests := []struct {
+		name string
+		dir  string
+		want []string
+	}{
+		{"hwmon", HWMonPath(), []string{"hwmon0", "hwmon1", "hwmon2"}},
+		{"thermal", ThermalPath(), []string{"cooling_device0", "thermal_zone0"}},
+		{"pwm", PWMPath(), []string{"pwmchip0"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, err := os.ReadDir(tt.dir)
+			if err != nil {
+				t.Fatalf("读取 %s 失败: %v", tt.dir, err)
+			}
+			var got []string
+			for _, e := range entries {
+				got = append(got, e.Name())
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("%s 下的设备 = %v, 期望 %v", tt.dir, got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("%s 下的设备 = %v, 期望 %v", tt.dir, got, tt.want)
+					break
+				}
+			}
+		})
+	}
+}
@@ -6,6 +6,8 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+
+	"github.com/fanap/pkg/sysfs"
 )
 
 // Zone 温度区域接口
@@ -25,10 +27,11 @@ type ThermalZone struct {
 func NewZone(zoneName string) (*ThermalZone, error) {
 	// 如果zoneName已经是完整路径
 	if filepath.IsAbs(zoneName) {
-		if _, err := os.Stat(zoneName); err != nil {
+		zonePath := sysfs.Path(zoneName)
+		if _, err := os.Stat(zonePath); err != nil {
 			return nil, fmt.Errorf("温度区域路径不存在: %w", err)
 		}
-		return &ThermalZone{path: zoneName}, nil
+		return &ThermalZone{path: zonePath}, nil
 	}
 
 	// 自动查找CPU温度区域
@@ -69,7 +72,7 @@ func (z *ThermalZone) Close() error {
 
 // findThermalZone 查找温度区域
 func findThermalZone(zoneName string) (string, error) {
-	thermalPath := "/sys/class/thermal"
+	thermalPath := sysfs.ThermalPath()
 
 	entries, err := os.ReadDir(thermalPath)
 	if err != nil {
@@ -0,0 +1,245 @@
+package thinkpad
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/fanap/pkg/sysfs"
+)
+
+// Options ThinkPad风扇的设置
+type Options struct {
+	// Path 风扇控制文件，为空时使用 DefaultPath
+	Path string
+	// Watchdog 看门狗超时时间（1-120秒），0表示不启用
+	Watchdog time.Duration
+	// FullSpeed 全速运行（失效保护、停转启动、退出时全速）使用的档位: full-speed（默认）、disengaged
+	FullSpeed string
+	// MinPWM、MaxPWM PWM范围
+	MinPWM int
+	MaxPWM int
+}
+
+// Fan 通过 /proc/acpi/ibm/fan 控制的ThinkPad风扇
+type Fan struct {
+	path string
+	opts Options
+	orig string
+
+	mu     sync.Mutex
+	level  string
+	closed bool
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewFan 检查风扇控制文件是否可写并启用看门狗
+// 启动时的档位在关闭时恢复；启用看门狗后在后台定期重发当前档位，fanap异常退出后固件在超时后切回auto
+func NewFan(opts Options) (*Fan, error) {
+	if opts.Path == "" {
+		opts.Path = DefaultPath
+	}
+	if opts.FullSpeed == "" {
+		opts.FullSpeed = LevelFullSpeed
+	}
+	if opts.FullSpeed != LevelFullSpeed && opts.FullSpeed != LevelDisengaged {
+		return nil, fmt.Errorf("全速档位必须是 %s 或 %s", LevelFullSpeed, LevelDisengaged)
+	}
+	if opts.Watchdog < 0 || opts.Watchdog > MaxWatchdog {
+		return nil, fmt.Errorf("看门狗超时时间必须在0-%d秒之间", int(MaxWatchdog.Seconds()))
+	}
+	if opts.MinPWM < 0 || opts.MaxPWM > 255 || opts.MinPWM >= opts.MaxPWM {
+		return nil, fmt.Errorf("无效的PWM范围 %d-%d", opts.MinPWM, opts.MaxPWM)
+	}
+
+	f := &Fan{path: sysfs.Path(opts.Path), opts: opts}
+	st, err := ReadStatus(f.path)
+	if err != nil {
+		return nil, err
+	}
+	if !st.Supports("level") {
+		return nil, fmt.Errorf("%s 不支持设置档位，需要以 fan_control=1 加载thinkpad_acpi（如在 /etc/modprobe.d 中添加 options thinkpad_acpi fan_control=1）", opts.Path)
+	}
+	f.orig = st.Level
+	if !ValidLevel(f.orig) {
+		log.Printf("ThinkPad风扇的档位 %q 无法恢复，退出时恢复为 %s", f.orig, LevelAuto)
+		f.orig = LevelAuto
+	}
+
+	if opts.Watchdog > 0 {
+		if !st.Supports("watchdog") {
+			return nil, fmt.Errorf("%s 不支持看门狗", opts.Path)
+		}
+		if err := writeCommand(f.path, fmt.Sprintf("watchdog %d", watchdogSeconds(opts.Watchdog))); err != nil {
+			return nil, fmt.Errorf("设置看门狗失败: %w", err)
+		}
+		f.stop = make(chan struct{})
+		f.done = make(chan struct{})
+		go f.keepalive(opts.Watchdog / 2)
+	}
+	return f, nil
+}
+
+// watchdogSeconds 把看门狗超时时间换算为秒，不足1秒的部分向上取整
+func watchdogSeconds(d time.Duration) int {
+	return int((d + time.Second - 1) / time.Second)
+}
+
+// keepalive 定期重发当前档位，重置看门狗
+// 零转速模式停转期间等情况下控制循环不会写入档位，不能依赖SetSpeed重置看门狗
+func (f *Fan) keepalive(interval time.Duration) {
+	defer close(f.done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-f.stop:
+			return
+		case <-ticker.C:
+		}
+
+		f.mu.Lock()
+		if f.level != "" {
+			if err := writeCommand(f.path, "level "+f.level); err != nil {
+				log.Printf("ThinkPad风扇重置看门狗失败: %v", err)
+			}
+		}
+		f.mu.Unlock()
+	}
+}
+
+// SetSpeed 设置风扇速度，限制在PWM范围内后向上取整到0-7档
+func (f *Fan) SetSpeed(pwm int) error {
+	if pwm < f.opts.MinPWM {
+		pwm = f.opts.MinPWM
+	}
+	if pwm > f.opts.MaxPWM {
+		pwm = f.opts.MaxPWM
+	}
+	return f.set(fmt.Sprint(LevelForPWM(pwm)))
+}
+
+// SetFullSpeed 以full-speed（或disengaged）运行，不受最大PWM限制
+func (f *Fan) SetFullSpeed() error {
+	return f.set(f.opts.FullSpeed)
+}
+
+// StopFan 以档位0让风扇停转（零转速模式），不受最小PWM限制
+func (f *Fan) StopFan() error {
+	return f.set("0")
+}
+
+// set 写入档位，每次都写入以重置看门狗
+func (f *Fan) set(level string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := writeCommand(f.path, "level "+level); err != nil {
+		return err
+	}
+	f.level = level
+	return nil
+}
+
+// GetSpeed 读取当前档位并换算为PWM，自动模式下返回错误
+func (f *Fan) GetSpeed() (int, error) {
+	st, err := ReadStatus(f.path)
+	if err != nil {
+		return 0, err
+	}
+	return PWMForLevel(st.Level)
+}
+
+// GetRPM 读取风扇转速
+func (f *Fan) GetRPM() (int, error) {
+	st, err := ReadStatus(f.path)
+	if err != nil {
+		return 0, err
+	}
+	if st.Speed < 0 {
+		return 0, fmt.Errorf("风扇状态中没有转速")
+	}
+	return st.Speed, nil
+}
+
+// GetMinSpeed 获取最小速度
+func (f *Fan) GetMinSpeed() int {
+	return f.opts.MinPWM
+}
+
+// GetMaxSpeed 获取最大速度
+func (f *Fan) GetMaxSpeed() int {
+	return f.opts.MaxPWM
+}
+
+// Close 停止重置看门狗，关闭看门狗后恢复启动时的档位，只有第一次调用（包括 Release）生效
+func (f *Fan) Close() error {
+	if !f.stopKeepalive() {
+		return nil
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.disableWatchdog(); err != nil {
+		return err
+	}
+	if err := writeCommand(f.path, "level "+f.orig); err != nil {
+		return fmt.Errorf("恢复ThinkPad风扇档位失败: %w", err)
+	}
+	return nil
+}
+
+// Release 停止重置看门狗并关闭看门狗，不恢复启动时的档位，风扇保持最后设置的档位
+// 不关闭看门狗时固件会在超时后切回auto
+func (f *Fan) Release() error {
+	if !f.stopKeepalive() {
+		return nil
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.disableWatchdog()
+}
+
+// stopKeepalive 标记风扇已关闭并停止重置看门狗，已经关闭过时返回false
+func (f *Fan) stopKeepalive() bool {
+	f.mu.Lock()
+	closed := f.closed
+	f.closed = true
+	f.level = ""
+	f.mu.Unlock()
+
+	if closed {
+		return false
+	}
+	if f.stop != nil {
+		close(f.stop)
+		<-f.done
+	}
+	return true
+}
+
+// disableWatchdog 关闭看门狗，需要持有mu
+func (f *Fan) disableWatchdog() error {
+	if f.opts.Watchdog <= 0 {
+		return nil
+	}
+	if err := writeCommand(f.path, "watchdog 0"); err != nil {
+		return fmt.Errorf("关闭看门狗失败: %w", err)
+	}
+	return nil
+}
+
+// String 描述风扇，用于日志
+func (f *Fan) String() string {
+	s := fmt.Sprintf("ThinkPad风扇 %s (档位0-%d", f.opts.Path, MaxLevel)
+	if f.opts.Watchdog > 0 {
+		s += fmt.Sprintf(", 看门狗 %ds", watchdogSeconds(f.opts.Watchdog))
+	}
+	return s + ")"
+}
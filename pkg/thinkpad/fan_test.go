@@ -0,0 +1,235 @@
+package thinkpad
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fanap/pkg/sysfs"
+	"github.com/fanap/pkg/sysfs/fixture"
+)
+
+// newLaptop 创建笔记本的模拟目录树，返回其中的ThinkPad风扇
+func newLaptop(t *testing.T) (*fixture.Tree, *fixture.ThinkPadFan) {
+	t.Helper()
+
+	tree, err := fixture.Laptop(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	sysfs.SetRoot(tree.Root)
+	t.Cleanup(func() { sysfs.SetRoot("") })
+	return tree, tree.ThinkPad
+}
+
+func TestFanRelease(t *testing.T) {
+	tree, tp := newLaptop(t)
+
+	f, err := NewFan(Options{Watchdog: DefaultWatchdog, MinPWM: 0, MaxPWM: 255})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tree.Step(); err != nil {
+		t.Fatal(err)
+	}
+	if tp.Watchdog != 30 {
+		t.Fatalf("打开后看门狗 = %d, 期望 30", tp.Watchdog)
+	}
+
+	if err := f.SetFullSpeed(); err != nil {
+		t.Fatal(err)
+	}
+	if err := tree.Step(); err != nil {
+		t.Fatal(err)
+	}
+
+	// 退出策略为full或leave时关闭看门狗，固件不会在超时后切回auto
+	if err := f.Release(); err != nil {
+		t.Fatal(err)
+	}
+	if err := tree.Step(); err != nil {
+		t.Fatal(err)
+	}
+	if tp.Watchdog != 0 {
+		t.Errorf("释放后看门狗 = %d, 期望 0", tp.Watchdog)
+	}
+	if tp.Level != LevelFullSpeed {
+		t.Errorf("释放后档位 = %q, 期望 %q", tp.Level, LevelFullSpeed)
+	}
+
+	// 之后的 Close 不再恢复启动时的档位
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := tree.Step(); err != nil {
+		t.Fatal(err)
+	}
+	if tp.Level != LevelFullSpeed {
+		t.Errorf("释放后关闭 档位 = %q, 期望 %q", tp.Level, LevelFullSpeed)
+	}
+}
+
+func TestFanReleaseStopsKeepalive(t *testing.T) {
+	tree, tp := newLaptop(t)
+
+	f, err := NewFan(Options{Watchdog: 2 * time.Second, MinPWM: 0, MaxPWM: 255})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := f.SetSpeed(100); err != nil {
+		t.Fatal(err)
+	}
+	if err := tree.Step(); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Release(); err != nil {
+		t.Fatal(err)
+	}
+	if err := tree.Step(); err != nil {
+		t.Fatal(err)
+	}
+
+	// 释放后不再重发档位，控制文件保持模拟器写入的状态
+	time.Sleep(1500 * time.Millisecond)
+	st, err := ReadStatus(tp.Path)
+	if err != nil {
+		t.Fatalf("释放后仍在写入命令: %v", err)
+	}
+	if st.Level != "3" {
+		t.Errorf("释放后档位 = %q, 期望 3", st.Level)
+	}
+}
+
+func TestFanClose(t *testing.T) {
+	tests := []struct {
+		name     string
+		orig     string
+		watchdog time.Duration
+	}{
+		{"auto", LevelAuto, 0},
+		{"数字档位", "2", 0},
+		{"启用看门狗", LevelAuto, DefaultWatchdog},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tree, tp := newLaptop(t)
+			if tt.orig != LevelAuto {
+				if err := os.WriteFile(tp.Path, []byte("level "+tt.orig), 0644); err != nil {
+					t.Fatal(err)
+				}
+				if err := tree.Step(); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			f, err := NewFan(Options{Watchdog: tt.watchdog, MinPWM: 0, MaxPWM: 255})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := tree.Step(); err != nil {
+				t.Fatal(err)
+			}
+			if want := int(tt.watchdog / time.Second); tp.Watchdog != want {
+				t.Errorf("打开后看门狗 = %d, 期望 %d", tp.Watchdog, want)
+			}
+
+			if err := f.SetSpeed(132); err != nil {
+				t.Fatal(err)
+			}
+			if err := tree.Step(); err != nil {
+				t.Fatal(err)
+			}
+			if tp.Level != "4" {
+				t.Errorf("SetSpeed(132) 后档位 = %q, 期望 4", tp.Level)
+			}
+			if pwm, err := f.GetSpeed(); err != nil || pwm != 145 {
+				t.Errorf("GetSpeed() = %d, %v, 期望 145", pwm, err)
+			}
+			if rpm, err := f.GetRPM(); err != nil || rpm != 3200 {
+				t.Errorf("GetRPM() = %d, %v, 期望 3200", rpm, err)
+			}
+
+			// 关闭时恢复启动时的档位，重复关闭不再写入
+			if err := f.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if err := tree.Step(); err != nil {
+				t.Fatal(err)
+			}
+			if tp.Level != tt.orig {
+				t.Errorf("关闭后档位 = %q, 期望 %q", tp.Level, tt.orig)
+			}
+			if err := f.SetSpeed(255); err != nil {
+				t.Fatal(err)
+			}
+			if err := f.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if data, _ := os.ReadFile(tp.Path); string(data) != "level 7" {
+				t.Errorf("重复关闭后写入了 %q", data)
+			}
+		})
+	}
+}
+
+func TestFanKeepalive(t *testing.T) {
+	tree, tp := newLaptop(t)
+
+	f, err := NewFan(Options{Watchdog: 2 * time.Second, MinPWM: 0, MaxPWM: 255})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err := f.SetSpeed(100); err != nil {
+		t.Fatal(err)
+	}
+	if err := tree.Step(); err != nil {
+		t.Fatal(err)
+	}
+
+	// 控制循环没有写入时，后台每隔一半的超时时间重发当前档位
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		data, err := os.ReadFile(tp.Path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.TrimSpace(string(data)) == "level 3" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("超时时间内没有重发档位")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+func TestNewFanErrors(t *testing.T) {
+	_, tp := newLaptop(t)
+
+	tests := []struct {
+		name string
+		opts Options
+	}{
+		{"看门狗过长", Options{Watchdog: 2 * MaxWatchdog, MaxPWM: 255}},
+		{"负的看门狗", Options{Watchdog: -time.Second, MaxPWM: 255}},
+		{"未知的全速档位", Options{FullSpeed: "7", MaxPWM: 255}},
+		{"无效的PWM范围", Options{MinPWM: 200, MaxPWM: 100}},
+		{"文件不存在", Options{Path: "/proc/acpi/ibm/fan2", MaxPWM: 255}},
+	}
+	for _, tt := range tests {
+		if _, err := NewFan(tt.opts); err == nil {
+			t.Errorf("%s: NewFan 应返回错误", tt.name)
+		}
+	}
+
+	// 没有以fan_control=1加载时不能设置档位
+	if err := os.WriteFile(tp.Path, []byte("status:\t\tenabled\nspeed:\t\t2650\nlevel:\t\tauto\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewFan(Options{MaxPWM: 255}); err == nil {
+		t.Error("不支持level命令时 NewFan 应返回错误")
+	}
+}
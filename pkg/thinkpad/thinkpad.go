@@ -0,0 +1,156 @@
+// Package thinkpad 通过thinkpad_acpi的 /proc/acpi/ibm/fan 接口控制ThinkPad笔记本的风扇
+//
+// 读取该文件得到风扇状态，写入命令控制风扇：
+//
+//	status:		enabled
+//	speed:		2650
+//	level:		auto
+//	commands:	level <level> (<level> is 0-7, auto, disengaged, full-speed)
+//	commands:	enable, disable
+//	commands:	watchdog <timeout> (<timeout> is 0 (off), 1-120 (seconds))
+//
+// 只有以 fan_control=1 加载thinkpad_acpi时才能写入（文件中会列出commands）。
+// 档位0为停转，1-7由固件按档位调速；full-speed为固件控制下的最高转速，disengaged不再调速，转速可能更高。
+// 看门狗在指定时间内没有收到level等命令时把风扇切回auto，fanap异常退出后固件会恢复自动控制
+package thinkpad
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultPath thinkpad_acpi的风扇控制文件
+const DefaultPath = "/proc/acpi/ibm/fan"
+
+// MaxLevel 最高的数字档位
+const MaxLevel = 7
+
+// 非数字的档位
+const (
+	LevelAuto       = "auto"
+	LevelFullSpeed  = "full-speed"
+	LevelDisengaged = "disengaged"
+)
+
+// 看门狗超时时间
+const (
+	DefaultWatchdog = 30 * time.Second
+	MaxWatchdog     = 120 * time.Second
+)
+
+// Status 风扇控制文件的内容
+type Status struct {
+	// Enabled 风扇是否启用（status: enabled）
+	Enabled bool
+	// Speed 转速（RPM），没有speed行时为-1
+	Speed int
+	// Level 当前档位：0-7、auto、full-speed或disengaged
+	Level string
+	// Commands 支持的命令，如 "level"、"enable"、"watchdog"；没有以fan_control=1加载时为空
+	Commands []string
+}
+
+// ReadStatus 读取风扇控制文件
+func ReadStatus(path string) (Status, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Status{}, fmt.Errorf("读取风扇状态失败: %w", err)
+	}
+	return ParseStatus(string(data))
+}
+
+// ParseStatus 解析风扇控制文件的内容
+func ParseStatus(data string) (Status, error) {
+	st := Status{Speed: -1}
+	for _, line := range strings.Split(data, "\n") {
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch strings.TrimSpace(key) {
+		case "status":
+			st.Enabled = value == "enabled"
+		case "speed":
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return st, fmt.Errorf("无效的转速 %q", value)
+			}
+			st.Speed = n
+		case "level":
+			st.Level = value
+		case "commands":
+			// "level <level> (...)"、"enable, disable"，括号中的说明也以逗号分隔，先去掉
+			if i := strings.Index(value, "("); i >= 0 {
+				value = value[:i]
+			}
+			for _, cmd := range strings.Split(value, ",") {
+				if f := strings.Fields(cmd); len(f) > 0 {
+					st.Commands = append(st.Commands, f[0])
+				}
+			}
+		}
+	}
+	if st.Level == "" {
+		return st, fmt.Errorf("风扇状态中没有level")
+	}
+	return st, nil
+}
+
+// Supports 检查是否支持命令
+func (s Status) Supports(cmd string) bool {
+	for _, c := range s.Commands {
+		if c == cmd {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidLevel 检查档位是否可以写入
+func ValidLevel(level string) bool {
+	switch level {
+	case LevelAuto, LevelFullSpeed, LevelDisengaged:
+		return true
+	}
+	n, err := strconv.Atoi(level)
+	return err == nil && n >= 0 && n <= MaxLevel
+}
+
+// LevelForPWM 把PWM（0-255）向上取整到数字档位，保证冷却能力不低于计算结果；只有0对应停转
+func LevelForPWM(pwm int) int {
+	if pwm <= 0 {
+		return 0
+	}
+	if pwm >= 255 {
+		return MaxLevel
+	}
+	return (pwm*MaxLevel + 254) / 255
+}
+
+// PWMForLevel 把档位换算为PWM，auto无法换算
+// 向下取整，LevelForPWM 换算回来时得到同一档位
+func PWMForLevel(level string) (int, error) {
+	switch level {
+	case LevelFullSpeed, LevelDisengaged:
+		return 255, nil
+	case LevelAuto:
+		return 0, fmt.Errorf("风扇处于自动模式")
+	}
+	n, err := strconv.Atoi(level)
+	if err != nil || n < 0 || n > MaxLevel {
+		return 0, fmt.Errorf("无效的档位 %q", level)
+	}
+	return n * 255 / MaxLevel, nil
+}
+
+// writeCommand 向风扇控制文件写入一条命令
+func writeCommand(path, cmd string) error {
+	if err := os.WriteFile(path, []byte(cmd), 0644); err != nil {
+		return fmt.Errorf("写入 %q 失败: %w", cmd, err)
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package thinkpad
+
+import (
+	"strings"
+	"testing"
+)
+
+const statusFull = "status:\t\tenabled\nspeed:\t\t2650\nlevel:\t\tauto\n" +
+	"commands:\tlevel <level> (<level> is 0-7, auto, disengaged, full-speed)\n" +
+	"commands:\tenable, disable\n" +
+	"commands:\twatchdog <timeout> (<timeout> is 0 (off), 1-120 (seconds))\n"
+
+func TestParseStatus(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    Status
+		wantErr bool
+	}{
+		{"fan_control=1", statusFull, Status{Enabled: true, Speed: 2650, Level: "auto", Commands: []string{"level", "enable", "disable", "watchdog"}}, false},
+		{"没有commands", "status:\t\tenabled\nspeed:\t\t0\nlevel:\t\t0\n", Status{Enabled: true, Speed: 0, Level: "0"}, false},
+		{"没有speed", "status:\t\tdisabled\nlevel:\t\tfull-speed\n", Status{Speed: -1, Level: "full-speed"}, false},
+		{"没有level", "status:\t\tenabled\nspeed:\t\t2650\n", Status{}, true},
+		{"无效的转速", "speed:\t\tfast\nlevel:\t\tauto\n", Status{}, true},
+		{"空文件", "", Status{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseStatus(tt.data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseStatus() 错误 = %v, 期望出错 %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got.Enabled != tt.want.Enabled || got.Speed != tt.want.Speed || got.Level != tt.want.Level ||
+				strings.Join(got.Commands, ",") != strings.Join(tt.want.Commands, ",") {
+				t.Errorf("ParseStatus() = %+v, 期望 %+v", got, tt.want)
+			}
+		})
+	}
+
+	st, err := ParseStatus(statusFull)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !st.Supports("watchdog") || st.Supports("speed") {
+		t.Errorf("Supports 与 commands %v 不一致", st.Commands)
+	}
+}
+
+func TestLevelForPWM(t *testing.T) {
+	tests := []struct {
+		pwm  int
+		want int
+	}{
+		{-5, 0},
+		{0, 0},
+		{1, 1},
+		{36, 1},
+		{37, 2},
+		{132, 4},
+		{254, 7},
+		{255, 7},
+		{300, 7},
+	}
+	for _, tt := range tests {
+		if got := LevelForPWM(tt.pwm); got != tt.want {
+			t.Errorf("LevelForPWM(%d) = %d, 期望 %d", tt.pwm, got, tt.want)
+		}
+	}
+}
+
+func TestPWMForLevel(t *testing.T) {
+	tests := []struct {
+		level   string
+		want    int
+		wantErr bool
+	}{
+		{"0", 0, false},
+		{"1", 36, false},
+		{"2", 72, false},
+		{"4", 145, false},
+		{"7", 255, false},
+		{LevelFullSpeed, 255, false},
+		{LevelDisengaged, 255, false},
+		{LevelAuto, 0, true},
+		{"8", 0, true},
+		{"-1", 0, true},
+		{"fast", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := PWMForLevel(tt.level)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("PWMForLevel(%q) 错误 = %v, 期望出错 %v", tt.level, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("PWMForLevel(%q) = %d, 期望 %d", tt.level, got, tt.want)
+		}
+	}
+
+	// 每个档位换算为PWM后再取整回到同一档位
+	for n := 0; n <= MaxLevel; n++ {
+		pwm, err := PWMForLevel(string(rune('0' + n)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := LevelForPWM(pwm); got != n {
+			t.Errorf("档位 %d -> PWM %d -> 档位 %d", n, pwm, got)
+		}
+	}
+}
+
+func TestValidLevel(t *testing.T) {
+	for _, level := range []string{"0", "7", LevelAuto, LevelFullSpeed, LevelDisengaged} {
+		if !ValidLevel(level) {
+			t.Errorf("ValidLevel(%q) = false, 期望 true", level)
+		}
+	}
+	for _, level := range []string{"", "8", "-1", "full"} {
+		if ValidLevel(level) {
+			t.Errorf("ValidLevel(%q) = true, 期望 false", level)
+		}
+	}
+}
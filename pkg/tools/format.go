@@ -0,0 +1,45 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// 输出格式
+const (
+	FormatTable = "table"
+	FormatJSON  = "json"
+	FormatYAML  = "yaml"
+)
+
+// ParseFormat 检查输出格式，空字符串表示 FormatTable
+func ParseFormat(s string) (string, error) {
+	switch s {
+	case "", FormatTable:
+		return FormatTable, nil
+	case FormatJSON, FormatYAML:
+		return s, nil
+	}
+	return "", fmt.Errorf("不支持的输出格式 %q（可选: table、json、yaml）", s)
+}
+
+// tableWriter 可以输出为文本的结果
+type tableWriter interface {
+	WriteTable(w io.Writer) error
+}
+
+// Write 按指定格式输出 -list 或 -check 的结果
+func Write(w io.Writer, v tableWriter, format string) error {
+	switch format {
+	case FormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	case FormatYAML:
+		return writeYAML(w, v)
+	case "", FormatTable:
+		return v.WriteTable(w)
+	}
+	return fmt.Errorf("不支持的输出格式 %q（可选: table、json、yaml）", format)
+}
@@ -0,0 +1,93 @@
+package tools
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "更新testdata中的期望输出")
+
+// testInventory 包含需要加引号的标签、空列表和省略的字段
+func testInventory() *Inventory {
+	temp, crit := 45.5, 100.0
+	rpm, value, enable := 1200, 128, 1
+	return &Inventory{
+		HWMonPath: "/sys/class/hwmon",
+		Chips: []Chip{
+			{
+				ID:        "hwmon0",
+				Name:      "nct6775",
+				Path:      "/sys/class/hwmon/hwmon0",
+				Device:    "nct6775.656",
+				FanDevice: true,
+				Temps: []Temp{
+					{Channel: "temp1", Label: "CPU: Package", Path: "/sys/class/hwmon/hwmon0/temp1_input", Selector: "hwmon:nct6775/CPU: Package", CPU: true, Temp: &temp, Crit: &crit},
+					{Channel: "temp2", Label: "on", Path: "/sys/class/hwmon/hwmon0/temp2_input", Selector: "hwmon:nct6775/temp2"},
+				},
+				Fans: []Fan{
+					{Channel: "fan1", Label: "1.0", Path: "/sys/class/hwmon/hwmon0/fan1_input", RPM: &rpm},
+				},
+				PWMs: []PWM{
+					{Channel: "pwm1", Label: "-12V", Path: "/sys/class/hwmon/hwmon0/pwm1", Selector: "nct6775/pwm1", DeviceSelector: "nct6775.656/pwm1", Value: &value, Enable: &enable, FanInput: "fan1", RPM: &rpm},
+				},
+			},
+			{
+				ID:    "hwmon1",
+				Name:  "acpitz",
+				Path:  "/sys/class/hwmon/hwmon1",
+				Temps: []Temp{},
+				Fans:  []Fan{},
+				PWMs:  []PWM{},
+			},
+		},
+		Summary: Summary{Chips: 2, Temps: 2, Fans: 1, PWMs: 1},
+	}
+}
+
+func TestWriteGolden(t *testing.T) {
+	for _, format := range []string{FormatJSON, FormatYAML} {
+		t.Run(format, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := Write(&buf, testInventory(), format); err != nil {
+				t.Fatal(err)
+			}
+
+			golden := filepath.Join("testdata", "inventory."+format)
+			if *update {
+				if err := os.WriteFile(golden, buf.Bytes(), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := buf.String(); got != string(want) {
+				t.Errorf("输出与 %s 不一致（使用 -update 更新）:\n%s", golden, got)
+			}
+		})
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{"", FormatTable, false},
+		{"table", FormatTable, false},
+		{"json", FormatJSON, false},
+		{"yaml", FormatYAML, false},
+		{"xml", "", true},
+	}
+	for _, tt := range tests {
+		got, err := ParseFormat(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseFormat(%q) = %q, %v, 期望 %q", tt.in, got, err, tt.want)
+		}
+	}
+}
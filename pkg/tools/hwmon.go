@@ -2,469 +2,422 @@ package tools
 
 import (
 	"fmt"
+	"io"
 	"os"
-	"path/filepath"
-	"regexp"
 	"strings"
+
+	"github.com/fanap/pkg/sysfs"
 )
 
-// CheckHWMon 检查hwmon设备（诊断模式）
-func CheckHWMon() {
-	hwmonPath := "/sys/class/hwmon"
+// 诊断状态
+const (
+	// StatusOK 找到温度传感器和PWM风扇
+	StatusOK = "ok"
+	// StatusNoHWMon hwmon目录不存在
+	StatusNoHWMon = "no_hwmon"
+	// StatusError 读取hwmon目录失败
+	StatusError = "error"
+	// StatusEmpty hwmon目录为空
+	StatusEmpty = "empty"
+	// StatusNoDevices 有hwmon设备但没有温度传感器和PWM风扇
+	StatusNoDevices = "no_devices"
+	// StatusNoPWM 只找到温度传感器
+	StatusNoPWM = "no_pwm"
+	// StatusNoSensor 只找到PWM风扇
+	StatusNoSensor = "no_sensor"
+)
 
-	fmt.Println("=== HWMon设备诊断 ===")
-	fmt.Println()
+// Diagnosis -check 的诊断结果
+type Diagnosis struct {
+	// HWMonPath hwmon设备类目录
+	HWMonPath string `json:"hwmon_path"`
+	// Status 诊断状态（StatusOK 等）
+	Status string `json:"status"`
+	// Error 读取hwmon目录的错误
+	Error   string  `json:"error,omitempty"`
+	Chips   []Chip  `json:"chips"`
+	Summary Summary `json:"summary"`
+	// Sensor、PWM 自动检测会选用的温度传感器和PWM风扇的选择器，未找到时为空
+	Sensor string `json:"sensor,omitempty"`
+	PWM    string `json:"pwm,omitempty"`
+	// Command 建议的启动命令
+	Command string `json:"command,omitempty"`
+	// UID 当前用户，控制风扇需要root权限
+	UID  int  `json:"uid"`
+	Root bool `json:"root"`
+}
 
-	// 1. 检查hwmon目录
-	fmt.Println("1. 检查hwmon目录:")
-	if _, err := os.Stat(hwmonPath); err != nil {
-		fmt.Printf("   ✗ hwmon目录不存在: %s\n", hwmonPath)
-		fmt.Println("   建议: 确保内核支持hwmon，或加载相关内核模块")
-		return
+// CheckHWMon 诊断hwmon设备，找出可用的温度传感器和PWM风扇并给出建议
+func CheckHWMon() *Diagnosis {
+	d := &Diagnosis{
+		HWMonPath: sysfs.HWMonPath(),
+		Chips:     []Chip{},
+		UID:       os.Getuid(),
 	}
-	fmt.Printf("   ✓ hwmon目录存在\n")
+	d.Root = d.UID == 0
 
-	// 2. 读取hwmon目录
-	entries, err := os.ReadDir(hwmonPath)
-	if err != nil {
-		fmt.Printf("   ✗ 读取hwmon目录失败: %v\n", err)
-		return
+	if _, err := os.Stat(d.HWMonPath); err != nil {
+		d.Status = StatusNoHWMon
+		d.Error = err.Error()
+		return d
 	}
 
-	if len(entries) == 0 {
-		fmt.Println("   ✗ hwmon目录为空")
-		fmt.Println("   建议: 加载内核模块")
-		fmt.Println("   - Intel CPU: sudo modprobe coretemp")
-		fmt.Println("   - AMD CPU: sudo modprobe k10temp")
-		return
+	inv, err := ListHWMon()
+	if err != nil {
+		d.Status = StatusError
+		d.Error = err.Error()
+		return d
 	}
-
-	fmt.Printf("   ✓ 找到 %d 个hwmon设备\n", len(entries))
-	fmt.Println()
-
-	// 3. 检查每个设备
-	fmt.Println("2. 检查每个hwmon设备:")
-	deviceCount := 0
-	sensorCount := 0
-	pwmCount := 0
-
-	for _, entry := range entries {
-		// hwmon设备可能是符号链接，需要检查实际路径
-		fullPath := filepath.Join(hwmonPath, entry.Name())
-
-		// 检查是否为目录（包括符号链接指向的目录）
-		info, err := os.Lstat(fullPath)
-		if err != nil {
-			continue
+	d.Chips = inv.Chips
+	d.Summary = inv.Summary
+	d.Sensor, d.PWM = inv.autoSelectors()
+
+	switch {
+	case d.Summary.Chips == 0:
+		d.Status = StatusEmpty
+	case d.Summary.Temps == 0 && d.Summary.PWMs == 0:
+		d.Status = StatusNoDevices
+	case d.Summary.PWMs == 0:
+		d.Status = StatusNoPWM
+		d.Command = "sudo fanap -sensor " + shellQuote(firstNonEmpty(d.Sensor, inv.firstTemp(), "<温度传感器>")) + " -pwm auto -verbose"
+	case d.Summary.Temps == 0:
+		d.Status = StatusNoSensor
+		d.Command = "sudo fanap -sensor auto -pwm " + shellQuote(firstNonEmpty(d.PWM, inv.firstPWM(), "<PWM风扇>")) + " -verbose"
+	default:
+		d.Status = StatusOK
+		if d.Sensor != "" && d.PWM != "" {
+			d.Command = "sudo fanap -verbose"
+		} else {
+			// 自动检测无法识别时需要手动指定
+			d.Command = "sudo fanap -sensor " + shellQuote(firstNonEmpty(d.Sensor, inv.firstTemp())) +
+				" -pwm " + shellQuote(firstNonEmpty(d.PWM, inv.firstPWM())) + " -verbose"
 		}
+	}
+	return d
+}
 
-		// 如果是符号链接，获取目标信息
-		if info.Mode()&os.ModeSymlink != 0 {
-			target, err := os.Readlink(fullPath)
-			if err != nil {
-				continue
-			}
-			// 解析相对路径
-			if !filepath.IsAbs(target) {
-				fullPath = filepath.Join(hwmonPath, target)
-			} else {
-				fullPath = target
-			}
-			info, err = os.Stat(fullPath)
-			if err != nil || !info.IsDir() {
-				continue
+// autoSelectors 返回自动检测会选用的温度传感器和PWM风扇的选择器
+func (inv *Inventory) autoSelectors() (sensor, pwm string) {
+	for _, c := range inv.Chips {
+		for _, t := range c.Temps {
+			if sensor == "" && t.CPU {
+				sensor = t.Selector
 			}
-		} else if !info.IsDir() {
-			// 不是目录也不是符号链接，跳过
-			continue
 		}
+		if pwm == "" && c.FanDevice && len(c.PWMs) > 0 {
+			pwm = c.PWMs[0].Selector
+		}
+	}
+	return sensor, pwm
+}
 
-		deviceCount++
-		deviceDir := fullPath
-
-		// 读取设备名称
-		namePath := filepath.Join(deviceDir, "name")
-		deviceName := "unknown"
-		if data, err := os.ReadFile(namePath); err == nil {
-			deviceName = strings.TrimSpace(string(data))
+// firstTemp 返回第一个温度通道的选择器
+func (inv *Inventory) firstTemp() string {
+	for _, c := range inv.Chips {
+		if len(c.Temps) > 0 {
+			return c.Temps[0].Selector
 		}
+	}
+	return ""
+}
 
-		fmt.Printf("   设备 %d: %s\n", deviceCount, entry.Name())
-		fmt.Printf("     名称: %s\n", deviceName)
-
-		// 检查温度传感器
-		deviceSensorCount := 0
-		for i := 1; i <= 10; i++ {
-			tempInput := filepath.Join(deviceDir, fmt.Sprintf("temp%d_input", i))
-			if _, err := os.Stat(tempInput); err == nil {
-				deviceSensorCount++
-				sensorCount++
-
-				// 读取温度值
-				data, err := os.ReadFile(tempInput)
-				temp := "N/A"
-				if err == nil {
-					tempRaw := strings.TrimSpace(string(data))
-					if tempVal, err := parseTemp(tempRaw); err == nil {
-						temp = fmt.Sprintf("%.1f°C", tempVal)
-					}
-				}
-
-				// 读取标签
-				labelPath := filepath.Join(deviceDir, fmt.Sprintf("temp%d_label", i))
-				label := ""
-				if data, err := os.ReadFile(labelPath); err == nil {
-					label = strings.TrimSpace(string(data))
-				}
-
-				// 判断是否为CPU传感器
-				isCPU := isCPUSensor(deviceName, label)
-				cpuMark := ""
-				if isCPU {
-					cpuMark = " [CPU]"
-				}
-
-				fmt.Printf("     ✓ 温度%d: %s (%s%s) [%s]\n", i, tempInput, label, cpuMark, temp)
-			}
+// firstPWM 返回第一个PWM通道的选择器
+func (inv *Inventory) firstPWM() string {
+	for _, c := range inv.Chips {
+		if len(c.PWMs) > 0 {
+			return c.PWMs[0].Selector
 		}
+	}
+	return ""
+}
+
+// WriteTable 以文本形式输出诊断结果
+func (d *Diagnosis) WriteTable(w io.Writer) error {
+	fmt.Fprintln(w, "=== HWMon设备诊断 ===")
+	fmt.Fprintln(w)
+
+	// 1. 检查hwmon目录
+	fmt.Fprintln(w, "1. 检查hwmon目录:")
+	switch d.Status {
+	case StatusNoHWMon:
+		fmt.Fprintf(w, "   ✗ hwmon目录不存在: %s\n", d.HWMonPath)
+		fmt.Fprintln(w, "   建议: 确保内核支持hwmon，或加载相关内核模块")
+		return nil
+	case StatusError:
+		fmt.Fprintf(w, "   ✓ hwmon目录存在\n")
+		fmt.Fprintf(w, "   ✗ %s\n", d.Error)
+		return nil
+	}
+	fmt.Fprintf(w, "   ✓ hwmon目录存在\n")
+
+	if d.Status == StatusEmpty {
+		fmt.Fprintln(w, "   ✗ hwmon目录为空")
+		fmt.Fprintln(w, "   建议: 加载内核模块")
+		fmt.Fprintln(w, "   - Intel CPU: sudo modprobe coretemp")
+		fmt.Fprintln(w, "   - AMD CPU: sudo modprobe k10temp")
+		return nil
+	}
+
+	fmt.Fprintf(w, "   ✓ 找到 %d 个hwmon设备\n", d.Summary.Chips)
+	fmt.Fprintln(w)
 
-		if deviceSensorCount == 0 {
-			fmt.Printf("     ✗ 未找到温度传感器\n")
+	// 2. 检查每个设备
+	fmt.Fprintln(w, "2. 检查每个hwmon设备:")
+	for i, chip := range d.Chips {
+		fmt.Fprintf(w, "   设备 %d: %s\n", i+1, chip.ID)
+		fmt.Fprintf(w, "     名称: %s\n", chipName(chip))
+		if chip.Device != "" {
+			fmt.Fprintf(w, "     设备路径: %s\n", chip.Device)
 		}
 
-		// 检查PWM风扇
-		devicePWMCount := 0
-		for i := 1; i <= 10; i++ {
-			pwmPath := filepath.Join(deviceDir, fmt.Sprintf("pwm%d", i))
-			if _, err := os.Stat(pwmPath); err == nil {
-				devicePWMCount++
-				pwmCount++
-
-				// 读取PWM值
-				data, err := os.ReadFile(pwmPath)
-				pwm := "N/A"
-				if err == nil {
-					pwmRaw := strings.TrimSpace(string(data))
-					if pwmVal, err := parseInt(pwmRaw); err == nil {
-						pwm = fmt.Sprintf("%d", pwmVal)
-					}
-				}
-
-				// 读取标签
-				labelPath := filepath.Join(deviceDir, fmt.Sprintf("fan%d_label", i))
-				label := ""
-				if data, err := os.ReadFile(labelPath); err == nil {
-					label = strings.TrimSpace(string(data))
-				}
-
-				fmt.Printf("     ✓ PWM%d: %s (%s) [PWM=%s]\n", i, pwmPath, label, pwm)
-			}
+		for _, t := range chip.Temps {
+			fmt.Fprintf(w, "     ✓ 温度%s: %s (%s%s) [%s]%s\n",
+				strings.TrimPrefix(t.Channel, "temp"), t.Path, t.Label, cpuMark(t), formatTemp(t), tempLimits(t))
+		}
+		if len(chip.Temps) == 0 {
+			fmt.Fprintf(w, "     ✗ 未找到温度传感器\n")
 		}
 
-		if devicePWMCount == 0 {
-			fmt.Printf("     ✗ 未找到PWM风扇\n")
+		for _, p := range chip.PWMs {
+			fmt.Fprintf(w, "     ✓ PWM%s: %s (%s) [PWM=%s]%s\n",
+				strings.TrimPrefix(p.Channel, "pwm"), p.Path, p.Label, formatInt(p.Value), pwmAttrs(chip, p))
+		}
+		if len(chip.PWMs) == 0 {
+			fmt.Fprintf(w, "     ✗ 未找到PWM风扇\n")
 		}
 
-		fmt.Println()
+		fmt.Fprintln(w)
 	}
 
-	// 4. 总结
-	fmt.Println("3. 总结:")
-	fmt.Printf("   设备数量: %d\n", deviceCount)
-	fmt.Printf("   温度传感器: %d\n", sensorCount)
-	fmt.Printf("   PWM风扇: %d\n", pwmCount)
-	fmt.Println()
-
-	// 5. 建议
-	fmt.Println("4. 建议:")
-	if sensorCount == 0 && pwmCount == 0 {
-		fmt.Println("   ✗ 未找到任何温度传感器或PWM风扇")
-		fmt.Println("   可能原因:")
-		fmt.Println("   1. 内核模块未加载")
-		fmt.Println("      - Intel CPU: sudo modprobe coretemp")
-		fmt.Println("      - AMD CPU: sudo modprobe k10temp")
-		fmt.Println("   2. 设备需要特定的内核驱动程序")
-		fmt.Println("   3. BIOS中未启用硬件监控")
-	} else if sensorCount > 0 && pwmCount == 0 {
-		fmt.Println("   ⚠ 找到温度传感器但未找到PWM风扇")
-		fmt.Println("   可能原因:")
-		fmt.Println("   1. 系统使用4针风扇（无PWM控制）")
-		fmt.Println("   2. 风扇由BIOS或主板独立控制")
-		fmt.Println("   3. 需要特定的驱动程序")
-		fmt.Println()
-		fmt.Println("   建议命令:")
-		fmt.Println("   sudo fanap -sensor <温度传感器路径> -pwm auto -verbose")
-	} else if sensorCount == 0 && pwmCount > 0 {
-		fmt.Println("   ⚠ 找到PWM风扇但未找到温度传感器")
-		fmt.Println("   可能原因:")
-		fmt.Println("   1. 温度传感器在其他位置")
-		fmt.Println("   2. 需要加载温度传感器驱动")
-		fmt.Println()
-		fmt.Println("   建议命令:")
-		fmt.Println("   sudo fanap -sensor auto -pwm <PWM路径> -verbose")
-	} else {
-		fmt.Println("   ✓ 系统配置正常，可以使用自动检测模式")
-		fmt.Println()
-		fmt.Println("   建议命令:")
-		fmt.Println("   sudo fanap -verbose")
+	// 3. 总结
+	fmt.Fprintln(w, "3. 总结:")
+	fmt.Fprintf(w, "   设备数量: %d\n", d.Summary.Chips)
+	fmt.Fprintf(w, "   温度传感器: %d\n", d.Summary.Temps)
+	fmt.Fprintf(w, "   PWM风扇: %d\n", d.Summary.PWMs)
+	fmt.Fprintln(w)
+
+	// 4. 建议
+	fmt.Fprintln(w, "4. 建议:")
+	switch d.Status {
+	case StatusNoDevices:
+		fmt.Fprintln(w, "   ✗ 未找到任何温度传感器或PWM风扇")
+		fmt.Fprintln(w, "   可能原因:")
+		fmt.Fprintln(w, "   1. 内核模块未加载")
+		fmt.Fprintln(w, "      - Intel CPU: sudo modprobe coretemp")
+		fmt.Fprintln(w, "      - AMD CPU: sudo modprobe k10temp")
+		fmt.Fprintln(w, "   2. 设备需要特定的内核驱动程序")
+		fmt.Fprintln(w, "   3. BIOS中未启用硬件监控")
+	case StatusNoPWM:
+		fmt.Fprintln(w, "   ⚠ 找到温度传感器但未找到PWM风扇")
+		fmt.Fprintln(w, "   可能原因:")
+		fmt.Fprintln(w, "   1. 系统使用4针风扇（无PWM控制）")
+		fmt.Fprintln(w, "   2. 风扇由BIOS或主板独立控制")
+		fmt.Fprintln(w, "   3. 需要特定的驱动程序")
+	case StatusNoSensor:
+		fmt.Fprintln(w, "   ⚠ 找到PWM风扇但未找到温度传感器")
+		fmt.Fprintln(w, "   可能原因:")
+		fmt.Fprintln(w, "   1. 温度传感器在其他位置")
+		fmt.Fprintln(w, "   2. 需要加载温度传感器驱动")
+	default:
+		if d.Sensor != "" && d.PWM != "" {
+			fmt.Fprintln(w, "   ✓ 系统配置正常，可以使用自动检测模式")
+		} else {
+			fmt.Fprintln(w, "   ⚠ 自动检测无法识别温度传感器或PWM风扇，需要手动指定")
+		}
 	}
+	if d.Command != "" {
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "   建议命令:")
+		fmt.Fprintf(w, "   %s\n", d.Command)
+	}
+	fmt.Fprintln(w)
 
-	fmt.Println()
-
-	// 6. 检查权限
-	fmt.Println("5. 权限检查:")
-	uid := os.Getuid()
-	if uid == 0 {
-		fmt.Println("   ✓ 当前用户: root")
+	// 5. 检查权限
+	fmt.Fprintln(w, "5. 权限检查:")
+	if d.Root {
+		fmt.Fprintln(w, "   ✓ 当前用户: root")
 	} else {
-		fmt.Printf("   ✗ 当前用户: UID=%d (非root)\n", uid)
-		fmt.Println("   建议使用 sudo 运行程序")
+		fmt.Fprintf(w, "   ✗ 当前用户: UID=%d (非root)\n", d.UID)
+		fmt.Fprintln(w, "   建议使用 sudo 运行程序")
 	}
+	return nil
 }
 
-// ListHWMon 列出所有可用的硬件监控设备
-func ListHWMon() {
-	hwmonPath := "/sys/class/hwmon"
-
-	if _, err := os.Stat(hwmonPath); err != nil {
-		fmt.Printf("错误: hwmon目录不存在: %s\n", hwmonPath)
-		fmt.Println("\n可能的原因:")
-		fmt.Println("1. 需要root权限运行此程序")
-		fmt.Println("2. hwmon硬件监控接口不支持")
-		os.Exit(1)
+// WriteTable 以文本形式输出检测结果
+func (inv *Inventory) WriteTable(w io.Writer) error {
+	fmt.Fprint(w, "=== 可用的硬件监控设备 ===\n\n")
+
+	if len(inv.Chips) == 0 {
+		fmt.Fprintln(w, "警告: hwmon目录为空")
+		fmt.Fprintln(w, "\n建议:")
+		fmt.Fprintln(w, "1. 确保使用root权限运行")
+		fmt.Fprintln(w, "2. 加载内核模块:")
+		fmt.Fprintln(w, "   - Intel CPU: sudo modprobe coretemp")
+		fmt.Fprintln(w, "   - AMD CPU: sudo modprobe k10temp")
+		fmt.Fprintln(w, "3. 检查BIOS设置")
+		return nil
 	}
 
-	entries, err := os.ReadDir(hwmonPath)
-	if err != nil {
-		fmt.Printf("错误: 读取hwmon目录失败: %v\n", err)
-		os.Exit(1)
-	}
-
-	fmt.Println("=== 可用的硬件监控设备 ===\n")
-
-	if len(entries) == 0 {
-		fmt.Println("警告: hwmon目录为空")
-		fmt.Println("\n建议:")
-		fmt.Println("1. 确保使用root权限运行")
-		fmt.Println("2. 加载内核模块:")
-		fmt.Println("   - Intel CPU: sudo modprobe coretemp")
-		fmt.Println("   - AMD CPU: sudo modprobe k10temp")
-		fmt.Println("3. 检查BIOS设置")
-		return
-	}
-
-	fmt.Printf("找到 %d 个hwmon设备\n\n", len(entries))
-
-	sensorCount := 0
-	pwmCount := 0
-
-	for _, entry := range entries {
-		// hwmon设备可能是符号链接，需要检查实际路径
-		fullPath := filepath.Join(hwmonPath, entry.Name())
-
-		// 检查是否为目录（包括符号链接指向的目录）
-		info, err := os.Lstat(fullPath)
-		if err != nil {
-			continue
-		}
-
-		// 如果是符号链接，获取目标信息
-		if info.Mode()&os.ModeSymlink != 0 {
-			target, err := os.Readlink(fullPath)
-			if err != nil {
-				continue
-			}
-			// 解析相对路径
-			if !filepath.IsAbs(target) {
-				fullPath = filepath.Join(hwmonPath, target)
-			} else {
-				fullPath = target
-			}
-		}
+	fmt.Fprintf(w, "找到 %d 个hwmon设备\n\n", len(inv.Chips))
 
-		devicePath := filepath.Join(fullPath, "name")
-		deviceName := "unknown"
-		if data, err := os.ReadFile(devicePath); err == nil {
-			deviceName = strings.TrimSpace(string(data))
-		} else {
-			// name文件可能不存在，这很正常
-			deviceName = "unknown"
+	for _, chip := range inv.Chips {
+		fmt.Fprintf(w, "设备: %s\n", chip.ID)
+		fmt.Fprintf(w, "  名称: %s\n", chipName(chip))
+		if chip.Device != "" {
+			fmt.Fprintf(w, "  设备路径: %s\n", chip.Device)
 		}
 
-		fmt.Printf("设备: %s\n", entry.Name())
-		fmt.Printf("  名称: %s\n", deviceName)
-
 		// 列出温度传感器
-		for i := 1; i <= 10; i++ {
-			tempInput := filepath.Join(fullPath, fmt.Sprintf("temp%d_input", i))
-			if _, err := os.Stat(tempInput); err == nil {
-				labelPath := filepath.Join(fullPath, fmt.Sprintf("temp%d_label", i))
-				label := ""
-				if data, err := os.ReadFile(labelPath); err == nil {
-					label = strings.TrimSpace(string(data))
-				}
-
-				// 读取当前温度
-				data, err := os.ReadFile(tempInput)
-				temp := "N/A"
-				if err == nil {
-					tempRaw := strings.TrimSpace(string(data))
-					if tempVal, err := parseTemp(tempRaw); err == nil {
-						temp = fmt.Sprintf("%.1f°C", tempVal)
-					}
-				}
-
-				// 判断是否为CPU传感器
-				isCPU := isCPUSensor(deviceName, label)
-				cpuMark := ""
-				if isCPU {
-					cpuMark = " [CPU]"
-				}
-
-				fmt.Printf("  温度%d: %s (%s%s) [%s]\n", i, tempInput, label, cpuMark, temp)
-				sensorCount++
-			}
+		for _, t := range chip.Temps {
+			fmt.Fprintf(w, "  温度%s: %s (%s%s) [%s]%s\n",
+				strings.TrimPrefix(t.Channel, "temp"), t.Path, t.Label, cpuMark(t), formatTemp(t), tempLimits(t))
+			fmt.Fprintf(w, "    选择器: %s\n", t.Selector)
 		}
 
 		// 列出PWM风扇
-		for i := 1; i <= 10; i++ {
-			pwmPath := filepath.Join(fullPath, fmt.Sprintf("pwm%d", i))
-			if _, err := os.Stat(pwmPath); err == nil {
-				labelPath := filepath.Join(fullPath, fmt.Sprintf("fan%d_label", i))
-				label := ""
-				if data, err := os.ReadFile(labelPath); err == nil {
-					label = strings.TrimSpace(string(data))
-				}
-
-				// 读取当前PWM值
-				data, err := os.ReadFile(pwmPath)
-				pwm := "N/A"
-				if err == nil {
-					pwmRaw := strings.TrimSpace(string(data))
-					if pwmVal, err := parseInt(pwmRaw); err == nil {
-						pwm = fmt.Sprintf("%d", pwmVal)
-					}
-				}
-
-				// 判断是否为风扇设备
-				isFan := isFanDevice(deviceName)
-				fanMark := ""
-				if isFan {
-					fanMark = " [风扇]"
-				}
-
-				fmt.Printf("  风扇%d: %s (%s%s) [PWM=%s]\n", i, pwmPath, label, fanMark, pwm)
-				pwmCount++
+		fanMark := ""
+		if chip.FanDevice {
+			fanMark = " [风扇]"
+		}
+		for _, p := range chip.PWMs {
+			fmt.Fprintf(w, "  风扇%s: %s (%s%s) [PWM=%s]%s\n",
+				strings.TrimPrefix(p.Channel, "pwm"), p.Path, p.Label, fanMark, formatInt(p.Value), pwmAttrs(chip, p))
+			fmt.Fprintf(w, "    选择器: %s\n", p.Selector)
+			if p.DeviceSelector != "" {
+				fmt.Fprintf(w, "            %s\n", p.DeviceSelector)
 			}
 		}
 
-		fmt.Println()
+		fmt.Fprintln(w)
 	}
 
-	if sensorCount == 0 && pwmCount == 0 {
-		fmt.Println("警告: 未找到任何温度传感器或PWM风扇")
-		fmt.Println("\n可能的原因:")
-		fmt.Println("1. 需要root权限运行此程序")
-		fmt.Println("2. 内核模块未加载")
-		fmt.Println("   - Intel CPU: sudo modprobe coretemp")
-		fmt.Println("   - AMD CPU: sudo modprobe k10temp")
-		fmt.Println("3. 系统不支持hwmon硬件监控")
-		fmt.Println("4. 设备需要特定的内核驱动程序")
+	if inv.Summary.Temps == 0 && inv.Summary.PWMs == 0 {
+		fmt.Fprintln(w, "警告: 未找到任何温度传感器或PWM风扇")
+		fmt.Fprintln(w, "\n可能的原因:")
+		fmt.Fprintln(w, "1. 需要root权限运行此程序")
+		fmt.Fprintln(w, "2. 内核模块未加载")
+		fmt.Fprintln(w, "   - Intel CPU: sudo modprobe coretemp")
+		fmt.Fprintln(w, "   - AMD CPU: sudo modprobe k10temp")
+		fmt.Fprintln(w, "3. 系统不支持hwmon硬件监控")
+		fmt.Fprintln(w, "4. 设备需要特定的内核驱动程序")
+		return nil
+	}
+
+	fmt.Fprintf(w, "共找到 %d 个温度传感器，%d 个PWM风扇\n", inv.Summary.Temps, inv.Summary.PWMs)
+	fmt.Fprintln(w, "\n使用方法:")
+	if inv.Summary.Temps > 0 {
+		fmt.Fprintln(w, "  # 使用自动检测（推荐）")
+		fmt.Fprintln(w, "  sudo fanap -verbose")
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "  # 手动指定传感器")
+		fmt.Fprintln(w, "  sudo fanap -sensor <传感器选择器> -pwm <风扇选择器> -verbose")
+		fmt.Fprintln(w)
 	} else {
-		fmt.Printf("共找到 %d 个温度传感器，%d 个PWM风扇\n", sensorCount, pwmCount)
-		fmt.Println("\n使用方法:")
-		if sensorCount > 0 {
-			fmt.Println("  # 使用自动检测（推荐）")
-			fmt.Println("  sudo fanap -verbose")
-			fmt.Println()
-			fmt.Println("  # 手动指定传感器")
-			fmt.Println("  sudo fanap -sensor <传感器路径> -pwm <风扇路径> -verbose")
-			fmt.Println()
-		} else if pwmCount > 0 {
-			fmt.Println("  找到PWM风扇但未找到温度传感器")
-			fmt.Println("  手动指定传感器:")
-			fmt.Println("  sudo fanap -sensor <传感器路径> -pwm <风扇路径> -verbose")
-			fmt.Println()
-		}
-		fmt.Println("示例:")
-		if sensorCount > 0 && pwmCount > 0 {
-			fmt.Println("  sudo fanap -sensor /sys/class/hwmon/hwmon0/temp1_input \\")
-			fmt.Println("              -pwm /sys/class/hwmon/hwmon0/pwm1 \\")
-			fmt.Println("              -verbose")
-		} else if sensorCount > 0 {
-			fmt.Println("  sudo fanap -sensor /sys/class/hwmon/hwmon0/temp1_input \\")
-			fmt.Println("              -pwm auto \\")
-			fmt.Println("              -verbose")
-		}
+		fmt.Fprintln(w, "  找到PWM风扇但未找到温度传感器")
+		fmt.Fprintln(w, "  手动指定传感器:")
+		fmt.Fprintln(w, "  sudo fanap -sensor <传感器选择器> -pwm <风扇选择器> -verbose")
+		fmt.Fprintln(w)
+	}
+	if inv.Summary.Temps > 0 {
+		sensor, pwm := inv.autoSelectors()
+		fmt.Fprintln(w, "示例:")
+		fmt.Fprintf(w, "  sudo fanap -sensor %s \\\n", shellQuote(firstNonEmpty(sensor, inv.firstTemp())))
+		fmt.Fprintf(w, "              -pwm %s \\\n", shellQuote(firstNonEmpty(pwm, inv.firstPWM(), "auto")))
+		fmt.Fprintln(w, "              -verbose")
 	}
+	return nil
 }
 
-// isCPUSensor 判断是否为CPU温度传感器
-func isCPUSensor(deviceName, label string) bool {
-	// 检查设备名称
-	cpuDevicePatterns := []string{
-		`(?i)cpu`,
-		`(?i)coretemp`,
-		`(?i)k10temp`,   // AMD处理器
-		`(?i)nct6775`,   // 常见主板传感器
-		`(?i)asus`,      // ASUS主板
-		`(?i)it87`,      // IT87芯片
-		`(?i)acpi`,      // ACPI接口
+// chipName 返回芯片名称，没有name文件时为 "unknown"
+func chipName(chip Chip) string {
+	if chip.Name == "" {
+		return "unknown"
 	}
+	return chip.Name
+}
 
-	for _, pattern := range cpuDevicePatterns {
-		if regexp.MustCompile(pattern).MatchString(deviceName) {
-			return true
-		}
+// cpuMark 标记CPU温度传感器
+func cpuMark(t Temp) string {
+	if t.CPU {
+		return " [CPU]"
 	}
+	return ""
+}
 
-	// 检查标签
-	cpuLabelPatterns := []string{
-		`(?i)cpu.*temp`,
-		`(?i)core.*temp`,
-		`(?i)package.*id`,
-		`(?i)tccd`,
+// formatTemp 格式化当前温度
+func formatTemp(t Temp) string {
+	if t.Temp == nil {
+		return "N/A"
 	}
+	return fmt.Sprintf("%.1f°C", *t.Temp)
+}
 
-	for _, pattern := range cpuLabelPatterns {
-		if regexp.MustCompile(pattern).MatchString(label) {
-			return true
-		}
+// formatInt 格式化可选的整数值
+func formatInt(v *int) string {
+	if v == nil {
+		return "N/A"
 	}
-
-	return false
+	return fmt.Sprint(*v)
 }
 
-// isFanDevice 判断是否为风扇设备
-func isFanDevice(deviceName string) bool {
-	fanPatterns := []string{
-		`(?i)fan`,
-		`(?i)pwm`,
-		`(?i)asus`,
-		`(?i)nct6775`,
-		`(?i)it87`,
+// tempLimits 格式化温度通道的阈值
+func tempLimits(t Temp) string {
+	var parts []string
+	for _, l := range []struct {
+		name string
+		v    *float64
+	}{{"min", t.Min}, {"max", t.Max}, {"crit", t.Crit}} {
+		if l.v != nil {
+			parts = append(parts, fmt.Sprintf("%s=%.1f°C", l.name, *l.v))
+		}
+	}
+	if len(parts) == 0 {
+		return ""
 	}
+	return " " + strings.Join(parts, " ")
+}
 
-	for _, pattern := range fanPatterns {
-		if regexp.MustCompile(pattern).MatchString(deviceName) {
-			return true
+// pwmAttrs 格式化PWM通道的控制模式和对应风扇的转速
+func pwmAttrs(chip Chip, p PWM) string {
+	var parts []string
+	if p.Enable != nil {
+		parts = append(parts, fmt.Sprintf("enable=%d", *p.Enable))
+	}
+	if p.Mode != nil {
+		parts = append(parts, fmt.Sprintf("mode=%d", *p.Mode))
+	}
+	if p.RPM != nil {
+		parts = append(parts, fmt.Sprintf("%d RPM", *p.RPM))
+	}
+	for _, f := range chip.Fans {
+		if f.Path == p.FanInput && f.Min != nil {
+			parts = append(parts, fmt.Sprintf("min=%d RPM", *f.Min))
 		}
 	}
-	return false
+	if len(parts) == 0 {
+		return ""
+	}
+	return " " + strings.Join(parts, " ")
 }
 
-func parseTemp(s string) (float64, error) {
-	val := strings.TrimSpace(s)
-	var temp int
-	_, err := fmt.Sscanf(val, "%d", &temp)
-	if err != nil {
-		return 0, err
+// firstNonEmpty 返回第一个非空字符串
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
 	}
-	return float64(temp) / 1000.0, nil
+	return ""
 }
 
-func parseInt(s string) (int, error) {
-	var val int
-	_, err := fmt.Sscanf(s, "%d", &val)
-	return val, err
+// shellQuote 为包含空格等特殊字符的参数加单引号
+func shellQuote(s string) string {
+	if s == "" || strings.ContainsAny(s, " \t'\"$`\\*?[]()&;|<>") {
+		if strings.HasPrefix(s, "<") && strings.HasSuffix(s, ">") {
+			// 占位符保持原样
+			return s
+		}
+		return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+	}
+	return s
 }
@@ -0,0 +1,194 @@
+package tools
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/fanap/pkg/hwmon"
+	"github.com/fanap/pkg/sysfs"
+)
+
+// Inventory -list 的检测结果
+type Inventory struct {
+	// HWMonPath hwmon设备类目录
+	HWMonPath string `json:"hwmon_path"`
+	// Chips 所有hwmon芯片
+	Chips []Chip `json:"chips"`
+	// Summary 统计
+	Summary Summary `json:"summary"`
+}
+
+// Summary 检测到的设备数量
+type Summary struct {
+	Chips int `json:"chips"`
+	Temps int `json:"temps"`
+	Fans  int `json:"fans"`
+	PWMs  int `json:"pwms"`
+}
+
+// Chip 一个hwmon芯片及其通道
+type Chip struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Path   string `json:"path"`
+	Device string `json:"device,omitempty"`
+	// FanDevice 是否为自动检测时会选用的风扇控制芯片
+	FanDevice bool   `json:"fan_device"`
+	Temps     []Temp `json:"temps"`
+	Fans      []Fan  `json:"fans"`
+	PWMs      []PWM  `json:"pwms"`
+}
+
+// Temp 温度通道
+type Temp struct {
+	Channel string `json:"channel"`
+	Label   string `json:"label,omitempty"`
+	Path    string `json:"path"`
+	// Selector 不依赖hwmonN编号的传感器选择器，可直接用于 -sensor 或配置文件
+	Selector string `json:"selector"`
+	// CPU 是否为自动检测时会选用的CPU温度传感器
+	CPU bool `json:"cpu"`
+	// Temp 当前温度（摄氏度），读取失败时为空
+	Temp *float64 `json:"temp,omitempty"`
+	Min  *float64 `json:"min,omitempty"`
+	Max  *float64 `json:"max,omitempty"`
+	Crit *float64 `json:"crit,omitempty"`
+}
+
+// Fan 风扇转速通道
+type Fan struct {
+	Channel string `json:"channel"`
+	Label   string `json:"label,omitempty"`
+	Path    string `json:"path"`
+	// RPM 当前转速，读取失败时为空
+	RPM *int `json:"rpm,omitempty"`
+	Min *int `json:"min,omitempty"`
+	Max *int `json:"max,omitempty"`
+}
+
+// PWM PWM输出通道
+type PWM struct {
+	Channel string `json:"channel"`
+	Label   string `json:"label,omitempty"`
+	Path    string `json:"path"`
+	// Selector 按芯片名称的风扇选择器，可直接用于 -pwm 或配置文件
+	Selector string `json:"selector"`
+	// DeviceSelector 按设备路径的风扇选择器，同型号芯片有多个时使用，虚拟设备为空
+	DeviceSelector string `json:"device_selector,omitempty"`
+	// Value 当前PWM值，读取失败时为空
+	Value  *int `json:"value,omitempty"`
+	Enable *int `json:"enable,omitempty"`
+	Mode   *int `json:"mode,omitempty"`
+	// FanInput 同编号的转速文件
+	FanInput string `json:"fan_input,omitempty"`
+	// RPM 同编号风扇的当前转速
+	RPM *int `json:"rpm,omitempty"`
+}
+
+// ListHWMon 枚举所有hwmon设备及其温度、转速和PWM通道
+func ListHWMon() (*Inventory, error) {
+	hwmonPath := sysfs.HWMonPath()
+	if _, err := os.Stat(hwmonPath); err != nil {
+		return nil, fmt.Errorf("hwmon目录不存在: %s", hwmonPath)
+	}
+
+	chips, err := hwmon.Scan()
+	if err != nil {
+		return nil, err
+	}
+
+	inv := &Inventory{HWMonPath: hwmonPath, Chips: []Chip{}}
+	for _, c := range chips {
+		chip := newChip(c)
+		inv.Summary.Chips++
+		inv.Summary.Temps += len(chip.Temps)
+		inv.Summary.Fans += len(chip.Fans)
+		inv.Summary.PWMs += len(chip.PWMs)
+		inv.Chips = append(inv.Chips, chip)
+	}
+	return inv, nil
+}
+
+// newChip 读取芯片各通道的当前值并生成选择器
+func newChip(c hwmon.Chip) Chip {
+	chip := Chip{
+		ID:        c.ID,
+		Name:      c.Name,
+		Path:      c.Path,
+		Device:    c.Device,
+		FanDevice: hwmon.IsFanDevice(c.Name),
+		Temps:     []Temp{},
+		Fans:      []Fan{},
+		PWMs:      []PWM{},
+	}
+
+	for _, t := range c.Temps {
+		temp := Temp{
+			Channel:  t.Name(),
+			Label:    t.Label,
+			Path:     t.Input,
+			Selector: tempSelector(c, t),
+			CPU:      hwmon.IsCPUSensor(c.Name, t.Label),
+			Min:      t.Min,
+			Max:      t.Max,
+			Crit:     t.Crit,
+		}
+		if v, err := t.Read(); err == nil {
+			temp.Temp = &v
+		}
+		chip.Temps = append(chip.Temps, temp)
+	}
+
+	for _, f := range c.Fans {
+		fan := Fan{Channel: f.Name(), Label: f.Label, Path: f.Input, Min: f.Min, Max: f.Max}
+		if v, err := f.Read(); err == nil {
+			fan.RPM = &v
+		}
+		chip.Fans = append(chip.Fans, fan)
+	}
+
+	for _, p := range c.PWMs {
+		pwm := PWM{
+			Channel:  p.Name(),
+			Label:    p.Label,
+			Path:     p.Path,
+			Selector: pwmSelector(c, p),
+			Enable:   p.Enable,
+			Mode:     p.Mode,
+			FanInput: p.FanInput,
+		}
+		if c.Device != "" {
+			pwm.DeviceSelector = "device:" + c.Device + "/" + p.Name()
+		}
+		if v, err := p.Read(); err == nil {
+			pwm.Value = &v
+		}
+		if f, ok := c.Fan(p.Index); ok {
+			if v, err := f.Read(); err == nil {
+				pwm.RPM = &v
+			}
+		}
+		chip.PWMs = append(chip.PWMs, pwm)
+	}
+
+	return chip
+}
+
+// tempSelector 返回温度通道的选择器，芯片没有名称时使用路径
+func tempSelector(c hwmon.Chip, t hwmon.Temp) string {
+	if c.Name == "" {
+		return t.Input
+	}
+	if t.Label != "" {
+		return "hwmon:" + c.Name + "/" + t.Label
+	}
+	return "hwmon:" + c.Name + "/" + t.Name()
+}
+
+// pwmSelector 返回PWM通道的选择器，芯片没有名称时使用路径
+func pwmSelector(c hwmon.Chip, p hwmon.PWM) string {
+	if c.Name == "" {
+		return p.Path
+	}
+	return "hwmon:" + c.Name + "/" + p.Name()
+}
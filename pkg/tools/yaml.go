@@ -0,0 +1,162 @@
+package tools
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// writeYAML 将结构体以YAML格式写出
+// 只支持检测结果用到的类型（结构体、切片、指针、字符串、布尔值和数字），
+// 字段名和omitempty取自json标签，保证JSON和YAML输出的字段一致
+func writeYAML(w io.Writer, v any) error {
+	var b strings.Builder
+	yamlValue(&b, reflect.ValueOf(v), 0, false)
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// yamlValue 写出一个值；inline为true时值紧跟在 "key:" 或 "- " 之后
+func yamlValue(b *strings.Builder, v reflect.Value, indent int, inline bool) {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			b.WriteString(" null\n")
+			return
+		}
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		fields := yamlFields(v)
+		if len(fields) == 0 {
+			b.WriteString(" {}\n")
+			return
+		}
+		if inline {
+			b.WriteString("\n")
+		}
+		for _, f := range fields {
+			writeIndent(b, indent)
+			b.WriteString(f.name + ":")
+			yamlValue(b, f.value, indent+1, true)
+		}
+	case reflect.Slice, reflect.Array:
+		if v.Len() == 0 {
+			b.WriteString(" []\n")
+			return
+		}
+		if inline {
+			b.WriteString("\n")
+		}
+		for i := 0; i < v.Len(); i++ {
+			writeIndent(b, indent)
+			b.WriteString("-")
+			yamlItem(b, v.Index(i), indent+1)
+		}
+	default:
+		b.WriteString(" " + yamlScalar(v) + "\n")
+	}
+}
+
+// yamlItem 写出列表项，结构体的第一个字段与 "- " 写在同一行
+func yamlItem(b *strings.Builder, v reflect.Value, indent int) {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			b.WriteString(" null\n")
+			return
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		yamlValue(b, v, indent, true)
+		return
+	}
+
+	fields := yamlFields(v)
+	if len(fields) == 0 {
+		b.WriteString(" {}\n")
+		return
+	}
+	for i, f := range fields {
+		if i == 0 {
+			b.WriteString(" ")
+		} else {
+			writeIndent(b, indent)
+		}
+		b.WriteString(f.name + ":")
+		yamlValue(b, f.value, indent+1, true)
+	}
+}
+
+type yamlField struct {
+	name  string
+	value reflect.Value
+}
+
+// yamlFields 返回结构体需要输出的字段
+func yamlFields(v reflect.Value) []yamlField {
+	var fields []yamlField
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		name, opts, _ := strings.Cut(sf.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = sf.Name
+		}
+		fv := v.Field(i)
+		if strings.Contains(opts, "omitempty") && fv.IsZero() {
+			continue
+		}
+		if fv.Kind() == reflect.Slice && strings.Contains(opts, "omitempty") && fv.Len() == 0 {
+			continue
+		}
+		fields = append(fields, yamlField{name: name, value: fv})
+	}
+	return fields
+}
+
+// yamlScalar 格式化标量，必要时加引号
+func yamlScalar(v reflect.Value) string {
+	switch v.Kind() {
+	case reflect.String:
+		return yamlString(v.String())
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', -1, 64)
+	}
+	return yamlString(fmt.Sprint(v.Interface()))
+}
+
+// yamlString 字符串可能被解析为其他类型或包含特殊字符时使用双引号
+func yamlString(s string) string {
+	if s == "" || s != strings.TrimSpace(s) || strings.ContainsAny(s, ":#{}[],&*!|>'\"%@`\n\t\\") ||
+		strings.ContainsAny(s[:1], "-?") {
+		return strconv.Quote(s)
+	}
+	switch strings.ToLower(s) {
+	case "true", "false", "yes", "no", "on", "off", "null", "~":
+		return strconv.Quote(s)
+	}
+	if _, err := strconv.ParseFloat(s, 64); err == nil {
+		return strconv.Quote(s)
+	}
+	return s
+}
+
+func writeIndent(b *strings.Builder, n int) {
+	b.WriteString(strings.Repeat("  ", n))
+}
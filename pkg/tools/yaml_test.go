@@ -0,0 +1,35 @@
+package tools
+
+import "testing"
+
+func TestYAMLString(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"nct6775", "nct6775"},
+		{"Package id 0", "Package id 0"},
+		{"/sys/class/hwmon/hwmon0", "/sys/class/hwmon/hwmon0"},
+		// 会被解析为其他类型的字符串
+		{"on", `"on"`},
+		{"Off", `"Off"`},
+		{"null", `"null"`},
+		{"~", `"~"`},
+		{"1.0", `"1.0"`},
+		{"42", `"42"`},
+		// 特殊字符
+		{"", `""`},
+		{"-12V", `"-12V"`},
+		{"?", `"?"`},
+		{"CPU: Package", `"CPU: Package"`},
+		{"fan #1", `"fan #1"`},
+		{" padded", `" padded"`},
+		{`a"b`, `"a\"b"`},
+		{"a\nb", `"a\nb"`},
+	}
+	for _, tt := range tests {
+		if got := yamlString(tt.in); got != tt.want {
+			t.Errorf("yamlString(%q) = %s, 期望 %s", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+// Package virtual 由多个温度传感器组合而成的虚拟传感器
+//
+// 组合方式：
+//
+//	max       所有输入中的最高温度，如所有CPU核心、所有硬盘中最热的一个
+//	avg       所有输入的平均温度
+//	weighted  所有输入的加权平均
+//	delta     第一个输入减去第二个输入，如CPU温度高出进风温度的部分
+//
+// 虚拟传感器实现与普通传感器相同的接口，可以用在任何接受温度传感器的地方，也可以作为其他虚拟传感器的输入。
+// 任一输入读取失败时虚拟传感器读取失败，由控制器按传感器失效处理
+package virtual
+
+import (
+	"fmt"
+	"strings"
+)
+
+// 组合方式
+const (
+	OpMax      = "max"
+	OpAvg      = "avg"
+	OpWeighted = "weighted"
+	OpDelta    = "delta"
+)
+
+// Source 温度来源
+type Source interface {
+	GetTemperature() (float64, error)
+	Close() error
+}
+
+// Input 虚拟传感器的一个输入
+type Input struct {
+	// Name 输入的名称，用于错误信息
+	Name string
+	// Source 温度来源，由虚拟传感器负责关闭
+	Source Source
+	// Weight 加权平均时的权重，0表示1
+	Weight float64
+}
+
+// Sensor 虚拟传感器
+type Sensor struct {
+	op     string
+	inputs []Input
+}
+
+// ValidateOp 验证组合方式和输入数量
+func ValidateOp(op string, inputs int) error {
+	switch op {
+	case OpMax, OpAvg, OpWeighted:
+		if inputs < 1 {
+			return fmt.Errorf("虚拟传感器至少需要一个输入")
+		}
+	case OpDelta:
+		if inputs != 2 {
+			return fmt.Errorf("delta需要两个输入（被减数和减数）")
+		}
+	default:
+		return fmt.Errorf("未知的组合方式 %q（可选: max、avg、weighted、delta）", op)
+	}
+	return nil
+}
+
+// New 创建虚拟传感器，出错时不关闭输入
+func New(op string, inputs []Input) (*Sensor, error) {
+	if err := ValidateOp(op, len(inputs)); err != nil {
+		return nil, err
+	}
+
+	s := &Sensor{op: op, inputs: make([]Input, len(inputs))}
+	for i, in := range inputs {
+		if in.Weight < 0 {
+			return nil, fmt.Errorf("输入 %s 的权重不能为负数", in.Name)
+		}
+		if in.Weight == 0 {
+			in.Weight = 1
+		}
+		s.inputs[i] = in
+	}
+	return s, nil
+}
+
+// GetTemperature 读取所有输入并计算温度
+func (s *Sensor) GetTemperature() (float64, error) {
+	temps := make([]float64, len(s.inputs))
+	for i, in := range s.inputs {
+		t, err := in.Source.GetTemperature()
+		if err != nil {
+			return 0, fmt.Errorf("读取 %s 失败: %w", in.Name, err)
+		}
+		temps[i] = t
+	}
+
+	switch s.op {
+	case OpDelta:
+		return temps[0] - temps[1], nil
+	case OpMax:
+		result := temps[0]
+		for _, t := range temps[1:] {
+			if t > result {
+				result = t
+			}
+		}
+		return result, nil
+	}
+
+	var sum, weightSum float64
+	for i, t := range temps {
+		w := 1.0
+		if s.op == OpWeighted {
+			w = s.inputs[i].Weight
+		}
+		sum += t * w
+		weightSum += w
+	}
+	return sum / weightSum, nil
+}
+
+// Close 关闭所有输入
+func (s *Sensor) Close() error {
+	var errs []string
+	for _, in := range s.inputs {
+		if err := in.Source.Close(); err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", in.Name, err))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("关闭输入失败: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// String 描述虚拟传感器，用于日志
+func (s *Sensor) String() string {
+	names := make([]string, len(s.inputs))
+	for i, in := range s.inputs {
+		names[i] = in.Name
+		if s.op == OpWeighted && in.Weight != 1 {
+			names[i] = fmt.Sprintf("%s×%g", in.Name, in.Weight)
+		}
+	}
+	return s.op + "(" + strings.Join(names, ", ") + ")"
+}
@@ -0,0 +1,140 @@
+package virtual
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// fakeSource 返回固定读数的温度来源
+type fakeSource struct {
+	temp     float64
+	err      error
+	closeErr error
+	closed   int
+}
+
+func (s *fakeSource) GetTemperature() (float64, error) {
+	return s.temp, s.err
+}
+
+func (s *fakeSource) Close() error {
+	s.closed++
+	return s.closeErr
+}
+
+// inputs 按温度创建输入，名称依次为a、b、c...
+func inputs(temps ...float64) []Input {
+	in := make([]Input, len(temps))
+	for i, t := range temps {
+		in[i] = Input{Name: string(rune('a' + i)), Source: &fakeSource{temp: t}}
+	}
+	return in
+}
+
+func TestOps(t *testing.T) {
+	weighted := inputs(40, 60)
+	weighted[0].Weight = 3
+
+	tests := []struct {
+		name   string
+		op     string
+		inputs []Input
+		want   float64
+	}{
+		{"最高温度", OpMax, inputs(45, 62, 50), 62},
+		{"单个输入", OpMax, inputs(45), 45},
+		{"负数中的最高温度", OpMax, inputs(-10, -5), -5},
+		{"平均", OpAvg, inputs(40, 50, 60), 50},
+		{"加权平均", OpWeighted, weighted, 45},
+		{"未指定权重", OpWeighted, inputs(40, 60), 50},
+		{"平均忽略权重", OpAvg, weighted, 50},
+		{"差值", OpDelta, inputs(62, 35), 27},
+		{"负的差值", OpDelta, inputs(30, 35), -5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := New(tt.op, tt.inputs)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := s.GetTemperature()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("GetTemperature() = %v, 期望 %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewErrors(t *testing.T) {
+	negative := inputs(40, 50)
+	negative[1].Weight = -1
+
+	tests := []struct {
+		name   string
+		op     string
+		inputs []Input
+	}{
+		{"未知的组合方式", "min", inputs(40)},
+		{"没有输入", OpMax, nil},
+		{"delta只有一个输入", OpDelta, inputs(40)},
+		{"delta有三个输入", OpDelta, inputs(40, 30, 20)},
+		{"负的权重", OpWeighted, negative},
+	}
+	for _, tt := range tests {
+		if _, err := New(tt.op, tt.inputs); err == nil {
+			t.Errorf("%s: New 应返回错误", tt.name)
+		}
+	}
+}
+
+func TestInputError(t *testing.T) {
+	in := inputs(40, 50)
+	in[1].Source.(*fakeSource).err = errors.New("读取超时")
+
+	s, err := New(OpMax, in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 任一输入失败时整个虚拟传感器失败，错误中包含输入名称
+	_, err = s.GetTemperature()
+	if err == nil || !strings.Contains(err.Error(), "b") || !strings.Contains(err.Error(), "读取超时") {
+		t.Errorf("GetTemperature() 错误 = %v, 期望包含输入名称和原因", err)
+	}
+}
+
+func TestClose(t *testing.T) {
+	in := inputs(40, 50, 60)
+	in[1].Source.(*fakeSource).closeErr = errors.New("忙")
+
+	s, err := New(OpAvg, in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 关闭所有输入，部分失败时返回汇总的错误
+	err = s.Close()
+	if err == nil || !strings.Contains(err.Error(), "b: 忙") {
+		t.Errorf("Close() = %v, 期望包含 b: 忙", err)
+	}
+	for _, input := range in {
+		if c := input.Source.(*fakeSource).closed; c != 1 {
+			t.Errorf("输入 %s 关闭次数 = %d, 期望 1", input.Name, c)
+		}
+	}
+}
+
+func TestString(t *testing.T) {
+	in := inputs(40, 50)
+	in[0].Weight = 2
+	s, err := New(OpWeighted, in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := s.String(), "weighted(a×2, b)"; got != want {
+		t.Errorf("String() = %q, 期望 %q", got, want)
+	}
+}